@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/database"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+// Backfill derived analytics (trips, stops and daily distance) from stored
+// vehicle_locations.
+//
+//	go run cmd/backfill/main.go -vehicle B1234XYZ -from 1700000000 -to 1700086400
+func main() {
+	vehicleID := flag.String("vehicle", "", "vehicle ID to backfill (default: all vehicles)")
+	from := flag.Int64("from", 0, "start unix timestamp (inclusive)")
+	to := flag.Int64("to", time.Now().Unix(), "end unix timestamp (inclusive)")
+	flag.Parse()
+
+	// Initialize logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to initialize logger:", err)
+	}
+	defer logger.Sync()
+
+	// Load configuration
+	cfg, err := config.LoadConfig("./configs")
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	// Initialize database
+	db, err := database.NewConnection(&cfg.Database, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx); err != nil {
+		logger.Fatal("Failed to run migrations", zap.Error(err))
+	}
+
+	// Initialize repositories and services
+	vehicleLocationRepo := repositories.NewVehicleLocationRepository(db.Pool, logger)
+	tripRepo := repositories.NewTripRepository(db.Pool, logger)
+	distanceRepo := repositories.NewDistanceRepository(db.Pool, logger)
+	tripService := services.NewTripService(tripRepo, vehicleLocationRepo, &cfg.Trips, logger)
+	distanceService := services.NewDistanceService(distanceRepo, vehicleLocationRepo, &cfg.Odometer, logger)
+
+	vehicleIDs := []string{*vehicleID}
+	if *vehicleID == "" {
+		vehicleIDs, err = vehicleLocationRepo.GetVehicleIDs(ctx)
+		if err != nil {
+			logger.Fatal("Failed to list vehicles", zap.Error(err))
+		}
+	}
+
+	logger.Info("Starting backfill",
+		zap.Int("vehicle_count", len(vehicleIDs)),
+		zap.Int64("from", *from),
+		zap.Int64("to", *to))
+
+	for _, id := range vehicleIDs {
+		trips, err := tripService.Backfill(ctx, id, *from, *to)
+		if err != nil {
+			logger.Error("Trip backfill failed", zap.Error(err), zap.String("vehicle_id", id))
+			continue
+		}
+		if err := distanceService.Backfill(ctx, id, *from, *to); err != nil {
+			logger.Error("Distance backfill failed", zap.Error(err), zap.String("vehicle_id", id))
+			continue
+		}
+		logger.Info("Vehicle backfilled", zap.String("vehicle_id", id), zap.Int("trips", trips))
+	}
+
+	logger.Info("Backfill completed")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/database"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+// Import a GTFS static feed, replacing the active feed and its stop and
+// corridor geofences.
+//
+//	go run cmd/gtfsimport/main.go -file gtfs.zip -version 2024-06-01
+func main() {
+	file := flag.String("file", "", "path to the GTFS zip archive")
+	version := flag.String("version", "", "feed version label (default: feed_info.txt or archive hash)")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Initialize logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to initialize logger:", err)
+	}
+	defer logger.Sync()
+
+	archive, err := os.ReadFile(*file)
+	if err != nil {
+		logger.Fatal("Failed to read GTFS archive", zap.Error(err), zap.String("file", *file))
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig("./configs")
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	// Initialize database
+	db, err := database.NewConnection(&cfg.Database, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx); err != nil {
+		logger.Fatal("Failed to run migrations", zap.Error(err))
+	}
+
+	gtfsRepo := repositories.NewGTFSRepository(db.Pool, logger)
+	gtfsService := services.NewGTFSService(gtfsRepo, &cfg.GTFS, logger)
+
+	feed, err := gtfsService.Import(ctx, archive, *version)
+	if err != nil {
+		logger.Fatal("GTFS import failed", zap.Error(err))
+	}
+
+	logger.Info("GTFS import completed",
+		zap.Int64("feed_id", feed.ID),
+		zap.String("version", feed.Version),
+		zap.Int("routes", feed.RouteCount),
+		zap.Int("stops", feed.StopCount),
+		zap.Int("trips", feed.TripCount),
+		zap.Int("stop_times", feed.StopTimeCount),
+		zap.Int("shapes", feed.ShapeCount))
+}
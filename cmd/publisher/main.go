@@ -130,12 +130,15 @@ func publishVehicleLocations(publisher *mqtt.LocationPublisher, vehicles []*Vehi
 		timestamp := time.Now().Unix()
 		
 		// Publish location
-		err := publisher.PublishLocation(
-			vehicle.ID,
-			vehicle.Latitude,
-			vehicle.Longitude,
-			timestamp,
-		)
+		speed, heading := vehicle.Speed, vehicle.Direction
+		err := publisher.PublishMessage(mqtt.LocationMessage{
+			VehicleID: vehicle.ID,
+			Latitude:  vehicle.Latitude,
+			Longitude: vehicle.Longitude,
+			Timestamp: timestamp,
+			Speed:     &speed,
+			Heading:   &heading,
+		})
 		
 		if err != nil {
 			logger.Error("Failed to publish vehicle location", 
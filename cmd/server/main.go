@@ -17,11 +17,14 @@ import (
 
 	"github.com/ivanadhi/transjakarta-fleet/internal/config"
 	"github.com/ivanadhi/transjakarta-fleet/internal/database"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
 	"github.com/ivanadhi/transjakarta-fleet/internal/handlers"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
 	"github.com/ivanadhi/transjakarta-fleet/internal/mqtt"
 	"github.com/ivanadhi/transjakarta-fleet/internal/rabbitmq"
 	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
 	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+	"github.com/ivanadhi/transjakarta-fleet/internal/worker"
 	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
 )
 
@@ -56,27 +59,93 @@ func main() {
 	vehicleLocationRepo := repositories.NewVehicleLocationRepository(db.Pool, zapLogger)
 	geofenceRepo := repositories.NewGeofenceRepository(db.Pool, zapLogger)
 	geofenceEventRepo := repositories.NewGeofenceEventRepository(db.Pool, zapLogger)
-
-	// Initialize RabbitMQ client
-	rabbitClient, err := rabbitmq.NewClient(&cfg.RabbitMQ, zapLogger)
+	tripRepo := repositories.NewTripRepository(db.Pool, zapLogger)
+	distanceRepo := repositories.NewDistanceRepository(db.Pool, zapLogger)
+	idleRepo := repositories.NewIdleRepository(db.Pool, zapLogger)
+	vehicleRepo := repositories.NewVehicleRepository(db.Pool, zapLogger)
+	quarantineRepo := repositories.NewQuarantineRepository(db.Pool, zapLogger)
+	gtfsRepo := repositories.NewGTFSRepository(db.Pool, zapLogger)
+	assignmentRepo := repositories.NewTripAssignmentRepository(db.Pool, zapLogger)
+	stopVisitRepo := repositories.NewStopVisitRepository(db.Pool, zapLogger)
+	driverRepo := repositories.NewDriverRepository(db.Pool, zapLogger)
+	shiftRepo := repositories.NewDriverShiftRepository(db.Pool, zapLogger)
+	drivingEventRepo := repositories.NewDrivingEventRepository(db.Pool, zapLogger)
+	outboxRepo := repositories.NewOutboxRepository(db.Pool, zapLogger)
+	processedEventRepo := repositories.NewProcessedEventRepository(db.Pool, zapLogger)
+	webhookRepo := repositories.NewWebhookRepository(db.Pool, zapLogger)
+	notificationRepo := repositories.NewNotificationRepository(db.Pool, zapLogger)
+
+	// Initialize event bus
+	bus, err := eventbus.New(cfg, zapLogger)
 	if err != nil {
-		zapLogger.Fatal("Failed to create RabbitMQ client", zap.Error(err))
+		zapLogger.Fatal("Failed to create event bus", zap.Error(err))
 	}
-	defer rabbitClient.Close()
+	defer bus.Close()
 
-	// Initialize RabbitMQ publisher
-	rabbitPublisher := rabbitmq.NewPublisher(rabbitClient, zapLogger)
+	// Initialize event publisher
+	eventPublisher := eventbus.NewPublisher(bus, cfg.EventBus.Source, zapLogger)
 
 	// Initialize geofence detector
 	geofenceDetector := geofence.NewDetector(geofenceRepo, geofenceEventRepo, zapLogger)
 
 	// Initialize services
-	geofenceService := services.NewGeofenceService(geofenceDetector, rabbitPublisher, zapLogger)
-	locationService := services.NewEnhancedLocationService(vehicleLocationRepo, geofenceService, zapLogger)
+	geocodeService, err := services.NewGeocodeService(&cfg.Geocode, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("Failed to initialize reverse geocoding", zap.Error(err))
+	}
+	outboxRelay := services.NewOutboxRelay(outboxRepo, eventPublisher, &cfg.Outbox, zapLogger)
+	geofenceService := services.NewGeofenceService(geofenceDetector, geofenceRepo, outboxRelay, geocodeService, zapLogger)
+	tripService := services.NewTripService(tripRepo, vehicleLocationRepo, &cfg.Trips, zapLogger)
+	distanceService := services.NewDistanceService(distanceRepo, vehicleLocationRepo, &cfg.Odometer, zapLogger)
+	idleService := services.NewIdleService(idleRepo, geofenceRepo, eventPublisher, &cfg.Idle, zapLogger)
+	registryService := services.NewVehicleRegistryService(vehicleRepo, quarantineRepo, &cfg.Registry, zapLogger)
+	gtfsService := services.NewGTFSService(gtfsRepo, &cfg.GTFS, zapLogger)
+	heartbeatService := services.NewHeartbeatService(vehicleLocationRepo, eventPublisher, &cfg.Heartbeat, zapLogger)
+	assignmentService := services.NewTripAssignmentService(assignmentRepo, gtfsRepo, zapLogger)
+	realtimeService := services.NewGTFSRealtimeService(assignmentRepo, gtfsRepo, heartbeatService, &cfg.GTFS, zapLogger)
+	adherenceService := services.NewScheduleAdherenceService(stopVisitRepo, assignmentRepo, vehicleRepo, gtfsRepo, eventPublisher, &cfg.Adherence, zapLogger)
+	stopVisitService := services.NewStopVisitService(stopVisitRepo, geofenceRepo, adherenceService, &cfg.GTFS, zapLogger)
+	headwayService := services.NewHeadwayService(assignmentRepo, gtfsRepo, adherenceService, eventPublisher, &cfg.Headway, zapLogger)
+	etaService := services.NewETAService(stopVisitRepo, assignmentRepo, gtfsRepo, adherenceService, &cfg.ETA, zapLogger)
+	driverService := services.NewDriverService(driverRepo, shiftRepo, zapLogger)
+	webhookService := services.NewWebhookService(webhookRepo, &cfg.Webhooks, zapLogger)
+	notificationService := services.NewNotificationService(notificationRepo, registryService, &cfg.Notifications, zapLogger)
+	behaviorService := services.NewDrivingBehaviorService(drivingEventRepo, registryService, eventPublisher, &cfg.Behavior, zapLogger)
+	var mapMatchService services.MapMatchService
+	if cfg.MapMatch.Enabled {
+		mapMatchService, err = services.NewMapMatchService(vehicleLocationRepo, &cfg.MapMatch, zapLogger)
+		if err != nil {
+			zapLogger.Fatal("Failed to initialize map matching", zap.Error(err))
+		}
+	}
+	locationService := services.NewEnhancedLocationService(vehicleLocationRepo, geofenceService, mapMatchService, driverService, &cfg.Processing, zapLogger, tripService, distanceService, idleService, heartbeatService, stopVisitService, headwayService, etaService, behaviorService)
 
 	// Initialize handlers
-	vehicleHandler := handlers.NewVehicleHandler(locationService, zapLogger)
-	geofenceHandler := handlers.NewGeofenceHandler(geofenceService, zapLogger)
+	appHandlers := &routeHandlers{
+		vehicle:    handlers.NewVehicleHandler(locationService, heartbeatService, geocodeService, zapLogger),
+		registry:   handlers.NewVehicleRegistryHandler(registryService, zapLogger),
+		geofence:   handlers.NewGeofenceHandler(geofenceService, geocodeService, zapLogger),
+		trip:       handlers.NewTripHandler(tripService, zapLogger),
+		distance:   handlers.NewDistanceHandler(distanceService, zapLogger),
+		idle:       handlers.NewIdleHandler(idleService, zapLogger),
+		gtfs:       handlers.NewGTFSHandler(gtfsService, zapLogger),
+		assignment: handlers.NewTripAssignmentHandler(assignmentService, zapLogger),
+		realtime:   handlers.NewGTFSRealtimeHandler(realtimeService, zapLogger),
+		stopVisit:  handlers.NewStopVisitHandler(stopVisitService, zapLogger),
+		adherence:  handlers.NewScheduleAdherenceHandler(adherenceService, zapLogger),
+		headway:    handlers.NewHeadwayHandler(headwayService, zapLogger),
+		eta:        handlers.NewETAHandler(etaService, zapLogger),
+		driver:     handlers.NewDriverHandler(driverService, zapLogger),
+		behavior:   handlers.NewDrivingBehaviorHandler(behaviorService, zapLogger),
+		webhook:    handlers.NewWebhookHandler(webhookService, zapLogger),
+		rule:       handlers.NewNotificationRuleHandler(notificationService, zapLogger),
+	}
+
+	// Parked messages are kept by the RabbitMQ backend only
+	if rabbitBus, ok := bus.(*eventbus.RabbitMQBus); ok {
+		parkingLot := rabbitmq.NewParkingLot(rabbitBus.Client(), rabbitBus.Publisher(), zapLogger)
+		appHandlers.parkingLot = handlers.NewParkingLotHandler(parkingLot, zapLogger)
+	}
 
 	// Initialize MQTT client
 	mqttClient, err := mqtt.NewClient(&cfg.MQTT, zapLogger)
@@ -91,7 +160,8 @@ func main() {
 	defer mqttClient.Disconnect()
 
 	// Initialize MQTT subscriber
-	locationSubscriber := mqtt.NewLocationSubscriber(mqttClient, locationService, zapLogger)
+	locationSubscriber := mqtt.NewLocationSubscriber(mqttClient, locationService, registryService, zapLogger)
+	driverSubscriber := mqtt.NewDriverSubscriber(mqttClient, driverService, zapLogger)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -99,6 +169,7 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		ErrorHandler: errorHandler,
+		BodyLimit:    cfg.GTFS.MaxUploadSize, // GTFS archives are the largest uploads
 	})
 
 	// Middleware
@@ -113,7 +184,7 @@ func main() {
 	}))
 
 	// Routes
-	setupRoutes(app, vehicleHandler, geofenceHandler, db, mqttClient, rabbitClient)
+	setupRoutes(app, appHandlers, db, mqttClient, cfg.EventBus.Backend, bus, outboxRelay, heartbeatService)
 
 	// Context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -131,6 +202,73 @@ func main() {
 		}
 	}()
 
+	// Start driver login subscriber
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := driverSubscriber.Start(ctx); err != nil {
+			zapLogger.Error("Driver subscriber error", zap.Error(err))
+		}
+	}()
+
+	// Start location processing
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := locationService.Start(ctx); err != nil {
+			zapLogger.Error("Location processing error", zap.Error(err))
+		}
+	}()
+
+	// Start heartbeat monitor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := heartbeatService.Start(ctx); err != nil {
+			zapLogger.Error("Heartbeat monitor error", zap.Error(err))
+		}
+	}()
+
+	// Start headway monitor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := headwayService.Start(ctx); err != nil {
+			zapLogger.Error("Headway monitor error", zap.Error(err))
+		}
+	}()
+
+	// Start ETA predictor
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := etaService.Start(ctx); err != nil {
+			zapLogger.Error("ETA predictor error", zap.Error(err))
+		}
+	}()
+
+	// Start outbox relay
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := outboxRelay.Start(ctx); err != nil {
+			zapLogger.Error("Outbox relay error", zap.Error(err))
+		}
+	}()
+
+	// The in-memory bus only reaches handlers in this process, so the
+	// worker runs here
+	if cfg.EventBus.Backend == eventbus.BackendMemory {
+		geofenceWorker := worker.New(bus, processedEventRepo, webhookService, notificationService, &cfg.Idempotency, zapLogger)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := geofenceWorker.Run(ctx); err != nil {
+				zapLogger.Error("Geofence worker error", zap.Error(err))
+			}
+		}()
+	}
+
 	// Graceful shutdown handler
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -153,7 +291,7 @@ func main() {
 	zapLogger.Info("Server starting with full geofencing integration", 
 		zap.String("address", address),
 		zap.String("mqtt_broker", cfg.MQTT.Broker+":"+cfg.MQTT.Port),
-		zap.String("rabbitmq_url", cfg.RabbitMQ.URL))
+		zap.String("event_bus", cfg.EventBus.Backend))
 	
 	// Start HTTP server in goroutine
 	wg.Add(1)
@@ -169,7 +307,31 @@ func main() {
 	zapLogger.Info("Server stopped gracefully")
 }
 
-func setupRoutes(app *fiber.App, vehicleHandler *handlers.VehicleHandler, geofenceHandler *handlers.GeofenceHandler, db *database.DB, mqttClient *mqtt.Client, rabbitClient *rabbitmq.Client) {
+// routeHandlers groups the HTTP handlers registered by setupRoutes
+type routeHandlers struct {
+	vehicle    *handlers.VehicleHandler
+	registry   *handlers.VehicleRegistryHandler
+	geofence   *handlers.GeofenceHandler
+	trip       *handlers.TripHandler
+	distance   *handlers.DistanceHandler
+	idle       *handlers.IdleHandler
+	gtfs       *handlers.GTFSHandler
+	assignment *handlers.TripAssignmentHandler
+	realtime   *handlers.GTFSRealtimeHandler
+	stopVisit  *handlers.StopVisitHandler
+	adherence  *handlers.ScheduleAdherenceHandler
+	headway    *handlers.HeadwayHandler
+	eta        *handlers.ETAHandler
+	driver     *handlers.DriverHandler
+	behavior   *handlers.DrivingBehaviorHandler
+	parkingLot *handlers.ParkingLotHandler
+	webhook    *handlers.WebhookHandler
+	rule       *handlers.NotificationRuleHandler
+}
+
+func setupRoutes(app *fiber.App, h *routeHandlers, db *database.DB, mqttClient *mqtt.Client, busBackend string, bus eventbus.EventBus, outboxRelay services.OutboxRelay, heartbeatService services.HeartbeatService) {
+	rabbitBus, _ := bus.(*eventbus.RabbitMQBus)
+
 	// Health check
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -181,6 +343,23 @@ func setupRoutes(app *fiber.App, vehicleHandler *handlers.VehicleHandler, geofen
 				"MQTT Integration",
 				"Geofencing Detection",
 				"RabbitMQ Event Processing",
+				"Trip Segmentation",
+				"Odometer & Daily Distance",
+				"Idle Detection",
+				"Offline Monitoring",
+				"Vehicle Registry",
+				"GTFS Static Import",
+				"GTFS-realtime Feeds",
+				"Stop Visits & Dwell Times",
+				"Schedule Adherence",
+				"Headway & Bunching Monitoring",
+				"ETA Prediction",
+				"Map Matching",
+				"Reverse Geocoding",
+				"Driver Shifts & Reports",
+				"Driving Behavior & Safety Scores",
+				"Webhook Subscriptions",
+				"Notification Rules",
 			},
 		})
 	})
@@ -210,14 +389,31 @@ func setupRoutes(app *fiber.App, vehicleHandler *handlers.VehicleHandler, geofen
 			health["status"] = "degraded"
 		}
 
-		// Check RabbitMQ health
-		if rabbitClient.IsConnected() {
-			health["rabbitmq"] = "connected"
+		// Check event bus health, reported under the backend's name
+		if bus.IsConnected() {
+			health[busBackend] = "connected"
+		} else if rabbitBus != nil {
+			health[busBackend] = rabbitBus.Client().Stats().State
+			health["status"] = "degraded"
 		} else {
-			health["rabbitmq"] = "disconnected"
+			health[busBackend] = "disconnected"
 			health["status"] = "degraded"
 		}
 
+		// Vehicle connectivity details
+		online, offline := 0, 0
+		for _, status := range heartbeatService.GetAllStatuses() {
+			if status.Status == models.ConnectionOnline {
+				online++
+			} else {
+				offline++
+			}
+		}
+		health["vehicles"] = fiber.Map{
+			"online":  online,
+			"offline": offline,
+		}
+
 		statusCode := 200
 		if health["status"] == "unhealthy" {
 			statusCode = 503
@@ -228,14 +424,103 @@ func setupRoutes(app *fiber.App, vehicleHandler *handlers.VehicleHandler, geofen
 		return c.Status(statusCode).JSON(health)
 	})
 
+	// GTFS-realtime feeds for journey planners, outside the versioned API
+	app.Get("/gtfs-rt/vehicle-positions", h.realtime.VehiclePositions)
+	app.Get("/gtfs-rt/trip-updates", h.realtime.TripUpdates)
+
 	// API routes
 	api := app.Group("/api/v1")
 	
 	// Vehicle routes
 	vehicles := api.Group("/vehicles")
-	vehicles.Get("/:vehicle_id/location", vehicleHandler.GetLatestLocation)
-	vehicles.Get("/:vehicle_id/history", vehicleHandler.GetLocationHistory)
-	vehicles.Get("/:vehicle_id/geofence-events", geofenceHandler.GetGeofenceEvents)
+	vehicles.Get("/status", h.vehicle.GetConnectionStatuses)
+	vehicles.Get("/:vehicle_id/status", h.vehicle.GetConnectionStatus)
+	vehicles.Get("/:vehicle_id/location", h.vehicle.GetLatestLocation)
+	vehicles.Get("/:vehicle_id/history", h.vehicle.GetLocationHistory)
+	vehicles.Get("/:vehicle_id/geofence-events", h.geofence.GetGeofenceEvents)
+	vehicles.Get("/:vehicle_id/trips", h.trip.GetTrips)
+	vehicles.Get("/:vehicle_id/distance", h.distance.GetVehicleDistance)
+	vehicles.Get("/:vehicle_id/idle", h.idle.GetIdleIntervals)
+	vehicles.Get("/assignments", h.assignment.GetAssignments)
+	vehicles.Get("/:vehicle_id/assignment", h.assignment.GetAssignment)
+	vehicles.Put("/:vehicle_id/assignment", h.assignment.Assign)
+	vehicles.Delete("/:vehicle_id/assignment", h.assignment.Unassign)
+	vehicles.Get("/:vehicle_id/stop-visits", h.stopVisit.GetVehicleStopVisits)
+	vehicles.Get("/:vehicle_id/eta", h.eta.GetVehicleETA)
+	vehicles.Get("/:vehicle_id/shift", h.driver.GetCurrentShift)
+	vehicles.Put("/:vehicle_id/shift", h.driver.StartShift)
+	vehicles.Delete("/:vehicle_id/shift", h.driver.EndShift)
+	vehicles.Get("/:vehicle_id/driving-events", h.behavior.GetVehicleEvents)
+
+	// Vehicle registry routes
+	vehicles.Get("/", h.registry.ListVehicles)
+	vehicles.Post("/", h.registry.CreateVehicle)
+	vehicles.Get("/:vehicle_id", h.registry.GetVehicle)
+	vehicles.Put("/:vehicle_id", h.registry.UpdateVehicle)
+	vehicles.Delete("/:vehicle_id", h.registry.DeleteVehicle)
+	api.Get("/quarantined-locations", h.registry.GetQuarantinedLocations)
+
+	// Geofence routes
+	geofences := api.Group("/geofences")
+	geofences.Get("/", h.geofence.ListGeofences)
+	geofences.Put("/:geofence_id/tags", h.geofence.UpdateGeofenceTags)
+
+	// Driver routes
+	drivers := api.Group("/drivers")
+	drivers.Get("/", h.driver.ListDrivers)
+	drivers.Post("/", h.driver.CreateDriver)
+	drivers.Get("/:driver_id", h.driver.GetDriver)
+	drivers.Put("/:driver_id", h.driver.UpdateDriver)
+	drivers.Delete("/:driver_id", h.driver.DeleteDriver)
+	drivers.Get("/:driver_id/shifts", h.driver.GetDriverShifts)
+	drivers.Get("/:driver_id/report", h.driver.GetDriverReport)
+	drivers.Get("/:driver_id/driving-events", h.behavior.GetDriverEvents)
+	drivers.Get("/:driver_id/safety", h.behavior.GetDriverSafety)
+
+	// Report routes
+	reports := api.Group("/reports")
+	reports.Get("/distance/daily", h.distance.GetFleetDailyReport)
+	reports.Get("/idle", h.idle.GetIdleReport)
+	reports.Get("/dwell", h.stopVisit.GetDwellReport)
+	reports.Get("/otp", h.adherence.GetOnTimeReport)
+	reports.Get("/drivers", h.driver.GetDriverReports)
+	reports.Get("/safety", h.behavior.GetSafetyReport)
+
+	// Stop routes
+	stops := api.Group("/stops")
+	stops.Get("/:stop_id/visits", h.stopVisit.GetStopVisits)
+	stops.Get("/:stop_id/dwell", h.stopVisit.GetStopDwell)
+	stops.Get("/:stop_id/arrivals", h.eta.GetStopArrivals)
+
+	// Corridor routes
+	api.Get("/corridors/:route_id/headways", h.headway.GetCorridorHeadways)
+
+	// GTFS routes
+	gtfs := api.Group("/gtfs")
+	gtfs.Post("/import", h.gtfs.ImportFeed)
+	gtfs.Get("/feeds", h.gtfs.GetFeeds)
+	gtfs.Get("/feeds/:feed_id", h.gtfs.GetFeed)
+	gtfs.Get("/trips/:trip_id/adherence", h.adherence.GetTripAdherence)
+	gtfs.Get("/routes/:route_id/otp", h.adherence.GetRouteOnTimePerformance)
+
+	// Webhook routes
+	webhooks := api.Group("/webhooks")
+	webhooks.Get("/", h.webhook.ListSubscriptions)
+	webhooks.Post("/", h.webhook.CreateSubscription)
+	webhooks.Get("/:webhook_id", h.webhook.GetSubscription)
+	webhooks.Put("/:webhook_id", h.webhook.UpdateSubscription)
+	webhooks.Delete("/:webhook_id", h.webhook.DeleteSubscription)
+	webhooks.Get("/:webhook_id/deliveries", h.webhook.GetDeliveries)
+
+	// Notification rule routes
+	rules := api.Group("/notification-rules")
+	rules.Get("/", h.rule.ListRules)
+	rules.Post("/", h.rule.CreateRule)
+	rules.Get("/:rule_id", h.rule.GetRule)
+	rules.Put("/:rule_id", h.rule.UpdateRule)
+	rules.Delete("/:rule_id", h.rule.DeleteRule)
+	rules.Get("/:rule_id/notifications", h.rule.GetNotifications)
+	api.Get("/notification-channels", h.rule.ListChannels)
 
 	// System status routes
 	api.Get("/mqtt/status", func(c *fiber.Ctx) error {
@@ -245,13 +530,48 @@ func setupRoutes(app *fiber.App, vehicleHandler *handlers.VehicleHandler, geofen
 		})
 	})
 
-	api.Get("/rabbitmq/status", func(c *fiber.Ctx) error {
+	outboxStatus := func(ctx context.Context) fiber.Map {
+		outbox := fiber.Map{}
+		if pending, err := outboxRelay.Pending(ctx); err == nil {
+			outbox["pending"] = pending
+		} else {
+			outbox["error"] = "failed to count pending messages"
+		}
+		return outbox
+	}
+
+	api.Get("/event-bus/status", func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+
 		return c.JSON(fiber.Map{
-			"connected": rabbitClient.IsConnected(),
+			"backend":   busBackend,
+			"connected": bus.IsConnected(),
+			"outbox":    outboxStatus(ctx),
 			"timestamp": time.Now().UTC(),
 		})
 	})
 
+	if rabbitBus != nil {
+		api.Get("/rabbitmq/status", func(c *fiber.Ctx) error {
+			ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+			defer cancel()
+
+			return c.JSON(fiber.Map{
+				"connected":  rabbitBus.IsConnected(),
+				"connection": rabbitBus.Client().Stats(),
+				"publisher":  rabbitBus.Publisher().Stats(),
+				"outbox":     outboxStatus(ctx),
+				"timestamp":  time.Now().UTC(),
+			})
+		})
+
+		// Messages the worker gave up on
+		api.Get("/rabbitmq/parked", h.parkingLot.GetParkedMessages)
+		api.Post("/rabbitmq/parked/replay", h.parkingLot.ReplayParkedMessages)
+		api.Delete("/rabbitmq/parked", h.parkingLot.PurgeParkedMessages)
+	}
+
 	// Statistics endpoint
 	api.Get("/stats", func(c *fiber.Ctx) error {
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			RabbitMQ:  RabbitMQConfig{MaxAttempts: 5},
+			NATS:      NATSConfig{MaxAttempts: 5},
+			MemoryBus: MemoryBusConfig{MaxAttempts: 1},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{
+			name:    "zero rabbitmq attempts",
+			modify:  func(c *Config) { c.RabbitMQ.MaxAttempts = 0 },
+			wantErr: "rabbitmq.max_attempts must be at least 1",
+		},
+		{
+			name:    "negative nats attempts",
+			modify:  func(c *Config) { c.NATS.MaxAttempts = -1 },
+			wantErr: "nats.max_attempts must be at least 1",
+		},
+		{
+			name:    "zero memory bus attempts",
+			modify:  func(c *Config) { c.MemoryBus.MaxAttempts = 0 },
+			wantErr: "memory_bus.max_attempts must be at least 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+
+			err := config.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
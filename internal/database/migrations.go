@@ -92,13 +92,569 @@ var migrations = []Migration{
 			);
 		`,
 	},
+	{
+		Version: 7,
+		Name:    "create_vehicle_trips_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicle_trips (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT NOT NULL,
+				start_latitude DECIMAL(10, 8) NOT NULL,
+				start_longitude DECIMAL(11, 8) NOT NULL,
+				end_latitude DECIMAL(10, 8) NOT NULL,
+				end_longitude DECIMAL(11, 8) NOT NULL,
+				distance_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+				duration_seconds BIGINT NOT NULL DEFAULT 0,
+				max_speed_kmh DOUBLE PRECISION NOT NULL DEFAULT 0,
+				avg_speed_kmh DOUBLE PRECISION NOT NULL DEFAULT 0,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_vehicle_trips_vehicle_start ON vehicle_trips(vehicle_id, start_time DESC);
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "create_vehicle_stops_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicle_stops (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT NOT NULL,
+				latitude DECIMAL(10, 8) NOT NULL,
+				longitude DECIMAL(11, 8) NOT NULL,
+				duration_seconds BIGINT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_vehicle_stops_vehicle_start ON vehicle_stops(vehicle_id, start_time DESC);
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "create_vehicle_odometers_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicle_odometers (
+				vehicle_id VARCHAR(50) PRIMARY KEY,
+				total_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+				last_latitude DECIMAL(10, 8) NOT NULL,
+				last_longitude DECIMAL(11, 8) NOT NULL,
+				last_timestamp BIGINT NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "create_vehicle_daily_distances_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicle_daily_distances (
+				vehicle_id VARCHAR(50) NOT NULL,
+				day DATE NOT NULL,
+				distance_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+				point_count INTEGER NOT NULL DEFAULT 0,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				PRIMARY KEY (vehicle_id, day)
+			);
+			CREATE INDEX IF NOT EXISTS idx_vehicle_daily_distances_day ON vehicle_daily_distances(day);
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "add_ignition_to_vehicle_locations",
+		SQL: `
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS ignition BOOLEAN;
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "add_type_to_geofences",
+		SQL: `
+			ALTER TABLE geofences ADD COLUMN IF NOT EXISTS geofence_type VARCHAR(30) NOT NULL DEFAULT 'landmark';
+			CREATE INDEX IF NOT EXISTS idx_geofences_type ON geofences(geofence_type);
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "create_idle_intervals_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS idle_intervals (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT,
+				duration_seconds BIGINT NOT NULL DEFAULT 0,
+				latitude DECIMAL(10, 8) NOT NULL,
+				longitude DECIMAL(11, 8) NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_idle_intervals_vehicle_start ON idle_intervals(vehicle_id, start_time DESC);
+			CREATE INDEX IF NOT EXISTS idx_idle_intervals_start ON idle_intervals(start_time);
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "create_vehicles_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicles (
+				vehicle_id VARCHAR(50) PRIMARY KEY,
+				plate_number VARCHAR(20) NOT NULL UNIQUE,
+				operator VARCHAR(100) NOT NULL DEFAULT '',
+				corridor VARCHAR(20) NOT NULL DEFAULT '',
+				vehicle_type VARCHAR(20) NOT NULL
+					CHECK (vehicle_type IN ('articulated', 'maxi', 'e-bus', 'mikrotrans')),
+				capacity INTEGER NOT NULL CHECK (capacity > 0),
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_vehicles_corridor ON vehicles(corridor);
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "insert_simulator_vehicles",
+		SQL: `
+			INSERT INTO vehicles (vehicle_id, plate_number, operator, corridor, vehicle_type, capacity) VALUES
+			('B1234XYZ', 'B 1234 XYZ', 'Transjakarta', '1', 'articulated', 150),
+			('B5678ABC', 'B 5678 ABC', 'Transjakarta', '1', 'maxi', 80),
+			('B9012DEF', 'B 9012 DEF', 'Transjakarta', '6', 'e-bus', 60),
+			('B3456GHI', 'B 3456 GHI', 'Transjakarta', '9', 'maxi', 80),
+			('B7890JKL', 'B 7890 JKL', 'Transjakarta', 'JAK.10', 'mikrotrans', 11)
+			ON CONFLICT DO NOTHING;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "create_quarantined_locations_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS quarantined_locations (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				latitude DECIMAL(10, 8) NOT NULL,
+				longitude DECIMAL(11, 8) NOT NULL,
+				timestamp BIGINT NOT NULL,
+				reason VARCHAR(50) NOT NULL,
+				received_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_quarantined_locations_vehicle ON quarantined_locations(vehicle_id);
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "add_gtfs_columns_to_geofences",
+		SQL: `
+			ALTER TABLE geofences ADD COLUMN IF NOT EXISTS path JSONB;
+			ALTER TABLE geofences ADD COLUMN IF NOT EXISTS feed_id BIGINT;
+			ALTER TABLE geofences ADD COLUMN IF NOT EXISTS external_id VARCHAR(100) NOT NULL DEFAULT '';
+			CREATE INDEX IF NOT EXISTS idx_geofences_feed ON geofences(feed_id);
+
+			ALTER TABLE geofence_events DROP CONSTRAINT IF EXISTS geofence_events_geofence_id_fkey;
+			ALTER TABLE geofence_events ADD CONSTRAINT geofence_events_geofence_id_fkey
+				FOREIGN KEY (geofence_id) REFERENCES geofences(id) ON DELETE SET NULL;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "create_gtfs_tables",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS gtfs_feeds (
+				id BIGSERIAL PRIMARY KEY,
+				version VARCHAR(100) NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'importing',
+				error TEXT NOT NULL DEFAULT '',
+				agency_count INTEGER NOT NULL DEFAULT 0,
+				route_count INTEGER NOT NULL DEFAULT 0,
+				stop_count INTEGER NOT NULL DEFAULT 0,
+				trip_count INTEGER NOT NULL DEFAULT 0,
+				stop_time_count INTEGER NOT NULL DEFAULT 0,
+				shape_count INTEGER NOT NULL DEFAULT 0,
+				activated_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_gtfs_feeds_status ON gtfs_feeds(status);
+
+			CREATE TABLE IF NOT EXISTS gtfs_agencies (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				agency_id VARCHAR(100) NOT NULL,
+				agency_name VARCHAR(255) NOT NULL,
+				agency_url VARCHAR(255) NOT NULL DEFAULT '',
+				agency_timezone VARCHAR(50) NOT NULL DEFAULT '',
+				PRIMARY KEY (feed_id, agency_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS gtfs_routes (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				route_id VARCHAR(100) NOT NULL,
+				agency_id VARCHAR(100) NOT NULL DEFAULT '',
+				route_short_name VARCHAR(50) NOT NULL DEFAULT '',
+				route_long_name VARCHAR(255) NOT NULL DEFAULT '',
+				route_type INTEGER NOT NULL,
+				route_color VARCHAR(10) NOT NULL DEFAULT '',
+				PRIMARY KEY (feed_id, route_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS gtfs_stops (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				stop_id VARCHAR(100) NOT NULL,
+				stop_code VARCHAR(50) NOT NULL DEFAULT '',
+				stop_name VARCHAR(255) NOT NULL DEFAULT '',
+				latitude DECIMAL(10, 8) NOT NULL,
+				longitude DECIMAL(11, 8) NOT NULL,
+				location_type INTEGER NOT NULL DEFAULT 0,
+				parent_station VARCHAR(100) NOT NULL DEFAULT '',
+				PRIMARY KEY (feed_id, stop_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS gtfs_trips (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				trip_id VARCHAR(100) NOT NULL,
+				route_id VARCHAR(100) NOT NULL,
+				service_id VARCHAR(100) NOT NULL,
+				trip_headsign VARCHAR(255) NOT NULL DEFAULT '',
+				direction_id INTEGER NOT NULL DEFAULT 0,
+				shape_id VARCHAR(100) NOT NULL DEFAULT '',
+				PRIMARY KEY (feed_id, trip_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_gtfs_trips_route ON gtfs_trips(feed_id, route_id);
+
+			CREATE TABLE IF NOT EXISTS gtfs_stop_times (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				trip_id VARCHAR(100) NOT NULL,
+				stop_sequence INTEGER NOT NULL,
+				stop_id VARCHAR(100) NOT NULL,
+				arrival_seconds INTEGER NOT NULL,
+				departure_seconds INTEGER NOT NULL,
+				shape_dist_traveled DOUBLE PRECISION NOT NULL DEFAULT 0,
+				PRIMARY KEY (feed_id, trip_id, stop_sequence)
+			);
+			CREATE INDEX IF NOT EXISTS idx_gtfs_stop_times_stop ON gtfs_stop_times(feed_id, stop_id);
+
+			CREATE TABLE IF NOT EXISTS gtfs_shapes (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				shape_id VARCHAR(100) NOT NULL,
+				shape_pt_sequence INTEGER NOT NULL,
+				latitude DECIMAL(10, 8) NOT NULL,
+				longitude DECIMAL(11, 8) NOT NULL,
+				shape_dist_traveled DOUBLE PRECISION NOT NULL DEFAULT 0,
+				PRIMARY KEY (feed_id, shape_id, shape_pt_sequence)
+			);
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "create_vehicle_trip_assignments_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS vehicle_trip_assignments (
+				vehicle_id VARCHAR(50) PRIMARY KEY,
+				trip_id VARCHAR(100) NOT NULL,
+				route_id VARCHAR(100) NOT NULL,
+				direction_id INTEGER NOT NULL DEFAULT 0,
+				start_date CHAR(8) NOT NULL,
+				assigned_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_vehicle_trip_assignments_route ON vehicle_trip_assignments(route_id, direction_id);
+		`,
+	},
+	{
+		Version: 20,
+		Name:    "create_stop_visits_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS stop_visits (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				stop_id VARCHAR(100) NOT NULL,
+				stop_name VARCHAR(255) NOT NULL DEFAULT '',
+				route_id VARCHAR(100) NOT NULL DEFAULT '',
+				trip_id VARCHAR(100) NOT NULL DEFAULT '',
+				direction_id INTEGER,
+				start_date VARCHAR(8) NOT NULL DEFAULT '',
+				stop_sequence INTEGER,
+				arrival_time BIGINT NOT NULL,
+				departure_time BIGINT,
+				dwell_seconds BIGINT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_stop_visits_stop_arrival ON stop_visits(stop_id, arrival_time DESC);
+			CREATE INDEX IF NOT EXISTS idx_stop_visits_vehicle_arrival ON stop_visits(vehicle_id, arrival_time DESC);
+			CREATE INDEX IF NOT EXISTS idx_stop_visits_trip ON stop_visits(trip_id, start_date);
+		`,
+	},
+	{
+		Version: 21,
+		Name:    "add_schedule_adherence_to_stop_visits",
+		SQL: `
+			ALTER TABLE stop_visits ADD COLUMN IF NOT EXISTS trip_matched BOOLEAN NOT NULL DEFAULT FALSE;
+			ALTER TABLE stop_visits ADD COLUMN IF NOT EXISTS scheduled_arrival BIGINT;
+			ALTER TABLE stop_visits ADD COLUMN IF NOT EXISTS scheduled_departure BIGINT;
+			ALTER TABLE stop_visits ADD COLUMN IF NOT EXISTS arrival_delay BIGINT;
+			ALTER TABLE stop_visits ADD COLUMN IF NOT EXISTS departure_delay BIGINT;
+			CREATE INDEX IF NOT EXISTS idx_stop_visits_route_arrival ON stop_visits(route_id, arrival_time DESC);
+		`,
+	},
+	{
+		Version: 22,
+		Name:    "add_map_matching_to_vehicle_locations",
+		SQL: `
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS matched_latitude DOUBLE PRECISION;
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS matched_longitude DOUBLE PRECISION;
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS road_id VARCHAR(100);
+		`,
+	},
+	{
+		Version: 23,
+		Name:    "create_drivers_and_shifts_tables",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS drivers (
+				driver_id VARCHAR(50) PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				license_number VARCHAR(50) NOT NULL DEFAULT '',
+				operator VARCHAR(100) NOT NULL DEFAULT '',
+				phone VARCHAR(30) NOT NULL DEFAULT '',
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE TABLE IF NOT EXISTS driver_shifts (
+				id BIGSERIAL PRIMARY KEY,
+				driver_id VARCHAR(50) NOT NULL,
+				vehicle_id VARCHAR(50) NOT NULL,
+				start_time BIGINT NOT NULL,
+				end_time BIGINT,
+				source VARCHAR(10) NOT NULL CHECK (source IN ('api', 'mqtt')),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_driver_shifts_open_vehicle ON driver_shifts(vehicle_id) WHERE end_time IS NULL;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_driver_shifts_open_driver ON driver_shifts(driver_id) WHERE end_time IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_driver_shifts_driver_start ON driver_shifts(driver_id, start_time DESC);
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS driver_id VARCHAR(50);
+			ALTER TABLE geofence_events ADD COLUMN IF NOT EXISTS driver_id VARCHAR(50);
+			ALTER TABLE idle_intervals ADD COLUMN IF NOT EXISTS driver_id VARCHAR(50);
+			CREATE INDEX IF NOT EXISTS idx_geofence_events_driver ON geofence_events(driver_id, timestamp) WHERE driver_id IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_idle_intervals_driver ON idle_intervals(driver_id, start_time) WHERE driver_id IS NOT NULL;
+		`,
+	},
+	{
+		Version: 24,
+		Name:    "create_driving_events_table",
+		SQL: `
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION;
+			ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION;
+			CREATE TABLE IF NOT EXISTS driving_events (
+				id BIGSERIAL PRIMARY KEY,
+				vehicle_id VARCHAR(50) NOT NULL,
+				driver_id VARCHAR(50),
+				event_type VARCHAR(30) NOT NULL CHECK (event_type IN ('harsh_braking', 'harsh_acceleration', 'sharp_cornering')),
+				value DOUBLE PRECISION NOT NULL,
+				speed_kmh DOUBLE PRECISION NOT NULL DEFAULT 0,
+				latitude DOUBLE PRECISION NOT NULL,
+				longitude DOUBLE PRECISION NOT NULL,
+				timestamp BIGINT NOT NULL,
+				source VARCHAR(20) NOT NULL CHECK (source IN ('accelerometer', 'gps')),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_driving_events_vehicle ON driving_events(vehicle_id, timestamp DESC);
+			CREATE INDEX IF NOT EXISTS idx_driving_events_driver ON driving_events(driver_id, timestamp DESC) WHERE driver_id IS NOT NULL;
+		`,
+	},
+	{
+		Version: 25,
+		Name:    "create_outbox_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS outbox (
+				id BIGSERIAL PRIMARY KEY,
+				routing_key VARCHAR(100) NOT NULL,
+				payload JSONB NOT NULL,
+				mandatory BOOLEAN NOT NULL DEFAULT FALSE,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+				sent_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_outbox_sent ON outbox(sent_at) WHERE sent_at IS NOT NULL;
+		`,
+	},
+	{
+		Version: 26,
+		Name:    "create_processed_events_table",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS processed_events (
+				event_id VARCHAR(100) PRIMARY KEY,
+				processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+		`,
+	},
+	{
+		Version: 27,
+		Name:    "create_webhook_tables",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+				id BIGSERIAL PRIMARY KEY,
+				url TEXT NOT NULL,
+				secret VARCHAR(128) NOT NULL,
+				event_types TEXT[] NOT NULL DEFAULT '{}',
+				description TEXT NOT NULL DEFAULT '',
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				consecutive_failures INTEGER NOT NULL DEFAULT 0,
+				disabled_at TIMESTAMP WITH TIME ZONE,
+				disabled_reason TEXT,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id BIGSERIAL PRIMARY KEY,
+				subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+				event_id VARCHAR(100) NOT NULL,
+				event_type VARCHAR(200) NOT NULL,
+				payload JSONB NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT,
+				next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+				completed_at TIMESTAMP WITH TIME ZONE,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				UNIQUE (subscription_id, event_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+			CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id DESC);
+			CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_completed ON webhook_deliveries(completed_at) WHERE completed_at IS NOT NULL;
+			CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+				id BIGSERIAL PRIMARY KEY,
+				delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+				attempt INTEGER NOT NULL,
+				status_code INTEGER,
+				error TEXT,
+				duration_ms INTEGER NOT NULL,
+				attempted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempt);
+		`,
+	},
+	{
+		Version: 28,
+		Name:    "create_notification_rule_tables",
+		SQL: `
+			ALTER TABLE geofences ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+			CREATE INDEX IF NOT EXISTS idx_geofences_tags ON geofences USING GIN(tags);
+			ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS groups TEXT[] NOT NULL DEFAULT '{}';
+			CREATE TABLE IF NOT EXISTS notification_rules (
+				id BIGSERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL UNIQUE,
+				description TEXT NOT NULL DEFAULT '',
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				conditions JSONB NOT NULL DEFAULT '{}',
+				channel VARCHAR(100) NOT NULL,
+				template TEXT NOT NULL,
+				throttle_seconds INTEGER NOT NULL DEFAULT 0,
+				throttle_scope VARCHAR(20) NOT NULL DEFAULT 'vehicle' CHECK (throttle_scope IN ('vehicle', 'rule')),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			);
+			CREATE TABLE IF NOT EXISTS notification_rule_throttles (
+				rule_id BIGINT NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+				throttle_key VARCHAR(100) NOT NULL,
+				last_notified_at TIMESTAMP WITH TIME ZONE NOT NULL,
+				PRIMARY KEY (rule_id, throttle_key)
+			);
+			CREATE TABLE IF NOT EXISTS notifications (
+				id BIGSERIAL PRIMARY KEY,
+				rule_id BIGINT NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+				event_id VARCHAR(100) NOT NULL,
+				event_type VARCHAR(200) NOT NULL,
+				vehicle_id VARCHAR(50) NOT NULL DEFAULT '',
+				channel VARCHAR(100) NOT NULL,
+				message TEXT NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+				error TEXT,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+				sent_at TIMESTAMP WITH TIME ZONE,
+				UNIQUE (rule_id, event_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_notifications_rule ON notifications(rule_id, id DESC);
+			CREATE INDEX IF NOT EXISTS idx_notifications_created ON notifications(created_at);
+
+			-- Replaces the landmark announcements hardcoded in the worker
+			INSERT INTO notification_rules (name, description, conditions, channel, template, throttle_seconds)
+			VALUES (
+				'landmark-arrival',
+				'Announce vehicles arriving at landmarks',
+				'{"event_types": ["geofence.entry"], "geofence_types": ["landmark"]}',
+				'log',
+				'Vehicle {{.VehicleID}} is now at {{.Geofence.Name}}',
+				600
+			)
+			ON CONFLICT (name) DO NOTHING;
+		`,
+	},
+	{
+		Version: 29,
+		Name:    "create_gtfs_calendar_tables",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS gtfs_calendar (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				service_id VARCHAR(100) NOT NULL,
+				monday BOOLEAN NOT NULL,
+				tuesday BOOLEAN NOT NULL,
+				wednesday BOOLEAN NOT NULL,
+				thursday BOOLEAN NOT NULL,
+				friday BOOLEAN NOT NULL,
+				saturday BOOLEAN NOT NULL,
+				sunday BOOLEAN NOT NULL,
+				start_date CHAR(8) NOT NULL,
+				end_date CHAR(8) NOT NULL,
+				PRIMARY KEY (feed_id, service_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS gtfs_calendar_dates (
+				feed_id BIGINT NOT NULL REFERENCES gtfs_feeds(id) ON DELETE CASCADE,
+				service_id VARCHAR(100) NOT NULL,
+				date CHAR(8) NOT NULL,
+				exception_type SMALLINT NOT NULL CHECK (exception_type IN (1, 2)),
+				PRIMARY KEY (feed_id, service_id, date)
+			);
+			CREATE INDEX IF NOT EXISTS idx_gtfs_trips_service ON gtfs_trips(feed_id, service_id);
+		`,
+	},
+	{
+		Version: 30,
+		Name:    "insert_remaining_simulator_vehicles",
+		SQL: `
+			INSERT INTO vehicles (vehicle_id, plate_number, operator, corridor, vehicle_type, capacity) VALUES
+			('B2468MNO', 'B 2468 MNO', 'Transjakarta', '2', 'articulated', 150),
+			('B1357PQR', 'B 1357 PQR', 'Transjakarta', '3', 'maxi', 80),
+			('B8642STU', 'B 8642 STU', 'Transjakarta', '4', 'e-bus', 60),
+			('B9753VWX', 'B 9753 VWX', 'Transjakarta', '6', 'articulated', 150),
+			('B4681YZA', 'B 4681 YZA', 'Transjakarta', 'JAK.10', 'mikrotrans', 11)
+			ON CONFLICT DO NOTHING;
+		`,
+	},
 }
 
+// migrationLockID is the advisory lock key held while migrating
+const migrationLockID = 7203311
+
 func (db *DB) RunMigrations(ctx context.Context) error {
 	db.Logger.Info("Starting database migrations")
 
+	// The server and the worker both migrate at startup; the advisory lock
+	// lets one of them apply pending migrations while the other waits
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migrations: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
 	// Create migrations table first
-	_, err := db.Pool.Exec(ctx, `
+	_, err = db.Pool.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
@@ -0,0 +1,55 @@
+package eventbus
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+// ProcessedStore remembers the events a handler has handled.
+type ProcessedStore interface {
+	IsProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkProcessed(ctx context.Context, eventID string) error
+}
+
+// Deduplicate wraps a handler so an event delivered again is skipped.
+// Events without an id are always handled.
+//
+// The check and the mark are separate from the handler, so a redelivery
+// racing the first delivery on another replica can still be handled twice.
+// Handlers whose effects are database writes should make them through
+// ProcessedEventRepository.ProcessOnce instead, which commits the writes
+// and the mark together.
+func Deduplicate(store ProcessedStore, handler Handler, logger *zap.Logger) Handler {
+	return func(ctx context.Context, event *events.Event) error {
+		if event.ID == "" {
+			return handler(ctx, event)
+		}
+
+		processed, err := store.IsProcessed(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		if processed {
+			logger.Info("Skipping event already processed",
+				zap.String("event_id", event.ID),
+				zap.String("subject", event.Subject))
+			return nil
+		}
+
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+
+		// The handler's effects are done; failing to record them only risks
+		// handling the event again
+		if err := store.MarkProcessed(ctx, event.ID); err != nil {
+			logger.Error("Failed to record processed event",
+				zap.Error(err),
+				zap.String("event_id", event.ID))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+// Package eventbus carries fleet events from the server to the worker over a
+// pluggable broker: RabbitMQ, NATS JetStream or, within a single process,
+// memory.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+// Backends selectable with event_bus.backend
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendNATS     = "nats"
+	BackendMemory   = "memory"
+)
+
+// WorkerTopics matches the topics delivered to subscribers: the worker
+// handles geofence events and sends every event to webhooks. Like AMQP
+// binding keys, * stands for one dot-separated word and # for any number.
+const WorkerTopics = "#"
+
+// ErrUnroutable is returned when a mandatory event matches no subscription
+var ErrUnroutable = errors.New("event unroutable")
+
+// Handler handles an event. Errors wrapping events.ErrRejected are given up
+// on at once; other errors are retried with backoff.
+type Handler func(ctx context.Context, event *events.Event) error
+
+// EventBus publishes events on topics such as geofence.geofence_entry and
+// delivers those matching WorkerTopics to the worker.
+type EventBus interface {
+	// Publish sends an event. A mandatory event fails when the backend can
+	// tell it would not reach the worker.
+	Publish(ctx context.Context, topic string, event *events.Event, mandatory bool) error
+	// Subscribe hands events to handler until the context ends, at least
+	// once each; events are partitioned by subject, so one vehicle's events
+	// are handled in order.
+	Subscribe(ctx context.Context, handler Handler) error
+	IsConnected() bool
+	Close()
+}
+
+// New creates the event bus selected in the configuration.
+func New(cfg *config.Config, logger *zap.Logger) (EventBus, error) {
+	switch cfg.EventBus.Backend {
+	case BackendRabbitMQ:
+		return NewRabbitMQBus(&cfg.RabbitMQ, logger)
+	case BackendNATS:
+		return NewNATSBus(&cfg.NATS, logger)
+	case BackendMemory:
+		return NewMemoryBus(&cfg.MemoryBus, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus backend %q, expected %q, %q or %q",
+			cfg.EventBus.Backend, BackendRabbitMQ, BackendNATS, BackendMemory)
+	}
+}
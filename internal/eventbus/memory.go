@@ -0,0 +1,169 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/partition"
+)
+
+// MemoryBus delivers events to a subscriber in the same process, for tests
+// and single-binary deployments. Events matching WorkerTopics are buffered
+// until they are handled; others are dropped. Nothing survives a restart.
+type MemoryBus struct {
+	config *config.MemoryBusConfig
+	logger *zap.Logger
+
+	queue      chan *memoryDelivery
+	subscribed atomic.Bool
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+type memoryDelivery struct {
+	event    *events.Event
+	attempts int // failed so far
+}
+
+func NewMemoryBus(cfg *config.MemoryBusConfig, logger *zap.Logger) *MemoryBus {
+	return &MemoryBus{
+		config: cfg,
+		logger: logger,
+		queue:  make(chan *memoryDelivery, max(cfg.BufferSize, 1)),
+		closed: make(chan struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, event *events.Event, mandatory bool) error {
+	if !matchTopic(WorkerTopics, topic) {
+		if mandatory {
+			return fmt.Errorf("%w: %s", ErrUnroutable, topic)
+		}
+		return nil
+	}
+
+	// The subscriber must not see later changes to the caller's event
+	copied := *event
+	select {
+	case b.queue <- &memoryDelivery{event: &copied}:
+		return nil
+	case <-b.closed:
+		return errors.New("event bus closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe handles buffered events until the context ends. The bus has a
+// single subscriber.
+func (b *MemoryBus) Subscribe(ctx context.Context, handler Handler) error {
+	if !b.subscribed.CompareAndSwap(false, true) {
+		return errors.New("in-memory event bus already has a subscriber")
+	}
+	defer b.subscribed.Store(false)
+
+	pool := partition.NewPool(ctx, b.config.Workers, b.config.BufferSize)
+	b.logger.Info("Started consuming in-memory events", zap.Int("workers", pool.Workers()))
+
+	func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.closed:
+				return
+			case delivery := <-b.queue:
+				if !pool.Dispatch(ctx, delivery.event.PartitionKey(), func(workCtx context.Context) {
+					b.handle(workCtx, delivery, handler)
+				}) {
+					return
+				}
+			}
+		}
+	}()
+
+	if pool.Stop(b.config.ShutdownTimeout) {
+		b.logger.Warn("In-memory event handlers still running after shutdown timeout, cancelled them")
+	}
+	b.logger.Info("In-memory event consumer stopping")
+	return nil
+}
+
+func (b *MemoryBus) handle(ctx context.Context, delivery *memoryDelivery, handler Handler) {
+	err := handler(ctx, delivery.event)
+	if err == nil {
+		return
+	}
+
+	delivery.attempts++
+	if errors.Is(err, events.ErrRejected) || delivery.attempts >= b.config.MaxAttempts {
+		b.logger.Error("In-memory event dropped",
+			zap.Error(err),
+			zap.String("event_id", delivery.event.ID),
+			zap.String("event_type", delivery.event.Type),
+			zap.Int("attempts", delivery.attempts))
+		return
+	}
+
+	delay := RetryDelay(b.config.RetryDelay, b.config.RetryMaxDelay, delivery.attempts)
+	b.logger.Warn("In-memory event scheduled for retry",
+		zap.Error(err),
+		zap.String("event_id", delivery.event.ID),
+		zap.Int("attempts", delivery.attempts),
+		zap.Duration("delay", delay))
+
+	time.AfterFunc(delay, func() {
+		select {
+		case b.queue <- delivery:
+		case <-b.closed:
+		}
+	})
+}
+
+// IsConnected reports whether the bus is open; there is no broker to lose.
+func (b *MemoryBus) IsConnected() bool {
+	select {
+	case <-b.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *MemoryBus) Close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+// matchTopic reports whether a topic matches an AMQP-style pattern.
+func matchTopic(pattern, topic string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchWords(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(topic); i++ {
+			if matchWords(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && matchWords(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchWords(pattern[1:], topic[1:])
+	}
+}
@@ -0,0 +1,238 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/partition"
+)
+
+// NATSBus stores events in a JetStream stream as structured CloudEvents and
+// delivers them to the worker through a durable consumer. Messages the
+// worker fails on are redelivered with backoff and terminated once out of
+// attempts; they stay in the stream until it expires them.
+type NATSBus struct {
+	config *config.NATSConfig
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	logger *zap.Logger
+}
+
+// NewNATSBus connects to the server and creates or updates the stream.
+func NewNATSBus(cfg *config.NATSConfig, logger *zap.Logger) (*NATSBus, error) {
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name("transjakarta-fleet"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("NATS connection lost", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("NATS reconnected", zap.String("url", conn.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PublishTimeout)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       cfg.Stream,
+		Subjects:   []string{cfg.Subject + ".>"},
+		Storage:    jetstream.FileStorage,
+		MaxAge:     cfg.MaxAge,
+		Duplicates: cfg.DuplicateWindow,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", cfg.Stream, err)
+	}
+
+	logger.Info("Connected to NATS JetStream",
+		zap.String("url", conn.ConnectedUrl()),
+		zap.String("stream", cfg.Stream))
+
+	return &NATSBus{
+		config: cfg,
+		conn:   conn,
+		js:     js,
+		logger: logger,
+	}, nil
+}
+
+// Publish stores an event in the stream. Every topic is stored, so
+// mandatory has no effect. The event id is the message id, so an event
+// published again within the duplicate window is stored once.
+func (b *NATSBus) Publish(ctx context.Context, topic string, event *events.Event, mandatory bool) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	message := &nats.Msg{
+		Subject: b.subject(topic),
+		Header:  nats.Header{"Content-Type": []string{events.ContentType}},
+		Data:    body,
+	}
+
+	var opts []jetstream.PublishOpt
+	if event.ID != "" {
+		opts = append(opts, jetstream.WithMsgID(event.ID))
+	}
+
+	pubCtx, cancel := context.WithTimeout(ctx, b.config.PublishTimeout)
+	defer cancel()
+
+	if _, err := b.js.PublishMsg(pubCtx, message, opts...); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", message.Subject, err)
+	}
+	return nil
+}
+
+func (b *NATSBus) Subscribe(ctx context.Context, handler Handler) error {
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.config.Stream, jetstream.ConsumerConfig{
+		Durable:       b.config.Durable,
+		FilterSubject: b.subject(natsWildcards(WorkerTopics)),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.config.AckWait,
+		MaxDeliver:    b.config.MaxAttempts,
+		MaxAckPending: b.config.MaxAckPending,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to create consumer %s: %w", b.config.Durable, err)
+	}
+
+	pool := partition.NewPool(ctx, b.config.Workers, b.config.MaxAckPending)
+
+	// Messages arrive one at a time; dispatching blocks while the vehicle's
+	// partition is full
+	consumeContext, err := consumer.Consume(func(message jetstream.Msg) {
+		event, err := decodeNATSMessage(message)
+		if err != nil {
+			b.logger.Error("Failed to parse NATS message, terminating it",
+				zap.Error(err),
+				zap.String("subject", message.Subject()))
+			message.TermWithReason(events.TruncateReason(err.Error()))
+			return
+		}
+
+		// Not dispatched messages are redelivered after the ack wait
+		pool.Dispatch(ctx, event.PartitionKey(), func(workCtx context.Context) {
+			b.handle(workCtx, message, event, handler)
+		})
+	}, jetstream.PullMaxMessages(max(b.config.MaxAckPending, 1)))
+	if err != nil {
+		pool.Stop(0)
+		return fmt.Errorf("failed to start consuming: %w", err)
+	}
+
+	b.logger.Info("Started consuming NATS messages",
+		zap.String("stream", b.config.Stream),
+		zap.String("consumer", b.config.Durable),
+		zap.Int("workers", pool.Workers()))
+
+	<-ctx.Done()
+	consumeContext.Stop()
+	<-consumeContext.Closed()
+
+	if pool.Stop(b.config.ShutdownTimeout) {
+		b.logger.Warn("NATS handlers still running after shutdown timeout, cancelled them")
+	}
+	b.logger.Info("NATS consumer stopping")
+	return nil
+}
+
+func (b *NATSBus) handle(ctx context.Context, message jetstream.Msg, event *events.Event, handler Handler) {
+	err := handler(ctx, event)
+	if err == nil {
+		if err := message.Ack(); err != nil {
+			b.logger.Error("Failed to acknowledge NATS message", zap.Error(err))
+		}
+		return
+	}
+
+	attempts := 1
+	if metadata, metaErr := message.Metadata(); metaErr == nil {
+		attempts = int(metadata.NumDelivered)
+	}
+
+	if errors.Is(err, events.ErrRejected) || attempts >= b.config.MaxAttempts {
+		b.logger.Error("NATS message terminated",
+			zap.Error(err),
+			zap.String("event_id", event.ID),
+			zap.String("event_type", event.Type),
+			zap.Int("attempts", attempts))
+		if err := message.TermWithReason(events.TruncateReason(err.Error())); err != nil {
+			b.logger.Error("Failed to terminate NATS message", zap.Error(err))
+		}
+		return
+	}
+
+	delay := RetryDelay(b.config.RetryDelay, b.config.RetryMaxDelay, attempts)
+	b.logger.Warn("NATS message scheduled for retry",
+		zap.Error(err),
+		zap.String("event_id", event.ID),
+		zap.Int("attempts", attempts),
+		zap.Duration("delay", delay))
+	if err := message.NakWithDelay(delay); err != nil {
+		b.logger.Error("Failed to reject NATS message", zap.Error(err))
+	}
+}
+
+func (b *NATSBus) IsConnected() bool {
+	return b.conn.IsConnected()
+}
+
+func (b *NATSBus) Close() {
+	b.conn.Close()
+}
+
+func (b *NATSBus) subject(topic string) string {
+	return b.config.Subject + "." + topic
+}
+
+// natsWildcards turns an AMQP-style topic pattern into a NATS subject
+// pattern, where > stands for the remaining words.
+func natsWildcards(pattern string) string {
+	words := strings.Split(pattern, ".")
+	for i, word := range words {
+		if word == "#" {
+			words[i] = ">"
+		}
+	}
+	return strings.Join(words, ".")
+}
+
+func decodeNATSMessage(message jetstream.Msg) (*events.Event, error) {
+	var event events.Event
+	if err := json.Unmarshal(message.Data(), &event); err != nil {
+		return nil, fmt.Errorf("invalid structured event: %w", err)
+	}
+	if event.SpecVersion == "" {
+		return nil, errors.New("structured event without specversion")
+	}
+	if event.ID == "" {
+		event.ID = message.Headers().Get(jetstream.MsgIDHeader)
+	}
+	return &event, nil
+}
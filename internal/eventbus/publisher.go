@@ -0,0 +1,85 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+// Publisher builds events from fleet messages and publishes them on a bus.
+type Publisher struct {
+	bus    EventBus
+	source string
+	logger *zap.Logger
+}
+
+// NewPublisher creates a publisher stamping events with the given
+// CloudEvents source.
+func NewPublisher(bus EventBus, source string, logger *zap.Logger) *Publisher {
+	return &Publisher{
+		bus:    bus,
+		source: source,
+		logger: logger,
+	}
+}
+
+func (p *Publisher) PublishGeofenceEvent(ctx context.Context, message *events.GeofenceEventMessage) error {
+	topic := message.Topic()
+
+	event, err := events.New(ctx, topic, message.VehicleID, time.Unix(message.Timestamp, 0), message)
+	if err != nil {
+		return fmt.Errorf("failed to build geofence event: %w", err)
+	}
+
+	// Geofence events must reach the worker, so unroutable ones fail
+	if err := p.PublishEvent(ctx, topic, event, true); err != nil {
+		p.logger.Error("Failed to publish geofence event",
+			zap.Error(err),
+			zap.String("vehicle_id", message.VehicleID),
+			zap.String("event", message.Event))
+		return fmt.Errorf("failed to publish geofence event: %w", err)
+	}
+
+	p.logger.Info("Geofence event published successfully",
+		zap.String("vehicle_id", message.VehicleID),
+		zap.String("event", message.Event),
+		zap.String("topic", topic))
+
+	return nil
+}
+
+func (p *Publisher) PublishVehicleEvent(ctx context.Context, message *events.VehicleEventMessage) error {
+	topic := message.Topic()
+
+	event, err := events.New(ctx, topic, message.VehicleID, time.Unix(message.Timestamp, 0), message)
+	if err != nil {
+		return fmt.Errorf("failed to build vehicle event: %w", err)
+	}
+
+	if err := p.PublishEvent(ctx, topic, event, false); err != nil {
+		p.logger.Error("Failed to publish vehicle event",
+			zap.Error(err),
+			zap.String("vehicle_id", message.VehicleID),
+			zap.String("event", message.Event))
+		return fmt.Errorf("failed to publish vehicle event: %w", err)
+	}
+
+	p.logger.Info("Vehicle event published successfully",
+		zap.String("vehicle_id", message.VehicleID),
+		zap.String("event", message.Event),
+		zap.String("topic", topic))
+
+	return nil
+}
+
+// PublishEvent sends a prepared event, filling in the source if it has none.
+func (p *Publisher) PublishEvent(ctx context.Context, topic string, event *events.Event, mandatory bool) error {
+	if event.Source == "" {
+		event.Source = p.source
+	}
+	return p.bus.Publish(ctx, topic, event, mandatory)
+}
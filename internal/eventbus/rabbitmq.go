@@ -0,0 +1,61 @@
+package eventbus
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/rabbitmq"
+)
+
+// RabbitMQBus publishes events to the fleet exchange and consumes the
+// worker queue, retrying failed messages through delay queues and parking
+// those out of attempts.
+type RabbitMQBus struct {
+	client    *rabbitmq.Client
+	publisher *rabbitmq.Publisher
+	consumer  *rabbitmq.Consumer
+}
+
+func NewRabbitMQBus(cfg *config.RabbitMQConfig, logger *zap.Logger) (*RabbitMQBus, error) {
+	client, err := rabbitmq.NewClient(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher := rabbitmq.NewPublisher(client, logger)
+	return &RabbitMQBus{
+		client:    client,
+		publisher: publisher,
+		consumer:  rabbitmq.NewConsumer(client, publisher, logger),
+	}, nil
+}
+
+func (b *RabbitMQBus) Publish(ctx context.Context, topic string, event *events.Event, mandatory bool) error {
+	return b.publisher.PublishEvent(ctx, topic, event, mandatory)
+}
+
+func (b *RabbitMQBus) Subscribe(ctx context.Context, handler Handler) error {
+	return b.consumer.StartConsuming(ctx, rabbitmq.MessageHandler(handler))
+}
+
+func (b *RabbitMQBus) IsConnected() bool {
+	return b.client.IsConnected()
+}
+
+func (b *RabbitMQBus) Close() {
+	b.client.Close()
+}
+
+// Client returns the underlying connection, for status reporting.
+func (b *RabbitMQBus) Client() *rabbitmq.Client {
+	return b.client
+}
+
+// Publisher returns the underlying publisher, e.g. to replay parked
+// messages.
+func (b *RabbitMQBus) Publisher() *rabbitmq.Publisher {
+	return b.publisher
+}
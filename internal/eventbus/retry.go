@@ -0,0 +1,13 @@
+package eventbus
+
+import "time"
+
+// RetryDelay is the delay before the attempt after the given failed one,
+// doubling from base up to limit. Webhook deliveries back off the same way.
+func RetryDelay(base, limit time.Duration, failed int) time.Duration {
+	delay := base
+	for i := 1; i < failed && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,134 @@
+// Package events defines the fleet events exchanged over the event bus and
+// their CloudEvents envelope, independent of the broker carrying them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SpecVersion = "1.0"
+	// ContentType is the media type of an event in structured mode
+	ContentType = "application/cloudevents+json"
+
+	// typePrefix and schemaVersion name event types, e.g.
+	// id.transjakarta.fleet.geofence.entry.v1
+	typePrefix    = "id.transjakarta.fleet."
+	schemaVersion = 1
+)
+
+// ErrRejected marks events that cannot succeed however often they are
+// retried, such as malformed or unsupported ones. Buses give up on them
+// without retrying.
+var ErrRejected = errors.New("event rejected")
+
+// Event is a CloudEvents 1.0 envelope. CorrelationID is an extension
+// attribute shared by the events raised from one location update.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a context whose events carry the given
+// correlation id.
+func ContextWithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// New wraps data in an envelope with a new id. The subject is the vehicle
+// the event is about and occurred the time it happened. The source is left
+// to the publisher.
+func New(ctx context.Context, topic, subject string, occurred time.Time, data interface{}) (*Event, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Type:            Type(topic),
+		Subject:         subject,
+		Time:            occurred.UTC(),
+		DataContentType: "application/json",
+		CorrelationID:   correlationID,
+		Data:            body,
+	}, nil
+}
+
+// PartitionKey is the vehicle whose events must be handled in order: the
+// subject or, for events without one, the vehicle_id in the data. Events
+// without a readable vehicle share the empty key.
+func (e *Event) PartitionKey() string {
+	if e.Subject != "" {
+		return e.Subject
+	}
+
+	var data struct {
+		VehicleID string `json:"vehicle_id"`
+	}
+	json.Unmarshal(e.Data, &data)
+	return data.VehicleID
+}
+
+// TruncateReason shortens the error recorded with a failed event to what
+// brokers keep in a header or termination reason, without splitting a
+// multi-byte character.
+func TruncateReason(reason string) string {
+	const maxLength = 512
+	if len(reason) <= maxLength {
+		return reason
+	}
+	end := maxLength
+	for end > 0 && !utf8.RuneStart(reason[end]) {
+		end--
+	}
+	return reason[:end]
+}
+
+// Type names the events published on a topic, e.g. geofence.geofence_entry
+// is id.transjakarta.fleet.geofence.entry.v1.
+func Type(topic string) string {
+	category, event, _ := strings.Cut(topic, ".")
+	event = strings.TrimPrefix(event, category+"_")
+	return fmt.Sprintf("%s%s.%s.v%d", typePrefix, category, event, schemaVersion)
+}
+
+// IsCategory reports whether the event is of the current schema version in
+// a category such as "geofence".
+func (e *Event) IsCategory(category string) bool {
+	return strings.HasPrefix(e.Type, typePrefix+category+".") &&
+		strings.HasSuffix(e.Type, fmt.Sprintf(".v%d", schemaVersion))
+}
+
+// Name returns the event type without its prefix and version, e.g.
+// geofence.entry, or the whole type for events from other producers.
+func (e *Event) Name() string {
+	name, ok := strings.CutPrefix(e.Type, typePrefix)
+	if !ok {
+		return e.Type
+	}
+	if i := strings.LastIndex(name, ".v"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		wantLen int
+	}{
+		{name: "short", reason: "connection refused", wantLen: 18},
+		{name: "exactly the limit", reason: strings.Repeat("a", 512), wantLen: 512},
+		{name: "ascii over the limit", reason: strings.Repeat("a", 600), wantLen: 512},
+		// "é" is two bytes, the 512th byte starts one
+		{name: "does not split a character", reason: strings.Repeat("a", 511) + "é" + "b", wantLen: 511},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateReason(tt.reason)
+			if len(got) != tt.wantLen {
+				t.Errorf("length = %d, want %d", len(got), tt.wantLen)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncated reason is not valid UTF-8")
+			}
+			if !strings.HasPrefix(tt.reason, got) {
+				t.Errorf("truncated reason is not a prefix")
+			}
+		})
+	}
+}
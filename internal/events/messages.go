@@ -0,0 +1,81 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type GeofenceEventMessage struct {
+	VehicleID    string   `json:"vehicle_id"`
+	Event        string   `json:"event"`
+	Location     Location `json:"location"`
+	Timestamp    int64    `json:"timestamp"`
+	GeofenceName string   `json:"geofence_name,omitempty"`
+	GeofenceID   int64    `json:"geofence_id,omitempty"`
+	GeofenceType string   `json:"geofence_type,omitempty"`
+	GeofenceTags []string `json:"geofence_tags,omitempty"`
+	Distance     float64  `json:"distance,omitempty"`
+	DriverID     string   `json:"driver_id,omitempty"`
+	Address      *Address `json:"address,omitempty"`
+}
+
+// Topic returns the topic geofence events are published on.
+func (m *GeofenceEventMessage) Topic() string {
+	return fmt.Sprintf("geofence.%s", m.Event)
+}
+
+// VehicleEventMessage carries vehicle state events (idle, connectivity, ...)
+// that are not tied to a geofence.
+type VehicleEventMessage struct {
+	VehicleID string                 `json:"vehicle_id"`
+	Event     string                 `json:"event"`
+	Location  Location               `json:"location"`
+	Timestamp int64                  `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Topic returns the topic vehicle events are published on.
+func (m *VehicleEventMessage) Topic() string {
+	return fmt.Sprintf("vehicle.%s", m.Event)
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Address names where an event happened, when reverse geocoding is enabled.
+type Address struct {
+	Street    string `json:"street,omitempty"`
+	Kelurahan string `json:"kelurahan,omitempty"`
+	Kecamatan string `json:"kecamatan,omitempty"`
+	City      string `json:"city,omitempty"`
+}
+
+// DecodeGeofenceEvent reads the data of a geofence event. Other event types
+// and malformed data are rejected.
+func DecodeGeofenceEvent(event *Event) (*GeofenceEventMessage, error) {
+	if !event.IsCategory("geofence") {
+		return nil, fmt.Errorf("%w: unsupported event type %q", ErrRejected, event.Type)
+	}
+
+	var message GeofenceEventMessage
+	if err := json.Unmarshal(event.Data, &message); err != nil {
+		return nil, fmt.Errorf("%w: invalid geofence event data: %v", ErrRejected, err)
+	}
+	return &message, nil
+}
+
+// DecodeVehicleEvent reads the data of a vehicle event. Other event types
+// and malformed data are rejected.
+func DecodeVehicleEvent(event *Event) (*VehicleEventMessage, error) {
+	if !event.IsCategory("vehicle") {
+		return nil, fmt.Errorf("%w: unsupported event type %q", ErrRejected, event.Type)
+	}
+
+	var message VehicleEventMessage
+	if err := json.Unmarshal(event.Data, &message); err != nil {
+		return nil, fmt.Errorf("%w: invalid vehicle event data: %v", ErrRejected, err)
+	}
+	return &message, nil
+}
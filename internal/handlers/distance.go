@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+const dateLayout = "2006-01-02"
+
+type DistanceHandler struct {
+	distanceService services.DistanceService
+	logger          *zap.Logger
+}
+
+func NewDistanceHandler(distanceService services.DistanceService, logger *zap.Logger) *DistanceHandler {
+	return &DistanceHandler{
+		distanceService: distanceService,
+		logger:          logger,
+	}
+}
+
+func (h *DistanceHandler) GetVehicleDistance(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	// Both dates default to today
+	today := time.Now().Format(dateLayout)
+	to := c.Query("to", today)
+	from := c.Query("from", to)
+
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid from date format, expected YYYY-MM-DD",
+		})
+	}
+
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid to date format, expected YYYY-MM-DD",
+		})
+	}
+
+	if fromDate.After(toDate) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "from must not be after to",
+		})
+	}
+
+	ctx := c.Context()
+	report, err := h.distanceService.GetDistance(ctx, vehicleID, from, to)
+	if err != nil {
+		h.logger.Error("Failed to get vehicle distance",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get vehicle distance",
+		})
+	}
+
+	return c.JSON(report)
+}
+
+func (h *DistanceHandler) GetFleetDailyReport(c *fiber.Ctx) error {
+	date := c.Query("date", time.Now().Format(dateLayout))
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid date format, expected YYYY-MM-DD",
+		})
+	}
+
+	ctx := c.Context()
+	report, err := h.distanceService.GetFleetDailyReport(ctx, date)
+	if err != nil {
+		h.logger.Error("Failed to get fleet distance report",
+			zap.Error(err),
+			zap.String("date", date))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get fleet distance report",
+		})
+	}
+
+	return c.JSON(report)
+}
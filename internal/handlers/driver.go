@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type DriverHandler struct {
+	driverService services.DriverService
+	logger        *zap.Logger
+}
+
+type driverRequest struct {
+	DriverID      string `json:"driver_id"`
+	Name          string `json:"name"`
+	LicenseNumber string `json:"license_number"`
+	Operator      string `json:"operator"`
+	Phone         string `json:"phone"`
+	Active        *bool  `json:"active"`
+}
+
+type shiftRequest struct {
+	DriverID  string `json:"driver_id"`
+	StartTime int64  `json:"start_time"`
+}
+
+func NewDriverHandler(driverService services.DriverService, logger *zap.Logger) *DriverHandler {
+	return &DriverHandler{
+		driverService: driverService,
+		logger:        logger,
+	}
+}
+
+func (h *DriverHandler) ListDrivers(c *fiber.Ctx) error {
+	ctx := c.Context()
+	drivers, err := h.driverService.ListDrivers(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list drivers", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list drivers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":   len(drivers),
+		"drivers": drivers,
+	})
+}
+
+func (h *DriverHandler) GetDriver(c *fiber.Ctx) error {
+	ctx := c.Context()
+	driver, err := h.driverService.GetDriver(ctx, c.Params("driver_id"))
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driver")
+	}
+
+	return c.JSON(driver)
+}
+
+func (h *DriverHandler) CreateDriver(c *fiber.Ctx) error {
+	var req driverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	driver := req.toModel(req.DriverID)
+
+	ctx := c.Context()
+	if err := h.driverService.CreateDriver(ctx, driver); err != nil {
+		return h.respondError(c, err, "Failed to create driver")
+	}
+
+	return c.Status(201).JSON(driver)
+}
+
+func (h *DriverHandler) UpdateDriver(c *fiber.Ctx) error {
+	var req driverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	driver := req.toModel(c.Params("driver_id"))
+
+	ctx := c.Context()
+	if err := h.driverService.UpdateDriver(ctx, driver); err != nil {
+		return h.respondError(c, err, "Failed to update driver")
+	}
+
+	return c.JSON(driver)
+}
+
+func (h *DriverHandler) DeleteDriver(c *fiber.Ctx) error {
+	ctx := c.Context()
+	if err := h.driverService.DeleteDriver(ctx, c.Params("driver_id")); err != nil {
+		return h.respondError(c, err, "Failed to delete driver")
+	}
+
+	return c.SendStatus(204)
+}
+
+func (h *DriverHandler) GetCurrentShift(c *fiber.Ctx) error {
+	ctx := c.Context()
+	shift, err := h.driverService.GetCurrentShift(ctx, c.Params("vehicle_id"))
+	if err != nil {
+		return h.respondError(c, err, "Failed to get shift")
+	}
+
+	return c.JSON(shift)
+}
+
+func (h *DriverHandler) StartShift(c *fiber.Ctx) error {
+	var req shiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ctx := c.Context()
+	shift, err := h.driverService.StartShift(ctx, c.Params("vehicle_id"), req.DriverID, req.StartTime, models.ShiftSourceAPI)
+	if err != nil {
+		return h.respondError(c, err, "Failed to start shift")
+	}
+
+	return c.JSON(shift)
+}
+
+func (h *DriverHandler) EndShift(c *fiber.Ctx) error {
+	ctx := c.Context()
+	shift, err := h.driverService.EndShift(ctx, c.Params("vehicle_id"), 0)
+	if err != nil {
+		return h.respondError(c, err, "Failed to end shift")
+	}
+
+	return c.JSON(shift)
+}
+
+func (h *DriverHandler) GetDriverShifts(c *fiber.Ctx) error {
+	driverID := c.Params("driver_id")
+
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	shifts, err := h.driverService.GetDriverShifts(ctx, driverID, startTime, endTime)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driver shifts")
+	}
+
+	return c.JSON(fiber.Map{
+		"driver_id": driverID,
+		"start":     startTime,
+		"end":       endTime,
+		"count":     len(shifts),
+		"shifts":    shifts,
+	})
+}
+
+func (h *DriverHandler) GetDriverReport(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	report, err := h.driverService.GetDriverReport(ctx, c.Params("driver_id"), startTime, endTime)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driver report")
+	}
+
+	return c.JSON(fiber.Map{
+		"start":  startTime,
+		"end":    endTime,
+		"report": report,
+	})
+}
+
+func (h *DriverHandler) GetDriverReports(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	reports, err := h.driverService.GetDriverReports(ctx, startTime, endTime)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driver reports")
+	}
+
+	return c.JSON(fiber.Map{
+		"start":   startTime,
+		"end":     endTime,
+		"count":   len(reports),
+		"drivers": reports,
+	})
+}
+
+func (h *DriverHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidDriver):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Driver or shift not found",
+		})
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Driver already registered",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func (r *driverRequest) toModel(driverID string) *models.Driver {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return &models.Driver{
+		DriverID:      driverID,
+		Name:          r.Name,
+		LicenseNumber: r.LicenseNumber,
+		Operator:      r.Operator,
+		Phone:         r.Phone,
+		Active:        active,
+	}
+}
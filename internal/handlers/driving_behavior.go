@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type DrivingBehaviorHandler struct {
+	behaviorService services.DrivingBehaviorService
+	logger          *zap.Logger
+}
+
+func NewDrivingBehaviorHandler(behaviorService services.DrivingBehaviorService, logger *zap.Logger) *DrivingBehaviorHandler {
+	return &DrivingBehaviorHandler{
+		behaviorService: behaviorService,
+		logger:          logger,
+	}
+}
+
+func (h *DrivingBehaviorHandler) GetVehicleEvents(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	events, err := h.behaviorService.GetVehicleEvents(ctx, vehicleID, startTime, endTime, parseLimit(c, 100, 1000))
+	if err != nil {
+		h.logger.Error("Failed to get driving events",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get driving events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"vehicle_id": vehicleID,
+		"count":      len(events),
+		"events":     events,
+	})
+}
+
+func (h *DrivingBehaviorHandler) GetDriverEvents(c *fiber.Ctx) error {
+	driverID := c.Params("driver_id")
+
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context()
+	events, err := h.behaviorService.GetDriverEvents(ctx, driverID, startTime, endTime, parseLimit(c, 100, 1000))
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driving events")
+	}
+
+	return c.JSON(fiber.Map{
+		"driver_id": driverID,
+		"count":     len(events),
+		"events":    events,
+	})
+}
+
+// GetDriverSafety returns daily safety scores for the `from` to `to` days,
+// defaulting to the last seven days.
+func (h *DrivingBehaviorHandler) GetDriverSafety(c *fiber.Ctx) error {
+	driverID := c.Params("driver_id")
+
+	to := c.Query("to", time.Now().Format(dateLayout))
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid to date format, expected YYYY-MM-DD",
+		})
+	}
+
+	from := c.Query("from", toDate.AddDate(0, 0, -6).Format(dateLayout))
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid from date format, expected YYYY-MM-DD",
+		})
+	}
+
+	if fromDate.After(toDate) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "from must not be after to",
+		})
+	}
+
+	ctx := c.Context()
+	scores, err := h.behaviorService.GetDriverScores(ctx, driverID, from, to)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get driver safety scores")
+	}
+
+	return c.JSON(fiber.Map{
+		"driver_id": driverID,
+		"from":      from,
+		"to":        to,
+		"days":      scores,
+	})
+}
+
+func (h *DrivingBehaviorHandler) GetSafetyReport(c *fiber.Ctx) error {
+	date := c.Query("date", time.Now().Format(dateLayout))
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid date format, expected YYYY-MM-DD",
+		})
+	}
+
+	ctx := c.Context()
+	scores, err := h.behaviorService.GetDailyScores(ctx, date)
+	if err != nil {
+		h.logger.Error("Failed to get safety report",
+			zap.Error(err),
+			zap.String("date", date))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get safety report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"date":    date,
+		"count":   len(scores),
+		"drivers": scores,
+	})
+}
+
+func (h *DrivingBehaviorHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, services.ErrInvalidDriver) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
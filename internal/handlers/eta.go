@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type ETAHandler struct {
+	etaService services.ETAService
+	logger     *zap.Logger
+}
+
+func NewETAHandler(etaService services.ETAService, logger *zap.Logger) *ETAHandler {
+	return &ETAHandler{
+		etaService: etaService,
+		logger:     logger,
+	}
+}
+
+func (h *ETAHandler) GetVehicleETA(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	ctx := c.Context()
+	predictions, err := h.etaService.GetVehicleETA(ctx, vehicleID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "vehicle is not running a known trip",
+		})
+	}
+	if err != nil {
+		h.logger.Error("Failed to predict vehicle arrivals",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to predict vehicle arrivals",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"vehicle_id":   vehicleID,
+		"generated_at": time.Now().Unix(),
+		"count":        len(predictions),
+		"stops":        predictions,
+	})
+}
+
+func (h *ETAHandler) GetStopArrivals(c *fiber.Ctx) error {
+	stopID := c.Params("stop_id")
+	if stopID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "stop_id is required",
+		})
+	}
+
+	ctx := c.Context()
+	arrivals, err := h.etaService.GetStopArrivals(ctx, stopID, parseLimit(c, 10, 50))
+	if err != nil {
+		h.logger.Error("Failed to predict stop arrivals",
+			zap.Error(err),
+			zap.String("stop_id", stopID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to predict stop arrivals",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stop_id":      stopID,
+		"generated_at": time.Now().Unix(),
+		"count":        len(arrivals),
+		"arrivals":     arrivals,
+	})
+}
@@ -1,22 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
 	"github.com/ivanadhi/transjakarta-fleet/internal/services"
 )
 
 type GeofenceHandler struct {
 	geofenceService services.GeofenceService
+	geocodeService  services.GeocodeService
 	logger          *zap.Logger
 }
 
-func NewGeofenceHandler(geofenceService services.GeofenceService, logger *zap.Logger) *GeofenceHandler {
+type geofenceTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func NewGeofenceHandler(geofenceService services.GeofenceService, geocodeService services.GeocodeService, logger *zap.Logger) *GeofenceHandler {
 	return &GeofenceHandler{
 		geofenceService: geofenceService,
+		geocodeService:  geocodeService,
 		logger:          logger,
 	}
 }
@@ -50,9 +58,73 @@ func (h *GeofenceHandler) GetGeofenceEvents(c *fiber.Ctx) error {
 		})
 	}
 
+	for _, event := range events {
+		event.Address = h.geocodeService.ReverseGeocode(event.Latitude, event.Longitude)
+	}
+
 	return c.JSON(fiber.Map{
 		"vehicle_id": vehicleID,
 		"count":      len(events),
 		"events":     events,
 	})
-}
\ No newline at end of file
+}
+
+func (h *GeofenceHandler) ListGeofences(c *fiber.Ctx) error {
+	geofenceType := c.Query("type")
+
+	ctx := c.Context()
+	geofences, err := h.geofenceService.ListGeofences(ctx, geofenceType)
+	if err != nil {
+		h.logger.Error("Failed to list geofences", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list geofences",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":     len(geofences),
+		"geofences": geofences,
+	})
+}
+
+func (h *GeofenceHandler) UpdateGeofenceTags(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("geofence_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid geofence_id",
+		})
+	}
+
+	var req geofenceTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ctx := c.Context()
+	geofence, err := h.geofenceService.UpdateGeofenceTags(ctx, id, req.Tags)
+	if err != nil {
+		return h.respondError(c, err, "Failed to update geofence tags")
+	}
+
+	return c.JSON(geofence)
+}
+
+func (h *GeofenceHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidGeofence):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Geofence not found",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
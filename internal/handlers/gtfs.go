@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type GTFSHandler struct {
+	gtfsService services.GTFSService
+	logger      *zap.Logger
+}
+
+func NewGTFSHandler(gtfsService services.GTFSService, logger *zap.Logger) *GTFSHandler {
+	return &GTFSHandler{
+		gtfsService: gtfsService,
+		logger:      logger,
+	}
+}
+
+// ImportFeed accepts a GTFS zip as the multipart field "feed" and imports it
+// in the background; poll the returned feed for its final status.
+func (h *GTFSHandler) ImportFeed(c *fiber.Ctx) error {
+	header, err := c.FormFile("feed")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "multipart file field 'feed' is required",
+		})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "failed to read uploaded feed",
+		})
+	}
+	defer file.Close()
+
+	archive, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "failed to read uploaded feed",
+		})
+	}
+
+	feed, err := h.gtfsService.StartImport(archive, c.FormValue("version"))
+	if err != nil {
+		h.logger.Error("Failed to start GTFS import", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to start GTFS import",
+		})
+	}
+
+	return c.Status(202).JSON(feed)
+}
+
+func (h *GTFSHandler) GetFeeds(c *fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100 // Cap at 100 for performance
+	}
+
+	ctx := c.Context()
+	feeds, err := h.gtfsService.GetFeeds(ctx, limit)
+	if err != nil {
+		h.logger.Error("Failed to get GTFS feeds", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get GTFS feeds",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(feeds),
+		"feeds": feeds,
+	})
+}
+
+func (h *GTFSHandler) GetFeed(c *fiber.Ctx) error {
+	feedID, err := strconv.ParseInt(c.Params("feed_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid feed_id",
+		})
+	}
+
+	ctx := c.Context()
+	feed, err := h.gtfsService.GetFeed(ctx, feedID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "GTFS feed not found",
+		})
+	}
+	if err != nil {
+		h.logger.Error("Failed to get GTFS feed", zap.Error(err), zap.Int64("feed_id", feedID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get GTFS feed",
+		})
+	}
+
+	return c.JSON(feed)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+
+	gtfsrt "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type GTFSRealtimeHandler struct {
+	realtimeService services.GTFSRealtimeService
+	logger          *zap.Logger
+}
+
+func NewGTFSRealtimeHandler(realtimeService services.GTFSRealtimeService, logger *zap.Logger) *GTFSRealtimeHandler {
+	return &GTFSRealtimeHandler{
+		realtimeService: realtimeService,
+		logger:          logger,
+	}
+}
+
+func (h *GTFSRealtimeHandler) VehiclePositions(c *fiber.Ctx) error {
+	return h.serve(c, "vehicle positions", h.realtimeService.VehiclePositions)
+}
+
+func (h *GTFSRealtimeHandler) TripUpdates(c *fiber.Ctx) error {
+	return h.serve(c, "trip updates", h.realtimeService.TripUpdates)
+}
+
+// serve writes the feed as protobuf, or as JSON for debugging when the
+// request has ?format=json.
+func (h *GTFSRealtimeHandler) serve(c *fiber.Ctx, name string, build func(ctx context.Context) (*gtfsrt.FeedMessage, error)) error {
+	ctx := c.Context()
+	feed, err := build(ctx)
+	if err != nil {
+		h.logger.Error("Failed to build GTFS-realtime feed", zap.Error(err), zap.String("feed", name))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to build GTFS-realtime " + name,
+		})
+	}
+
+	if c.Query("format") == "json" {
+		body, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true}.Marshal(feed)
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(body)
+	}
+
+	body, err := proto.Marshal(feed)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "application/x-protobuf")
+	return c.Send(body)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type HeadwayHandler struct {
+	headwayService services.HeadwayService
+	logger         *zap.Logger
+}
+
+func NewHeadwayHandler(headwayService services.HeadwayService, logger *zap.Logger) *HeadwayHandler {
+	return &HeadwayHandler{
+		headwayService: headwayService,
+		logger:         logger,
+	}
+}
+
+func (h *HeadwayHandler) GetCorridorHeadways(c *fiber.Ctx) error {
+	routeID := c.Params("route_id")
+	if routeID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "route_id is required",
+		})
+	}
+
+	directions := h.headwayService.GetCorridorHeadways(routeID)
+
+	return c.JSON(fiber.Map{
+		"route_id":   routeID,
+		"count":      len(directions),
+		"directions": directions,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type IdleHandler struct {
+	idleService services.IdleService
+	logger      *zap.Logger
+}
+
+func NewIdleHandler(idleService services.IdleService, logger *zap.Logger) *IdleHandler {
+	return &IdleHandler{
+		idleService: idleService,
+		logger:      logger,
+	}
+}
+
+func (h *IdleHandler) GetIdleIntervals(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	intervals, err := h.idleService.GetIdleIntervals(ctx, vehicleID, startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get idle intervals",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get idle intervals",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"vehicle_id": vehicleID,
+		"count":      len(intervals),
+		"intervals":  intervals,
+	})
+}
+
+func (h *IdleHandler) GetIdleReport(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	summaries, err := h.idleService.GetIdleSummary(ctx, startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get idle report", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get idle report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"start":    startTime,
+		"end":      endTime,
+		"count":    len(summaries),
+		"vehicles": summaries,
+	})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type NotificationRuleHandler struct {
+	notificationService services.NotificationService
+	logger              *zap.Logger
+}
+
+type notificationRuleRequest struct {
+	Name            string                `json:"name"`
+	Description     string                `json:"description"`
+	Enabled         *bool                 `json:"enabled"`
+	Conditions      models.RuleConditions `json:"conditions"`
+	Channel         string                `json:"channel"`
+	Template        string                `json:"template"`
+	ThrottleSeconds int                   `json:"throttle_seconds"`
+	ThrottleScope   string                `json:"throttle_scope"`
+}
+
+func NewNotificationRuleHandler(notificationService services.NotificationService, logger *zap.Logger) *NotificationRuleHandler {
+	return &NotificationRuleHandler{
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+func (h *NotificationRuleHandler) ListRules(c *fiber.Ctx) error {
+	ctx := c.Context()
+	rules, err := h.notificationService.ListRules(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list notification rules", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list notification rules",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(rules),
+		"rules": rules,
+	})
+}
+
+func (h *NotificationRuleHandler) GetRule(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("rule_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid rule_id",
+		})
+	}
+
+	ctx := c.Context()
+	rule, err := h.notificationService.GetRule(ctx, id)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get notification rule")
+	}
+
+	return c.JSON(rule)
+}
+
+func (h *NotificationRuleHandler) CreateRule(c *fiber.Ctx) error {
+	var req notificationRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	rule := req.toModel(0)
+
+	ctx := c.Context()
+	if err := h.notificationService.CreateRule(ctx, rule); err != nil {
+		return h.respondError(c, err, "Failed to create notification rule")
+	}
+
+	return c.Status(201).JSON(rule)
+}
+
+func (h *NotificationRuleHandler) UpdateRule(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("rule_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid rule_id",
+		})
+	}
+
+	var req notificationRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	rule := req.toModel(id)
+
+	ctx := c.Context()
+	if err := h.notificationService.UpdateRule(ctx, rule); err != nil {
+		return h.respondError(c, err, "Failed to update notification rule")
+	}
+
+	return c.JSON(rule)
+}
+
+func (h *NotificationRuleHandler) DeleteRule(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("rule_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid rule_id",
+		})
+	}
+
+	ctx := c.Context()
+	if err := h.notificationService.DeleteRule(ctx, id); err != nil {
+		return h.respondError(c, err, "Failed to delete notification rule")
+	}
+
+	return c.SendStatus(204)
+}
+
+func (h *NotificationRuleHandler) GetNotifications(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("rule_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid rule_id",
+		})
+	}
+
+	limit := parseLimit(c, 50, 500)
+
+	ctx := c.Context()
+	notifications, err := h.notificationService.GetNotifications(ctx, id, limit)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get notifications")
+	}
+
+	return c.JSON(fiber.Map{
+		"rule_id":       id,
+		"count":         len(notifications),
+		"notifications": notifications,
+	})
+}
+
+func (h *NotificationRuleHandler) ListChannels(c *fiber.Ctx) error {
+	channels := h.notificationService.ListChannels()
+
+	return c.JSON(fiber.Map{
+		"count":    len(channels),
+		"channels": channels,
+	})
+}
+
+func (h *NotificationRuleHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidNotificationRule):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Notification rule not found",
+		})
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Notification rule name already in use",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func (r *notificationRuleRequest) toModel(id int64) *models.NotificationRule {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+
+	return &models.NotificationRule{
+		ID:              id,
+		Name:            r.Name,
+		Description:     r.Description,
+		Enabled:         enabled,
+		Conditions:      r.Conditions,
+		Channel:         r.Channel,
+		Template:        r.Template,
+		ThrottleSeconds: r.ThrottleSeconds,
+		ThrottleScope:   r.ThrottleScope,
+	}
+}
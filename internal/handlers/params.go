@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseTimeRange reads optional unix `start` and `end` query parameters,
+// defaulting to the last window up to now.
+func parseTimeRange(c *fiber.Ctx, window time.Duration) (int64, int64, error) {
+	now := time.Now()
+
+	endTime, err := strconv.ParseInt(c.Query("end", strconv.FormatInt(now.Unix(), 10)), 10, 64)
+	if err != nil {
+		return 0, 0, fiber.NewError(400, "invalid end time format")
+	}
+
+	startTime, err := strconv.ParseInt(c.Query("start", strconv.FormatInt(endTime-int64(window.Seconds()), 10)), 10, 64)
+	if err != nil {
+		return 0, 0, fiber.NewError(400, "invalid start time format")
+	}
+
+	if startTime > endTime {
+		return 0, 0, fiber.NewError(400, "start must not be after end")
+	}
+
+	return startTime, endTime, nil
+}
+
+// parseLimit reads the optional `limit` query parameter, falling back to def
+// when missing or invalid and capping at max for performance.
+func parseLimit(c *fiber.Ctx, def, max int) int {
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(def)))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
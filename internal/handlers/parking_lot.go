@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/rabbitmq"
+)
+
+// ParkingLotHandler exposes the messages the worker gave up on
+type ParkingLotHandler struct {
+	parkingLot *rabbitmq.ParkingLot
+	logger     *zap.Logger
+}
+
+func NewParkingLotHandler(parkingLot *rabbitmq.ParkingLot, logger *zap.Logger) *ParkingLotHandler {
+	return &ParkingLotHandler{
+		parkingLot: parkingLot,
+		logger:     logger,
+	}
+}
+
+func (h *ParkingLotHandler) GetParkedMessages(c *fiber.Ctx) error {
+	count, err := h.parkingLot.Count()
+	if err != nil {
+		return h.respondError(c, err, "Failed to inspect parked messages")
+	}
+
+	messages, err := h.parkingLot.Inspect(parseLimit(c, 50, 500))
+	if err != nil {
+		return h.respondError(c, err, "Failed to inspect parked messages")
+	}
+
+	return c.JSON(fiber.Map{
+		"count":    count,
+		"messages": messages,
+	})
+}
+
+// ReplayParkedMessages replays up to `limit` parked messages, or only those
+// with the `message_id` query parameter.
+func (h *ParkingLotHandler) ReplayParkedMessages(c *fiber.Ctx) error {
+	ctx := c.Context()
+	replayed, err := h.parkingLot.Replay(ctx, c.Query("message_id"), parseLimit(c, 100, 10000))
+	if err != nil {
+		h.logger.Error("Failed to replay parked messages",
+			zap.Error(err),
+			zap.Int("replayed", replayed))
+		return c.Status(500).JSON(fiber.Map{
+			"error":    "Failed to replay parked messages",
+			"replayed": replayed,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"replayed": replayed,
+	})
+}
+
+func (h *ParkingLotHandler) PurgeParkedMessages(c *fiber.Ctx) error {
+	purged, err := h.parkingLot.Purge()
+	if err != nil {
+		return h.respondError(c, err, "Failed to purge parked messages")
+	}
+
+	return c.JSON(fiber.Map{
+		"purged": purged,
+	})
+}
+
+func (h *ParkingLotHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
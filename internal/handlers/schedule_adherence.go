@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type ScheduleAdherenceHandler struct {
+	adherenceService services.ScheduleAdherenceService
+	logger           *zap.Logger
+}
+
+func NewScheduleAdherenceHandler(adherenceService services.ScheduleAdherenceService, logger *zap.Logger) *ScheduleAdherenceHandler {
+	return &ScheduleAdherenceHandler{
+		adherenceService: adherenceService,
+		logger:           logger,
+	}
+}
+
+func (h *ScheduleAdherenceHandler) GetTripAdherence(c *fiber.Ctx) error {
+	tripID := c.Params("trip_id")
+	if tripID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "trip_id is required",
+		})
+	}
+
+	startDate := c.Query("start_date")
+	if startDate != "" {
+		if _, err := time.Parse("20060102", startDate); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "start_date must be YYYYMMDD",
+			})
+		}
+	}
+
+	ctx := c.Context()
+	performance, visits, err := h.adherenceService.GetTripAdherence(ctx, tripID, startDate)
+	if err != nil {
+		h.logger.Error("Failed to get trip adherence",
+			zap.Error(err),
+			zap.String("trip_id", tripID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get trip adherence",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"performance": performance,
+		"visits":      visits,
+	})
+}
+
+func (h *ScheduleAdherenceHandler) GetRouteOnTimePerformance(c *fiber.Ctx) error {
+	routeID := c.Params("route_id")
+	if routeID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "route_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	results, err := h.adherenceService.GetRouteOnTimePerformance(ctx, routeID, startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get route on-time performance",
+			zap.Error(err),
+			zap.String("route_id", routeID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get route on-time performance",
+		})
+	}
+
+	performance := &models.OnTimePerformance{RouteID: routeID}
+	if len(results) > 0 {
+		performance = results[0]
+	}
+
+	return c.JSON(fiber.Map{
+		"start":       startTime,
+		"end":         endTime,
+		"performance": performance,
+	})
+}
+
+func (h *ScheduleAdherenceHandler) GetOnTimeReport(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	results, err := h.adherenceService.GetRouteOnTimePerformance(ctx, "", startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get on-time performance report", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get on-time performance report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"start":  startTime,
+		"end":    endTime,
+		"count":  len(results),
+		"routes": results,
+	})
+}
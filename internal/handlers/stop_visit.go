@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type StopVisitHandler struct {
+	visitService services.StopVisitService
+	logger       *zap.Logger
+}
+
+func NewStopVisitHandler(visitService services.StopVisitService, logger *zap.Logger) *StopVisitHandler {
+	return &StopVisitHandler{
+		visitService: visitService,
+		logger:       logger,
+	}
+}
+
+func (h *StopVisitHandler) GetStopVisits(c *fiber.Ctx) error {
+	stopID := c.Params("stop_id")
+	if stopID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "stop_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	visits, err := h.visitService.GetStopVisits(ctx, stopID, startTime, endTime, parseLimit(c, 100, 1000))
+	if err != nil {
+		h.logger.Error("Failed to get stop visits",
+			zap.Error(err),
+			zap.String("stop_id", stopID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get stop visits",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stop_id": stopID,
+		"count":   len(visits),
+		"visits":  visits,
+	})
+}
+
+func (h *StopVisitHandler) GetStopDwell(c *fiber.Ctx) error {
+	stopID := c.Params("stop_id")
+	if stopID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "stop_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	stats, err := h.visitService.GetStopDwell(ctx, stopID, startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get stop dwell statistics",
+			zap.Error(err),
+			zap.String("stop_id", stopID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get stop dwell statistics",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"start": startTime,
+		"end":   endTime,
+		"dwell": stats,
+	})
+}
+
+func (h *StopVisitHandler) GetVehicleStopVisits(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	visits, err := h.visitService.GetVehicleStopVisits(ctx, vehicleID, startTime, endTime, parseLimit(c, 100, 1000))
+	if err != nil {
+		h.logger.Error("Failed to get vehicle stop visits",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get vehicle stop visits",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"vehicle_id": vehicleID,
+		"count":      len(visits),
+		"visits":     visits,
+	})
+}
+
+// GetDwellReport ranks stops by average dwell time, optionally for one route,
+// so planners can spot congested halte.
+func (h *StopVisitHandler) GetDwellReport(c *fiber.Ctx) error {
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	routeID := c.Query("route_id")
+
+	ctx := c.Context()
+	stats, err := h.visitService.GetDwellReport(ctx, routeID, startTime, endTime, parseLimit(c, 20, 500))
+	if err != nil {
+		h.logger.Error("Failed to get dwell report", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get dwell report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"start":    startTime,
+		"end":      endTime,
+		"route_id": routeID,
+		"count":    len(stats),
+		"stops":    stats,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type TripHandler struct {
+	tripService services.TripService
+	logger      *zap.Logger
+}
+
+func NewTripHandler(tripService services.TripService, logger *zap.Logger) *TripHandler {
+	return &TripHandler{
+		tripService: tripService,
+		logger:      logger,
+	}
+}
+
+func (h *TripHandler) GetTrips(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	// Parse optional time range, defaults to everything up to now
+	startTime, err := strconv.ParseInt(c.Query("start", "0"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid start time format",
+		})
+	}
+
+	endTime, err := strconv.ParseInt(c.Query("end", strconv.FormatInt(time.Now().Unix(), 10)), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid end time format",
+		})
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500 // Cap at 500 for performance
+	}
+
+	ctx := c.Context()
+	trips, err := h.tripService.GetTrips(ctx, vehicleID, startTime, endTime, limit)
+	if err != nil {
+		h.logger.Error("Failed to get trips",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get trips",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"vehicle_id": vehicleID,
+		"count":      len(trips),
+		"trips":      trips,
+	})
+}
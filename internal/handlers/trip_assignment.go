@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type TripAssignmentHandler struct {
+	assignmentService services.TripAssignmentService
+	logger            *zap.Logger
+}
+
+type assignmentRequest struct {
+	TripID    string `json:"trip_id"`
+	StartDate string `json:"start_date"`
+}
+
+func NewTripAssignmentHandler(assignmentService services.TripAssignmentService, logger *zap.Logger) *TripAssignmentHandler {
+	return &TripAssignmentHandler{
+		assignmentService: assignmentService,
+		logger:            logger,
+	}
+}
+
+func (h *TripAssignmentHandler) GetAssignment(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+
+	ctx := c.Context()
+	assignment, err := h.assignmentService.GetAssignment(ctx, vehicleID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get trip assignment")
+	}
+
+	return c.JSON(assignment)
+}
+
+func (h *TripAssignmentHandler) GetAssignments(c *fiber.Ctx) error {
+	ctx := c.Context()
+	assignments, err := h.assignmentService.GetAssignments(ctx)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get trip assignments")
+	}
+
+	return c.JSON(fiber.Map{
+		"count":       len(assignments),
+		"assignments": assignments,
+	})
+}
+
+func (h *TripAssignmentHandler) Assign(c *fiber.Ctx) error {
+	var req assignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ctx := c.Context()
+	assignment, err := h.assignmentService.Assign(ctx, c.Params("vehicle_id"), req.TripID, req.StartDate)
+	if err != nil {
+		return h.respondError(c, err, "Failed to assign trip")
+	}
+
+	return c.JSON(assignment)
+}
+
+func (h *TripAssignmentHandler) Unassign(c *fiber.Ctx) error {
+	ctx := c.Context()
+	if err := h.assignmentService.Unassign(ctx, c.Params("vehicle_id")); err != nil {
+		return h.respondError(c, err, "Failed to remove trip assignment")
+	}
+
+	return c.SendStatus(204)
+}
+
+func (h *TripAssignmentHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAssignment):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Trip assignment not found",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
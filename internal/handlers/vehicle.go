@@ -6,18 +6,23 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
 	"github.com/ivanadhi/transjakarta-fleet/internal/services"
 )
 
 type VehicleHandler struct {
-	locationService services.LocationService
-	logger          *zap.Logger
+	locationService  services.LocationService
+	heartbeatService services.HeartbeatService
+	geocodeService   services.GeocodeService
+	logger           *zap.Logger
 }
 
-func NewVehicleHandler(locationService services.LocationService, logger *zap.Logger) *VehicleHandler {
+func NewVehicleHandler(locationService services.LocationService, heartbeatService services.HeartbeatService, geocodeService services.GeocodeService, logger *zap.Logger) *VehicleHandler {
 	return &VehicleHandler{
-		locationService: locationService,
-		logger:          logger,
+		locationService:  locationService,
+		heartbeatService: heartbeatService,
+		geocodeService:   geocodeService,
+		logger:           logger,
 	}
 }
 
@@ -40,11 +45,62 @@ func (h *VehicleHandler) GetLatestLocation(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"vehicle_id": location.VehicleID,
 		"latitude":   location.Latitude,
 		"longitude":  location.Longitude,
 		"timestamp":  location.Timestamp,
+	}
+	if address := h.geocodeService.ReverseGeocode(location.Latitude, location.Longitude); address != nil {
+		response["address"] = address
+	}
+
+	if connection, ok := h.heartbeatService.GetStatus(vehicleID); ok {
+		response["connection"] = fiber.Map{
+			"status":        connection.Status,
+			"last_seen":     connection.LastSeen,
+			"offline_since": connection.OfflineSince,
+		}
+	}
+
+	return c.JSON(response)
+}
+
+func (h *VehicleHandler) GetConnectionStatus(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+	if vehicleID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "vehicle_id is required",
+		})
+	}
+
+	connection, ok := h.heartbeatService.GetStatus(vehicleID)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Vehicle has never reported",
+		})
+	}
+	connection.Address = h.geocodeService.ReverseGeocode(connection.Latitude, connection.Longitude)
+
+	return c.JSON(connection)
+}
+
+func (h *VehicleHandler) GetConnectionStatuses(c *fiber.Ctx) error {
+	statuses := h.heartbeatService.GetAllStatuses()
+
+	online := 0
+	for _, status := range statuses {
+		status.Address = h.geocodeService.ReverseGeocode(status.Latitude, status.Longitude)
+		if status.Status == models.ConnectionOnline {
+			online++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"count":    len(statuses),
+		"online":   online,
+		"offline":  len(statuses) - online,
+		"vehicles": statuses,
 	})
 }
 
@@ -100,6 +156,9 @@ func (h *VehicleHandler) GetLocationHistory(c *fiber.Ctx) error {
 			"longitude":  location.Longitude,
 			"timestamp":  location.Timestamp,
 		}
+		if address := h.geocodeService.ReverseGeocode(location.Latitude, location.Longitude); address != nil {
+			result[i]["address"] = address
+		}
 	}
 
 	return c.JSON(fiber.Map{
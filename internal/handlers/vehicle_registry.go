@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type VehicleRegistryHandler struct {
+	registryService services.VehicleRegistryService
+	logger          *zap.Logger
+}
+
+type vehicleRequest struct {
+	VehicleID   string   `json:"vehicle_id"`
+	PlateNumber string   `json:"plate_number"`
+	Operator    string   `json:"operator"`
+	Corridor    string   `json:"corridor"`
+	VehicleType string   `json:"vehicle_type"`
+	Capacity    int      `json:"capacity"`
+	Active      *bool    `json:"active"`
+	Groups      []string `json:"groups"`
+}
+
+func NewVehicleRegistryHandler(registryService services.VehicleRegistryService, logger *zap.Logger) *VehicleRegistryHandler {
+	return &VehicleRegistryHandler{
+		registryService: registryService,
+		logger:          logger,
+	}
+}
+
+func (h *VehicleRegistryHandler) ListVehicles(c *fiber.Ctx) error {
+	ctx := c.Context()
+	vehicles, err := h.registryService.ListVehicles(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list vehicles", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list vehicles",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":    len(vehicles),
+		"vehicles": vehicles,
+	})
+}
+
+func (h *VehicleRegistryHandler) GetVehicle(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+
+	ctx := c.Context()
+	vehicle, err := h.registryService.GetVehicle(ctx, vehicleID)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get vehicle")
+	}
+
+	return c.JSON(vehicle)
+}
+
+func (h *VehicleRegistryHandler) CreateVehicle(c *fiber.Ctx) error {
+	var req vehicleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	vehicle := req.toModel(req.VehicleID)
+
+	ctx := c.Context()
+	if err := h.registryService.CreateVehicle(ctx, vehicle); err != nil {
+		return h.respondError(c, err, "Failed to create vehicle")
+	}
+
+	return c.Status(201).JSON(vehicle)
+}
+
+func (h *VehicleRegistryHandler) UpdateVehicle(c *fiber.Ctx) error {
+	var req vehicleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	vehicle := req.toModel(c.Params("vehicle_id"))
+
+	ctx := c.Context()
+	if err := h.registryService.UpdateVehicle(ctx, vehicle); err != nil {
+		return h.respondError(c, err, "Failed to update vehicle")
+	}
+
+	return c.JSON(vehicle)
+}
+
+func (h *VehicleRegistryHandler) DeleteVehicle(c *fiber.Ctx) error {
+	vehicleID := c.Params("vehicle_id")
+
+	ctx := c.Context()
+	if err := h.registryService.DeleteVehicle(ctx, vehicleID); err != nil {
+		return h.respondError(c, err, "Failed to delete vehicle")
+	}
+
+	return c.SendStatus(204)
+}
+
+func (h *VehicleRegistryHandler) GetQuarantinedLocations(c *fiber.Ctx) error {
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500 // Cap at 500 for performance
+	}
+
+	ctx := c.Context()
+	locations, err := h.registryService.GetQuarantinedLocations(ctx, limit)
+	if err != nil {
+		h.logger.Error("Failed to get quarantined locations", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to get quarantined locations",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":     len(locations),
+		"locations": locations,
+	})
+}
+
+func (h *VehicleRegistryHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidVehicle):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Vehicle not found",
+		})
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Vehicle or plate number already registered",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func (r *vehicleRequest) toModel(vehicleID string) *models.Vehicle {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return &models.Vehicle{
+		VehicleID:   vehicleID,
+		PlateNumber: r.PlateNumber,
+		Operator:    r.Operator,
+		Corridor:    r.Corridor,
+		VehicleType: r.VehicleType,
+		Capacity:    r.Capacity,
+		Active:      active,
+		Groups:      r.Groups,
+	}
+}
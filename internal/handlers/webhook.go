@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type WebhookHandler struct {
+	webhookService services.WebhookService
+	logger         *zap.Logger
+}
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+func NewWebhookHandler(webhookService services.WebhookService, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *fiber.Ctx) error {
+	ctx := c.Context()
+	subscriptions, err := h.webhookService.ListSubscriptions(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list webhook subscriptions", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list webhook subscriptions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"count":         len(subscriptions),
+		"subscriptions": subscriptions,
+	})
+}
+
+func (h *WebhookHandler) GetSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid webhook_id",
+		})
+	}
+
+	ctx := c.Context()
+	subscription, err := h.webhookService.GetSubscription(ctx, id)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get webhook subscription")
+	}
+
+	return c.JSON(subscription)
+}
+
+func (h *WebhookHandler) CreateSubscription(c *fiber.Ctx) error {
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	subscription := req.toModel(0)
+
+	ctx := c.Context()
+	if err := h.webhookService.CreateSubscription(ctx, subscription); err != nil {
+		return h.respondError(c, err, "Failed to create webhook subscription")
+	}
+
+	// The only response carrying a generated secret
+	return c.Status(201).JSON(subscription)
+}
+
+func (h *WebhookHandler) UpdateSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid webhook_id",
+		})
+	}
+
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	subscription := req.toModel(id)
+
+	ctx := c.Context()
+	if err := h.webhookService.UpdateSubscription(ctx, subscription); err != nil {
+		return h.respondError(c, err, "Failed to update webhook subscription")
+	}
+
+	return c.JSON(subscription)
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid webhook_id",
+		})
+	}
+
+	ctx := c.Context()
+	if err := h.webhookService.DeleteSubscription(ctx, id); err != nil {
+		return h.respondError(c, err, "Failed to delete webhook subscription")
+	}
+
+	return c.SendStatus(204)
+}
+
+func (h *WebhookHandler) GetDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("webhook_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid webhook_id",
+		})
+	}
+
+	limit := parseLimit(c, 50, 500)
+
+	ctx := c.Context()
+	deliveries, err := h.webhookService.GetDeliveries(ctx, id, limit)
+	if err != nil {
+		return h.respondError(c, err, "Failed to get webhook deliveries")
+	}
+
+	return c.JSON(fiber.Map{
+		"subscription_id": id,
+		"count":           len(deliveries),
+		"deliveries":      deliveries,
+	})
+}
+
+func (h *WebhookHandler) respondError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repositories.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Webhook subscription not found",
+		})
+	}
+
+	h.logger.Error(message, zap.Error(err))
+	return c.Status(500).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func (r *webhookRequest) toModel(id int64) *models.WebhookSubscription {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+
+	return &models.WebhookSubscription{
+		ID:          id,
+		URL:         r.URL,
+		Secret:      r.Secret,
+		EventTypes:  r.EventTypes,
+		Description: r.Description,
+		Enabled:     enabled,
+	}
+}
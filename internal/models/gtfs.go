@@ -0,0 +1,256 @@
+package models
+
+import "time"
+
+// GTFS feed import states
+const (
+	GTFSFeedImporting  = "importing"
+	GTFSFeedActive     = "active"
+	GTFSFeedSuperseded = "superseded"
+	GTFSFeedFailed     = "failed"
+)
+
+type GTFSFeed struct {
+	ID            int64      `json:"id"`
+	Version       string     `json:"version"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	AgencyCount   int        `json:"agency_count"`
+	RouteCount    int        `json:"route_count"`
+	StopCount     int        `json:"stop_count"`
+	TripCount     int        `json:"trip_count"`
+	StopTimeCount int        `json:"stop_time_count"`
+	ShapeCount    int        `json:"shape_count"`
+	ActivatedAt   *time.Time `json:"activated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type GTFSAgency struct {
+	AgencyID string `json:"agency_id"`
+	Name     string `json:"agency_name"`
+	URL      string `json:"agency_url"`
+	Timezone string `json:"agency_timezone"`
+}
+
+type GTFSRoute struct {
+	RouteID   string `json:"route_id"`
+	AgencyID  string `json:"agency_id"`
+	ShortName string `json:"route_short_name"`
+	LongName  string `json:"route_long_name"`
+	RouteType int    `json:"route_type"`
+	Color     string `json:"route_color"`
+}
+
+type GTFSStop struct {
+	StopID        string  `json:"stop_id"`
+	Code          string  `json:"stop_code"`
+	Name          string  `json:"stop_name"`
+	Latitude      float64 `json:"stop_lat"`
+	Longitude     float64 `json:"stop_lon"`
+	LocationType  int     `json:"location_type"`
+	ParentStation string  `json:"parent_station"`
+}
+
+type GTFSTrip struct {
+	TripID      string `json:"trip_id"`
+	RouteID     string `json:"route_id"`
+	ServiceID   string `json:"service_id"`
+	Headsign    string `json:"trip_headsign"`
+	DirectionID int    `json:"direction_id"`
+	ShapeID     string `json:"shape_id"`
+}
+
+// GTFSCalendar is the weekly pattern of a service between two dates in
+// YYYYMMDD format.
+type GTFSCalendar struct {
+	ServiceID string `json:"service_id"`
+	Monday    bool   `json:"monday"`
+	Tuesday   bool   `json:"tuesday"`
+	Wednesday bool   `json:"wednesday"`
+	Thursday  bool   `json:"thursday"`
+	Friday    bool   `json:"friday"`
+	Saturday  bool   `json:"saturday"`
+	Sunday    bool   `json:"sunday"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// GTFS calendar_dates exception types
+const (
+	GTFSServiceAdded   = 1
+	GTFSServiceRemoved = 2
+)
+
+// GTFSCalendarDate adds or removes a service on one date.
+type GTFSCalendarDate struct {
+	ServiceID     string `json:"service_id"`
+	Date          string `json:"date"`
+	ExceptionType int    `json:"exception_type"`
+}
+
+// GTFSStopTime stores arrival and departure as seconds after midnight of the
+// service day; values may exceed 24h for trips running past midnight.
+type GTFSStopTime struct {
+	TripID            string  `json:"trip_id"`
+	StopSequence      int     `json:"stop_sequence"`
+	StopID            string  `json:"stop_id"`
+	ArrivalSeconds    int     `json:"arrival_seconds"`
+	DepartureSeconds  int     `json:"departure_seconds"`
+	ShapeDistTraveled float64 `json:"shape_dist_traveled"`
+}
+
+type GTFSShapePoint struct {
+	ShapeID           string  `json:"shape_id"`
+	Sequence          int     `json:"shape_pt_sequence"`
+	Latitude          float64 `json:"shape_pt_lat"`
+	Longitude         float64 `json:"shape_pt_lon"`
+	ShapeDistTraveled float64 `json:"shape_dist_traveled"`
+}
+
+// GTFSStopCall is a scheduled call of a trip at a stop, with the trip's
+// route and direction.
+type GTFSStopCall struct {
+	GTFSStopTime
+	RouteID        string `json:"route_id"`
+	RouteShortName string `json:"route_short_name"`
+	DirectionID    int    `json:"direction_id"`
+}
+
+// GTFSTripStop is a scheduled stop of a trip joined with the stop location.
+type GTFSTripStop struct {
+	GTFSStopTime
+	StopName  string  `json:"stop_name"`
+	Latitude  float64 `json:"stop_lat"`
+	Longitude float64 `json:"stop_lon"`
+}
+
+// TripAssignment links a vehicle to the GTFS trip it is currently running.
+// StartDate is the service date in YYYYMMDD format.
+type TripAssignment struct {
+	VehicleID   string    `json:"vehicle_id"`
+	TripID      string    `json:"trip_id"`
+	RouteID     string    `json:"route_id"`
+	DirectionID int       `json:"direction_id"`
+	StartDate   string    `json:"start_date"`
+	AssignedAt  time.Time `json:"assigned_at"`
+}
+
+// StopVisit is an actual arrival at, and departure from, a halte. Trip fields
+// are filled when the vehicle has a trip assignment at arrival time, or when
+// its trip was matched from the schedule (TripMatched). Delays are actual
+// minus scheduled seconds, so positive values are late.
+type StopVisit struct {
+	ID                 int64     `json:"id"`
+	VehicleID          string    `json:"vehicle_id"`
+	StopID             string    `json:"stop_id"`
+	StopName           string    `json:"stop_name"`
+	RouteID            string    `json:"route_id,omitempty"`
+	TripID             string    `json:"trip_id,omitempty"`
+	DirectionID        *int      `json:"direction_id,omitempty"`
+	StartDate          string    `json:"start_date,omitempty"`
+	StopSequence       *int      `json:"stop_sequence,omitempty"`
+	TripMatched        bool      `json:"trip_matched,omitempty"`
+	ArrivalTime        int64     `json:"arrival_time"`
+	DepartureTime      *int64    `json:"departure_time,omitempty"`
+	DwellSeconds       int64     `json:"dwell_seconds"`
+	ScheduledArrival   *int64    `json:"scheduled_arrival,omitempty"`
+	ScheduledDeparture *int64    `json:"scheduled_departure,omitempty"`
+	ArrivalDelay       *int64    `json:"arrival_delay,omitempty"`
+	DepartureDelay     *int64    `json:"departure_delay,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type StopDwellStats struct {
+	StopID             string  `json:"stop_id"`
+	StopName           string  `json:"stop_name"`
+	VisitCount         int     `json:"visit_count"`
+	AvgDwellSeconds    float64 `json:"avg_dwell_seconds"`
+	MedianDwellSeconds float64 `json:"median_dwell_seconds"`
+	P90DwellSeconds    float64 `json:"p90_dwell_seconds"`
+	MaxDwellSeconds    int64   `json:"max_dwell_seconds"`
+}
+
+// OnTimePerformance summarizes arrival delays at halte for a trip or route. An
+// arrival is on time when its delay lies within the configured tolerances.
+type OnTimePerformance struct {
+	RouteID         string  `json:"route_id,omitempty"`
+	TripID          string  `json:"trip_id,omitempty"`
+	StartDate       string  `json:"start_date,omitempty"`
+	Observations    int     `json:"observations"`
+	OnTime          int     `json:"on_time"`
+	Early           int     `json:"early"`
+	Late            int     `json:"late"`
+	OnTimePercent   float64 `json:"on_time_percent"`
+	AvgDelaySeconds float64 `json:"avg_delay_seconds"`
+}
+
+// Headway states
+const (
+	HeadwayNormal   = "normal"
+	HeadwayBunching = "bunching"
+	HeadwayGap      = "gap"
+)
+
+// VehicleHeadway is the spacing between a vehicle and the one ahead of it on
+// the same route direction. HeadwaySeconds is the time since the leader passed
+// the vehicle's current position, or an estimate from the gap and the
+// vehicle's speed when that passage is no longer known (Estimated).
+type VehicleHeadway struct {
+	VehicleID       string   `json:"vehicle_id"`
+	TripID          string   `json:"trip_id"`
+	Latitude        float64  `json:"latitude"`
+	Longitude       float64  `json:"longitude"`
+	DistanceAlong   float64  `json:"distance_along"`
+	LeaderVehicleID string   `json:"leader_vehicle_id,omitempty"`
+	GapMeters       float64  `json:"gap_meters,omitempty"`
+	HeadwaySeconds  *int64   `json:"headway_seconds,omitempty"`
+	Estimated       bool     `json:"estimated,omitempty"`
+	Ratio           *float64 `json:"ratio,omitempty"`
+	Status          string   `json:"status,omitempty"`
+	Timestamp       int64    `json:"timestamp"`
+}
+
+// CorridorHeadways is the latest headway snapshot of a route direction, with
+// vehicles ordered from the front of the route.
+type CorridorHeadways struct {
+	RouteID               string            `json:"route_id"`
+	DirectionID           int               `json:"direction_id"`
+	PlannedHeadwaySeconds *int64            `json:"planned_headway_seconds,omitempty"`
+	Vehicles              []*VehicleHeadway `json:"vehicles"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+}
+
+// SegmentTravelTime is the observed running time between two consecutive
+// stops, from departure to arrival, for one hour of the day.
+type SegmentTravelTime struct {
+	FromStopID    string  `json:"from_stop_id"`
+	ToStopID      string  `json:"to_stop_id"`
+	Hour          int     `json:"hour"`
+	MedianSeconds float64 `json:"median_seconds"`
+	Samples       int     `json:"samples"`
+}
+
+// StopDwellProfile is the observed dwell time at a stop for one hour of the day.
+type StopDwellProfile struct {
+	StopID        string  `json:"stop_id"`
+	Hour          int     `json:"hour"`
+	MedianSeconds float64 `json:"median_seconds"`
+	Samples       int     `json:"samples"`
+}
+
+// StopArrivalPrediction is the estimated arrival of a vehicle at an upcoming
+// stop of its trip.
+type StopArrivalPrediction struct {
+	VehicleID        string  `json:"vehicle_id"`
+	TripID           string  `json:"trip_id"`
+	RouteID          string  `json:"route_id"`
+	DirectionID      int     `json:"direction_id"`
+	Headsign         string  `json:"headsign,omitempty"`
+	StopID           string  `json:"stop_id"`
+	StopName         string  `json:"stop_name"`
+	StopSequence     int     `json:"stop_sequence"`
+	PredictedArrival int64   `json:"predicted_arrival"`
+	SecondsAway      int64   `json:"seconds_away"`
+	ScheduledArrival *int64  `json:"scheduled_arrival,omitempty"`
+	DistanceMeters   float64 `json:"distance_meters"`
+}
@@ -3,31 +3,402 @@ package models
 import "time"
 
 type VehicleLocation struct {
-	ID        int64     `json:"id"`
-	VehicleID string    `json:"vehicle_id"`
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Timestamp int64     `json:"timestamp"`
+	ID        int64   `json:"id"`
+	VehicleID string  `json:"vehicle_id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timestamp int64   `json:"timestamp"`
+	Ignition  *bool   `json:"ignition,omitempty"`
+	// Speed (km/h) and Heading (degrees clockwise from north) as reported by
+	// the onboard unit
+	Speed   *float64 `json:"speed,omitempty"`
+	Heading *float64 `json:"heading,omitempty"`
+	// Accelerometer readings in m/s², forward and to the left positive. They
+	// are used for behavior detection and not stored.
+	LongitudinalAccel *float64 `json:"-"`
+	LateralAccel      *float64 `json:"-"`
+	// Matched coordinates and road are set when map matching snaps the
+	// position to the road network
+	MatchedLatitude  *float64 `json:"matched_latitude,omitempty"`
+	MatchedLongitude *float64 `json:"matched_longitude,omitempty"`
+	RoadID           *string  `json:"road_id,omitempty"`
+	// DriverID is the driver on shift on the vehicle when it reported
+	DriverID  *string   `json:"driver_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Address is a position resolved against local boundary and street data.
+type Address struct {
+	Street    string `json:"street,omitempty"`
+	Kelurahan string `json:"kelurahan,omitempty"`
+	Kecamatan string `json:"kecamatan,omitempty"`
+	City      string `json:"city,omitempty"`
+}
+
+// Geofence types
+const (
+	GeofenceTypeLandmark = "landmark"
+	GeofenceTypeDepot    = "depot"
+	GeofenceTypeStop     = "stop"
+	GeofenceTypeCorridor = "corridor"
+)
+
 type Geofence struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Radius    int       `json:"radius"`
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    int     `json:"radius"`
+	// Path is a polyline of [lat, lng] pairs; when set, Radius is the
+	// corridor half-width around the path instead of around the center.
+	Path       [][]float64 `json:"path,omitempty"`
+	FeedID     *int64      `json:"feed_id,omitempty"`
+	ExternalID string      `json:"external_id,omitempty"`
+	// Tags label geofences for notification rules, e.g. "depot"
+	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type GeofenceEvent struct {
-	ID          int64     `json:"id"`
+	ID         int64     `json:"id"`
+	VehicleID  string    `json:"vehicle_id"`
+	GeofenceID *int64    `json:"geofence_id,omitempty"`
+	EventType  string    `json:"event_type"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Timestamp  int64     `json:"timestamp"`
+	DriverID   *string   `json:"driver_id,omitempty"`
+	Address    *Address  `json:"address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Trip struct {
+	ID              int64     `json:"id"`
+	VehicleID       string    `json:"vehicle_id"`
+	StartTime       int64     `json:"start_time"`
+	EndTime         int64     `json:"end_time"`
+	StartLatitude   float64   `json:"start_latitude"`
+	StartLongitude  float64   `json:"start_longitude"`
+	EndLatitude     float64   `json:"end_latitude"`
+	EndLongitude    float64   `json:"end_longitude"`
+	DistanceMeters  float64   `json:"distance_meters"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	MaxSpeedKmh     float64   `json:"max_speed_kmh"`
+	AvgSpeedKmh     float64   `json:"avg_speed_kmh"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type VehicleStop struct {
+	ID              int64     `json:"id"`
+	VehicleID       string    `json:"vehicle_id"`
+	StartTime       int64     `json:"start_time"`
+	EndTime         int64     `json:"end_time"`
+	Latitude        float64   `json:"latitude"`
+	Longitude       float64   `json:"longitude"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type VehicleOdometer struct {
+	VehicleID     string    `json:"vehicle_id"`
+	TotalMeters   float64   `json:"total_meters"`
+	LastLatitude  float64   `json:"last_latitude"`
+	LastLongitude float64   `json:"last_longitude"`
+	LastTimestamp int64     `json:"last_timestamp"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type DailyDistance struct {
+	VehicleID      string    `json:"vehicle_id"`
+	Date           string    `json:"date"`
+	DistanceMeters float64   `json:"distance_meters"`
+	PointCount     int       `json:"point_count"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type IdleInterval struct {
+	ID              int64     `json:"id"`
+	VehicleID       string    `json:"vehicle_id"`
+	StartTime       int64     `json:"start_time"`
+	EndTime         *int64    `json:"end_time,omitempty"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Latitude        float64   `json:"latitude"`
+	Longitude       float64   `json:"longitude"`
+	DriverID        *string   `json:"driver_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type IdleSummary struct {
+	VehicleID          string `json:"vehicle_id"`
+	IntervalCount      int    `json:"interval_count"`
+	TotalIdleSeconds   int64  `json:"total_idle_seconds"`
+	LongestIdleSeconds int64  `json:"longest_idle_seconds"`
+}
+
+// Vehicle connection states
+const (
+	ConnectionOnline  = "online"
+	ConnectionOffline = "offline"
+)
+
+type VehicleConnection struct {
+	VehicleID     string     `json:"vehicle_id"`
+	Status        string     `json:"status"`
+	LastSeen      time.Time  `json:"last_seen"`
+	LastTimestamp int64      `json:"last_timestamp"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	Address       *Address   `json:"address,omitempty"`
+	OfflineSince  *time.Time `json:"offline_since,omitempty"`
+}
+
+// Vehicle types operated by TransJakarta
+const (
+	VehicleTypeArticulated = "articulated"
+	VehicleTypeMaxi        = "maxi"
+	VehicleTypeEBus        = "e-bus"
+	VehicleTypeMikrotrans  = "mikrotrans"
+)
+
+type Vehicle struct {
 	VehicleID   string    `json:"vehicle_id"`
-	GeofenceID  *int64    `json:"geofence_id,omitempty"`
-	EventType   string    `json:"event_type"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	Timestamp   int64     `json:"timestamp"`
+	PlateNumber string    `json:"plate_number"`
+	Operator    string    `json:"operator"`
+	Corridor    string    `json:"corridor"`
+	VehicleType string    `json:"vehicle_type"`
+	Capacity    int       `json:"capacity"`
+	Active      bool      `json:"active"`
+	Groups      []string  `json:"groups"`
 	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type QuarantinedLocation struct {
+	ID         int64     `json:"id"`
+	VehicleID  string    `json:"vehicle_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Timestamp  int64     `json:"timestamp"`
+	Reason     string    `json:"reason"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+type Driver struct {
+	DriverID      string    `json:"driver_id"`
+	Name          string    `json:"name"`
+	LicenseNumber string    `json:"license_number"`
+	Operator      string    `json:"operator"`
+	Phone         string    `json:"phone"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Shift sources
+const (
+	ShiftSourceAPI  = "api"
+	ShiftSourceMQTT = "mqtt"
+)
+
+// DriverShift links a driver to a vehicle for a time interval; EndTime is nil
+// while the shift is open.
+type DriverShift struct {
+	ID        int64     `json:"id"`
+	DriverID  string    `json:"driver_id"`
+	VehicleID string    `json:"vehicle_id"`
+	StartTime int64     `json:"start_time"`
+	EndTime   *int64    `json:"end_time,omitempty"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DriverReport summarizes a driver's shifts and behavior over a time range.
+type DriverReport struct {
+	DriverID        string  `json:"driver_id"`
+	Name            string  `json:"name"`
+	ShiftCount      int     `json:"shift_count"`
+	OnDutySeconds   int64   `json:"on_duty_seconds"`
+	IdleCount       int     `json:"idle_count"`
+	IdleSeconds     int64   `json:"idle_seconds"`
+	IdleRatio       float64 `json:"idle_ratio"`
+	GeofenceEntries int     `json:"geofence_entries"`
+	DrivingEvents   int     `json:"driving_events"`
+}
+
+// Driving event types
+const (
+	DrivingEventHarshBraking      = "harsh_braking"
+	DrivingEventHarshAcceleration = "harsh_acceleration"
+	DrivingEventSharpCornering    = "sharp_cornering"
+)
+
+// Driving event sources
+const (
+	DrivingSourceAccelerometer = "accelerometer"
+	DrivingSourceGPS           = "gps"
+)
+
+// DrivingEvent is a harsh maneuver; Value is the peak acceleration in m/s²,
+// negative when braking.
+type DrivingEvent struct {
+	ID        int64     `json:"id"`
+	VehicleID string    `json:"vehicle_id"`
+	DriverID  *string   `json:"driver_id,omitempty"`
+	EventType string    `json:"event_type"`
+	Value     float64   `json:"value"`
+	SpeedKmh  float64   `json:"speed_kmh"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Timestamp int64     `json:"timestamp"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DriverSafetyScore rates a driver's day from 100 down, by harsh events per
+// hour on duty.
+type DriverSafetyScore struct {
+	DriverID          string  `json:"driver_id"`
+	Name              string  `json:"name"`
+	Date              string  `json:"date"`
+	OnDutySeconds     int64   `json:"on_duty_seconds"`
+	HarshBraking      int     `json:"harsh_braking"`
+	HarshAcceleration int     `json:"harsh_acceleration"`
+	SharpCornering    int     `json:"sharp_cornering"`
+	Score             float64 `json:"score"`
+}
+
+// OutboxMessage is an event saved with the change that raised it, waiting to
+// be published to RabbitMQ.
+type OutboxMessage struct {
+	ID         int64      `json:"id"`
+	RoutingKey string     `json:"routing_key"`
+	Payload    []byte     `json:"payload"`
+	Mandatory  bool       `json:"mandatory"`
+	Attempts   int        `json:"attempts"`
+	LastError  *string    `json:"last_error,omitempty"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// WebhookSubscription sends fleet events to a partner endpoint. EventTypes
+// filters events by name, e.g. "geofence.entry" or "vehicle.*"; an empty
+// filter receives every event. The secret is only returned when set.
+type WebhookSubscription struct {
+	ID                  int64      `json:"id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	Description         string     `json:"description"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      *string    `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription, retried until it
+// succeeds or runs out of attempts.
+type WebhookDelivery struct {
+	ID             int64                     `json:"id"`
+	SubscriptionID int64                     `json:"subscription_id"`
+	EventID        string                    `json:"event_id"`
+	EventType      string                    `json:"event_type"`
+	Payload        []byte                    `json:"-"`
+	Status         string                    `json:"status"`
+	Attempts       int                       `json:"attempts"`
+	LastError      *string                   `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time                `json:"next_attempt_at,omitempty"`
+	CompletedAt    *time.Time                `json:"completed_at,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	AttemptLog     []*WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+
+	// Endpoint of the subscription, filled in when a delivery is claimed
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookDeliveryAttempt records one POST of a delivery. StatusCode is nil
+// when no response was received.
+type WebhookDeliveryAttempt struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       *string   `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// Notification rule throttle scopes
+const (
+	ThrottlePerVehicle = "vehicle"
+	ThrottlePerRule    = "rule"
+)
+
+// NotificationRule sends a message rendered from Template to Channel when an
+// event matches all of its conditions. After notifying, the rule stays
+// silent for ThrottleSeconds, per vehicle or for the whole rule.
+type NotificationRule struct {
+	ID              int64          `json:"id"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	Enabled         bool           `json:"enabled"`
+	Conditions      RuleConditions `json:"conditions"`
+	Channel         string         `json:"channel"`
+	Template        string         `json:"template"`
+	ThrottleSeconds int            `json:"throttle_seconds"`
+	ThrottleScope   string         `json:"throttle_scope"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// RuleConditions restrict the events a rule notifies about. Empty
+// conditions are ignored; a list matches when any of its values does.
+type RuleConditions struct {
+	// EventTypes are event names such as "geofence.entry" or "vehicle.*"
+	EventTypes []string `json:"event_types,omitempty"`
+	// Geofence conditions only match geofence events
+	GeofenceNames []string `json:"geofence_names,omitempty"`
+	GeofenceTypes []string `json:"geofence_types,omitempty"`
+	GeofenceTags  []string `json:"geofence_tags,omitempty"`
+	// Vehicle conditions are matched against the vehicle registry
+	VehicleIDs    []string `json:"vehicle_ids,omitempty"`
+	VehicleGroups []string `json:"vehicle_groups,omitempty"`
+	VehicleTypes  []string `json:"vehicle_types,omitempty"`
+	Operators     []string `json:"operators,omitempty"`
+	Corridors     []string `json:"corridors,omitempty"`
+	// TimeFrom and TimeTo ("HH:MM") bound the local time of the event; a
+	// window such as 22:00-05:00 spans midnight
+	TimeFrom string `json:"time_from,omitempty"`
+	TimeTo   string `json:"time_to,omitempty"`
+}
+
+// Notification states
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is a message a rule sent, or failed to send, for an event.
+type Notification struct {
+	ID        int64      `json:"id"`
+	RuleID    int64      `json:"rule_id"`
+	EventID   string     `json:"event_id"`
+	EventType string     `json:"event_type"`
+	VehicleID string     `json:"vehicle_id,omitempty"`
+	Channel   string     `json:"channel"`
+	Message   string     `json:"message"`
+	Status    string     `json:"status"`
+	Error     *string    `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
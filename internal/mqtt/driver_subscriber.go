@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+// Driver message events
+const (
+	DriverLogin  = "login"
+	DriverLogout = "logout"
+)
+
+// DriverSubscriber starts and ends driver shifts from login messages sent by
+// onboard units.
+type DriverSubscriber struct {
+	client        *Client
+	driverService services.DriverService
+	logger        *zap.Logger
+	topicPattern  *regexp.Regexp
+}
+
+type DriverMessage struct {
+	DriverID  string `json:"driver_id"`
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func NewDriverSubscriber(client *Client, driverService services.DriverService, logger *zap.Logger) *DriverSubscriber {
+	// Pattern: /fleet/vehicle/{vehicle_id}/driver
+	pattern := regexp.MustCompile(`^/fleet/vehicle/([^/]+)/driver$`)
+
+	return &DriverSubscriber{
+		client:        client,
+		driverService: driverService,
+		logger:        logger,
+		topicPattern:  pattern,
+	}
+}
+
+func (s *DriverSubscriber) Start(ctx context.Context) error {
+	if !s.client.IsConnected() {
+		return fmt.Errorf("MQTT client not connected")
+	}
+
+	topic := "/fleet/vehicle/+/driver"
+	if err := s.client.Subscribe(topic, 1, s.handleDriverMessage); err != nil {
+		return fmt.Errorf("failed to subscribe to driver topic: %w", err)
+	}
+
+	s.logger.Info("Driver subscriber started", zap.String("topic_pattern", topic))
+
+	<-ctx.Done()
+
+	s.logger.Info("Driver subscriber stopping")
+	return nil
+}
+
+func (s *DriverSubscriber) handleDriverMessage(topic string, payload []byte) error {
+	matches := s.topicPattern.FindStringSubmatch(topic)
+	if len(matches) != 2 || matches[1] == "" {
+		return fmt.Errorf("topic does not match expected pattern: %s", topic)
+	}
+	vehicleID := matches[1]
+
+	var message DriverMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		s.logger.Error("Failed to parse driver message",
+			zap.Error(err),
+			zap.String("topic", topic),
+			zap.String("payload", string(payload)))
+		return fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	switch message.Event {
+	case DriverLogin:
+		_, err = s.driverService.StartShift(ctx, vehicleID, message.DriverID, message.Timestamp, models.ShiftSourceMQTT)
+	case DriverLogout:
+		_, err = s.driverService.EndShift(ctx, vehicleID, message.Timestamp)
+	default:
+		err = fmt.Errorf("unknown driver event %q", message.Event)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to process driver message",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID),
+			zap.String("driver_id", message.DriverID),
+			zap.String("event", message.Event))
+		return err
+	}
+
+	return nil
+}
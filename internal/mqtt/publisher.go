@@ -20,17 +20,21 @@ func NewLocationPublisher(client *Client, logger *zap.Logger) *LocationPublisher
 }
 
 func (p *LocationPublisher) PublishLocation(vehicleID string, latitude, longitude float64, timestamp int64) error {
-	if !p.client.IsConnected() {
-		return fmt.Errorf("MQTT client not connected")
-	}
-	
-	// Create location message
-	message := LocationMessage{
+	return p.PublishMessage(LocationMessage{
 		VehicleID: vehicleID,
 		Latitude:  latitude,
 		Longitude: longitude,
 		Timestamp: timestamp,
+	})
+}
+
+// PublishMessage publishes a full location message, including optional
+// telemetry such as speed and heading.
+func (p *LocationPublisher) PublishMessage(message LocationMessage) error {
+	if !p.client.IsConnected() {
+		return fmt.Errorf("MQTT client not connected")
 	}
+	vehicleID := message.VehicleID
 	
 	// Convert to JSON
 	payload, err := json.Marshal(message)
@@ -16,6 +16,7 @@ import (
 type LocationSubscriber struct {
 	client          *Client
 	locationService services.LocationService
+	registryService services.VehicleRegistryService
 	logger          *zap.Logger
 	topicPattern    *regexp.Regexp
 }
@@ -25,9 +26,16 @@ type LocationMessage struct {
 	Latitude  float64 `json:"latitude" validate:"required,min=-90,max=90"`
 	Longitude float64 `json:"longitude" validate:"required,min=-180,max=180"`
 	Timestamp int64   `json:"timestamp" validate:"required,min=1"`
+	Ignition  *bool   `json:"ignition,omitempty"`
+	// Optional telemetry: speed in km/h, heading in degrees and accelerometer
+	// readings in m/s²
+	Speed             *float64 `json:"speed,omitempty" validate:"omitempty,min=0"`
+	Heading           *float64 `json:"heading,omitempty" validate:"omitempty,min=0,max=360"`
+	LongitudinalAccel *float64 `json:"longitudinal_accel,omitempty"`
+	LateralAccel      *float64 `json:"lateral_accel,omitempty"`
 }
 
-func NewLocationSubscriber(client *Client, locationService services.LocationService, logger *zap.Logger) *LocationSubscriber {
+func NewLocationSubscriber(client *Client, locationService services.LocationService, registryService services.VehicleRegistryService, logger *zap.Logger) *LocationSubscriber {
 	// Compile regex pattern for topic matching
 	// Pattern: /fleet/vehicle/{vehicle_id}/location
 	pattern := regexp.MustCompile(`^/fleet/vehicle/([^/]+)/location$`)
@@ -35,6 +43,7 @@ func NewLocationSubscriber(client *Client, locationService services.LocationServ
 	return &LocationSubscriber{
 		client:          client,
 		locationService: locationService,
+		registryService: registryService,
 		logger:          logger,
 		topicPattern:    pattern,
 	}
@@ -98,12 +107,27 @@ func (s *LocationSubscriber) handleLocationMessage(topic string, payload []byte)
 		Latitude:  locationMsg.Latitude,
 		Longitude: locationMsg.Longitude,
 		Timestamp: locationMsg.Timestamp,
+		Ignition:  locationMsg.Ignition,
+		Speed:     locationMsg.Speed,
+		Heading:   locationMsg.Heading,
+
+		LongitudinalAccel: locationMsg.LongitudinalAccel,
+		LateralAccel:      locationMsg.LateralAccel,
 	}
 	
-	// Save location via service
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	
+	// Apply the unknown-vehicle policy before ingesting
+	admitted, err := s.registryService.AdmitLocation(ctx, location)
+	if err != nil {
+		return fmt.Errorf("failed to check vehicle registry: %w", err)
+	}
+	if !admitted {
+		return nil
+	}
+	
+	// Save location via service
 	if err := s.locationService.SaveLocation(ctx, location); err != nil {
 		s.logger.Error("Failed to save vehicle location", 
 			zap.Error(err),
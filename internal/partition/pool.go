@@ -0,0 +1,103 @@
+// Package partition runs keyed jobs on a fixed set of goroutines, each owning
+// a share of the keys, so jobs with the same key run one at a time in the
+// order they were dispatched.
+package partition
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Pool is an ordered per-key worker pool.
+type Pool struct {
+	partitions []chan func(ctx context.Context)
+	wg         sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+	// Jobs outlive the context the pool was created with, up to the
+	// shutdown timeout
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewPool starts workers goroutines, each queueing up to buffer jobs.
+func NewPool(ctx context.Context, workers, buffer int) *Pool {
+	workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	pool := &Pool{
+		partitions: make([]chan func(ctx context.Context), max(workers, 1)),
+		ctx:        workCtx,
+		cancel:     cancel,
+	}
+
+	for i := range pool.partitions {
+		pool.partitions[i] = make(chan func(ctx context.Context), max(buffer, 1))
+		pool.wg.Add(1)
+		go func(jobs <-chan func(ctx context.Context)) {
+			defer pool.wg.Done()
+			for job := range jobs {
+				job(pool.ctx)
+			}
+		}(pool.partitions[i])
+	}
+	return pool
+}
+
+// Workers is the number of goroutines of the pool.
+func (p *Pool) Workers() int {
+	return len(p.partitions)
+}
+
+// Dispatch queues a job on the partition of key, waiting while it is full.
+// It reports false if ctx ended first or the pool is stopped.
+func (p *Pool) Dispatch(ctx context.Context, key string, job func(ctx context.Context)) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return false
+	}
+
+	select {
+	case p.partitions[Index(key, len(p.partitions))] <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop waits for the queued jobs, cancelling their context once timeout has
+// passed. Dispatches still waiting must be released by ending their context.
+func (p *Pool) Stop(timeout time.Duration) (timedOut bool) {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+
+	for _, partition := range p.partitions {
+		close(partition)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	defer p.cancel()
+	select {
+	case <-done:
+		return false
+	case <-time.After(timeout):
+		p.cancel()
+		<-done
+		return true
+	}
+}
+
+// Index assigns key to one of n partitions.
+func Index(key string, n int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(n))
+}
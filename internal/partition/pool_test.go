@@ -0,0 +1,141 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolKeepsOrderPerKey(t *testing.T) {
+	pool := NewPool(context.Background(), 4, 2)
+
+	const jobs = 200
+	keys := []string{"B1234ABC", "B5678DEF", "B9012GHI", "B3456JKL", "B7890MNO", ""}
+
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	for i := 0; i < jobs; i++ {
+		for _, key := range keys {
+			if !pool.Dispatch(context.Background(), key, func(ctx context.Context) {
+				mu.Lock()
+				seen[key] = append(seen[key], i)
+				mu.Unlock()
+			}) {
+				t.Fatalf("job %d of %q not dispatched", i, key)
+			}
+		}
+	}
+
+	if pool.Stop(time.Second) {
+		t.Fatal("Stop timed out")
+	}
+
+	for _, key := range keys {
+		got := seen[key]
+		if len(got) != jobs {
+			t.Fatalf("key %q ran %d jobs, want %d", key, len(got), jobs)
+		}
+		for i, job := range got {
+			if job != i {
+				t.Fatalf("key %q ran job %d at position %d", key, job, i)
+			}
+		}
+	}
+}
+
+func TestPoolStopWhileDispatching(t *testing.T) {
+	pool := NewPool(context.Background(), 1, 1)
+
+	// Hold the only worker, then fill its queue
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.Dispatch(context.Background(), "a", func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+	ranQueued := make(chan struct{})
+	pool.Dispatch(context.Background(), "a", func(ctx context.Context) { close(ranQueued) })
+
+	// This one waits for room in the queue
+	dispatchCtx, cancelDispatch := context.WithCancel(context.Background())
+	dispatched := make(chan bool)
+	go func() {
+		dispatched <- pool.Dispatch(dispatchCtx, "a", func(ctx context.Context) {
+			t.Error("job dispatched after Stop ran")
+		})
+	}()
+
+	stopped := make(chan bool)
+	go func() {
+		// Let the dispatch above block first
+		time.Sleep(20 * time.Millisecond)
+		stopped <- pool.Stop(time.Second)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while a dispatch was waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelDispatch()
+	if <-dispatched {
+		t.Fatal("waiting dispatch reported queued after its context ended")
+	}
+
+	close(release)
+	if timedOut := <-stopped; timedOut {
+		t.Fatal("Stop timed out")
+	}
+
+	// Queued jobs still run before Stop returns
+	select {
+	case <-ranQueued:
+	default:
+		t.Fatal("queued job did not run before Stop returned")
+	}
+
+	if pool.Dispatch(context.Background(), "a", func(ctx context.Context) {}) {
+		t.Fatal("Dispatch after Stop reported queued")
+	}
+}
+
+func TestPoolStopTimeoutCancelsJobs(t *testing.T) {
+	// The jobs' context outlives the one the pool was created with
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewPool(ctx, 2, 1)
+	cancel()
+
+	cancelled := make(chan struct{})
+	pool.Dispatch(context.Background(), "a", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+
+	if !pool.Stop(20 * time.Millisecond) {
+		t.Fatal("Stop did not time out")
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("job context not cancelled by the timeout")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for _, n := range []int{1, 3, 8} {
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("B%04dXYZ", i)
+			index := Index(key, n)
+			if index < 0 || index >= n {
+				t.Fatalf("Index(%q, %d) = %d, out of range", key, n, index)
+			}
+			if again := Index(key, n); again != index {
+				t.Fatalf("Index(%q, %d) = %d then %d", key, n, index, again)
+			}
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
@@ -9,50 +13,98 @@ import (
 	"github.com/ivanadhi/transjakarta-fleet/internal/config"
 )
 
+// Connection states
+const (
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateClosed       = "closed"
+)
+
+// ErrClientClosed is returned once the client has been closed.
+var ErrClientClosed = errors.New("RabbitMQ client closed")
+
+// Client holds a supervised connection to the broker: when the connection or
+// its channel closes, it redials with exponential backoff and declares the
+// topology again.
 type Client struct {
+	config *config.RabbitMQConfig
+	logger *zap.Logger
+
+	mu      sync.RWMutex
 	conn    *amqp091.Connection
 	channel *amqp091.Channel
-	config  *config.RabbitMQConfig
-	logger  *zap.Logger
+	// ready is closed while connected and replaced when the connection drops
+	ready chan struct{}
+	stats ConnectionStats
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// ConnectionStats describes the broker connection over the client's life.
+type ConnectionStats struct {
+	State string `json:"state"`
+	// Reconnects counts successful reconnections and FailedAttempts the dials
+	// that failed while reconnecting
+	Reconnects     int64      `json:"reconnects"`
+	FailedAttempts int64      `json:"failed_attempts"`
+	ConnectedSince *time.Time `json:"connected_since,omitempty"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
 }
 
 func NewClient(cfg *config.RabbitMQConfig, logger *zap.Logger) (*Client, error) {
-	// Connect to RabbitMQ
-	conn, err := amqp091.Dial(cfg.URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	if cfg.EventMode != ModeBinary && cfg.EventMode != ModeStructured {
+		return nil, fmt.Errorf("invalid event mode %q, expected %q or %q", cfg.EventMode, ModeBinary, ModeStructured)
 	}
-	
-	// Create channel
-	channel, err := conn.Channel()
+
+	client := &Client{
+		config: cfg,
+		logger: logger,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	// The first connection must succeed; later failures are recovered
+	conn, channel, err := client.dial()
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to create RabbitMQ channel: %w", err)
+		return nil, err
 	}
-	
-	client := &Client{
-		conn:    conn,
-		channel: channel,
-		config:  cfg,
-		logger:  logger,
-	}
-	
-	// Setup exchange and queue
-	if err := client.setupInfrastructure(); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to setup RabbitMQ infrastructure: %w", err)
-	}
-	
+	client.connected(conn, channel)
+
+	go client.supervise()
+
 	logger.Info("RabbitMQ client connected successfully",
 		zap.String("exchange", cfg.Exchange),
 		zap.String("queue", cfg.Queue))
-	
+
 	return client, nil
 }
 
-func (c *Client) setupInfrastructure() error {
+// dial opens a connection and channel and declares the topology on it.
+func (c *Client) dial() (*amqp091.Connection, *amqp091.Channel, error) {
+	conn, err := amqp091.Dial(c.config.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to create RabbitMQ channel: %w", err)
+	}
+
+	if err := c.setupInfrastructure(channel); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to setup RabbitMQ infrastructure: %w", err)
+	}
+
+	return conn, channel, nil
+}
+
+func (c *Client) setupInfrastructure(channel *amqp091.Channel) error {
 	// Declare exchange
-	err := c.channel.ExchangeDeclare(
+	err := channel.ExchangeDeclare(
 		c.config.Exchange, // name
 		"topic",           // type
 		true,              // durable
@@ -64,9 +116,9 @@ func (c *Client) setupInfrastructure() error {
 	if err != nil {
 		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
-	
+
 	// Declare queue
-	_, err = c.channel.QueueDeclare(
+	_, err = channel.QueueDeclare(
 		c.config.Queue, // name
 		true,           // durable
 		false,          // delete when unused
@@ -77,11 +129,11 @@ func (c *Client) setupInfrastructure() error {
 	if err != nil {
 		return fmt.Errorf("failed to declare queue: %w", err)
 	}
-	
+
 	// Bind queue to exchange
-	err = c.channel.QueueBind(
+	err = channel.QueueBind(
 		c.config.Queue,    // queue name
-		"geofence.*",      // routing key pattern
+		"#",               // the worker handles every event
 		c.config.Exchange, // exchange
 		false,
 		nil,
@@ -89,21 +141,249 @@ func (c *Client) setupInfrastructure() error {
 	if err != nil {
 		return fmt.Errorf("failed to bind queue: %w", err)
 	}
-	
+
+	// Failed messages go through the dead-letter exchange, either to a delay
+	// queue that returns them to the work queue when their TTL expires, or
+	// to the parking queue once they are out of attempts
+	err = channel.ExchangeDeclare(
+		c.deadLetterExchange(), // name
+		"direct",               // type
+		true,                   // durable
+		false,                  // auto-deleted
+		false,                  // internal
+		false,                  // no-wait
+		nil,                    // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+
+	for _, delay := range c.retryDelays() {
+		// Queue arguments cannot change once declared, so each delay has
+		// its own queue
+		queue := c.retryQueue(delay)
+		_, err = channel.QueueDeclare(queue, true, false, false, false, amqp091.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "", // default exchange, by queue name
+			"x-dead-letter-routing-key": c.config.Queue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", queue, err)
+		}
+		if err := channel.QueueBind(queue, queue, c.deadLetterExchange(), false, nil); err != nil {
+			return fmt.Errorf("failed to bind retry queue %s: %w", queue, err)
+		}
+	}
+
+	if _, err := channel.QueueDeclare(c.parkingQueue(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare parking queue: %w", err)
+	}
+	if err := channel.QueueBind(c.parkingQueue(), c.parkingQueue(), c.deadLetterExchange(), false, nil); err != nil {
+		return fmt.Errorf("failed to bind parking queue: %w", err)
+	}
+
 	c.logger.Info("RabbitMQ infrastructure setup complete")
 	return nil
 }
 
-func (c *Client) Close() {
-	if c.channel != nil {
-		c.channel.Close()
+func (c *Client) deadLetterExchange() string {
+	return c.config.Exchange + ".dlx"
+}
+
+// parkingQueue holds messages that failed every attempt, until they are
+// replayed or purged.
+func (c *Client) parkingQueue() string {
+	return c.config.Queue + ".parked"
+}
+
+func (c *Client) retryQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", c.config.Queue, delay)
+}
+
+// retryDelays returns how long a message waits after each failed attempt
+// before the next: RetryDelay doubling up to RetryMaxDelay, for MaxAttempts-1
+// retries.
+func (c *Client) retryDelays() []time.Duration {
+	var delays []time.Duration
+	delay := max(c.config.RetryDelay, 100*time.Millisecond)
+	for attempt := 1; attempt < c.config.MaxAttempts; attempt++ {
+		if c.config.RetryMaxDelay > 0 && delay > c.config.RetryMaxDelay {
+			delay = c.config.RetryMaxDelay
+		}
+		delays = append(delays, delay)
+		delay *= 2
 	}
-	if c.conn != nil {
-		c.conn.Close()
+	return delays
+}
+
+// supervise waits for the connection or channel to close and reconnects,
+// until the client is closed.
+func (c *Client) supervise() {
+	for {
+		c.mu.RLock()
+		connClosed := c.conn.NotifyClose(make(chan *amqp091.Error, 1))
+		channelClosed := c.channel.NotifyClose(make(chan *amqp091.Error, 1))
+		c.mu.RUnlock()
+
+		var reason *amqp091.Error
+		select {
+		case <-c.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-channelClosed:
+		}
+
+		// Close shuts the connection after signalling done
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		c.disconnected(reason)
+
+		if !c.reconnect() {
+			return
+		}
 	}
-	c.logger.Info("RabbitMQ client closed")
+}
+
+// reconnect redials with exponential backoff. It returns false when the
+// client is closed first.
+func (c *Client) reconnect() bool {
+	backoff := max(c.config.ReconnectMinBackoff, 100*time.Millisecond)
+	for {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		conn, channel, err := c.dial()
+		if err != nil {
+			c.mu.Lock()
+			c.stats.FailedAttempts++
+			c.stats.LastError = err.Error()
+			c.mu.Unlock()
+
+			c.logger.Warn("RabbitMQ reconnection failed",
+				zap.Error(err),
+				zap.Duration("retry_in", backoff))
+
+			backoff *= 2
+			if c.config.ReconnectMaxBackoff > 0 && backoff > c.config.ReconnectMaxBackoff {
+				backoff = c.config.ReconnectMaxBackoff
+			}
+			continue
+		}
+
+		select {
+		case <-c.done:
+			conn.Close()
+			return false
+		default:
+		}
+
+		c.mu.Lock()
+		c.stats.Reconnects++
+		c.mu.Unlock()
+		c.connected(conn, channel)
+
+		c.logger.Info("RabbitMQ reconnected")
+		return true
+	}
+}
+
+func (c *Client) connected(conn *amqp091.Connection, channel *amqp091.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.conn = conn
+	c.channel = channel
+	c.stats.State = StateConnected
+	c.stats.ConnectedSince = &now
+	close(c.ready)
+}
+
+func (c *Client) disconnected(reason *amqp091.Error) {
+	c.mu.Lock()
+	now := time.Now()
+	c.stats.State = StateReconnecting
+	c.stats.ConnectedSince = nil
+	c.stats.DisconnectedAt = &now
+	if reason != nil {
+		c.stats.LastError = reason.Error()
+	}
+	c.ready = make(chan struct{})
+	conn := c.conn
+	c.mu.Unlock()
+
+	// The channel may close alone; drop the connection too so both are
+	// recreated together
+	conn.Close()
+
+	c.logger.Error("RabbitMQ connection lost, reconnecting", zap.Any("reason", reason))
+}
+
+// WaitConnected blocks until the client is connected, the context ends or
+// the client is closed.
+func (c *Client) WaitConnected(ctx context.Context) error {
+	c.mu.RLock()
+	ready := c.ready
+	c.mu.RUnlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OpenChannel opens a dedicated channel on the current connection, for
+// publishers and consumers. It closes with the connection.
+func (c *Client) OpenChannel() (*amqp091.Channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.stats.State != StateConnected {
+		return nil, fmt.Errorf("RabbitMQ client not connected")
+	}
+	return c.conn.Channel()
+}
+
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.stats.State = StateClosed
+		if c.channel != nil {
+			c.channel.Close()
+		}
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.logger.Info("RabbitMQ client closed")
+	})
 }
 
 func (c *Client) IsConnected() bool {
-	return c.conn != nil && !c.conn.IsClosed()
-}
\ No newline at end of file
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.stats.State == StateConnected && !c.conn.IsClosed()
+}
+
+// Stats returns the connection state and reconnection counters.
+func (c *Client) Stats() ConnectionStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.stats
+}
@@ -0,0 +1,134 @@
+package rabbitmq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+// Envelope modes of the CloudEvents AMQP binding
+const (
+	// ModeBinary sends the event data as the message body and the event
+	// attributes as cloudEvents:* application properties
+	ModeBinary = "binary"
+	// ModeStructured sends the whole event as a JSON document
+	ModeStructured = "structured"
+)
+
+// cloudEventsHeaderPrefix prefixes event attributes in binary mode
+const cloudEventsHeaderPrefix = "cloudEvents:"
+
+// encodeEvent builds the AMQP message carrying an event in the given mode.
+func encodeEvent(event *events.Event, mode string) (amqp091.Publishing, error) {
+	message := amqp091.Publishing{
+		DeliveryMode: amqp091.Persistent, // Make message persistent
+		Timestamp:    time.Now(),
+		MessageId:    event.ID,
+	}
+
+	if mode == ModeStructured {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return message, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		message.ContentType = events.ContentType
+		message.Body = body
+		return message, nil
+	}
+
+	headers := amqp091.Table{
+		cloudEventsHeaderPrefix + "specversion": event.SpecVersion,
+		cloudEventsHeaderPrefix + "id":          event.ID,
+		cloudEventsHeaderPrefix + "source":      event.Source,
+		cloudEventsHeaderPrefix + "type":        event.Type,
+		cloudEventsHeaderPrefix + "time":        event.Time.Format(time.RFC3339Nano),
+	}
+	if event.Subject != "" {
+		headers[cloudEventsHeaderPrefix+"subject"] = event.Subject
+	}
+	if event.CorrelationID != "" {
+		headers[cloudEventsHeaderPrefix+"correlationid"] = event.CorrelationID
+	}
+
+	message.Headers = headers
+	message.ContentType = event.DataContentType
+	message.Body = event.Data
+	return message, nil
+}
+
+// decodeEvent reads an event from a delivery in either mode. Messages
+// published before the envelope was introduced are accepted too: their
+// body is the data, identified by the AMQP message id or, lacking one, by
+// legacyEventID.
+func decodeEvent(delivery amqp091.Delivery) (*events.Event, error) {
+	header := func(name string) string {
+		value, _ := delivery.Headers[cloudEventsHeaderPrefix+name].(string)
+		return value
+	}
+
+	switch {
+	case header("specversion") != "":
+		event := &events.Event{
+			SpecVersion:     header("specversion"),
+			ID:              header("id"),
+			Source:          header("source"),
+			Type:            header("type"),
+			Subject:         header("subject"),
+			DataContentType: delivery.ContentType,
+			CorrelationID:   header("correlationid"),
+			Data:            delivery.Body,
+		}
+		if value := header("time"); value != "" {
+			occurred, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid event time %q: %w", value, err)
+			}
+			event.Time = occurred
+		}
+		return event, nil
+
+	case strings.HasPrefix(delivery.ContentType, events.ContentType):
+		var event events.Event
+		if err := json.Unmarshal(delivery.Body, &event); err != nil {
+			return nil, fmt.Errorf("invalid structured event: %w", err)
+		}
+		if event.SpecVersion == "" {
+			return nil, fmt.Errorf("structured event without specversion")
+		}
+		return &event, nil
+
+	default:
+		routingKey, _ := delivery.Headers[headerRoutingKey].(string)
+		if routingKey == "" {
+			routingKey = delivery.RoutingKey
+		}
+		id := delivery.MessageId
+		if id == "" {
+			id = legacyEventID(routingKey, delivery.Body)
+		}
+		return &events.Event{
+			ID:              id,
+			Type:            events.Type(routingKey),
+			Time:            delivery.Timestamp,
+			DataContentType: delivery.ContentType,
+			Data:            delivery.Body,
+		}, nil
+	}
+}
+
+// legacyEventID derives a stable id from the routing key and body, so a
+// redelivered message keeps its id for deduplication and notification rules.
+func legacyEventID(routingKey string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(routingKey))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return "legacy-" + hex.EncodeToString(hash.Sum(nil))
+}
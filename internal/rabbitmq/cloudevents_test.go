@@ -0,0 +1,186 @@
+package rabbitmq
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+func TestEncodeDecodeEvent(t *testing.T) {
+	event := &events.Event{
+		SpecVersion:     events.SpecVersion,
+		ID:              "3f2c9a4e-1b7d-4c55-9e0a-6d8f2b1c7e90",
+		Source:          "/transjakarta-fleet",
+		Type:            events.Type("geofence.geofence_entry"),
+		Subject:         "B1234ABC",
+		Time:            time.Date(2026, 10, 18, 8, 30, 15, 250000000, time.UTC),
+		DataContentType: "application/json",
+		CorrelationID:   "loc-42",
+		Data:            []byte(`{"vehicle_id":"B1234ABC","geofence_name":"Blok M"}`),
+	}
+
+	for _, mode := range []string{ModeBinary, ModeStructured} {
+		t.Run(mode, func(t *testing.T) {
+			message, err := encodeEvent(event, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if message.MessageId != event.ID {
+				t.Errorf("message id = %q, want %q", message.MessageId, event.ID)
+			}
+
+			got, err := decodeEvent(amqp091.Delivery{
+				Headers:     message.Headers,
+				ContentType: message.ContentType,
+				MessageId:   message.MessageId,
+				Body:        message.Body,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.SpecVersion != event.SpecVersion || got.ID != event.ID || got.Source != event.Source ||
+				got.Type != event.Type || got.Subject != event.Subject || got.CorrelationID != event.CorrelationID ||
+				got.DataContentType != event.DataContentType || !got.Time.Equal(event.Time) {
+				t.Errorf("decoded %+v, want %+v", got, event)
+			}
+			if string(got.Data) != string(event.Data) {
+				t.Errorf("data = %s, want %s", got.Data, event.Data)
+			}
+		})
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	body := []byte(`{"vehicle_id":"B1234ABC","event":"geofence_entry"}`)
+	published := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		delivery amqp091.Delivery
+		wantID   string
+		wantType string
+		wantErr  string
+	}{
+		{
+			name: "legacy message keeps its message id",
+			delivery: amqp091.Delivery{
+				RoutingKey: "geofence.geofence_entry",
+				MessageId:  "legacy-publisher-id",
+				Timestamp:  published,
+				Body:       body,
+			},
+			wantID:   "legacy-publisher-id",
+			wantType: events.Type("geofence.geofence_entry"),
+		},
+		{
+			name: "legacy message without id",
+			delivery: amqp091.Delivery{
+				RoutingKey: "geofence.geofence_entry",
+				Timestamp:  published,
+				Body:       body,
+			},
+			wantID:   legacyEventID("geofence.geofence_entry", body),
+			wantType: events.Type("geofence.geofence_entry"),
+		},
+		{
+			// Retried messages come back from the delay queue under the
+			// queue's routing key
+			name: "legacy message retried through a delay queue",
+			delivery: amqp091.Delivery{
+				RoutingKey: "geofence_alerts",
+				Headers:    amqp091.Table{headerRoutingKey: "geofence.geofence_entry", headerAttempts: int32(1)},
+				Timestamp:  published,
+				Body:       body,
+			},
+			wantID:   legacyEventID("geofence.geofence_entry", body),
+			wantType: events.Type("geofence.geofence_entry"),
+		},
+		{
+			name: "binary event with an invalid time",
+			delivery: amqp091.Delivery{
+				Headers: amqp091.Table{
+					"cloudEvents:specversion": "1.0",
+					"cloudEvents:id":          "1",
+					"cloudEvents:time":        "yesterday",
+				},
+				Body: body,
+			},
+			wantErr: "invalid event time",
+		},
+		{
+			name: "structured event that is not JSON",
+			delivery: amqp091.Delivery{
+				ContentType: events.ContentType,
+				Body:        []byte("not json"),
+			},
+			wantErr: "invalid structured event",
+		},
+		{
+			name: "structured event without specversion",
+			delivery: amqp091.Delivery{
+				ContentType: events.ContentType + "; charset=utf-8",
+				Body:        []byte(`{"id":"1","type":"x"}`),
+			},
+			wantErr: "without specversion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := decodeEvent(tt.delivery)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeEvent error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if event.ID != tt.wantID {
+				t.Errorf("id = %q, want %q", event.ID, tt.wantID)
+			}
+			if event.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", event.Type, tt.wantType)
+			}
+			if !event.Time.Equal(published) {
+				t.Errorf("time = %v, want %v", event.Time, published)
+			}
+			if string(event.Data) != string(body) {
+				t.Errorf("data = %s, want %s", event.Data, body)
+			}
+		})
+	}
+}
+
+func TestLegacyEventID(t *testing.T) {
+	body := []byte(`{"vehicle_id":"B1234ABC"}`)
+	id := legacyEventID("geofence.geofence_entry", body)
+
+	// Pinned so workers of different versions agree on the id of a message
+	// redelivered across a deploy
+	const want = "legacy-38802ab396081d43015b0cc422964d8f52d936843d8cb6c1ccdc184ded43066c"
+	if id != want {
+		t.Fatalf("id = %q, want %q", id, want)
+	}
+	if again := legacyEventID("geofence.geofence_entry", []byte(`{"vehicle_id":"B1234ABC"}`)); again != id {
+		t.Errorf("redelivered message id = %q, want %q", again, id)
+	}
+
+	for name, other := range map[string]string{
+		"other body":        legacyEventID("geofence.geofence_entry", []byte(`{"vehicle_id":"B5678DEF"}`)),
+		"other routing key": legacyEventID("geofence.geofence_exit", body),
+		// The separator keeps the key and body from running together
+		"shifted boundary": legacyEventID("geofence.geofence_entry{", body[1:]),
+	} {
+		if other == id {
+			t.Errorf("%s has the same id %q", name, id)
+		}
+	}
+}
@@ -2,34 +2,109 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/partition"
+)
+
+// Headers the consumer sets on failed messages
+const (
+	// headerAttempts counts the failed attempts to handle a message
+	headerAttempts = "x-attempts"
+	// headerLastError holds the error of the latest failed attempt
+	headerLastError = "x-last-error"
+	// headerRoutingKey keeps the routing key a message was first published
+	// with, to replay it after it has been parked
+	headerRoutingKey = "x-original-routing-key"
+	// headerParkedAt is when a message was parked
+	headerParkedAt = "x-parked-at"
 )
 
+// Consumer hands queued messages to a handler on a pool of goroutines.
+// Messages are partitioned by vehicle, so each vehicle's events are handled
+// one at a time in queue order. A message the handler fails on is retried
+// after a delay and parked once it is out of attempts.
 type Consumer struct {
-	client *Client
-	logger *zap.Logger
+	client    *Client
+	publisher forwarder
+	logger    *zap.Logger
+}
+
+// forwarder moves a consumed message to another exchange. Publisher returns
+// once the broker has confirmed the copy.
+type forwarder interface {
+	Forward(ctx context.Context, exchange, routingKey string, message amqp091.Publishing, mandatory bool) error
 }
 
-type MessageHandler func(ctx context.Context, message *GeofenceEventMessage) error
+// MessageHandler handles an event. Errors wrapping events.ErrRejected park
+// the message at once; other errors are retried.
+type MessageHandler func(ctx context.Context, event *events.Event) error
 
-func NewConsumer(client *Client, logger *zap.Logger) *Consumer {
+func NewConsumer(client *Client, publisher *Publisher, logger *zap.Logger) *Consumer {
 	return &Consumer{
-		client: client,
-		logger: logger,
+		client:    client,
+		publisher: publisher,
+		logger:    logger,
 	}
 }
 
+// StartConsuming delivers queued messages to handler until the context ends,
+// subscribing again on a new channel whenever the client reconnects.
 func (c *Consumer) StartConsuming(ctx context.Context, handler MessageHandler) error {
-	if !c.client.IsConnected() {
-		return fmt.Errorf("RabbitMQ client not connected")
+	for {
+		if err := c.client.WaitConnected(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		channel, messages, err := c.subscribe()
+		if err != nil {
+			c.logger.Error("Failed to start consuming, retrying", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(max(c.client.config.ReconnectMinBackoff, 100*time.Millisecond)):
+			}
+			continue
+		}
+
+		c.logger.Info("Started consuming RabbitMQ messages",
+			zap.String("queue", c.client.config.Queue),
+			zap.Int("prefetch", c.client.config.Prefetch),
+			zap.Int("workers", c.workers()))
+
+		c.consume(ctx, messages, handler)
+		channel.Close()
+
+		if ctx.Err() != nil {
+			c.logger.Info("RabbitMQ consumer stopping")
+			return nil
+		}
+		c.logger.Warn("RabbitMQ message channel closed, resubscribing")
 	}
-	
-	// Start consuming messages
-	messages, err := c.client.channel.Consume(
+}
+
+// subscribe opens a dedicated channel and starts a consumer on the queue.
+func (c *Consumer) subscribe() (*amqp091.Channel, <-chan amqp091.Delivery, error) {
+	channel, err := c.client.OpenChannel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	if err := channel.Qos(c.client.config.Prefetch, 0, false); err != nil {
+		channel.Close()
+		return nil, nil, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	messages, err := channel.Consume(
 		c.client.config.Queue, // queue
 		"",                    // consumer tag
 		false,                 // auto-ack (we'll ack manually)
@@ -39,57 +114,86 @@ func (c *Consumer) StartConsuming(ctx context.Context, handler MessageHandler) e
 		nil,                   // args
 	)
 	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		channel.Close()
+		return nil, nil, fmt.Errorf("failed to start consuming: %w", err)
 	}
-	
-	c.logger.Info("Started consuming RabbitMQ messages", 
-		zap.String("queue", c.client.config.Queue))
-	
-	// Process messages
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("RabbitMQ consumer stopping")
+
+	return channel, messages, nil
+}
+
+// consume dispatches deliveries to the workers until the context ends or the
+// channel closes, then waits for the messages already dispatched.
+func (c *Consumer) consume(ctx context.Context, messages <-chan amqp091.Delivery, handler MessageHandler) {
+	pool := partition.NewPool(ctx, c.workers(), c.client.config.Prefetch)
+
+	c.dispatch(ctx, messages, pool, handler)
+
+	// Deliveries not yet dispatched stay unacknowledged and are requeued
+	// when the channel closes
+	if pool.Stop(c.client.config.ShutdownTimeout) {
+		c.logger.Warn("RabbitMQ handlers still running after shutdown timeout, cancelled them")
+	}
+}
+
+// dispatch decodes each delivery once and queues it on the partition of its
+// vehicle. Messages that cannot be decoded share one partition.
+func (c *Consumer) dispatch(ctx context.Context, messages <-chan amqp091.Delivery, pool *partition.Pool, handler MessageHandler) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			event, err := decodeEvent(delivery)
+			var key string
+			if err == nil {
+				key = event.PartitionKey()
+			}
+
+			if !pool.Dispatch(ctx, key, func(workCtx context.Context) {
+				c.processMessage(workCtx, delivery, event, err, handler)
+			}) {
 				return
-			case delivery, ok := <-messages:
-				if !ok {
-					c.logger.Warn("RabbitMQ message channel closed")
-					return
-				}
-				
-				c.processMessage(ctx, delivery, handler)
 			}
 		}
-	}()
-	
-	// Wait for context cancellation
-	<-ctx.Done()
-	return nil
+	}
+}
+
+func (c *Consumer) workers() int {
+	return max(c.client.config.Workers, 1)
 }
 
-func (c *Consumer) processMessage(ctx context.Context, delivery amqp091.Delivery, handler MessageHandler) {
-	// Parse message
-	var message GeofenceEventMessage
-	if err := json.Unmarshal(delivery.Body, &message); err != nil {
+// processMessage handles a delivery decoded into event, or parks it when
+// decoding failed with decodeErr.
+func (c *Consumer) processMessage(ctx context.Context, delivery amqp091.Delivery, event *events.Event, decodeErr error, handler MessageHandler) {
+	if decodeErr != nil {
 		c.logger.Error("Failed to parse RabbitMQ message", 
-			zap.Error(err),
+			zap.Error(decodeErr),
 			zap.String("body", string(delivery.Body)))
-		delivery.Nack(false, false) // Reject and don't requeue
+		// Retrying cannot fix a malformed message
+		c.park(ctx, delivery, deliveryAttempts(delivery)+1, decodeErr)
 		return
 	}
 	
-	c.logger.Info("Processing geofence event", 
-		zap.String("vehicle_id", message.VehicleID),
-		zap.String("event", message.Event),
-		zap.String("geofence_name", message.GeofenceName))
+	c.logger.Debug("Processing RabbitMQ message", 
+		zap.String("event_id", event.ID),
+		zap.String("event_type", event.Type),
+		zap.String("subject", event.Subject))
 	
 	// Handle message
-	if err := handler(ctx, &message); err != nil {
+	if err := handler(ctx, event); err != nil {
 		c.logger.Error("Failed to handle RabbitMQ message", 
 			zap.Error(err),
-			zap.String("vehicle_id", message.VehicleID))
-		delivery.Nack(false, true) // Reject and requeue for retry
+			zap.String("event_id", event.ID),
+			zap.String("subject", event.Subject))
+		if errors.Is(err, events.ErrRejected) {
+			c.park(ctx, delivery, deliveryAttempts(delivery)+1, err)
+			return
+		}
+		c.retry(ctx, delivery, err)
 		return
 	}
 	
@@ -97,4 +201,96 @@ func (c *Consumer) processMessage(ctx context.Context, delivery amqp091.Delivery
 	if err := delivery.Ack(false); err != nil {
 		c.logger.Error("Failed to acknowledge RabbitMQ message", zap.Error(err))
 	}
-}
\ No newline at end of file
+}
+
+// retry moves a failed message to the delay queue of its next attempt, or
+// parks it once it is out of attempts.
+func (c *Consumer) retry(ctx context.Context, delivery amqp091.Delivery, cause error) {
+	attempts := deliveryAttempts(delivery) + 1
+	delays := c.client.retryDelays()
+	if attempts > len(delays) {
+		c.park(ctx, delivery, attempts, cause)
+		return
+	}
+
+	delay := delays[attempts-1]
+	if err := c.forward(ctx, delivery, c.client.retryQueue(delay), attempts, cause, nil); err != nil {
+		return
+	}
+
+	c.logger.Warn("RabbitMQ message scheduled for retry",
+		zap.String("message_id", delivery.MessageId),
+		zap.Int("attempts", attempts),
+		zap.Duration("delay", delay))
+}
+
+func (c *Consumer) park(ctx context.Context, delivery amqp091.Delivery, attempts int, cause error) {
+	headers := amqp091.Table{headerParkedAt: time.Now().UTC()}
+	if err := c.forward(ctx, delivery, c.client.parkingQueue(), attempts, cause, headers); err != nil {
+		return
+	}
+
+	c.logger.Error("RabbitMQ message parked",
+		zap.String("message_id", delivery.MessageId),
+		zap.Int("attempts", attempts),
+		zap.String("queue", c.client.parkingQueue()))
+}
+
+// forward publishes a copy of the delivery to the dead-letter exchange and
+// acks the original once the copy is confirmed. If it cannot be published
+// the delivery is requeued, so it is never lost.
+func (c *Consumer) forward(ctx context.Context, delivery amqp091.Delivery, routingKey string, attempts int, cause error, extra amqp091.Table) error {
+	headers := amqp091.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	// The broker's own dead-letter history grows with every pass through a
+	// delay queue; the attempts header replaces it
+	delete(headers, "x-death")
+	if _, ok := headers[headerRoutingKey]; !ok {
+		headers[headerRoutingKey] = delivery.RoutingKey
+	}
+	headers[headerAttempts] = int32(attempts)
+	headers[headerLastError] = events.TruncateReason(cause.Error())
+	for key, value := range extra {
+		headers[key] = value
+	}
+
+	message := amqp091.Publishing{
+		Headers:      headers,
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp091.Persistent,
+		MessageId:    delivery.MessageId,
+		Timestamp:    delivery.Timestamp,
+		Body:         delivery.Body,
+	}
+
+	if err := c.publisher.Forward(ctx, c.client.deadLetterExchange(), routingKey, message, true); err != nil {
+		c.logger.Error("Failed to forward RabbitMQ message, requeueing",
+			zap.Error(err),
+			zap.String("message_id", delivery.MessageId),
+			zap.String("routing_key", routingKey))
+		delivery.Nack(false, true)
+		return err
+	}
+
+	if err := delivery.Ack(false); err != nil {
+		// The original comes back and is handled again
+		c.logger.Error("Failed to acknowledge RabbitMQ message", zap.Error(err))
+	}
+	return nil
+}
+
+// deliveryAttempts returns how many times handling the delivery has failed.
+func deliveryAttempts(delivery amqp091.Delivery) int {
+	switch attempts := delivery.Headers[headerAttempts].(type) {
+	case int32:
+		return int(attempts)
+	case int64:
+		return int(attempts)
+	case int:
+		return attempts
+	default:
+		return 0
+	}
+}
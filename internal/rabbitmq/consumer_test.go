@@ -0,0 +1,271 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+// fakeForwarder records forwarded copies, failing them with err.
+type fakeForwarder struct {
+	err       error
+	exchanges []string
+	keys      []string
+	messages  []amqp091.Publishing
+}
+
+func (f *fakeForwarder) Forward(ctx context.Context, exchange, routingKey string, message amqp091.Publishing, mandatory bool) error {
+	if !mandatory {
+		return errors.New("forwarded copies must be mandatory")
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.exchanges = append(f.exchanges, exchange)
+	f.keys = append(f.keys, routingKey)
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+// fakeAcknowledger records how the original delivery was settled.
+type fakeAcknowledger struct {
+	settled []string
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.settled = append(a.settled, "ack")
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.settled = append(a.settled, fmt.Sprintf("nack requeue=%t", requeue))
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.settled = append(a.settled, fmt.Sprintf("reject requeue=%t", requeue))
+	return nil
+}
+
+func TestConsumerProcessMessage(t *testing.T) {
+	errHandler := errors.New("database unavailable")
+	body := []byte(`{"vehicle_id":"B1234ABC"}`)
+
+	tests := []struct {
+		name       string
+		headers    amqp091.Table
+		body       []byte
+		handlerErr error
+		forwardErr error
+
+		wantSettled string
+		// Empty when the message is not forwarded
+		wantKey          string
+		wantAttempts     int32
+		wantOriginalKey  string
+		wantParked       bool
+		wantErrorMessage string
+	}{
+		{
+			name:        "handled",
+			wantSettled: "ack",
+		},
+		{
+			name:             "first failure waits in the first delay queue",
+			handlerErr:       errHandler,
+			wantSettled:      "ack",
+			wantKey:          "geofence_alerts.retry.5s",
+			wantAttempts:     1,
+			wantOriginalKey:  "geofence.geofence_entry",
+			wantErrorMessage: errHandler.Error(),
+		},
+		{
+			name: "retried message keeps its original routing key",
+			headers: amqp091.Table{
+				headerAttempts:   int64(2),
+				headerRoutingKey: "geofence.geofence_exit",
+				"x-death":        []interface{}{amqp091.Table{"count": int64(1)}},
+			},
+			handlerErr:       errHandler,
+			wantSettled:      "ack",
+			wantKey:          "geofence_alerts.retry.20s",
+			wantAttempts:     3,
+			wantOriginalKey:  "geofence.geofence_exit",
+			wantErrorMessage: errHandler.Error(),
+		},
+		{
+			name:             "out of attempts is parked",
+			headers:          amqp091.Table{headerAttempts: int32(4)},
+			handlerErr:       errHandler,
+			wantSettled:      "ack",
+			wantKey:          "geofence_alerts.parked",
+			wantAttempts:     5,
+			wantOriginalKey:  "geofence.geofence_entry",
+			wantParked:       true,
+			wantErrorMessage: errHandler.Error(),
+		},
+		{
+			name:             "rejected event is parked at once",
+			handlerErr:       fmt.Errorf("%w: unknown geofence", events.ErrRejected),
+			wantSettled:      "ack",
+			wantKey:          "geofence_alerts.parked",
+			wantAttempts:     1,
+			wantOriginalKey:  "geofence.geofence_entry",
+			wantParked:       true,
+			wantErrorMessage: "event rejected: unknown geofence",
+		},
+		{
+			name:             "undecodable message is parked",
+			body:             []byte("{"),
+			wantSettled:      "ack",
+			wantKey:          "geofence_alerts.parked",
+			wantAttempts:     1,
+			wantOriginalKey:  "geofence.geofence_entry",
+			wantParked:       true,
+			wantErrorMessage: "invalid structured event",
+		},
+		{
+			name:        "unconfirmed copy requeues the original",
+			handlerErr:  errHandler,
+			forwardErr:  errors.New("publish not confirmed"),
+			wantSettled: "nack requeue=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarder := &fakeForwarder{err: tt.forwardErr}
+			consumer := &Consumer{
+				client: &Client{config: &config.RabbitMQConfig{
+					Exchange:      "fleet.events",
+					Queue:         "geofence_alerts",
+					MaxAttempts:   5,
+					RetryDelay:    5 * time.Second,
+					RetryMaxDelay: 5 * time.Minute,
+				}},
+				publisher: forwarder,
+				logger:    zap.NewNop(),
+			}
+
+			acknowledger := &fakeAcknowledger{}
+			delivery := amqp091.Delivery{
+				Acknowledger: acknowledger,
+				Headers:      tt.headers,
+				ContentType:  "application/json",
+				MessageId:    "3f2c9a4e",
+				Timestamp:    time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+				RoutingKey:   "geofence.geofence_entry",
+				Body:         body,
+			}
+			if tt.body != nil {
+				delivery.ContentType = events.ContentType
+				delivery.Body = tt.body
+			}
+
+			event, decodeErr := decodeEvent(delivery)
+			consumer.processMessage(context.Background(), delivery, event, decodeErr, func(ctx context.Context, event *events.Event) error {
+				return tt.handlerErr
+			})
+
+			if len(acknowledger.settled) != 1 || acknowledger.settled[0] != tt.wantSettled {
+				t.Fatalf("original settled %v, want %s", acknowledger.settled, tt.wantSettled)
+			}
+
+			if tt.wantKey == "" {
+				if len(forwarder.messages) != 0 {
+					t.Fatalf("forwarded %d copies, want none", len(forwarder.messages))
+				}
+				return
+			}
+			if len(forwarder.messages) != 1 {
+				t.Fatalf("forwarded %d copies, want 1", len(forwarder.messages))
+			}
+			if forwarder.exchanges[0] != "fleet.events.dlx" || forwarder.keys[0] != tt.wantKey {
+				t.Errorf("forwarded to %s/%s, want fleet.events.dlx/%s", forwarder.exchanges[0], forwarder.keys[0], tt.wantKey)
+			}
+
+			copied := forwarder.messages[0]
+			if copied.MessageId != delivery.MessageId || !copied.Timestamp.Equal(delivery.Timestamp) ||
+				copied.ContentType != delivery.ContentType || string(copied.Body) != string(delivery.Body) {
+				t.Errorf("copy %+v does not match the delivery", copied)
+			}
+			if copied.DeliveryMode != amqp091.Persistent {
+				t.Errorf("copy is not persistent")
+			}
+
+			headers := copied.Headers
+			if attempts, ok := headers[headerAttempts].(int32); !ok || attempts != tt.wantAttempts {
+				t.Errorf("%s = %#v, want %d", headerAttempts, headers[headerAttempts], tt.wantAttempts)
+			}
+			if key := headers[headerRoutingKey]; key != tt.wantOriginalKey {
+				t.Errorf("%s = %v, want %s", headerRoutingKey, key, tt.wantOriginalKey)
+			}
+			if reason, _ := headers[headerLastError].(string); !strings.HasPrefix(reason, tt.wantErrorMessage) {
+				t.Errorf("%s = %q, want %q", headerLastError, reason, tt.wantErrorMessage)
+			}
+			if _, ok := headers["x-death"]; ok {
+				t.Error("copy kept the broker's x-death history")
+			}
+			if _, parked := headers[headerParkedAt]; parked != tt.wantParked {
+				t.Errorf("%s set = %t, want %t", headerParkedAt, parked, tt.wantParked)
+			}
+
+			// The copy gets its own headers
+			if len(delivery.Headers) != len(tt.headers) {
+				t.Errorf("delivery headers modified to %v", delivery.Headers)
+			}
+		})
+	}
+}
+
+func TestRetryDelays(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		retryDelay  time.Duration
+		maxDelay    time.Duration
+		want        []time.Duration
+	}{
+		{
+			name:        "doubling up to the max delay",
+			maxAttempts: 6,
+			retryDelay:  5 * time.Second,
+			maxDelay:    30 * time.Second,
+			want:        []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second},
+		},
+		{
+			name:        "single attempt never retries",
+			maxAttempts: 1,
+			retryDelay:  5 * time.Second,
+		},
+		{
+			name:        "delay has a floor",
+			maxAttempts: 2,
+			want:        []time.Duration{100 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{config: &config.RabbitMQConfig{
+				MaxAttempts:   tt.maxAttempts,
+				RetryDelay:    tt.retryDelay,
+				RetryMaxDelay: tt.maxDelay,
+			}}
+
+			if got := client.retryDelays(); !slices.Equal(got, tt.want) {
+				t.Errorf("delays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// ParkingLot inspects, replays and purges the messages the worker gave up on.
+type ParkingLot struct {
+	client    *Client
+	publisher *Publisher
+	logger    *zap.Logger
+}
+
+// ParkedMessage describes a parked message. Body holds the payload when it
+// is valid JSON and RawBody otherwise.
+type ParkedMessage struct {
+	MessageID  string          `json:"message_id,omitempty"`
+	RoutingKey string          `json:"routing_key"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"last_error,omitempty"`
+	ParkedAt   *time.Time      `json:"parked_at,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	RawBody    string          `json:"raw_body,omitempty"`
+}
+
+func NewParkingLot(client *Client, publisher *Publisher, logger *zap.Logger) *ParkingLot {
+	return &ParkingLot{
+		client:    client,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Count returns the number of parked messages.
+func (p *ParkingLot) Count() (int, error) {
+	channel, err := p.client.OpenChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer channel.Close()
+
+	queue, err := channel.QueueDeclarePassive(p.client.parkingQueue(), true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect parking queue: %w", err)
+	}
+	return queue.Messages, nil
+}
+
+// Inspect returns up to limit parked messages, oldest first, leaving them
+// parked.
+func (p *ParkingLot) Inspect(limit int) ([]*ParkedMessage, error) {
+	channel, err := p.client.OpenChannel()
+	if err != nil {
+		return nil, err
+	}
+	// Closing the channel requeues every message fetched without an ack
+	defer channel.Close()
+
+	messages := []*ParkedMessage{}
+	for len(messages) < limit {
+		delivery, ok, err := channel.Get(p.client.parkingQueue(), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parking queue: %w", err)
+		}
+		if !ok {
+			break
+		}
+		messages = append(messages, parkedMessage(delivery))
+	}
+
+	return messages, nil
+}
+
+// Replay publishes up to limit parked messages back to the exchange with
+// their original routing keys, with a fresh set of attempts. A non-empty
+// messageID replays only the messages with that id. It returns the number
+// replayed.
+func (p *ParkingLot) Replay(ctx context.Context, messageID string, limit int) (int, error) {
+	channel, err := p.client.OpenChannel()
+	if err != nil {
+		return 0, err
+	}
+	// Messages skipped or left unacked are requeued when the channel closes
+	defer channel.Close()
+
+	queue, err := channel.QueueDeclarePassive(p.client.parkingQueue(), true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect parking queue: %w", err)
+	}
+
+	// Only the messages parked now are visited, so a replayed message that
+	// fails again and is parked meanwhile is not replayed twice
+	replayed := 0
+	for visited := 0; visited < queue.Messages && replayed < limit; visited++ {
+		delivery, ok, err := channel.Get(p.client.parkingQueue(), false)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read parking queue: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if messageID != "" && delivery.MessageId != messageID {
+			continue
+		}
+
+		routingKey, _ := delivery.Headers[headerRoutingKey].(string)
+		if routingKey == "" {
+			return replayed, fmt.Errorf("parked message %q has no original routing key", delivery.MessageId)
+		}
+
+		headers := amqp091.Table{}
+		for key, value := range delivery.Headers {
+			switch key {
+			case headerAttempts, headerLastError, headerRoutingKey, headerParkedAt, "x-death":
+			default:
+				headers[key] = value
+			}
+		}
+
+		message := amqp091.Publishing{
+			Headers:      headers,
+			ContentType:  delivery.ContentType,
+			DeliveryMode: amqp091.Persistent,
+			MessageId:    delivery.MessageId,
+			Timestamp:    delivery.Timestamp,
+			Body:         delivery.Body,
+		}
+		if err := p.publisher.Forward(ctx, p.client.config.Exchange, routingKey, message, true); err != nil {
+			return replayed, fmt.Errorf("failed to replay parked message %q: %w", delivery.MessageId, err)
+		}
+
+		if err := delivery.Ack(false); err != nil {
+			return replayed, fmt.Errorf("failed to remove replayed message %q: %w", delivery.MessageId, err)
+		}
+		replayed++
+	}
+
+	p.logger.Info("Parked messages replayed",
+		zap.Int("count", replayed),
+		zap.String("message_id", messageID))
+
+	return replayed, nil
+}
+
+// Purge deletes every parked message and returns how many there were.
+func (p *ParkingLot) Purge() (int, error) {
+	channel, err := p.client.OpenChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer channel.Close()
+
+	purged, err := channel.QueuePurge(p.client.parkingQueue(), false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge parking queue: %w", err)
+	}
+
+	p.logger.Warn("Parked messages purged", zap.Int("count", purged))
+	return purged, nil
+}
+
+func parkedMessage(delivery amqp091.Delivery) *ParkedMessage {
+	message := &ParkedMessage{
+		MessageID:  delivery.MessageId,
+		RoutingKey: delivery.RoutingKey,
+		Attempts:   deliveryAttempts(delivery),
+	}
+	if routingKey, ok := delivery.Headers[headerRoutingKey].(string); ok {
+		message.RoutingKey = routingKey
+	}
+	if lastError, ok := delivery.Headers[headerLastError].(string); ok {
+		message.LastError = lastError
+	}
+	if parkedAt, ok := delivery.Headers[headerParkedAt].(time.Time); ok {
+		message.ParkedAt = &parkedAt
+	}
+	if json.Valid(delivery.Body) {
+		message.Body = delivery.Body
+	} else {
+		message.RawBody = string(delivery.Body)
+	}
+	return message
+}
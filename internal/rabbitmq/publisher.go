@@ -2,85 +2,203 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+)
+
+var (
+	// ErrUnroutable is returned when no queue is bound for a message
+	ErrUnroutable = errors.New("message unroutable")
+	// ErrNacked is returned when the broker refuses a message
+	ErrNacked = errors.New("message nacked by broker")
 )
 
+// Publisher sends events on its own confirm-mode channel and waits for each
+// to be confirmed. It is safe for concurrent use.
 type Publisher struct {
-	client *Client
-	logger *zap.Logger
-}
+	client   *Client
+	logger   *zap.Logger
+	instance string // prefixes message ids
+
+	mu       sync.Mutex
+	channel  *amqp091.Channel
+	returns  chan amqp091.Return
+	sequence uint64
 
-type GeofenceEventMessage struct {
-	VehicleID string  `json:"vehicle_id"`
-	Event     string  `json:"event"`
-	Location  Location `json:"location"`
-	Timestamp int64   `json:"timestamp"`
-	GeofenceName string `json:"geofence_name,omitempty"`
-	Distance     float64 `json:"distance,omitempty"`
+	published atomic.Int64
+	retried   atomic.Int64
+	failed    atomic.Int64
 }
 
-type Location struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+// PublisherStats counts confirmed messages, retried attempts and messages
+// given up on.
+type PublisherStats struct {
+	Published int64 `json:"published"`
+	Retried   int64 `json:"retried"`
+	Failed    int64 `json:"failed"`
 }
 
 func NewPublisher(client *Client, logger *zap.Logger) *Publisher {
 	return &Publisher{
-		client: client,
-		logger: logger,
+		client:   client,
+		logger:   logger,
+		instance: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// PublishEvent sends an event in the configured envelope mode, with the
+// event id as message id so consumers can recognize redeliveries.
+func (p *Publisher) PublishEvent(ctx context.Context, routingKey string, event *events.Event, mandatory bool) error {
+	message, err := encodeEvent(event, p.client.config.EventMode)
+	if err != nil {
+		return err
 	}
+	return p.publish(ctx, p.client.config.Exchange, routingKey, message, mandatory)
 }
 
-func (p *Publisher) PublishGeofenceEvent(ctx context.Context, message *GeofenceEventMessage) error {
-	if !p.client.IsConnected() {
-		return fmt.Errorf("RabbitMQ client not connected")
+// Forward sends a copy of a consumed message to an exchange, keeping its id
+// and properties, e.g. to move it to a retry or parking queue.
+func (p *Publisher) Forward(ctx context.Context, exchange, routingKey string, message amqp091.Publishing, mandatory bool) error {
+	return p.publish(ctx, exchange, routingKey, message, mandatory)
+}
+
+// publish sends a message and waits for the broker to confirm it, retrying
+// up to PublishRetries times when it is nacked, times out or, if mandatory,
+// is returned as unroutable. A message without an id gets one per attempt.
+func (p *Publisher) publish(ctx context.Context, exchange, routingKey string, message amqp091.Publishing, mandatory bool) error {
+	backoff := p.client.config.PublishRetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= p.client.config.PublishRetries; attempt++ {
+		if attempt > 0 {
+			p.retried.Add(1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err = p.publishConfirmed(ctx, exchange, routingKey, message, mandatory); err == nil {
+			p.published.Add(1)
+			return nil
+		}
+
+		p.logger.Warn("RabbitMQ publish attempt failed",
+			zap.Error(err),
+			zap.String("routing_key", routingKey),
+			zap.Int("attempt", attempt+1))
 	}
-	
-	// Convert message to JSON
-	body, err := json.Marshal(message)
+
+	p.failed.Add(1)
+	return fmt.Errorf("gave up after %d attempts: %w", p.client.config.PublishRetries+1, err)
+}
+
+func (p *Publisher) publishConfirmed(ctx context.Context, exchange, routingKey string, message amqp091.Publishing, mandatory bool) error {
+	// Channels are not safe for concurrent use, and serializing publishes
+	// ties each return to the message it answers
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel, err := p.confirmChannel()
 	if err != nil {
-		return fmt.Errorf("failed to marshal geofence event: %w", err)
+		return err
+	}
+
+	p.sequence++
+	if message.MessageId == "" {
+		message.MessageId = fmt.Sprintf("%s-%d", p.instance, p.sequence)
 	}
-	
-	// Create routing key
-	routingKey := fmt.Sprintf("geofence.%s", message.Event)
-	
-	// Create context with timeout
-	pubCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+
+	pubCtx, cancel := context.WithTimeout(ctx, p.client.config.PublishTimeout)
 	defer cancel()
-	
-	// Publish message
-	err = p.client.channel.PublishWithContext(
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
 		pubCtx,
-		p.client.config.Exchange, // exchange
-		routingKey,               // routing key
-		false,                    // mandatory
-		false,                    // immediate
-		amqp091.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp091.Persistent, // Make message persistent
-			Timestamp:    time.Now(),
-			Body:         body,
-		},
+		exchange,   // exchange
+		routingKey, // routing key
+		mandatory,  // return if no queue is bound
+		false,      // immediate
+		message,
 	)
-	
 	if err != nil {
-		p.logger.Error("Failed to publish geofence event", 
-			zap.Error(err),
-			zap.String("vehicle_id", message.VehicleID),
-			zap.String("event", message.Event))
-		return fmt.Errorf("failed to publish geofence event: %w", err)
+		p.resetChannel()
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(pubCtx)
+	if err != nil {
+		// The confirm may still arrive; start over on a fresh channel
+		p.resetChannel()
+		return fmt.Errorf("no confirm from broker: %w", err)
+	}
+
+	// The broker sends a return before the ack of the message it returns
+	for returned := true; returned; {
+		select {
+		case r, ok := <-p.returns:
+			if !ok {
+				// Closed with the channel
+				returned = false
+			} else if r.MessageId == message.MessageId {
+				return fmt.Errorf("%w: %s (%d %s)", ErrUnroutable, routingKey, r.ReplyCode, r.ReplyText)
+			}
+		default:
+			returned = false
+		}
+	}
+
+	if !acked {
+		return ErrNacked
 	}
-	
-	p.logger.Info("Geofence event published successfully", 
-		zap.String("vehicle_id", message.VehicleID),
-		zap.String("event", message.Event),
-		zap.String("routing_key", routingKey))
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// confirmChannel returns the publisher's channel in confirm mode, opening one
+// on the current connection when needed. Callers hold p.mu.
+func (p *Publisher) confirmChannel() (*amqp091.Channel, error) {
+	if p.channel != nil && !p.channel.IsClosed() {
+		return p.channel, nil
+	}
+
+	channel, err := p.client.OpenChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	p.channel = channel
+	p.returns = channel.NotifyReturn(make(chan amqp091.Return, 1))
+	return channel, nil
+}
+
+// resetChannel drops the publisher's channel. Callers hold p.mu.
+func (p *Publisher) resetChannel() {
+	if p.channel != nil {
+		p.channel.Close()
+		p.channel = nil
+	}
+}
+
+// Stats returns the publish counters.
+func (p *Publisher) Stats() PublisherStats {
+	return PublisherStats{
+		Published: p.published.Load(),
+		Retried:   p.retried.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type distanceRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewDistanceRepository(db *pgxpool.Pool, logger *zap.Logger) DistanceRepository {
+	return &distanceRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetOdometer returns the odometer of a vehicle, or nil if none is recorded yet.
+func (r *distanceRepository) GetOdometer(ctx context.Context, vehicleID string) (*models.VehicleOdometer, error) {
+	query := `
+		SELECT vehicle_id, total_meters, last_latitude, last_longitude, last_timestamp, updated_at
+		FROM vehicle_odometers
+		WHERE vehicle_id = $1
+	`
+
+	odometer := &models.VehicleOdometer{}
+	err := r.db.QueryRow(ctx, query, vehicleID).Scan(
+		&odometer.VehicleID,
+		&odometer.TotalMeters,
+		&odometer.LastLatitude,
+		&odometer.LastLongitude,
+		&odometer.LastTimestamp,
+		&odometer.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get odometer",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get odometer for vehicle %s: %w", vehicleID, err)
+	}
+
+	return odometer, nil
+}
+
+// RecordDistance adds meters to the vehicle's daily rollup and odometer and
+// moves the odometer reference to the given location.
+func (r *distanceRepository) RecordDistance(ctx context.Context, vehicleID string, day string, meters float64, location *models.VehicleLocation) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vehicle_daily_distances (vehicle_id, day, distance_meters, point_count)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (vehicle_id, day) DO UPDATE SET
+			distance_meters = vehicle_daily_distances.distance_meters + EXCLUDED.distance_meters,
+			point_count = vehicle_daily_distances.point_count + 1,
+			updated_at = NOW()
+	`, vehicleID, day, meters)
+	if err != nil {
+		r.logger.Error("Failed to update daily distance",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return fmt.Errorf("failed to update daily distance: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO vehicle_odometers (vehicle_id, total_meters, last_latitude, last_longitude, last_timestamp)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (vehicle_id) DO UPDATE SET
+			total_meters = vehicle_odometers.total_meters + EXCLUDED.total_meters,
+			last_latitude = EXCLUDED.last_latitude,
+			last_longitude = EXCLUDED.last_longitude,
+			last_timestamp = EXCLUDED.last_timestamp,
+			updated_at = NOW()
+	`, vehicleID, meters, location.Latitude, location.Longitude, location.Timestamp)
+	if err != nil {
+		r.logger.Error("Failed to update odometer",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return fmt.Errorf("failed to update odometer: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *distanceRepository) GetDailyByVehicleID(ctx context.Context, vehicleID string, fromDay, toDay string) ([]*models.DailyDistance, error) {
+	query := `
+		SELECT vehicle_id, to_char(day, 'YYYY-MM-DD'), distance_meters, point_count, updated_at
+		FROM vehicle_daily_distances
+		WHERE vehicle_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID, fromDay, toDay)
+	if err != nil {
+		r.logger.Error("Failed to get daily distances",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get daily distances for vehicle %s: %w", vehicleID, err)
+	}
+	defer rows.Close()
+
+	return scanDailyDistances(rows)
+}
+
+func (r *distanceRepository) GetDailyByDay(ctx context.Context, day string) ([]*models.DailyDistance, error) {
+	query := `
+		SELECT vehicle_id, to_char(day, 'YYYY-MM-DD'), distance_meters, point_count, updated_at
+		FROM vehicle_daily_distances
+		WHERE day = $1
+		ORDER BY distance_meters DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, day)
+	if err != nil {
+		r.logger.Error("Failed to get fleet daily distances",
+			zap.Error(err),
+			zap.String("day", day))
+		return nil, fmt.Errorf("failed to get daily distances for %s: %w", day, err)
+	}
+	defer rows.Close()
+
+	return scanDailyDistances(rows)
+}
+
+// ReplaceDaily overwrites the daily rollups of a vehicle within the day range
+// and recomputes its odometer total, using last as the new reference point.
+func (r *distanceRepository) ReplaceDaily(ctx context.Context, vehicleID string, fromDay, toDay string, days []*models.DailyDistance, last *models.VehicleLocation) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM vehicle_daily_distances WHERE vehicle_id = $1 AND day BETWEEN $2 AND $3",
+		vehicleID, fromDay, toDay); err != nil {
+		return fmt.Errorf("failed to delete daily distances for vehicle %s: %w", vehicleID, err)
+	}
+
+	for _, day := range days {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO vehicle_daily_distances (vehicle_id, day, distance_meters, point_count)
+			VALUES ($1, $2, $3, $4)
+		`, vehicleID, day.Date, day.DistanceMeters, day.PointCount); err != nil {
+			return fmt.Errorf("failed to insert daily distance for vehicle %s: %w", vehicleID, err)
+		}
+	}
+
+	if last != nil {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO vehicle_odometers (vehicle_id, total_meters, last_latitude, last_longitude, last_timestamp)
+			VALUES ($1, 0, $2, $3, $4)
+			ON CONFLICT (vehicle_id) DO UPDATE SET
+				last_latitude = EXCLUDED.last_latitude,
+				last_longitude = EXCLUDED.last_longitude,
+				last_timestamp = EXCLUDED.last_timestamp
+			WHERE vehicle_odometers.last_timestamp <= EXCLUDED.last_timestamp
+		`, vehicleID, last.Latitude, last.Longitude, last.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to update odometer reference for vehicle %s: %w", vehicleID, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE vehicle_odometers SET
+			total_meters = (
+				SELECT COALESCE(SUM(distance_meters), 0)
+				FROM vehicle_daily_distances
+				WHERE vehicle_id = $1
+			),
+			updated_at = NOW()
+		WHERE vehicle_id = $1
+	`, vehicleID); err != nil {
+		return fmt.Errorf("failed to recompute odometer for vehicle %s: %w", vehicleID, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func scanDailyDistances(rows pgx.Rows) ([]*models.DailyDistance, error) {
+	var distances []*models.DailyDistance
+	for rows.Next() {
+		distance := &models.DailyDistance{}
+		err := rows.Scan(
+			&distance.VehicleID,
+			&distance.Date,
+			&distance.DistanceMeters,
+			&distance.PointCount,
+			&distance.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan daily distance: %w", err)
+		}
+		distances = append(distances, distance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily distance rows: %w", err)
+	}
+
+	return distances, nil
+}
@@ -0,0 +1,390 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type driverRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+type driverShiftRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewDriverRepository(db *pgxpool.Pool, logger *zap.Logger) DriverRepository {
+	return &driverRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func NewDriverShiftRepository(db *pgxpool.Pool, logger *zap.Logger) DriverShiftRepository {
+	return &driverShiftRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *driverRepository) Create(ctx context.Context, driver *models.Driver) error {
+	query := `
+		INSERT INTO drivers (driver_id, name, license_number, operator, phone, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		driver.DriverID,
+		driver.Name,
+		driver.LicenseNumber,
+		driver.Operator,
+		driver.Phone,
+		driver.Active,
+	).Scan(&driver.CreatedAt, &driver.UpdatedAt)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("driver %s: %w", driver.DriverID, ErrAlreadyExists)
+	}
+	if err != nil {
+		r.logger.Error("Failed to create driver",
+			zap.Error(err),
+			zap.String("driver_id", driver.DriverID))
+		return fmt.Errorf("failed to create driver: %w", err)
+	}
+
+	return nil
+}
+
+func (r *driverRepository) GetByID(ctx context.Context, driverID string) (*models.Driver, error) {
+	query := `
+		SELECT driver_id, name, license_number, operator, phone, active, created_at, updated_at
+		FROM drivers
+		WHERE driver_id = $1
+	`
+
+	driver := &models.Driver{}
+	err := r.db.QueryRow(ctx, query, driverID).Scan(
+		&driver.DriverID,
+		&driver.Name,
+		&driver.LicenseNumber,
+		&driver.Operator,
+		&driver.Phone,
+		&driver.Active,
+		&driver.CreatedAt,
+		&driver.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("driver %s: %w", driverID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get driver by ID",
+			zap.Error(err),
+			zap.String("driver_id", driverID))
+		return nil, fmt.Errorf("failed to get driver %s: %w", driverID, err)
+	}
+
+	return driver, nil
+}
+
+func (r *driverRepository) GetAll(ctx context.Context) ([]*models.Driver, error) {
+	query := `
+		SELECT driver_id, name, license_number, operator, phone, active, created_at, updated_at
+		FROM drivers
+		ORDER BY driver_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get all drivers", zap.Error(err))
+		return nil, fmt.Errorf("failed to get drivers: %w", err)
+	}
+	defer rows.Close()
+
+	var drivers []*models.Driver
+	for rows.Next() {
+		driver := &models.Driver{}
+		err := rows.Scan(
+			&driver.DriverID,
+			&driver.Name,
+			&driver.LicenseNumber,
+			&driver.Operator,
+			&driver.Phone,
+			&driver.Active,
+			&driver.CreatedAt,
+			&driver.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan driver", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan driver: %w", err)
+		}
+		drivers = append(drivers, driver)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating driver rows: %w", err)
+	}
+
+	return drivers, nil
+}
+
+func (r *driverRepository) Update(ctx context.Context, driver *models.Driver) error {
+	query := `
+		UPDATE drivers
+		SET name = $2, license_number = $3, operator = $4, phone = $5, active = $6, updated_at = NOW()
+		WHERE driver_id = $1
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		driver.DriverID,
+		driver.Name,
+		driver.LicenseNumber,
+		driver.Operator,
+		driver.Phone,
+		driver.Active,
+	).Scan(&driver.CreatedAt, &driver.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("driver %s: %w", driver.DriverID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to update driver",
+			zap.Error(err),
+			zap.String("driver_id", driver.DriverID))
+		return fmt.Errorf("failed to update driver: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a driver from the registry. Past shifts keep the driver id.
+func (r *driverRepository) Delete(ctx context.Context, driverID string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM drivers WHERE driver_id = $1", driverID)
+	if err != nil {
+		r.logger.Error("Failed to delete driver",
+			zap.Error(err),
+			zap.String("driver_id", driverID))
+		return fmt.Errorf("failed to delete driver: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("driver %s: %w", driverID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// Start opens a shift, first closing any open shift of the same vehicle or
+// driver at the new shift's start.
+func (r *driverShiftRepository) Start(ctx context.Context, shift *models.DriverShift) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE driver_shifts
+		SET end_time = GREATEST($3, start_time)
+		WHERE (vehicle_id = $1 OR driver_id = $2) AND end_time IS NULL
+	`, shift.VehicleID, shift.DriverID, shift.StartTime)
+	if err != nil {
+		r.logger.Error("Failed to close open shifts",
+			zap.Error(err),
+			zap.String("vehicle_id", shift.VehicleID),
+			zap.String("driver_id", shift.DriverID))
+		return fmt.Errorf("failed to close open shifts: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO driver_shifts (driver_id, vehicle_id, start_time, source)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, shift.DriverID, shift.VehicleID, shift.StartTime, shift.Source).Scan(&shift.ID, &shift.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to create shift",
+			zap.Error(err),
+			zap.String("vehicle_id", shift.VehicleID),
+			zap.String("driver_id", shift.DriverID))
+		return fmt.Errorf("failed to create shift: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// End closes the open shift of a vehicle and returns it.
+func (r *driverShiftRepository) End(ctx context.Context, vehicleID string, endTime int64) (*models.DriverShift, error) {
+	query := `
+		UPDATE driver_shifts
+		SET end_time = GREATEST($2, start_time)
+		WHERE vehicle_id = $1 AND end_time IS NULL
+		RETURNING id, driver_id, vehicle_id, start_time, end_time, source, created_at
+	`
+
+	shift := &models.DriverShift{}
+	err := r.db.QueryRow(ctx, query, vehicleID, endTime).Scan(
+		&shift.ID,
+		&shift.DriverID,
+		&shift.VehicleID,
+		&shift.StartTime,
+		&shift.EndTime,
+		&shift.Source,
+		&shift.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("open shift for vehicle %s: %w", vehicleID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to end shift",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to end shift: %w", err)
+	}
+
+	return shift, nil
+}
+
+func (r *driverShiftRepository) GetOpen(ctx context.Context) ([]*models.DriverShift, error) {
+	query := `
+		SELECT id, driver_id, vehicle_id, start_time, end_time, source, created_at
+		FROM driver_shifts
+		WHERE end_time IS NULL
+		ORDER BY vehicle_id
+	`
+
+	return r.query(ctx, query)
+}
+
+// GetByDriverID lists the shifts of a driver overlapping the time range.
+func (r *driverShiftRepository) GetByDriverID(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverShift, error) {
+	query := `
+		SELECT id, driver_id, vehicle_id, start_time, end_time, source, created_at
+		FROM driver_shifts
+		WHERE driver_id = $1 AND start_time <= $3 AND (end_time IS NULL OR end_time >= $2)
+		ORDER BY start_time DESC
+	`
+
+	return r.query(ctx, query, driverID, startTime, endTime)
+}
+
+// GetReports aggregates shifts, idle intervals, geofence entries and driving
+// events per driver within the range; an empty driverID reports every driver with a
+// shift in it.
+func (r *driverShiftRepository) GetReports(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverReport, error) {
+	query := `
+		WITH shifts AS (
+			SELECT driver_id, COUNT(*) AS shift_count,
+			       SUM(GREATEST(LEAST(COALESCE(end_time, EXTRACT(EPOCH FROM NOW())::BIGINT), $2)
+			                    - GREATEST(start_time, $1), 0))::BIGINT AS on_duty
+			FROM driver_shifts
+			WHERE start_time <= $2 AND (end_time IS NULL OR end_time >= $1)
+			GROUP BY driver_id
+		), idle AS (
+			SELECT driver_id, COUNT(*) AS idle_count, SUM(duration_seconds)::BIGINT AS idle_seconds
+			FROM idle_intervals
+			WHERE driver_id IS NOT NULL AND start_time BETWEEN $1 AND $2 AND end_time IS NOT NULL
+			GROUP BY driver_id
+		), entries AS (
+			SELECT driver_id, COUNT(*) AS entry_count
+			FROM geofence_events
+			WHERE driver_id IS NOT NULL AND timestamp BETWEEN $1 AND $2
+			GROUP BY driver_id
+		), driving AS (
+			SELECT driver_id, COUNT(*) AS event_count
+			FROM driving_events
+			WHERE driver_id IS NOT NULL AND timestamp BETWEEN $1 AND $2
+			GROUP BY driver_id
+		)
+		SELECT s.driver_id, COALESCE(d.name, ''), s.shift_count, s.on_duty,
+		       COALESCE(i.idle_count, 0), COALESCE(i.idle_seconds, 0), COALESCE(e.entry_count, 0),
+		       COALESCE(dv.event_count, 0)
+		FROM shifts s
+		LEFT JOIN drivers d ON d.driver_id = s.driver_id
+		LEFT JOIN idle i ON i.driver_id = s.driver_id
+		LEFT JOIN entries e ON e.driver_id = s.driver_id
+		LEFT JOIN driving dv ON dv.driver_id = s.driver_id
+		WHERE $3 = '' OR s.driver_id = $3
+		ORDER BY s.driver_id
+	`
+
+	rows, err := r.db.Query(ctx, query, startTime, endTime, driverID)
+	if err != nil {
+		r.logger.Error("Failed to get driver reports", zap.Error(err))
+		return nil, fmt.Errorf("failed to get driver reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*models.DriverReport
+	for rows.Next() {
+		report := &models.DriverReport{}
+		err := rows.Scan(
+			&report.DriverID,
+			&report.Name,
+			&report.ShiftCount,
+			&report.OnDutySeconds,
+			&report.IdleCount,
+			&report.IdleSeconds,
+			&report.GeofenceEntries,
+			&report.DrivingEvents,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan driver report: %w", err)
+		}
+		if report.OnDutySeconds > 0 {
+			report.IdleRatio = float64(report.IdleSeconds) / float64(report.OnDutySeconds)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating driver report rows: %w", err)
+	}
+
+	return reports, nil
+}
+
+func (r *driverShiftRepository) query(ctx context.Context, query string, args ...any) ([]*models.DriverShift, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to get driver shifts", zap.Error(err))
+		return nil, fmt.Errorf("failed to get driver shifts: %w", err)
+	}
+	defer rows.Close()
+
+	var shifts []*models.DriverShift
+	for rows.Next() {
+		shift := &models.DriverShift{}
+		err := rows.Scan(
+			&shift.ID,
+			&shift.DriverID,
+			&shift.VehicleID,
+			&shift.StartTime,
+			&shift.EndTime,
+			&shift.Source,
+			&shift.CreatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan driver shift", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan driver shift: %w", err)
+		}
+		shifts = append(shifts, shift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating driver shift rows: %w", err)
+	}
+
+	return shifts, nil
+}
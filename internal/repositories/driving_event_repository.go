@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type drivingEventRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewDrivingEventRepository(db *pgxpool.Pool, logger *zap.Logger) DrivingEventRepository {
+	return &drivingEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *drivingEventRepository) Create(ctx context.Context, event *models.DrivingEvent) error {
+	query := `
+		INSERT INTO driving_events (vehicle_id, driver_id, event_type, value, speed_kmh, latitude, longitude, timestamp, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		event.VehicleID,
+		event.DriverID,
+		event.EventType,
+		event.Value,
+		event.SpeedKmh,
+		event.Latitude,
+		event.Longitude,
+		event.Timestamp,
+		event.Source,
+	).Scan(&event.ID, &event.CreatedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to create driving event",
+			zap.Error(err),
+			zap.String("vehicle_id", event.VehicleID),
+			zap.String("event_type", event.EventType))
+		return fmt.Errorf("failed to create driving event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *drivingEventRepository) GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error) {
+	query := `
+		SELECT id, vehicle_id, driver_id, event_type, value, speed_kmh, latitude, longitude, timestamp, source, created_at
+		FROM driving_events
+		WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3
+		ORDER BY timestamp DESC
+		LIMIT $4
+	`
+
+	return r.query(ctx, query, vehicleID, startTime, endTime, limit)
+}
+
+func (r *drivingEventRepository) GetByDriverID(ctx context.Context, driverID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error) {
+	query := `
+		SELECT id, vehicle_id, driver_id, event_type, value, speed_kmh, latitude, longitude, timestamp, source, created_at
+		FROM driving_events
+		WHERE driver_id = $1 AND timestamp BETWEEN $2 AND $3
+		ORDER BY timestamp DESC
+		LIMIT $4
+	`
+
+	return r.query(ctx, query, driverID, startTime, endTime, limit)
+}
+
+// GetDailyScores splits shifts at local midnight to get time on duty per day
+// and joins it with the day's event counts.
+func (r *drivingEventRepository) GetDailyScores(ctx context.Context, driverID string, fromDay, toDay, timezone string) ([]*models.DriverSafetyScore, error) {
+	query := `
+		WITH days AS (
+			SELECT day::date AS day,
+			       EXTRACT(EPOCH FROM (day::date::timestamp AT TIME ZONE $4))::BIGINT AS day_start,
+			       EXTRACT(EPOCH FROM ((day::date + 1)::timestamp AT TIME ZONE $4))::BIGINT AS day_end
+			FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS day
+		), duty AS (
+			SELECT s.driver_id, d.day,
+			       SUM(GREATEST(LEAST(COALESCE(s.end_time, EXTRACT(EPOCH FROM NOW())::BIGINT), d.day_end)
+			                    - GREATEST(s.start_time, d.day_start), 0))::BIGINT AS on_duty
+			FROM days d
+			JOIN driver_shifts s ON s.start_time < d.day_end AND (s.end_time IS NULL OR s.end_time > d.day_start)
+			GROUP BY s.driver_id, d.day
+		), events AS (
+			SELECT e.driver_id, d.day,
+			       COUNT(*) FILTER (WHERE e.event_type = 'harsh_braking') AS braking,
+			       COUNT(*) FILTER (WHERE e.event_type = 'harsh_acceleration') AS acceleration,
+			       COUNT(*) FILTER (WHERE e.event_type = 'sharp_cornering') AS cornering
+			FROM days d
+			JOIN driving_events e ON e.timestamp >= d.day_start AND e.timestamp < d.day_end
+			WHERE e.driver_id IS NOT NULL
+			GROUP BY e.driver_id, d.day
+		)
+		SELECT driver_id, COALESCE(dr.name, ''), to_char(day, 'YYYY-MM-DD'), COALESCE(du.on_duty, 0),
+		       COALESCE(ev.braking, 0), COALESCE(ev.acceleration, 0), COALESCE(ev.cornering, 0)
+		FROM duty du
+		FULL JOIN events ev USING (driver_id, day)
+		LEFT JOIN drivers dr USING (driver_id)
+		WHERE $3 = '' OR driver_id = $3
+		ORDER BY day, driver_id
+	`
+
+	rows, err := r.db.Query(ctx, query, fromDay, toDay, driverID, timezone)
+	if err != nil {
+		r.logger.Error("Failed to get driver safety scores",
+			zap.Error(err),
+			zap.String("driver_id", driverID))
+		return nil, fmt.Errorf("failed to get driver safety scores: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []*models.DriverSafetyScore
+	for rows.Next() {
+		score := &models.DriverSafetyScore{}
+		err := rows.Scan(
+			&score.DriverID,
+			&score.Name,
+			&score.Date,
+			&score.OnDutySeconds,
+			&score.HarshBraking,
+			&score.HarshAcceleration,
+			&score.SharpCornering,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan driver safety score: %w", err)
+		}
+		scores = append(scores, score)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating driver safety score rows: %w", err)
+	}
+
+	return scores, nil
+}
+
+func (r *drivingEventRepository) query(ctx context.Context, query string, args ...any) ([]*models.DrivingEvent, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to get driving events", zap.Error(err))
+		return nil, fmt.Errorf("failed to get driving events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.DrivingEvent
+	for rows.Next() {
+		event := &models.DrivingEvent{}
+		err := rows.Scan(
+			&event.ID,
+			&event.VehicleID,
+			&event.DriverID,
+			&event.EventType,
+			&event.Value,
+			&event.SpeedKmh,
+			&event.Latitude,
+			&event.Longitude,
+			&event.Timestamp,
+			&event.Source,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan driving event", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan driving event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating driving event rows: %w", err)
+	}
+
+	return events, nil
+}
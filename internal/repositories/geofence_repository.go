@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
@@ -36,7 +38,7 @@ func NewGeofenceEventRepository(db *pgxpool.Pool, logger *zap.Logger) GeofenceEv
 
 func (r *geofenceRepository) GetAll(ctx context.Context) ([]*models.Geofence, error) {
 	query := `
-		SELECT id, name, latitude, longitude, radius, created_at, updated_at
+		SELECT id, name, geofence_type, latitude, longitude, radius, path, feed_id, external_id, tags, created_at, updated_at
 		FROM geofences
 		ORDER BY name
 	`
@@ -54,9 +56,14 @@ func (r *geofenceRepository) GetAll(ctx context.Context) ([]*models.Geofence, er
 		err := rows.Scan(
 			&geofence.ID,
 			&geofence.Name,
+			&geofence.Type,
 			&geofence.Latitude,
 			&geofence.Longitude,
 			&geofence.Radius,
+			&geofence.Path,
+			&geofence.FeedID,
+			&geofence.ExternalID,
+			&geofence.Tags,
 			&geofence.CreatedAt,
 			&geofence.UpdatedAt,
 		)
@@ -76,7 +83,7 @@ func (r *geofenceRepository) GetAll(ctx context.Context) ([]*models.Geofence, er
 
 func (r *geofenceRepository) GetByID(ctx context.Context, id int64) (*models.Geofence, error) {
 	query := `
-		SELECT id, name, latitude, longitude, radius, created_at, updated_at
+		SELECT id, name, geofence_type, latitude, longitude, radius, path, feed_id, external_id, tags, created_at, updated_at
 		FROM geofences
 		WHERE id = $1
 	`
@@ -85,9 +92,14 @@ func (r *geofenceRepository) GetByID(ctx context.Context, id int64) (*models.Geo
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&geofence.ID,
 		&geofence.Name,
+		&geofence.Type,
 		&geofence.Latitude,
 		&geofence.Longitude,
 		&geofence.Radius,
+		&geofence.Path,
+		&geofence.FeedID,
+		&geofence.ExternalID,
+		&geofence.Tags,
 		&geofence.CreatedAt,
 		&geofence.UpdatedAt,
 	)
@@ -102,10 +114,46 @@ func (r *geofenceRepository) GetByID(ctx context.Context, id int64) (*models.Geo
 	return geofence, nil
 }
 
+func (r *geofenceRepository) UpdateTags(ctx context.Context, id int64, tags []string) (*models.Geofence, error) {
+	query := `
+		UPDATE geofences
+		SET tags = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, name, geofence_type, latitude, longitude, radius, path, feed_id, external_id, tags, created_at, updated_at
+	`
+
+	geofence := &models.Geofence{}
+	err := r.db.QueryRow(ctx, query, id, tags).Scan(
+		&geofence.ID,
+		&geofence.Name,
+		&geofence.Type,
+		&geofence.Latitude,
+		&geofence.Longitude,
+		&geofence.Radius,
+		&geofence.Path,
+		&geofence.FeedID,
+		&geofence.ExternalID,
+		&geofence.Tags,
+		&geofence.CreatedAt,
+		&geofence.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("geofence %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to update geofence tags",
+			zap.Error(err),
+			zap.Int64("geofence_id", id))
+		return nil, fmt.Errorf("failed to update geofence %d tags: %w", id, err)
+	}
+
+	return geofence, nil
+}
+
 func (r *geofenceEventRepository) Create(ctx context.Context, event *models.GeofenceEvent) error {
 	query := `
-		INSERT INTO geofence_events (vehicle_id, geofence_id, event_type, latitude, longitude, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO geofence_events (vehicle_id, geofence_id, event_type, latitude, longitude, timestamp, driver_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at
 	`
 	
@@ -116,6 +164,7 @@ func (r *geofenceEventRepository) Create(ctx context.Context, event *models.Geof
 		event.Latitude,
 		event.Longitude,
 		event.Timestamp,
+		event.DriverID,
 	).Scan(&event.ID, &event.CreatedAt)
 	
 	if err != nil {
@@ -132,10 +181,56 @@ func (r *geofenceEventRepository) Create(ctx context.Context, event *models.Geof
 	return nil
 }
 
+func (r *geofenceEventRepository) CreateWithMessage(ctx context.Context, event *models.GeofenceEvent, message *models.OutboxMessage) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO geofence_events (vehicle_id, geofence_id, event_type, latitude, longitude, timestamp, driver_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`,
+		event.VehicleID,
+		event.GeofenceID,
+		event.EventType,
+		event.Latitude,
+		event.Longitude,
+		event.Timestamp,
+		event.DriverID,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to create geofence event",
+			zap.Error(err),
+			zap.String("vehicle_id", event.VehicleID))
+		return fmt.Errorf("failed to create geofence event: %w", err)
+	}
+
+	if err := insertOutboxMessage(ctx, tx, message); err != nil {
+		r.logger.Error("Failed to queue geofence event",
+			zap.Error(err),
+			zap.String("vehicle_id", event.VehicleID))
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit geofence event: %w", err)
+	}
+
+	r.logger.Debug("Geofence event created successfully",
+		zap.Int64("event_id", event.ID),
+		zap.Int64("outbox_id", message.ID),
+		zap.String("vehicle_id", event.VehicleID))
+
+	return nil
+}
+
 func (r *geofenceEventRepository) GetByVehicleID(ctx context.Context, vehicleID string, limit int) ([]*models.GeofenceEvent, error) {
 	query := `
 		SELECT ge.id, ge.vehicle_id, ge.geofence_id, ge.event_type, 
-		       ge.latitude, ge.longitude, ge.timestamp, ge.created_at
+		       ge.latitude, ge.longitude, ge.timestamp, ge.driver_id, ge.created_at
 		FROM geofence_events ge
 		WHERE ge.vehicle_id = $1
 		ORDER BY ge.timestamp DESC
@@ -162,6 +257,7 @@ func (r *geofenceEventRepository) GetByVehicleID(ctx context.Context, vehicleID
 			&event.Latitude,
 			&event.Longitude,
 			&event.Timestamp,
+			&event.DriverID,
 			&event.CreatedAt,
 		)
 		if err != nil {
@@ -0,0 +1,657 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/gtfs"
+)
+
+type gtfsRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// gtfsChildTables hold per-feed rows removed when a feed is superseded
+var gtfsChildTables = []string{
+	"gtfs_stop_times",
+	"gtfs_trips",
+	"gtfs_shapes",
+	"gtfs_calendar_dates",
+	"gtfs_calendar",
+	"gtfs_stops",
+	"gtfs_routes",
+	"gtfs_agencies",
+}
+
+func NewGTFSRepository(db *pgxpool.Pool, logger *zap.Logger) GTFSRepository {
+	return &gtfsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *gtfsRepository) CreateFeed(ctx context.Context, feed *models.GTFSFeed) error {
+	query := `
+		INSERT INTO gtfs_feeds (version, status)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, feed.Version, feed.Status).Scan(&feed.ID, &feed.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to create GTFS feed",
+			zap.Error(err),
+			zap.String("version", feed.Version))
+		return fmt.Errorf("failed to create GTFS feed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *gtfsRepository) Activate(ctx context.Context, feed *models.GTFSFeed, data *gtfs.Feed, geofences []*models.Geofence) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := copyFeed(ctx, tx, feed.ID, data); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"geofences"},
+		[]string{"name", "geofence_type", "latitude", "longitude", "radius", "path", "feed_id", "external_id"},
+		pgx.CopyFromSlice(len(geofences), func(i int) ([]any, error) {
+			g := geofences[i]
+			var path any
+			if len(g.Path) > 0 {
+				path = g.Path
+			}
+			return []any{g.Name, g.Type, g.Latitude, g.Longitude, g.Radius, path, feed.ID, g.ExternalID}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert GTFS geofences: %w", err)
+	}
+
+	// Supersede the previously active feed and drop its data; the feed row
+	// itself is kept as import history
+	rows, err := tx.Query(ctx, `
+		UPDATE gtfs_feeds SET status = $2
+		WHERE status = $1 AND id <> $3
+		RETURNING id
+	`, models.GTFSFeedActive, models.GTFSFeedSuperseded, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to supersede active GTFS feed: %w", err)
+	}
+	superseded, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("failed to supersede active GTFS feed: %w", err)
+	}
+
+	if len(superseded) > 0 {
+		if _, err := tx.Exec(ctx, "DELETE FROM geofences WHERE feed_id = ANY($1)", superseded); err != nil {
+			return fmt.Errorf("failed to delete superseded GTFS geofences: %w", err)
+		}
+		for _, table := range gtfsChildTables {
+			if _, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE feed_id = ANY($1)", superseded); err != nil {
+				return fmt.Errorf("failed to delete superseded rows from %s: %w", table, err)
+			}
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE gtfs_feeds
+		SET version = $2, status = $3, agency_count = $4, route_count = $5, stop_count = $6,
+		    trip_count = $7, stop_time_count = $8, shape_count = $9, activated_at = NOW()
+		WHERE id = $1
+		RETURNING activated_at
+	`,
+		feed.ID,
+		feed.Version,
+		models.GTFSFeedActive,
+		feed.AgencyCount,
+		feed.RouteCount,
+		feed.StopCount,
+		feed.TripCount,
+		feed.StopTimeCount,
+		feed.ShapeCount,
+	).Scan(&feed.ActivatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to activate GTFS feed: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("Failed to commit GTFS import",
+			zap.Error(err),
+			zap.Int64("feed_id", feed.ID))
+		return fmt.Errorf("failed to commit GTFS import: %w", err)
+	}
+
+	feed.Status = models.GTFSFeedActive
+	r.logger.Info("GTFS feed activated",
+		zap.Int64("feed_id", feed.ID),
+		zap.String("version", feed.Version),
+		zap.Int64s("superseded", superseded))
+
+	return nil
+}
+
+func copyFeed(ctx context.Context, tx pgx.Tx, feedID int64, data *gtfs.Feed) error {
+	copies := []struct {
+		table   string
+		columns []string
+		count   int
+		row     func(i int) []any
+	}{
+		{
+			"gtfs_agencies",
+			[]string{"feed_id", "agency_id", "agency_name", "agency_url", "agency_timezone"},
+			len(data.Agencies),
+			func(i int) []any {
+				a := data.Agencies[i]
+				return []any{feedID, a.AgencyID, a.Name, a.URL, a.Timezone}
+			},
+		},
+		{
+			"gtfs_routes",
+			[]string{"feed_id", "route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "route_color"},
+			len(data.Routes),
+			func(i int) []any {
+				rt := data.Routes[i]
+				return []any{feedID, rt.RouteID, rt.AgencyID, rt.ShortName, rt.LongName, rt.RouteType, rt.Color}
+			},
+		},
+		{
+			"gtfs_stops",
+			[]string{"feed_id", "stop_id", "stop_code", "stop_name", "latitude", "longitude", "location_type", "parent_station"},
+			len(data.Stops),
+			func(i int) []any {
+				s := data.Stops[i]
+				return []any{feedID, s.StopID, s.Code, s.Name, s.Latitude, s.Longitude, s.LocationType, s.ParentStation}
+			},
+		},
+		{
+			"gtfs_trips",
+			[]string{"feed_id", "trip_id", "route_id", "service_id", "trip_headsign", "direction_id", "shape_id"},
+			len(data.Trips),
+			func(i int) []any {
+				t := data.Trips[i]
+				return []any{feedID, t.TripID, t.RouteID, t.ServiceID, t.Headsign, t.DirectionID, t.ShapeID}
+			},
+		},
+		{
+			"gtfs_stop_times",
+			[]string{"feed_id", "trip_id", "stop_sequence", "stop_id", "arrival_seconds", "departure_seconds", "shape_dist_traveled"},
+			len(data.StopTimes),
+			func(i int) []any {
+				st := data.StopTimes[i]
+				return []any{feedID, st.TripID, st.StopSequence, st.StopID, st.ArrivalSeconds, st.DepartureSeconds, st.ShapeDistTraveled}
+			},
+		},
+		{
+			"gtfs_shapes",
+			[]string{"feed_id", "shape_id", "shape_pt_sequence", "latitude", "longitude", "shape_dist_traveled"},
+			len(data.Shapes),
+			func(i int) []any {
+				p := data.Shapes[i]
+				return []any{feedID, p.ShapeID, p.Sequence, p.Latitude, p.Longitude, p.ShapeDistTraveled}
+			},
+		},
+		{
+			"gtfs_calendar",
+			[]string{"feed_id", "service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"},
+			len(data.Calendars),
+			func(i int) []any {
+				c := data.Calendars[i]
+				return []any{feedID, c.ServiceID, c.Monday, c.Tuesday, c.Wednesday, c.Thursday, c.Friday, c.Saturday, c.Sunday, c.StartDate, c.EndDate}
+			},
+		},
+		{
+			"gtfs_calendar_dates",
+			[]string{"feed_id", "service_id", "date", "exception_type"},
+			len(data.CalendarDates),
+			func(i int) []any {
+				d := data.CalendarDates[i]
+				return []any{feedID, d.ServiceID, d.Date, d.ExceptionType}
+			},
+		},
+	}
+
+	for _, c := range copies {
+		row := c.row
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns,
+			pgx.CopyFromSlice(c.count, func(i int) ([]any, error) {
+				return row(i), nil
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", c.table, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *gtfsRepository) MarkFailed(ctx context.Context, feedID int64, reason string) error {
+	_, err := r.db.Exec(ctx,
+		"UPDATE gtfs_feeds SET status = $2, error = $3 WHERE id = $1",
+		feedID, models.GTFSFeedFailed, reason)
+	if err != nil {
+		r.logger.Error("Failed to mark GTFS feed failed",
+			zap.Error(err),
+			zap.Int64("feed_id", feedID))
+		return fmt.Errorf("failed to mark GTFS feed %d failed: %w", feedID, err)
+	}
+
+	return nil
+}
+
+const gtfsFeedColumns = `id, version, status, error, agency_count, route_count, stop_count,
+		       trip_count, stop_time_count, shape_count, activated_at, created_at`
+
+func (r *gtfsRepository) GetFeedByID(ctx context.Context, feedID int64) (*models.GTFSFeed, error) {
+	query := `SELECT ` + gtfsFeedColumns + ` FROM gtfs_feeds WHERE id = $1`
+
+	feed, err := scanGTFSFeed(r.db.QueryRow(ctx, query, feedID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GTFS feed %d: %w", feedID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get GTFS feed",
+			zap.Error(err),
+			zap.Int64("feed_id", feedID))
+		return nil, fmt.Errorf("failed to get GTFS feed %d: %w", feedID, err)
+	}
+
+	return feed, nil
+}
+
+func (r *gtfsRepository) GetFeeds(ctx context.Context, limit int) ([]*models.GTFSFeed, error) {
+	query := `SELECT ` + gtfsFeedColumns + ` FROM gtfs_feeds ORDER BY id DESC LIMIT $1`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS feeds", zap.Error(err))
+		return nil, fmt.Errorf("failed to get GTFS feeds: %w", err)
+	}
+	defer rows.Close()
+
+	var feeds []*models.GTFSFeed
+	for rows.Next() {
+		feed, err := scanGTFSFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan GTFS feed: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating GTFS feed rows: %w", err)
+	}
+
+	return feeds, nil
+}
+
+func scanGTFSFeed(row pgx.Row) (*models.GTFSFeed, error) {
+	feed := &models.GTFSFeed{}
+	err := row.Scan(
+		&feed.ID,
+		&feed.Version,
+		&feed.Status,
+		&feed.Error,
+		&feed.AgencyCount,
+		&feed.RouteCount,
+		&feed.StopCount,
+		&feed.TripCount,
+		&feed.StopTimeCount,
+		&feed.ShapeCount,
+		&feed.ActivatedAt,
+		&feed.CreatedAt,
+	)
+	return feed, err
+}
+
+func (r *gtfsRepository) GetActiveFeed(ctx context.Context) (*models.GTFSFeed, error) {
+	query := `SELECT ` + gtfsFeedColumns + ` FROM gtfs_feeds WHERE status = $1 ORDER BY id DESC LIMIT 1`
+
+	feed, err := scanGTFSFeed(r.db.QueryRow(ctx, query, models.GTFSFeedActive))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("active GTFS feed: %w", ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get active GTFS feed", zap.Error(err))
+		return nil, fmt.Errorf("failed to get active GTFS feed: %w", err)
+	}
+
+	return feed, nil
+}
+
+// GetAgencyTimezone returns the timezone of the feed's first agency, or an
+// empty string when the feed declares none.
+func (r *gtfsRepository) GetAgencyTimezone(ctx context.Context, feedID int64) (string, error) {
+	query := `
+		SELECT agency_timezone FROM gtfs_agencies
+		WHERE feed_id = $1 AND agency_timezone <> ''
+		ORDER BY agency_id
+		LIMIT 1
+	`
+
+	var timezone string
+	err := r.db.QueryRow(ctx, query, feedID).Scan(&timezone)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get agency timezone: %w", err)
+	}
+
+	return timezone, nil
+}
+
+func (r *gtfsRepository) GetTrip(ctx context.Context, feedID int64, tripID string) (*models.GTFSTrip, error) {
+	query := `
+		SELECT trip_id, route_id, service_id, trip_headsign, direction_id, shape_id
+		FROM gtfs_trips
+		WHERE feed_id = $1 AND trip_id = $2
+	`
+
+	trip := &models.GTFSTrip{}
+	err := r.db.QueryRow(ctx, query, feedID, tripID).Scan(
+		&trip.TripID,
+		&trip.RouteID,
+		&trip.ServiceID,
+		&trip.Headsign,
+		&trip.DirectionID,
+		&trip.ShapeID,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("GTFS trip %s: %w", tripID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get GTFS trip",
+			zap.Error(err),
+			zap.String("trip_id", tripID))
+		return nil, fmt.Errorf("failed to get GTFS trip %s: %w", tripID, err)
+	}
+
+	return trip, nil
+}
+
+func (r *gtfsRepository) GetTripStops(ctx context.Context, feedID int64, tripID string) ([]*models.GTFSTripStop, error) {
+	query := `
+		SELECT st.trip_id, st.stop_sequence, st.stop_id, st.arrival_seconds, st.departure_seconds,
+		       st.shape_dist_traveled, s.stop_name, s.latitude, s.longitude
+		FROM gtfs_stop_times st
+		JOIN gtfs_stops s ON s.feed_id = st.feed_id AND s.stop_id = st.stop_id
+		WHERE st.feed_id = $1 AND st.trip_id = $2
+		ORDER BY st.stop_sequence
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID, tripID)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS trip stops",
+			zap.Error(err),
+			zap.String("trip_id", tripID))
+		return nil, fmt.Errorf("failed to get stops for trip %s: %w", tripID, err)
+	}
+	defer rows.Close()
+
+	var stops []*models.GTFSTripStop
+	for rows.Next() {
+		stop := &models.GTFSTripStop{}
+		err := rows.Scan(
+			&stop.TripID,
+			&stop.StopSequence,
+			&stop.StopID,
+			&stop.ArrivalSeconds,
+			&stop.DepartureSeconds,
+			&stop.ShapeDistTraveled,
+			&stop.StopName,
+			&stop.Latitude,
+			&stop.Longitude,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trip stop: %w", err)
+		}
+		stops = append(stops, stop)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trip stop rows: %w", err)
+	}
+
+	return stops, nil
+}
+
+func (r *gtfsRepository) GetStopCalls(ctx context.Context, feedID int64, serviceIDs []string, stopID string, fromSeconds, toSeconds int) ([]*models.GTFSStopCall, error) {
+	query := `
+		SELECT st.trip_id, st.stop_sequence, st.stop_id, st.arrival_seconds, st.departure_seconds,
+		       st.shape_dist_traveled, t.route_id, COALESCE(r.route_short_name, ''), t.direction_id
+		FROM gtfs_stop_times st
+		JOIN gtfs_trips t ON t.feed_id = st.feed_id AND t.trip_id = st.trip_id
+		LEFT JOIN gtfs_routes r ON r.feed_id = t.feed_id AND r.route_id = t.route_id
+		WHERE st.feed_id = $1 AND st.stop_id = $2 AND st.arrival_seconds BETWEEN $3 AND $4
+		  AND ($5::TEXT[] IS NULL OR t.service_id = ANY($5))
+		ORDER BY st.arrival_seconds
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID, stopID, fromSeconds, toSeconds, serviceIDs)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS stop calls",
+			zap.Error(err),
+			zap.String("stop_id", stopID))
+		return nil, fmt.Errorf("failed to get calls at stop %s: %w", stopID, err)
+	}
+	defer rows.Close()
+
+	var calls []*models.GTFSStopCall
+	for rows.Next() {
+		call := &models.GTFSStopCall{}
+		err := rows.Scan(
+			&call.TripID,
+			&call.StopSequence,
+			&call.StopID,
+			&call.ArrivalSeconds,
+			&call.DepartureSeconds,
+			&call.ShapeDistTraveled,
+			&call.RouteID,
+			&call.RouteShortName,
+			&call.DirectionID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stop call: %w", err)
+		}
+		calls = append(calls, call)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stop call rows: %w", err)
+	}
+
+	return calls, nil
+}
+
+func (r *gtfsRepository) GetCalendars(ctx context.Context, feedID int64) ([]*models.GTFSCalendar, error) {
+	query := `
+		SELECT service_id, monday, tuesday, wednesday, thursday, friday, saturday, sunday, start_date, end_date
+		FROM gtfs_calendar
+		WHERE feed_id = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS calendars",
+			zap.Error(err),
+			zap.Int64("feed_id", feedID))
+		return nil, fmt.Errorf("failed to get calendars of feed %d: %w", feedID, err)
+	}
+	defer rows.Close()
+
+	var calendars []*models.GTFSCalendar
+	for rows.Next() {
+		calendar := &models.GTFSCalendar{}
+		err := rows.Scan(
+			&calendar.ServiceID,
+			&calendar.Monday,
+			&calendar.Tuesday,
+			&calendar.Wednesday,
+			&calendar.Thursday,
+			&calendar.Friday,
+			&calendar.Saturday,
+			&calendar.Sunday,
+			&calendar.StartDate,
+			&calendar.EndDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar: %w", err)
+		}
+		calendars = append(calendars, calendar)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar rows: %w", err)
+	}
+
+	return calendars, nil
+}
+
+func (r *gtfsRepository) GetCalendarDates(ctx context.Context, feedID int64) ([]*models.GTFSCalendarDate, error) {
+	query := `
+		SELECT service_id, date, exception_type
+		FROM gtfs_calendar_dates
+		WHERE feed_id = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS calendar dates",
+			zap.Error(err),
+			zap.Int64("feed_id", feedID))
+		return nil, fmt.Errorf("failed to get calendar dates of feed %d: %w", feedID, err)
+	}
+	defer rows.Close()
+
+	var dates []*models.GTFSCalendarDate
+	for rows.Next() {
+		date := &models.GTFSCalendarDate{}
+		if err := rows.Scan(&date.ServiceID, &date.Date, &date.ExceptionType); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar date: %w", err)
+		}
+		dates = append(dates, date)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar date rows: %w", err)
+	}
+
+	return dates, nil
+}
+
+func (r *gtfsRepository) GetShape(ctx context.Context, feedID int64, shapeID string) ([]*models.GTFSShapePoint, error) {
+	query := `
+		SELECT shape_id, shape_pt_sequence, latitude, longitude, shape_dist_traveled
+		FROM gtfs_shapes
+		WHERE feed_id = $1 AND shape_id = $2
+		ORDER BY shape_pt_sequence
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID, shapeID)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS shape",
+			zap.Error(err),
+			zap.String("shape_id", shapeID))
+		return nil, fmt.Errorf("failed to get shape %s: %w", shapeID, err)
+	}
+	defer rows.Close()
+
+	var points []*models.GTFSShapePoint
+	for rows.Next() {
+		point := &models.GTFSShapePoint{}
+		err := rows.Scan(
+			&point.ShapeID,
+			&point.Sequence,
+			&point.Latitude,
+			&point.Longitude,
+			&point.ShapeDistTraveled,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shape point: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shape rows: %w", err)
+	}
+
+	return points, nil
+}
+
+// GetRouteShapeID returns the shape used by most trips of a route direction.
+func (r *gtfsRepository) GetRouteShapeID(ctx context.Context, feedID int64, routeID string, directionID int) (string, error) {
+	query := `
+		SELECT shape_id
+		FROM gtfs_trips
+		WHERE feed_id = $1 AND route_id = $2 AND direction_id = $3 AND shape_id <> ''
+		GROUP BY shape_id
+		ORDER BY COUNT(*) DESC, shape_id
+		LIMIT 1
+	`
+
+	var shapeID string
+	err := r.db.QueryRow(ctx, query, feedID, routeID, directionID).Scan(&shapeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("shape for route %s direction %d: %w", routeID, directionID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get GTFS route shape",
+			zap.Error(err),
+			zap.String("route_id", routeID))
+		return "", fmt.Errorf("failed to get shape for route %s: %w", routeID, err)
+	}
+
+	return shapeID, nil
+}
+
+// GetRouteDepartures returns the distinct scheduled departure times, in
+// seconds of the service day, of trips of the given services on a route
+// direction from their first stop.
+func (r *gtfsRepository) GetRouteDepartures(ctx context.Context, feedID int64, serviceIDs []string, routeID string, directionID int, fromSeconds, toSeconds int) ([]int, error) {
+	query := `
+		SELECT DISTINCT st.departure_seconds
+		FROM gtfs_trips t
+		JOIN gtfs_stop_times st ON st.feed_id = t.feed_id AND st.trip_id = t.trip_id
+		WHERE t.feed_id = $1 AND t.route_id = $2 AND t.direction_id = $3
+		  AND st.stop_sequence = (
+		      SELECT MIN(stop_sequence) FROM gtfs_stop_times
+		      WHERE feed_id = t.feed_id AND trip_id = t.trip_id)
+		  AND st.departure_seconds BETWEEN $4 AND $5
+		  AND ($6::TEXT[] IS NULL OR t.service_id = ANY($6))
+		ORDER BY st.departure_seconds
+	`
+
+	rows, err := r.db.Query(ctx, query, feedID, routeID, directionID, fromSeconds, toSeconds, serviceIDs)
+	if err != nil {
+		r.logger.Error("Failed to get GTFS route departures",
+			zap.Error(err),
+			zap.String("route_id", routeID))
+		return nil, fmt.Errorf("failed to get departures for route %s: %w", routeID, err)
+	}
+
+	departures, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan route departures: %w", err)
+	}
+
+	return departures, nil
+}
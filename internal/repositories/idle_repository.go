@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type idleRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewIdleRepository(db *pgxpool.Pool, logger *zap.Logger) IdleRepository {
+	return &idleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Start records an open idle interval (end_time is NULL until it ends).
+func (r *idleRepository) Start(ctx context.Context, interval *models.IdleInterval) error {
+	query := `
+		INSERT INTO idle_intervals (vehicle_id, start_time, latitude, longitude, driver_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		interval.VehicleID,
+		interval.StartTime,
+		interval.Latitude,
+		interval.Longitude,
+		interval.DriverID,
+	).Scan(&interval.ID, &interval.CreatedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to create idle interval",
+			zap.Error(err),
+			zap.String("vehicle_id", interval.VehicleID))
+		return fmt.Errorf("failed to create idle interval: %w", err)
+	}
+
+	return nil
+}
+
+// End closes the open idle interval of a vehicle that started at startTime.
+func (r *idleRepository) End(ctx context.Context, vehicleID string, startTime, endTime int64) error {
+	query := `
+		UPDATE idle_intervals
+		SET end_time = $3, duration_seconds = $3 - start_time
+		WHERE vehicle_id = $1 AND start_time = $2 AND end_time IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, query, vehicleID, startTime, endTime); err != nil {
+		r.logger.Error("Failed to close idle interval",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return fmt.Errorf("failed to close idle interval: %w", err)
+	}
+
+	return nil
+}
+
+func (r *idleRepository) GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.IdleInterval, error) {
+	query := `
+		SELECT id, vehicle_id, start_time, end_time, duration_seconds, latitude, longitude, driver_id, created_at
+		FROM idle_intervals
+		WHERE vehicle_id = $1 AND start_time BETWEEN $2 AND $3
+		ORDER BY start_time DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID, startTime, endTime)
+	if err != nil {
+		r.logger.Error("Failed to get idle intervals",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get idle intervals for vehicle %s: %w", vehicleID, err)
+	}
+	defer rows.Close()
+
+	var intervals []*models.IdleInterval
+	for rows.Next() {
+		interval := &models.IdleInterval{}
+		err := rows.Scan(
+			&interval.ID,
+			&interval.VehicleID,
+			&interval.StartTime,
+			&interval.EndTime,
+			&interval.DurationSeconds,
+			&interval.Latitude,
+			&interval.Longitude,
+			&interval.DriverID,
+			&interval.CreatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan idle interval", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan idle interval: %w", err)
+		}
+		intervals = append(intervals, interval)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating idle interval rows: %w", err)
+	}
+
+	return intervals, nil
+}
+
+// GetSummary aggregates closed idle intervals per vehicle within the range.
+func (r *idleRepository) GetSummary(ctx context.Context, startTime, endTime int64) ([]*models.IdleSummary, error) {
+	query := `
+		SELECT vehicle_id, COUNT(*), COALESCE(SUM(duration_seconds), 0), COALESCE(MAX(duration_seconds), 0)
+		FROM idle_intervals
+		WHERE start_time BETWEEN $1 AND $2 AND end_time IS NOT NULL
+		GROUP BY vehicle_id
+		ORDER BY SUM(duration_seconds) DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, startTime, endTime)
+	if err != nil {
+		r.logger.Error("Failed to get idle summary", zap.Error(err))
+		return nil, fmt.Errorf("failed to get idle summary: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*models.IdleSummary
+	for rows.Next() {
+		summary := &models.IdleSummary{}
+		err := rows.Scan(
+			&summary.VehicleID,
+			&summary.IntervalCount,
+			&summary.TotalIdleSeconds,
+			&summary.LongestIdleSeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan idle summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating idle summary rows: %w", err)
+	}
+
+	return summaries, nil
+}
@@ -2,23 +2,232 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/gtfs"
+)
+
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrAlreadyExists = errors.New("record already exists")
 )
 
 type VehicleLocationRepository interface {
 	Create(ctx context.Context, location *models.VehicleLocation) error
 	GetLatestByVehicleID(ctx context.Context, vehicleID string) (*models.VehicleLocation, error)
 	GetHistoryByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.VehicleLocation, error)
+	GetVehicleIDs(ctx context.Context) ([]string, error)
+	GetLatestForAllVehicles(ctx context.Context) ([]*models.VehicleLocation, error)
+	UpdateMatch(ctx context.Context, location *models.VehicleLocation) error
 }
 
 type GeofenceRepository interface {
 	GetAll(ctx context.Context) ([]*models.Geofence, error)
 	GetByID(ctx context.Context, id int64) (*models.Geofence, error)
+	UpdateTags(ctx context.Context, id int64, tags []string) (*models.Geofence, error)
 }
 
 type GeofenceEventRepository interface {
 	Create(ctx context.Context, event *models.GeofenceEvent) error
+	// CreateWithMessage saves the event and queues its outbox message in the
+	// same transaction, so neither is kept without the other.
+	CreateWithMessage(ctx context.Context, event *models.GeofenceEvent, message *models.OutboxMessage) error
 	GetByVehicleID(ctx context.Context, vehicleID string, limit int) ([]*models.GeofenceEvent, error)
 }
 
+type TripRepository interface {
+	CreateTrip(ctx context.Context, trip *models.Trip) error
+	CreateStop(ctx context.Context, stop *models.VehicleStop) error
+	GetTripsByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.Trip, error)
+	DeleteByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) error
+}
+
+type DistanceRepository interface {
+	GetOdometer(ctx context.Context, vehicleID string) (*models.VehicleOdometer, error)
+	RecordDistance(ctx context.Context, vehicleID string, day string, meters float64, location *models.VehicleLocation) error
+	GetDailyByVehicleID(ctx context.Context, vehicleID string, fromDay, toDay string) ([]*models.DailyDistance, error)
+	GetDailyByDay(ctx context.Context, day string) ([]*models.DailyDistance, error)
+	ReplaceDaily(ctx context.Context, vehicleID string, fromDay, toDay string, days []*models.DailyDistance, last *models.VehicleLocation) error
+}
+
+type IdleRepository interface {
+	Start(ctx context.Context, interval *models.IdleInterval) error
+	End(ctx context.Context, vehicleID string, startTime, endTime int64) error
+	GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.IdleInterval, error)
+	GetSummary(ctx context.Context, startTime, endTime int64) ([]*models.IdleSummary, error)
+}
+
+type VehicleRepository interface {
+	Create(ctx context.Context, vehicle *models.Vehicle) error
+	GetByID(ctx context.Context, vehicleID string) (*models.Vehicle, error)
+	GetAll(ctx context.Context) ([]*models.Vehicle, error)
+	Update(ctx context.Context, vehicle *models.Vehicle) error
+	Delete(ctx context.Context, vehicleID string) error
+}
+
+type QuarantineRepository interface {
+	Create(ctx context.Context, location *models.QuarantinedLocation) error
+	GetRecent(ctx context.Context, limit int) ([]*models.QuarantinedLocation, error)
+}
+
+type GTFSRepository interface {
+	CreateFeed(ctx context.Context, feed *models.GTFSFeed) error
+	// Activate loads the feed data and geofences, marks the feed active and
+	// supersedes the previously active feed, all in one transaction.
+	Activate(ctx context.Context, feed *models.GTFSFeed, data *gtfs.Feed, geofences []*models.Geofence) error
+	MarkFailed(ctx context.Context, feedID int64, reason string) error
+	GetFeedByID(ctx context.Context, feedID int64) (*models.GTFSFeed, error)
+	GetFeeds(ctx context.Context, limit int) ([]*models.GTFSFeed, error)
+	GetActiveFeed(ctx context.Context) (*models.GTFSFeed, error)
+	GetAgencyTimezone(ctx context.Context, feedID int64) (string, error)
+	GetTrip(ctx context.Context, feedID int64, tripID string) (*models.GTFSTrip, error)
+	GetTripStops(ctx context.Context, feedID int64, tripID string) ([]*models.GTFSTripStop, error)
+	// GetStopCalls lists the trips of the given services scheduled to arrive
+	// at a stop between two times, in seconds of the service day. Nil
+	// serviceIDs does not filter by service.
+	GetStopCalls(ctx context.Context, feedID int64, serviceIDs []string, stopID string, fromSeconds, toSeconds int) ([]*models.GTFSStopCall, error)
+	GetCalendars(ctx context.Context, feedID int64) ([]*models.GTFSCalendar, error)
+	GetCalendarDates(ctx context.Context, feedID int64) ([]*models.GTFSCalendarDate, error)
+	GetShape(ctx context.Context, feedID int64, shapeID string) ([]*models.GTFSShapePoint, error)
+	GetRouteShapeID(ctx context.Context, feedID int64, routeID string, directionID int) (string, error)
+	// GetRouteDepartures lists the first-stop departure times of the given
+	// services' trips on a route direction. Nil serviceIDs does not filter by
+	// service.
+	GetRouteDepartures(ctx context.Context, feedID int64, serviceIDs []string, routeID string, directionID int, fromSeconds, toSeconds int) ([]int, error)
+}
+
+type TripAssignmentRepository interface {
+	Upsert(ctx context.Context, assignment *models.TripAssignment) error
+	GetByVehicleID(ctx context.Context, vehicleID string) (*models.TripAssignment, error)
+	GetAll(ctx context.Context) ([]*models.TripAssignment, error)
+	Delete(ctx context.Context, vehicleID string) error
+}
+
+type StopVisitRepository interface {
+	Arrive(ctx context.Context, visit *models.StopVisit) error
+	Depart(ctx context.Context, visitID int64, departureTime int64) error
+	GetByStopID(ctx context.Context, stopID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error)
+	GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error)
+	// GetDwellStats aggregates completed visits per stop, longest average
+	// dwell first. Empty stopID or routeID match every stop or route.
+	GetDwellStats(ctx context.Context, stopID, routeID string, startTime, endTime int64, limit int) ([]*models.StopDwellStats, error)
+	// LinkTrip updates the trip and schedule fields of a recorded visit.
+	LinkTrip(ctx context.Context, visit *models.StopVisit) error
+	GetByTrip(ctx context.Context, tripID, startDate string) ([]*models.StopVisit, error)
+	// GetOnTimePerformance aggregates arrival delays per route. Arrivals more
+	// than earlyTolerance seconds early or lateTolerance seconds late are not
+	// on time. An empty routeID matches every route.
+	GetOnTimePerformance(ctx context.Context, routeID string, startTime, endTime, earlyTolerance, lateTolerance int64) ([]*models.OnTimePerformance, error)
+	// GetSegmentTravelTimes and GetDwellProfiles aggregate visits since a
+	// time by hour of day, shifted by utcOffset seconds into local time.
+	// Segment runs longer than maxSeconds are ignored.
+	GetSegmentTravelTimes(ctx context.Context, since int64, utcOffset int, maxSeconds int64) ([]*models.SegmentTravelTime, error)
+	GetDwellProfiles(ctx context.Context, since int64, utcOffset int) ([]*models.StopDwellProfile, error)
+}
+
+type DriverRepository interface {
+	Create(ctx context.Context, driver *models.Driver) error
+	GetByID(ctx context.Context, driverID string) (*models.Driver, error)
+	GetAll(ctx context.Context) ([]*models.Driver, error)
+	Update(ctx context.Context, driver *models.Driver) error
+	Delete(ctx context.Context, driverID string) error
+}
+
+type DriverShiftRepository interface {
+	// Start opens a shift, closing any open shift of the same vehicle or
+	// driver in the same transaction.
+	Start(ctx context.Context, shift *models.DriverShift) error
+	End(ctx context.Context, vehicleID string, endTime int64) (*models.DriverShift, error)
+	GetOpen(ctx context.Context) ([]*models.DriverShift, error)
+	GetByDriverID(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverShift, error)
+	// GetReports aggregates driver activity within a time range. An empty
+	// driverID reports every driver with a shift in the range.
+	GetReports(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverReport, error)
+}
+
+type DrivingEventRepository interface {
+	Create(ctx context.Context, event *models.DrivingEvent) error
+	GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error)
+	GetByDriverID(ctx context.Context, driverID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error)
+	// GetDailyScores counts driving events and time on duty per driver and
+	// local day in timezone, between fromDay and toDay (YYYY-MM-DD). An
+	// empty driverID covers every driver on duty or with events. Scores are
+	// left to the caller.
+	GetDailyScores(ctx context.Context, driverID string, fromDay, toDay, timezone string) ([]*models.DriverSafetyScore, error)
+}
+
+type OutboxRepository interface {
+	// ProcessPending locks up to limit messages that are due, oldest first,
+	// skipping rows locked by other relays, and hands them to publish in
+	// order. Published messages are marked sent; the first failure is
+	// recorded, rescheduled after backoff of the message's failed attempts,
+	// and ends the batch. It returns the number sent.
+	ProcessPending(ctx context.Context, limit int, backoff func(failed int) time.Duration, publish func(*models.OutboxMessage) error) (int, error)
+	CountPending(ctx context.Context) (int, error)
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ProcessedEventRepository remembers the events a consumer has handled, so
+// redelivered events are handled once.
+type ProcessedEventRepository interface {
+	IsProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkProcessed(ctx context.Context, eventID string) error
+	// ProcessOnce runs fn in a transaction that also marks the event
+	// processed, so its writes happen exactly once. It returns false
+	// without calling fn when the event was already processed.
+	ProcessOnce(ctx context.Context, eventID string, fn func(tx pgx.Tx) error) (bool, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	// UpdateSubscription replaces a subscription's settings, keeping its
+	// secret when none is given. Re-enabling it clears the failure count.
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+	// GetEnabledSubscriptions returns the subscriptions receiving events.
+	GetEnabledSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	// CreateDeliveries queues an event for the given subscriptions. An event
+	// already queued for a subscription is skipped.
+	CreateDeliveries(ctx context.Context, subscriptionIDs []int64, eventID, eventType string, payload []byte) error
+	// ClaimDue takes up to limit pending deliveries that are due, skipping
+	// those claimed by other workers, and counts the attempt. A claim holds
+	// them for lease, after which they are due again if no result was saved.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	// RecordAttempt logs an attempt of a claimed delivery. A successful one
+	// completes it and clears the subscription's failure count. A failed one
+	// reschedules it at nextAttempt, or fails it when nextAttempt is nil, and
+	// disables the subscription once disableAfter attempts in a row have
+	// failed. It reports whether the subscription was disabled.
+	RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, success bool, nextAttempt *time.Time, disableAfter int) (bool, error)
+	// GetDeliveries returns a subscription's latest deliveries with their
+	// attempts.
+	GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*models.WebhookDelivery, error)
+	DeleteCompleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+type NotificationRepository interface {
+	CreateRule(ctx context.Context, rule *models.NotificationRule) error
+	GetRule(ctx context.Context, id int64) (*models.NotificationRule, error)
+	ListRules(ctx context.Context) ([]*models.NotificationRule, error)
+	UpdateRule(ctx context.Context, rule *models.NotificationRule) error
+	DeleteRule(ctx context.Context, id int64) error
+	// GetEnabledRules returns the rules evaluated against events.
+	GetEnabledRules(ctx context.Context) ([]*models.NotificationRule, error)
+	// ClaimNotification records a pending notification of a rule about an
+	// event. It returns false without recording it when the rule already
+	// notified about the event, or when throttle is set and the rule
+	// notified for throttleKey less than throttle before at.
+	ClaimNotification(ctx context.Context, notification *models.Notification, throttleKey string, throttle time.Duration, at time.Time) (bool, error)
+	// CompleteNotification marks a claimed notification sent, or failed
+	// with sendError.
+	CompleteNotification(ctx context.Context, id int64, sendError *string) error
+	GetNotifications(ctx context.Context, ruleID int64, limit int) ([]*models.Notification, error)
+	DeleteNotificationsBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,308 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type notificationRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewNotificationRepository(db *pgxpool.Pool, logger *zap.Logger) NotificationRepository {
+	return &notificationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const notificationRuleColumns = `
+	id, name, description, enabled, conditions, channel, template,
+	throttle_seconds, throttle_scope, created_at, updated_at
+`
+
+func scanNotificationRule(row pgx.Row) (*models.NotificationRule, error) {
+	rule := &models.NotificationRule{}
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Description,
+		&rule.Enabled,
+		&rule.Conditions,
+		&rule.Channel,
+		&rule.Template,
+		&rule.ThrottleSeconds,
+		&rule.ThrottleScope,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	return rule, err
+}
+
+func (r *notificationRepository) CreateRule(ctx context.Context, rule *models.NotificationRule) error {
+	query := `
+		INSERT INTO notification_rules (name, description, enabled, conditions, channel, template, throttle_seconds, throttle_scope)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		rule.Name,
+		rule.Description,
+		rule.Enabled,
+		rule.Conditions,
+		rule.Channel,
+		rule.Template,
+		rule.ThrottleSeconds,
+		rule.ThrottleScope,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("notification rule %s: %w", rule.Name, ErrAlreadyExists)
+	}
+	if err != nil {
+		r.logger.Error("Failed to create notification rule",
+			zap.Error(err),
+			zap.String("name", rule.Name))
+		return fmt.Errorf("failed to create notification rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *notificationRepository) GetRule(ctx context.Context, id int64) (*models.NotificationRule, error) {
+	query := `SELECT ` + notificationRuleColumns + ` FROM notification_rules WHERE id = $1`
+
+	rule, err := scanNotificationRule(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("notification rule %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get notification rule",
+			zap.Error(err),
+			zap.Int64("rule_id", id))
+		return nil, fmt.Errorf("failed to get notification rule %d: %w", id, err)
+	}
+
+	return rule, nil
+}
+
+func (r *notificationRepository) ListRules(ctx context.Context) ([]*models.NotificationRule, error) {
+	return r.queryRules(ctx, `SELECT `+notificationRuleColumns+` FROM notification_rules ORDER BY id`)
+}
+
+func (r *notificationRepository) GetEnabledRules(ctx context.Context) ([]*models.NotificationRule, error) {
+	return r.queryRules(ctx, `SELECT `+notificationRuleColumns+` FROM notification_rules WHERE enabled ORDER BY id`)
+}
+
+func (r *notificationRepository) queryRules(ctx context.Context, query string) ([]*models.NotificationRule, error) {
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get notification rules", zap.Error(err))
+		return nil, fmt.Errorf("failed to get notification rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.NotificationRule
+	for rows.Next() {
+		rule, err := scanNotificationRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *notificationRepository) UpdateRule(ctx context.Context, rule *models.NotificationRule) error {
+	query := `
+		UPDATE notification_rules
+		SET name = $2,
+			description = $3,
+			enabled = $4,
+			conditions = $5,
+			channel = $6,
+			template = $7,
+			throttle_seconds = $8,
+			throttle_scope = $9,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + notificationRuleColumns
+
+	updated, err := scanNotificationRule(r.db.QueryRow(ctx, query,
+		rule.ID,
+		rule.Name,
+		rule.Description,
+		rule.Enabled,
+		rule.Conditions,
+		rule.Channel,
+		rule.Template,
+		rule.ThrottleSeconds,
+		rule.ThrottleScope,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("notification rule %d: %w", rule.ID, ErrNotFound)
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("notification rule %s: %w", rule.Name, ErrAlreadyExists)
+	}
+	if err != nil {
+		r.logger.Error("Failed to update notification rule",
+			zap.Error(err),
+			zap.Int64("rule_id", rule.ID))
+		return fmt.Errorf("failed to update notification rule %d: %w", rule.ID, err)
+	}
+
+	*rule = *updated
+	return nil
+}
+
+func (r *notificationRepository) DeleteRule(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM notification_rules WHERE id = $1", id)
+	if err != nil {
+		r.logger.Error("Failed to delete notification rule",
+			zap.Error(err),
+			zap.Int64("rule_id", id))
+		return fmt.Errorf("failed to delete notification rule %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("notification rule %d: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *notificationRepository) ClaimNotification(ctx context.Context, notification *models.Notification, throttleKey string, throttle time.Duration, at time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO notifications (rule_id, event_id, event_type, vehicle_id, channel, message)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (rule_id, event_id) DO NOTHING
+		RETURNING id, status, created_at
+	`,
+		notification.RuleID,
+		notification.EventID,
+		notification.EventType,
+		notification.VehicleID,
+		notification.Channel,
+		notification.Message,
+	).Scan(&notification.ID, &notification.Status, &notification.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Redelivered event
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record notification: %w", err)
+	}
+
+	if throttle > 0 {
+		// Only claims the throttle when the last notification is old
+		// enough, so concurrent workers cannot both notify
+		var ruleID int64
+		err = tx.QueryRow(ctx, `
+			INSERT INTO notification_rule_throttles (rule_id, throttle_key, last_notified_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (rule_id, throttle_key) DO UPDATE
+			SET last_notified_at = EXCLUDED.last_notified_at
+			WHERE notification_rule_throttles.last_notified_at <= EXCLUDED.last_notified_at - $4 * INTERVAL '1 second'
+			RETURNING rule_id
+		`, notification.RuleID, throttleKey, at, throttle.Seconds()).Scan(&ruleID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to throttle notification rule %d: %w", notification.RuleID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit notification: %w", err)
+	}
+	return true, nil
+}
+
+func (r *notificationRepository) CompleteNotification(ctx context.Context, id int64, sendError *string) error {
+	query := `
+		UPDATE notifications
+		SET status = CASE WHEN $2::TEXT IS NULL THEN 'sent' ELSE 'failed' END,
+			error = $2,
+			sent_at = CASE WHEN $2::TEXT IS NULL THEN NOW() END
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, sendError); err != nil {
+		r.logger.Error("Failed to complete notification",
+			zap.Error(err),
+			zap.Int64("notification_id", id))
+		return fmt.Errorf("failed to complete notification %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r *notificationRepository) GetNotifications(ctx context.Context, ruleID int64, limit int) ([]*models.Notification, error) {
+	query := `
+		SELECT id, rule_id, event_id, event_type, vehicle_id, channel, message, status, error, created_at, sent_at
+		FROM notifications
+		WHERE rule_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, ruleID, limit)
+	if err != nil {
+		r.logger.Error("Failed to get notifications",
+			zap.Error(err),
+			zap.Int64("rule_id", ruleID))
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification := &models.Notification{}
+		err := rows.Scan(
+			&notification.ID,
+			&notification.RuleID,
+			&notification.EventID,
+			&notification.EventType,
+			&notification.VehicleID,
+			&notification.Channel,
+			&notification.Message,
+			&notification.Status,
+			&notification.Error,
+			&notification.CreatedAt,
+			&notification.SentAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *notificationRepository) DeleteNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM notifications WHERE created_at < $1", before)
+	if err != nil {
+		r.logger.Error("Failed to delete notifications", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete notifications: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type outboxRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewOutboxRepository(db *pgxpool.Pool, logger *zap.Logger) OutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// insertOutboxMessage queues a message within the caller's transaction.
+func insertOutboxMessage(ctx context.Context, tx pgx.Tx, message *models.OutboxMessage) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO outbox (routing_key, payload, mandatory)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, message.RoutingKey, message.Payload, message.Mandatory).Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue outbox message: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) ProcessPending(ctx context.Context, limit int, backoff func(failed int) time.Duration, publish func(*models.OutboxMessage) error) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The row locks are held until commit, so concurrent relays on other
+	// replicas skip these messages instead of publishing them twice
+	rows, err := tx.Query(ctx, `
+		SELECT id, routing_key, payload, mandatory, attempts, last_error, sent_at, created_at
+		FROM outbox
+		WHERE sent_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		r.logger.Error("Failed to claim outbox messages", zap.Error(err))
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	var messages []*models.OutboxMessage
+	for rows.Next() {
+		message := &models.OutboxMessage{}
+		if err := rows.Scan(
+			&message.ID,
+			&message.RoutingKey,
+			&message.Payload,
+			&message.Mandatory,
+			&message.Attempts,
+			&message.LastError,
+			&message.SentAt,
+			&message.CreatedAt,
+		); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	sent, failed, publishErr := publishBatch(messages, publish)
+	if failed != nil {
+		// Every earlier attempt failed too, or the message would be sent
+		delay := backoff(failed.Attempts + 1)
+		_, err := tx.Exec(ctx, `
+			UPDATE outbox SET
+				attempts = attempts + 1,
+				last_error = $2,
+				next_attempt_at = NOW() + $3::float8 * INTERVAL '1 second'
+			WHERE id = $1
+		`, failed.ID, publishErr.Error(), delay.Seconds())
+		if err != nil {
+			return 0, fmt.Errorf("failed to reschedule outbox message %d: %w", failed.ID, err)
+		}
+	}
+
+	if len(sent) > 0 {
+		_, err := tx.Exec(ctx, `
+			UPDATE outbox SET sent_at = NOW(), attempts = attempts + 1, last_error = NULL
+			WHERE id = ANY($1)
+		`, sent)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark outbox messages sent: %w", err)
+		}
+	}
+
+	// Messages published before a failed commit stay pending and are sent
+	// again: delivery is at least once
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+	}
+
+	return len(sent), nil
+}
+
+// publishBatch hands messages to publish in order and returns the ids of
+// those published. The first failure ends the batch: later messages wait
+// for the broker rather than failing in turn.
+func publishBatch(messages []*models.OutboxMessage, publish func(*models.OutboxMessage) error) ([]int64, *models.OutboxMessage, error) {
+	var sent []int64
+	for _, message := range messages {
+		if err := publish(message); err != nil {
+			return sent, message, err
+		}
+		sent = append(sent, message.ID)
+	}
+	return sent, nil, nil
+}
+
+func (r *outboxRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM outbox WHERE sent_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox messages: %w", err)
+	}
+	return count, nil
+}
+
+func (r *outboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM outbox WHERE sent_at < $1", before)
+	if err != nil {
+		r.logger.Error("Failed to delete sent outbox messages", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete sent outbox messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
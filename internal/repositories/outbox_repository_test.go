@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+func TestPublishBatch(t *testing.T) {
+	errUnconfirmed := errors.New("publish not confirmed")
+
+	tests := []struct {
+		name string
+		// Ids the broker does not confirm
+		failing    []int64
+		wantSent   []int64
+		wantFailed int64
+		// Ids handed to publish
+		wantTried []int64
+	}{
+		{
+			name:      "all confirmed",
+			wantSent:  []int64{1, 2, 3},
+			wantTried: []int64{1, 2, 3},
+		},
+		{
+			name:       "first failure ends the batch",
+			failing:    []int64{2},
+			wantSent:   []int64{1},
+			wantFailed: 2,
+			wantTried:  []int64{1, 2},
+		},
+		{
+			name:       "nothing sent when the oldest fails",
+			failing:    []int64{1, 3},
+			wantFailed: 1,
+			wantTried:  []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := []*models.OutboxMessage{{ID: 1}, {ID: 2}, {ID: 3}}
+
+			var tried []int64
+			sent, failed, err := publishBatch(messages, func(message *models.OutboxMessage) error {
+				tried = append(tried, message.ID)
+				if slices.Contains(tt.failing, message.ID) {
+					return errUnconfirmed
+				}
+				return nil
+			})
+
+			if !slices.Equal(sent, tt.wantSent) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if !slices.Equal(tried, tt.wantTried) {
+				t.Errorf("published %v, want %v", tried, tt.wantTried)
+			}
+			if tt.wantFailed == 0 {
+				if failed != nil || err != nil {
+					t.Errorf("failed = %v, %v, want none", failed, err)
+				}
+				return
+			}
+			if failed == nil || failed.ID != tt.wantFailed {
+				t.Fatalf("failed = %v, want message %d", failed, tt.wantFailed)
+			}
+			if !errors.Is(err, errUnconfirmed) {
+				t.Errorf("err = %v, want %v", err, errUnconfirmed)
+			}
+		})
+	}
+}
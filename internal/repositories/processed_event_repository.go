@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type processedEventRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewProcessedEventRepository(db *pgxpool.Pool, logger *zap.Logger) ProcessedEventRepository {
+	return &processedEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *processedEventRepository) IsProcessed(ctx context.Context, eventID string) (bool, error) {
+	var processed bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM processed_events WHERE event_id = $1)", eventID).Scan(&processed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check processed event %s: %w", eventID, err)
+	}
+	return processed, nil
+}
+
+func (r *processedEventRepository) MarkProcessed(ctx context.Context, eventID string) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO processed_events (event_id) VALUES ($1) ON CONFLICT (event_id) DO NOTHING", eventID)
+	if err != nil {
+		r.logger.Error("Failed to mark event processed",
+			zap.Error(err),
+			zap.String("event_id", eventID))
+		return fmt.Errorf("failed to mark event %s processed: %w", eventID, err)
+	}
+	return nil
+}
+
+func (r *processedEventRepository) ProcessOnce(ctx context.Context, eventID string, fn func(tx pgx.Tx) error) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The insert locks the event id, so a concurrent delivery of the same
+	// event waits here and then finds it processed
+	var inserted string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO processed_events (event_id) VALUES ($1)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING event_id
+	`, eventID).Scan(&inserted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark event %s processed: %w", eventID, err)
+	}
+
+	if err := fn(tx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit event %s: %w", eventID, err)
+	}
+	return true, nil
+}
+
+func (r *processedEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM processed_events WHERE processed_at < $1", before)
+	if err != nil {
+		r.logger.Error("Failed to delete processed events", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete processed events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
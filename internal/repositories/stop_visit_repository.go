@@ -0,0 +1,398 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type stopVisitRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewStopVisitRepository(db *pgxpool.Pool, logger *zap.Logger) StopVisitRepository {
+	return &stopVisitRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Arrive records an open visit (departure_time is NULL until departure).
+func (r *stopVisitRepository) Arrive(ctx context.Context, visit *models.StopVisit) error {
+	query := `
+		INSERT INTO stop_visits (vehicle_id, stop_id, stop_name, route_id, trip_id, direction_id,
+		                         start_date, stop_sequence, trip_matched, arrival_time,
+		                         scheduled_arrival, scheduled_departure, arrival_delay)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		visit.VehicleID,
+		visit.StopID,
+		visit.StopName,
+		visit.RouteID,
+		visit.TripID,
+		visit.DirectionID,
+		visit.StartDate,
+		visit.StopSequence,
+		visit.TripMatched,
+		visit.ArrivalTime,
+		visit.ScheduledArrival,
+		visit.ScheduledDeparture,
+		visit.ArrivalDelay,
+	).Scan(&visit.ID, &visit.CreatedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to create stop visit",
+			zap.Error(err),
+			zap.String("vehicle_id", visit.VehicleID),
+			zap.String("stop_id", visit.StopID))
+		return fmt.Errorf("failed to create stop visit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *stopVisitRepository) Depart(ctx context.Context, visitID int64, departureTime int64) error {
+	query := `
+		UPDATE stop_visits
+		SET departure_time = $2, dwell_seconds = $2 - arrival_time,
+		    departure_delay = $2 - scheduled_departure
+		WHERE id = $1 AND departure_time IS NULL
+	`
+
+	if _, err := r.db.Exec(ctx, query, visitID, departureTime); err != nil {
+		r.logger.Error("Failed to close stop visit",
+			zap.Error(err),
+			zap.Int64("visit_id", visitID))
+		return fmt.Errorf("failed to close stop visit: %w", err)
+	}
+
+	return nil
+}
+
+const stopVisitColumns = `id, vehicle_id, stop_id, stop_name, route_id, trip_id, direction_id, start_date,
+		       stop_sequence, trip_matched, arrival_time, departure_time, dwell_seconds,
+		       scheduled_arrival, scheduled_departure, arrival_delay, departure_delay, created_at`
+
+func (r *stopVisitRepository) GetByStopID(ctx context.Context, stopID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error) {
+	query := `
+		SELECT ` + stopVisitColumns + `
+		FROM stop_visits
+		WHERE stop_id = $1 AND arrival_time BETWEEN $2 AND $3
+		ORDER BY arrival_time DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, stopID, startTime, endTime, limit)
+	if err != nil {
+		r.logger.Error("Failed to get stop visits by stop",
+			zap.Error(err),
+			zap.String("stop_id", stopID))
+		return nil, fmt.Errorf("failed to get visits for stop %s: %w", stopID, err)
+	}
+
+	return scanStopVisits(rows)
+}
+
+func (r *stopVisitRepository) GetByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error) {
+	query := `
+		SELECT ` + stopVisitColumns + `
+		FROM stop_visits
+		WHERE vehicle_id = $1 AND arrival_time BETWEEN $2 AND $3
+		ORDER BY arrival_time DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID, startTime, endTime, limit)
+	if err != nil {
+		r.logger.Error("Failed to get stop visits by vehicle",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get stop visits for vehicle %s: %w", vehicleID, err)
+	}
+
+	return scanStopVisits(rows)
+}
+
+func (r *stopVisitRepository) GetDwellStats(ctx context.Context, stopID, routeID string, startTime, endTime int64, limit int) ([]*models.StopDwellStats, error) {
+	query := `
+		SELECT stop_id, MAX(stop_name), COUNT(*),
+		       AVG(dwell_seconds)::float8,
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY dwell_seconds),
+		       PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY dwell_seconds),
+		       MAX(dwell_seconds)
+		FROM stop_visits
+		WHERE arrival_time BETWEEN $1 AND $2 AND departure_time IS NOT NULL
+		  AND ($3 = '' OR stop_id = $3)
+		  AND ($4 = '' OR route_id = $4)
+		GROUP BY stop_id
+		ORDER BY AVG(dwell_seconds) DESC
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, startTime, endTime, stopID, routeID, limit)
+	if err != nil {
+		r.logger.Error("Failed to get dwell statistics", zap.Error(err))
+		return nil, fmt.Errorf("failed to get dwell statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*models.StopDwellStats
+	for rows.Next() {
+		stat := &models.StopDwellStats{}
+		err := rows.Scan(
+			&stat.StopID,
+			&stat.StopName,
+			&stat.VisitCount,
+			&stat.AvgDwellSeconds,
+			&stat.MedianDwellSeconds,
+			&stat.P90DwellSeconds,
+			&stat.MaxDwellSeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dwell statistics: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dwell statistics rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// LinkTrip sets the trip context of a visit recorded before its trip was
+// known. The departure delay is recomputed for visits already closed.
+func (r *stopVisitRepository) LinkTrip(ctx context.Context, visit *models.StopVisit) error {
+	query := `
+		UPDATE stop_visits
+		SET route_id = $2, trip_id = $3, direction_id = $4, start_date = $5, stop_sequence = $6,
+		    trip_matched = $7, scheduled_arrival = $8, scheduled_departure = $9, arrival_delay = $10,
+		    departure_delay = departure_time - $9
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		visit.ID,
+		visit.RouteID,
+		visit.TripID,
+		visit.DirectionID,
+		visit.StartDate,
+		visit.StopSequence,
+		visit.TripMatched,
+		visit.ScheduledArrival,
+		visit.ScheduledDeparture,
+		visit.ArrivalDelay,
+	)
+	if err != nil {
+		r.logger.Error("Failed to link stop visit to trip",
+			zap.Error(err),
+			zap.Int64("visit_id", visit.ID),
+			zap.String("trip_id", visit.TripID))
+		return fmt.Errorf("failed to link stop visit to trip: %w", err)
+	}
+
+	return nil
+}
+
+func (r *stopVisitRepository) GetByTrip(ctx context.Context, tripID, startDate string) ([]*models.StopVisit, error) {
+	query := `
+		SELECT ` + stopVisitColumns + `
+		FROM stop_visits
+		WHERE trip_id = $1 AND start_date = $2
+		ORDER BY stop_sequence NULLS LAST, arrival_time
+	`
+
+	rows, err := r.db.Query(ctx, query, tripID, startDate)
+	if err != nil {
+		r.logger.Error("Failed to get stop visits by trip",
+			zap.Error(err),
+			zap.String("trip_id", tripID),
+			zap.String("start_date", startDate))
+		return nil, fmt.Errorf("failed to get stop visits for trip %s: %w", tripID, err)
+	}
+
+	return scanStopVisits(rows)
+}
+
+func (r *stopVisitRepository) GetOnTimePerformance(ctx context.Context, routeID string, startTime, endTime, earlyTolerance, lateTolerance int64) ([]*models.OnTimePerformance, error) {
+	query := `
+		SELECT route_id, COUNT(*),
+		       COUNT(*) FILTER (WHERE arrival_delay BETWEEN -$4::bigint AND $5::bigint),
+		       COUNT(*) FILTER (WHERE arrival_delay < -$4::bigint),
+		       COUNT(*) FILTER (WHERE arrival_delay > $5::bigint),
+		       AVG(arrival_delay)::float8
+		FROM stop_visits
+		WHERE arrival_time BETWEEN $1 AND $2 AND arrival_delay IS NOT NULL
+		  AND ($3 = '' OR route_id = $3)
+		GROUP BY route_id
+		ORDER BY route_id
+	`
+
+	rows, err := r.db.Query(ctx, query, startTime, endTime, routeID, earlyTolerance, lateTolerance)
+	if err != nil {
+		r.logger.Error("Failed to get on-time performance", zap.Error(err))
+		return nil, fmt.Errorf("failed to get on-time performance: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.OnTimePerformance
+	for rows.Next() {
+		otp := &models.OnTimePerformance{}
+		err := rows.Scan(
+			&otp.RouteID,
+			&otp.Observations,
+			&otp.OnTime,
+			&otp.Early,
+			&otp.Late,
+			&otp.AvgDelaySeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan on-time performance: %w", err)
+		}
+		otp.OnTimePercent = 100 * float64(otp.OnTime) / float64(otp.Observations)
+		results = append(results, otp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating on-time performance rows: %w", err)
+	}
+
+	return results, nil
+}
+
+func scanStopVisits(rows pgx.Rows) ([]*models.StopVisit, error) {
+	defer rows.Close()
+
+	var visits []*models.StopVisit
+	for rows.Next() {
+		visit := &models.StopVisit{}
+		err := rows.Scan(
+			&visit.ID,
+			&visit.VehicleID,
+			&visit.StopID,
+			&visit.StopName,
+			&visit.RouteID,
+			&visit.TripID,
+			&visit.DirectionID,
+			&visit.StartDate,
+			&visit.StopSequence,
+			&visit.TripMatched,
+			&visit.ArrivalTime,
+			&visit.DepartureTime,
+			&visit.DwellSeconds,
+			&visit.ScheduledArrival,
+			&visit.ScheduledDeparture,
+			&visit.ArrivalDelay,
+			&visit.DepartureDelay,
+			&visit.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stop visit: %w", err)
+		}
+		visits = append(visits, visit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stop visit rows: %w", err)
+	}
+
+	return visits, nil
+}
+
+func (r *stopVisitRepository) GetSegmentTravelTimes(ctx context.Context, since int64, utcOffset int, maxSeconds int64) ([]*models.SegmentTravelTime, error) {
+	query := `
+		WITH ordered AS (
+			SELECT stop_id, departure_time,
+			       LEAD(stop_id) OVER w AS next_stop_id,
+			       LEAD(arrival_time) OVER w AS next_arrival
+			FROM stop_visits
+			WHERE arrival_time >= $1
+			WINDOW w AS (PARTITION BY vehicle_id ORDER BY arrival_time)
+		)
+		SELECT stop_id, next_stop_id, ((departure_time + $2) % 86400 / 3600)::int,
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY next_arrival - departure_time),
+		       COUNT(*)
+		FROM ordered
+		WHERE next_stop_id IS NOT NULL AND next_stop_id <> stop_id AND departure_time IS NOT NULL
+		  AND next_arrival - departure_time BETWEEN 0 AND $3
+		GROUP BY 1, 2, 3
+	`
+
+	rows, err := r.db.Query(ctx, query, since, utcOffset, maxSeconds)
+	if err != nil {
+		r.logger.Error("Failed to get segment travel times", zap.Error(err))
+		return nil, fmt.Errorf("failed to get segment travel times: %w", err)
+	}
+	defer rows.Close()
+
+	var segments []*models.SegmentTravelTime
+	for rows.Next() {
+		segment := &models.SegmentTravelTime{}
+		err := rows.Scan(
+			&segment.FromStopID,
+			&segment.ToStopID,
+			&segment.Hour,
+			&segment.MedianSeconds,
+			&segment.Samples,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment travel time: %w", err)
+		}
+		segments = append(segments, segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating segment travel time rows: %w", err)
+	}
+
+	return segments, nil
+}
+
+func (r *stopVisitRepository) GetDwellProfiles(ctx context.Context, since int64, utcOffset int) ([]*models.StopDwellProfile, error) {
+	query := `
+		SELECT stop_id, ((arrival_time + $2) % 86400 / 3600)::int,
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY dwell_seconds),
+		       COUNT(*)
+		FROM stop_visits
+		WHERE arrival_time >= $1 AND departure_time IS NOT NULL
+		GROUP BY 1, 2
+	`
+
+	rows, err := r.db.Query(ctx, query, since, utcOffset)
+	if err != nil {
+		r.logger.Error("Failed to get dwell profiles", zap.Error(err))
+		return nil, fmt.Errorf("failed to get dwell profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []*models.StopDwellProfile
+	for rows.Next() {
+		profile := &models.StopDwellProfile{}
+		err := rows.Scan(
+			&profile.StopID,
+			&profile.Hour,
+			&profile.MedianSeconds,
+			&profile.Samples,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dwell profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dwell profile rows: %w", err)
+	}
+
+	return profiles, nil
+}
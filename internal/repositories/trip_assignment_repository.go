@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type tripAssignmentRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewTripAssignmentRepository(db *pgxpool.Pool, logger *zap.Logger) TripAssignmentRepository {
+	return &tripAssignmentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *tripAssignmentRepository) Upsert(ctx context.Context, assignment *models.TripAssignment) error {
+	query := `
+		INSERT INTO vehicle_trip_assignments (vehicle_id, trip_id, route_id, direction_id, start_date)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (vehicle_id) DO UPDATE
+		SET trip_id = EXCLUDED.trip_id, route_id = EXCLUDED.route_id,
+		    direction_id = EXCLUDED.direction_id, start_date = EXCLUDED.start_date,
+		    assigned_at = NOW()
+		RETURNING assigned_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		assignment.VehicleID,
+		assignment.TripID,
+		assignment.RouteID,
+		assignment.DirectionID,
+		assignment.StartDate,
+	).Scan(&assignment.AssignedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to save trip assignment",
+			zap.Error(err),
+			zap.String("vehicle_id", assignment.VehicleID))
+		return fmt.Errorf("failed to save trip assignment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tripAssignmentRepository) GetByVehicleID(ctx context.Context, vehicleID string) (*models.TripAssignment, error) {
+	query := `
+		SELECT vehicle_id, trip_id, route_id, direction_id, start_date, assigned_at
+		FROM vehicle_trip_assignments
+		WHERE vehicle_id = $1
+	`
+
+	assignment := &models.TripAssignment{}
+	err := r.db.QueryRow(ctx, query, vehicleID).Scan(
+		&assignment.VehicleID,
+		&assignment.TripID,
+		&assignment.RouteID,
+		&assignment.DirectionID,
+		&assignment.StartDate,
+		&assignment.AssignedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("trip assignment for %s: %w", vehicleID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get trip assignment",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get trip assignment for %s: %w", vehicleID, err)
+	}
+
+	return assignment, nil
+}
+
+func (r *tripAssignmentRepository) GetAll(ctx context.Context) ([]*models.TripAssignment, error) {
+	query := `
+		SELECT vehicle_id, trip_id, route_id, direction_id, start_date, assigned_at
+		FROM vehicle_trip_assignments
+		ORDER BY vehicle_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get trip assignments", zap.Error(err))
+		return nil, fmt.Errorf("failed to get trip assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []*models.TripAssignment
+	for rows.Next() {
+		assignment := &models.TripAssignment{}
+		err := rows.Scan(
+			&assignment.VehicleID,
+			&assignment.TripID,
+			&assignment.RouteID,
+			&assignment.DirectionID,
+			&assignment.StartDate,
+			&assignment.AssignedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trip assignment: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trip assignment rows: %w", err)
+	}
+
+	return assignments, nil
+}
+
+func (r *tripAssignmentRepository) Delete(ctx context.Context, vehicleID string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM vehicle_trip_assignments WHERE vehicle_id = $1", vehicleID)
+	if err != nil {
+		r.logger.Error("Failed to delete trip assignment",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return fmt.Errorf("failed to delete trip assignment: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("trip assignment for %s: %w", vehicleID, ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type tripRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewTripRepository(db *pgxpool.Pool, logger *zap.Logger) TripRepository {
+	return &tripRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *tripRepository) CreateTrip(ctx context.Context, trip *models.Trip) error {
+	query := `
+		INSERT INTO vehicle_trips (
+			vehicle_id, start_time, end_time,
+			start_latitude, start_longitude, end_latitude, end_longitude,
+			distance_meters, duration_seconds, max_speed_kmh, avg_speed_kmh
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		trip.VehicleID,
+		trip.StartTime,
+		trip.EndTime,
+		trip.StartLatitude,
+		trip.StartLongitude,
+		trip.EndLatitude,
+		trip.EndLongitude,
+		trip.DistanceMeters,
+		trip.DurationSeconds,
+		trip.MaxSpeedKmh,
+		trip.AvgSpeedKmh,
+	).Scan(&trip.ID, &trip.CreatedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to create trip",
+			zap.Error(err),
+			zap.String("vehicle_id", trip.VehicleID))
+		return fmt.Errorf("failed to create trip: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tripRepository) CreateStop(ctx context.Context, stop *models.VehicleStop) error {
+	query := `
+		INSERT INTO vehicle_stops (vehicle_id, start_time, end_time, latitude, longitude, duration_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		stop.VehicleID,
+		stop.StartTime,
+		stop.EndTime,
+		stop.Latitude,
+		stop.Longitude,
+		stop.DurationSeconds,
+	).Scan(&stop.ID, &stop.CreatedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to create vehicle stop",
+			zap.Error(err),
+			zap.String("vehicle_id", stop.VehicleID))
+		return fmt.Errorf("failed to create vehicle stop: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tripRepository) GetTripsByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.Trip, error) {
+	query := `
+		SELECT id, vehicle_id, start_time, end_time,
+		       start_latitude, start_longitude, end_latitude, end_longitude,
+		       distance_meters, duration_seconds, max_speed_kmh, avg_speed_kmh, created_at
+		FROM vehicle_trips
+		WHERE vehicle_id = $1 AND start_time BETWEEN $2 AND $3
+		ORDER BY start_time DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, vehicleID, startTime, endTime, limit)
+	if err != nil {
+		r.logger.Error("Failed to get trips by vehicle ID",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get trips for vehicle %s: %w", vehicleID, err)
+	}
+	defer rows.Close()
+
+	var trips []*models.Trip
+	for rows.Next() {
+		trip := &models.Trip{}
+		err := rows.Scan(
+			&trip.ID,
+			&trip.VehicleID,
+			&trip.StartTime,
+			&trip.EndTime,
+			&trip.StartLatitude,
+			&trip.StartLongitude,
+			&trip.EndLatitude,
+			&trip.EndLongitude,
+			&trip.DistanceMeters,
+			&trip.DurationSeconds,
+			&trip.MaxSpeedKmh,
+			&trip.AvgSpeedKmh,
+			&trip.CreatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan trip", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan trip: %w", err)
+		}
+		trips = append(trips, trip)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trip rows: %w", err)
+	}
+
+	return trips, nil
+}
+
+func (r *tripRepository) DeleteByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM vehicle_trips WHERE vehicle_id = $1 AND start_time BETWEEN $2 AND $3",
+		vehicleID, startTime, endTime); err != nil {
+		return fmt.Errorf("failed to delete trips for vehicle %s: %w", vehicleID, err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM vehicle_stops WHERE vehicle_id = $1 AND start_time BETWEEN $2 AND $3",
+		vehicleID, startTime, endTime); err != nil {
+		return fmt.Errorf("failed to delete stops for vehicle %s: %w", vehicleID, err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type vehicleRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+type quarantineRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewVehicleRepository(db *pgxpool.Pool, logger *zap.Logger) VehicleRepository {
+	return &vehicleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func NewQuarantineRepository(db *pgxpool.Pool, logger *zap.Logger) QuarantineRepository {
+	return &quarantineRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *vehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	query := `
+		INSERT INTO vehicles (vehicle_id, plate_number, operator, corridor, vehicle_type, capacity, active, groups)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		vehicle.VehicleID,
+		vehicle.PlateNumber,
+		vehicle.Operator,
+		vehicle.Corridor,
+		vehicle.VehicleType,
+		vehicle.Capacity,
+		vehicle.Active,
+		vehicle.Groups,
+	).Scan(&vehicle.CreatedAt, &vehicle.UpdatedAt)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("vehicle %s: %w", vehicle.VehicleID, ErrAlreadyExists)
+	}
+	if err != nil {
+		r.logger.Error("Failed to create vehicle",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicle.VehicleID))
+		return fmt.Errorf("failed to create vehicle: %w", err)
+	}
+
+	return nil
+}
+
+func (r *vehicleRepository) GetByID(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	query := `
+		SELECT vehicle_id, plate_number, operator, corridor, vehicle_type, capacity, active, groups, created_at, updated_at
+		FROM vehicles
+		WHERE vehicle_id = $1
+	`
+
+	vehicle := &models.Vehicle{}
+	err := r.db.QueryRow(ctx, query, vehicleID).Scan(
+		&vehicle.VehicleID,
+		&vehicle.PlateNumber,
+		&vehicle.Operator,
+		&vehicle.Corridor,
+		&vehicle.VehicleType,
+		&vehicle.Capacity,
+		&vehicle.Active,
+		&vehicle.Groups,
+		&vehicle.CreatedAt,
+		&vehicle.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("vehicle %s: %w", vehicleID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get vehicle by ID",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return nil, fmt.Errorf("failed to get vehicle %s: %w", vehicleID, err)
+	}
+
+	return vehicle, nil
+}
+
+func (r *vehicleRepository) GetAll(ctx context.Context) ([]*models.Vehicle, error) {
+	query := `
+		SELECT vehicle_id, plate_number, operator, corridor, vehicle_type, capacity, active, groups, created_at, updated_at
+		FROM vehicles
+		ORDER BY vehicle_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get all vehicles", zap.Error(err))
+		return nil, fmt.Errorf("failed to get vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	var vehicles []*models.Vehicle
+	for rows.Next() {
+		vehicle := &models.Vehicle{}
+		err := rows.Scan(
+			&vehicle.VehicleID,
+			&vehicle.PlateNumber,
+			&vehicle.Operator,
+			&vehicle.Corridor,
+			&vehicle.VehicleType,
+			&vehicle.Capacity,
+			&vehicle.Active,
+			&vehicle.Groups,
+			&vehicle.CreatedAt,
+			&vehicle.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan vehicle", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan vehicle: %w", err)
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vehicle rows: %w", err)
+	}
+
+	return vehicles, nil
+}
+
+func (r *vehicleRepository) Update(ctx context.Context, vehicle *models.Vehicle) error {
+	query := `
+		UPDATE vehicles
+		SET plate_number = $2, operator = $3, corridor = $4, vehicle_type = $5,
+		    capacity = $6, active = $7, groups = $8, updated_at = NOW()
+		WHERE vehicle_id = $1
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		vehicle.VehicleID,
+		vehicle.PlateNumber,
+		vehicle.Operator,
+		vehicle.Corridor,
+		vehicle.VehicleType,
+		vehicle.Capacity,
+		vehicle.Active,
+		vehicle.Groups,
+	).Scan(&vehicle.CreatedAt, &vehicle.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("vehicle %s: %w", vehicle.VehicleID, ErrNotFound)
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("plate number %s: %w", vehicle.PlateNumber, ErrAlreadyExists)
+	}
+	if err != nil {
+		r.logger.Error("Failed to update vehicle",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicle.VehicleID))
+		return fmt.Errorf("failed to update vehicle: %w", err)
+	}
+
+	return nil
+}
+
+func (r *vehicleRepository) Delete(ctx context.Context, vehicleID string) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM vehicles WHERE vehicle_id = $1", vehicleID)
+	if err != nil {
+		r.logger.Error("Failed to delete vehicle",
+			zap.Error(err),
+			zap.String("vehicle_id", vehicleID))
+		return fmt.Errorf("failed to delete vehicle: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("vehicle %s: %w", vehicleID, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *quarantineRepository) Create(ctx context.Context, location *models.QuarantinedLocation) error {
+	query := `
+		INSERT INTO quarantined_locations (vehicle_id, latitude, longitude, timestamp, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, received_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		location.VehicleID,
+		location.Latitude,
+		location.Longitude,
+		location.Timestamp,
+		location.Reason,
+	).Scan(&location.ID, &location.ReceivedAt)
+
+	if err != nil {
+		r.logger.Error("Failed to quarantine location",
+			zap.Error(err),
+			zap.String("vehicle_id", location.VehicleID))
+		return fmt.Errorf("failed to quarantine location: %w", err)
+	}
+
+	return nil
+}
+
+func (r *quarantineRepository) GetRecent(ctx context.Context, limit int) ([]*models.QuarantinedLocation, error) {
+	query := `
+		SELECT id, vehicle_id, latitude, longitude, timestamp, reason, received_at
+		FROM quarantined_locations
+		ORDER BY received_at DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to get quarantined locations", zap.Error(err))
+		return nil, fmt.Errorf("failed to get quarantined locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []*models.QuarantinedLocation
+	for rows.Next() {
+		location := &models.QuarantinedLocation{}
+		err := rows.Scan(
+			&location.ID,
+			&location.VehicleID,
+			&location.Latitude,
+			&location.Longitude,
+			&location.Timestamp,
+			&location.Reason,
+			&location.ReceivedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined location: %w", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quarantined location rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -24,8 +24,8 @@ func NewVehicleLocationRepository(db *pgxpool.Pool, logger *zap.Logger) VehicleL
 
 func (r *vehicleLocationRepository) Create(ctx context.Context, location *models.VehicleLocation) error {
 	query := `
-		INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp, ignition, speed, heading, matched_latitude, matched_longitude, road_id, driver_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at
 	`
 
@@ -34,6 +34,13 @@ func (r *vehicleLocationRepository) Create(ctx context.Context, location *models
 		location.Latitude, 
 		location.Longitude, 
 		location.Timestamp,
+		location.Ignition,
+		location.Speed,
+		location.Heading,
+		location.MatchedLatitude,
+		location.MatchedLongitude,
+		location.RoadID,
+		location.DriverID,
 	).Scan(&location.ID, &location.CreatedAt)
 
 	if err != nil {
@@ -52,7 +59,7 @@ func (r *vehicleLocationRepository) Create(ctx context.Context, location *models
 
 func (r *vehicleLocationRepository) GetLatestByVehicleID(ctx context.Context, vehicleID string) (*models.VehicleLocation, error) {
 	query := `
-		SELECT id, vehicle_id, latitude, longitude, timestamp, created_at
+		SELECT id, vehicle_id, latitude, longitude, timestamp, ignition, speed, heading, matched_latitude, matched_longitude, road_id, driver_id, created_at
 		FROM vehicle_locations
 		WHERE vehicle_id = $1
 		ORDER BY timestamp DESC
@@ -66,6 +73,13 @@ func (r *vehicleLocationRepository) GetLatestByVehicleID(ctx context.Context, ve
 		&location.Latitude,
 		&location.Longitude,
 		&location.Timestamp,
+		&location.Ignition,
+		&location.Speed,
+		&location.Heading,
+		&location.MatchedLatitude,
+		&location.MatchedLongitude,
+		&location.RoadID,
+		&location.DriverID,
 		&location.CreatedAt,
 	)
 
@@ -81,7 +95,7 @@ func (r *vehicleLocationRepository) GetLatestByVehicleID(ctx context.Context, ve
 
 func (r *vehicleLocationRepository) GetHistoryByVehicleID(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.VehicleLocation, error) {
 	query := `
-		SELECT id, vehicle_id, latitude, longitude, timestamp, created_at
+		SELECT id, vehicle_id, latitude, longitude, timestamp, ignition, speed, heading, matched_latitude, matched_longitude, road_id, driver_id, created_at
 		FROM vehicle_locations
 		WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3
 		ORDER BY timestamp DESC
@@ -105,6 +119,13 @@ func (r *vehicleLocationRepository) GetHistoryByVehicleID(ctx context.Context, v
 			&location.Latitude,
 			&location.Longitude,
 			&location.Timestamp,
+			&location.Ignition,
+			&location.Speed,
+			&location.Heading,
+			&location.MatchedLatitude,
+			&location.MatchedLongitude,
+			&location.RoadID,
+			&location.DriverID,
 			&location.CreatedAt,
 		)
 		if err != nil {
@@ -119,4 +140,104 @@ func (r *vehicleLocationRepository) GetHistoryByVehicleID(ctx context.Context, v
 	}
 
 	return locations, nil
-}
\ No newline at end of file
+}
+
+func (r *vehicleLocationRepository) GetVehicleIDs(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT vehicle_id
+		FROM vehicle_locations
+		ORDER BY vehicle_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get vehicle IDs", zap.Error(err))
+		return nil, fmt.Errorf("failed to get vehicle IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var vehicleIDs []string
+	for rows.Next() {
+		var vehicleID string
+		if err := rows.Scan(&vehicleID); err != nil {
+			r.logger.Error("Failed to scan vehicle ID", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan vehicle ID: %w", err)
+		}
+		vehicleIDs = append(vehicleIDs, vehicleID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vehicle ID rows: %w", err)
+	}
+
+	return vehicleIDs, nil
+}
+
+// GetLatestForAllVehicles returns the most recent location of every vehicle.
+func (r *vehicleLocationRepository) GetLatestForAllVehicles(ctx context.Context) ([]*models.VehicleLocation, error) {
+	query := `
+		SELECT DISTINCT ON (vehicle_id) id, vehicle_id, latitude, longitude, timestamp, ignition, speed, heading, matched_latitude, matched_longitude, road_id, driver_id, created_at
+		FROM vehicle_locations
+		ORDER BY vehicle_id, timestamp DESC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get latest vehicle locations", zap.Error(err))
+		return nil, fmt.Errorf("failed to get latest vehicle locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []*models.VehicleLocation
+	for rows.Next() {
+		location := &models.VehicleLocation{}
+		err := rows.Scan(
+			&location.ID,
+			&location.VehicleID,
+			&location.Latitude,
+			&location.Longitude,
+			&location.Timestamp,
+			&location.Ignition,
+			&location.Speed,
+			&location.Heading,
+			&location.MatchedLatitude,
+			&location.MatchedLongitude,
+			&location.RoadID,
+			&location.DriverID,
+			&location.CreatedAt,
+		)
+		if err != nil {
+			r.logger.Error("Failed to scan vehicle location", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating location rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// UpdateMatch replaces the map-matched position of a stored location.
+func (r *vehicleLocationRepository) UpdateMatch(ctx context.Context, location *models.VehicleLocation) error {
+	query := `
+		UPDATE vehicle_locations
+		SET matched_latitude = $2, matched_longitude = $3, road_id = $4
+		WHERE id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, location.ID, location.MatchedLatitude, location.MatchedLongitude, location.RoadID)
+	if err != nil {
+		r.logger.Error("Failed to update matched location",
+			zap.Error(err),
+			zap.Int64("id", location.ID))
+		return fmt.Errorf("failed to update matched location: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
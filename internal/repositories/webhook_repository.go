@@ -0,0 +1,416 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type webhookRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewWebhookRepository(db *pgxpool.Pool, logger *zap.Logger) WebhookRepository {
+	return &webhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const webhookSubscriptionColumns = `
+	id, url, event_types, description, enabled, consecutive_failures,
+	disabled_at, disabled_reason, created_at, updated_at
+`
+
+func scanWebhookSubscription(row pgx.Row) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.EventTypes,
+		&subscription.Description,
+		&subscription.Enabled,
+		&subscription.ConsecutiveFailures,
+		&subscription.DisabledAt,
+		&subscription.DisabledReason,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	return subscription, err
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, description, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		subscription.URL,
+		subscription.Secret,
+		subscription.EventTypes,
+		subscription.Description,
+		subscription.Enabled,
+	).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Failed to create webhook subscription",
+			zap.Error(err),
+			zap.String("url", subscription.URL))
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) GetSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	subscription, err := scanWebhookSubscription(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("webhook subscription %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to get webhook subscription",
+			zap.Error(err),
+			zap.Int64("subscription_id", id))
+		return nil, fmt.Errorf("failed to get webhook subscription %d: %w", id, err)
+	}
+
+	return subscription, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+}
+
+func (r *webhookRepository) GetEnabledSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE enabled ORDER BY id`)
+}
+
+func (r *webhookRepository) querySubscriptions(ctx context.Context, query string) ([]*models.WebhookSubscription, error) {
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get webhook subscriptions", zap.Error(err))
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*models.WebhookSubscription
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2,
+			secret = COALESCE(NULLIF($3, ''), secret),
+			event_types = $4,
+			description = $5,
+			enabled = $6,
+			consecutive_failures = CASE WHEN $6 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $6 THEN NULL WHEN enabled THEN NOW() ELSE disabled_at END,
+			disabled_reason = CASE WHEN $6 THEN NULL WHEN enabled THEN 'disabled via API' ELSE disabled_reason END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + webhookSubscriptionColumns
+
+	updated, err := scanWebhookSubscription(r.db.QueryRow(ctx, query,
+		subscription.ID,
+		subscription.Secret,
+		subscription.EventTypes,
+		subscription.Description,
+		subscription.Enabled,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("webhook subscription %d: %w", subscription.ID, ErrNotFound)
+	}
+	if err != nil {
+		r.logger.Error("Failed to update webhook subscription",
+			zap.Error(err),
+			zap.Int64("subscription_id", subscription.ID))
+		return fmt.Errorf("failed to update webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	updated.Secret = subscription.Secret
+	*subscription = *updated
+	return nil
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		r.logger.Error("Failed to delete webhook subscription",
+			zap.Error(err),
+			zap.Int64("subscription_id", id))
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook subscription %d: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, subscriptionIDs []int64, eventID, eventType string, payload []byte) error {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT subscription_id, $2, $3, $4
+		FROM UNNEST($1::BIGINT[]) AS subscription_id
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, subscriptionIDs, eventID, eventType, payload); err != nil {
+		r.logger.Error("Failed to queue webhook deliveries",
+			zap.Error(err),
+			zap.String("event_id", eventID))
+		return fmt.Errorf("failed to queue webhook deliveries for event %s: %w", eventID, err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.enabled
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload,
+			d.status, d.attempts, d.created_at, s.url, s.secret
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		r.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, success bool, nextAttempt *time.Time, disableAfter int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+	`, delivery.ID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return false, fmt.Errorf("failed to log webhook attempt: %w", err)
+	}
+
+	disabled := false
+	if success {
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'succeeded', last_error = NULL, completed_at = NOW()
+			WHERE id = $1
+		`, delivery.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to complete webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		_, err = tx.Exec(ctx,
+			"UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1", delivery.SubscriptionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to reset webhook failures: %w", err)
+		}
+	} else {
+		if nextAttempt != nil {
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_deliveries SET last_error = $2, next_attempt_at = $3 WHERE id = $1
+			`, delivery.ID, attempt.Error, *nextAttempt)
+		} else {
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_deliveries
+				SET status = 'failed', last_error = $2, completed_at = NOW()
+				WHERE id = $1
+			`, delivery.ID, attempt.Error)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to reschedule webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		var failures int
+		err = tx.QueryRow(ctx, `
+			UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1
+			WHERE id = $1
+			RETURNING consecutive_failures
+		`, delivery.SubscriptionID).Scan(&failures)
+		if err != nil {
+			return false, fmt.Errorf("failed to count webhook failures: %w", err)
+		}
+
+		if disableAfter > 0 && failures >= disableAfter {
+			tag, err := tx.Exec(ctx, `
+				UPDATE webhook_subscriptions
+				SET enabled = FALSE, disabled_at = NOW(), disabled_reason = $2, updated_at = NOW()
+				WHERE id = $1 AND enabled
+			`, delivery.SubscriptionID, fmt.Sprintf("%d consecutive failed deliveries", failures))
+			if err != nil {
+				return false, fmt.Errorf("failed to disable webhook subscription: %w", err)
+			}
+			disabled = tag.RowsAffected() > 0
+
+			// Nothing more is sent to a disabled endpoint
+			_, err = tx.Exec(ctx, `
+				UPDATE webhook_deliveries
+				SET status = 'failed', last_error = 'subscription disabled', completed_at = NOW()
+				WHERE subscription_id = $1 AND status = 'pending' AND id <> $2
+			`, delivery.SubscriptionID, delivery.ID)
+			if err != nil {
+				return false, fmt.Errorf("failed to cancel webhook deliveries: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit webhook attempt: %w", err)
+	}
+	return disabled, nil
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT id, subscription_id, event_id, event_type, status, attempts, last_error,
+			CASE WHEN status = 'pending' THEN next_attempt_at END, completed_at, created_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		r.logger.Error("Failed to get webhook deliveries",
+			zap.Error(err),
+			zap.Int64("subscription_id", subscriptionID))
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	byID := make(map[int64]*models.WebhookDelivery)
+	var ids []int64
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.LastError,
+			&delivery.NextAttemptAt,
+			&delivery.CompletedAt,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+		byID[delivery.ID] = delivery
+		ids = append(ids, delivery.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	attemptRows, err := r.db.Query(ctx, `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY delivery_id, attempt
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook attempts: %w", err)
+	}
+	defer attemptRows.Close()
+
+	for attemptRows.Next() {
+		var deliveryID int64
+		attempt := &models.WebhookDeliveryAttempt{}
+		err := attemptRows.Scan(
+			&deliveryID,
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.AttemptedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		if delivery, ok := byID[deliveryID]; ok {
+			delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+		}
+	}
+
+	return deliveries, attemptRows.Err()
+}
+
+func (r *webhookRepository) DeleteCompleted(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM webhook_deliveries WHERE completed_at < $1", before)
+	if err != nil {
+		r.logger.Error("Failed to delete webhook deliveries", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
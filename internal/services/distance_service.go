@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/odometer"
+)
+
+const dayLayout = "2006-01-02"
+
+type DistanceService interface {
+	LocationProcessor
+	GetDistance(ctx context.Context, vehicleID string, fromDay, toDay string) (*DistanceReport, error)
+	GetFleetDailyReport(ctx context.Context, day string) (*FleetDistanceReport, error)
+	Backfill(ctx context.Context, vehicleID string, startTime, endTime int64) error
+}
+
+type DistanceReport struct {
+	VehicleID           string                  `json:"vehicle_id"`
+	From                string                  `json:"from"`
+	To                  string                  `json:"to"`
+	TotalDistanceMeters float64                 `json:"total_distance_meters"`
+	OdometerMeters      float64                 `json:"odometer_meters"`
+	Days                []*models.DailyDistance `json:"days"`
+}
+
+type FleetDistanceReport struct {
+	Date                string                  `json:"date"`
+	VehicleCount        int                     `json:"vehicle_count"`
+	TotalDistanceMeters float64                 `json:"total_distance_meters"`
+	Vehicles            []*models.DailyDistance `json:"vehicles"`
+}
+
+type distanceService struct {
+	distanceRepo        repositories.DistanceRepository
+	vehicleLocationRepo repositories.VehicleLocationRepository
+	filter              *odometer.Filter
+	maxGap              time.Duration
+	location            *time.Location
+	logger              *zap.Logger
+	mu                  sync.Mutex
+	references          map[string]*odometer.Point // last accepted point per vehicle
+	// Serialize each vehicle's odometer updates without holding mu across
+	// database calls
+	vehicleLocks map[string]*sync.Mutex
+}
+
+func NewDistanceService(
+	distanceRepo repositories.DistanceRepository,
+	vehicleLocationRepo repositories.VehicleLocationRepository,
+	cfg *config.OdometerConfig,
+	logger *zap.Logger,
+) DistanceService {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Warn("Unknown odometer timezone, falling back to WIB",
+			zap.Error(err),
+			zap.String("timezone", cfg.Timezone))
+		location = time.FixedZone("WIB", 7*60*60)
+	}
+
+	return &distanceService{
+		distanceRepo:        distanceRepo,
+		vehicleLocationRepo: vehicleLocationRepo,
+		filter:              odometer.NewFilter(cfg.MaxSpeedKmh, cfg.MaxGap, cfg.MinDistance),
+		maxGap:              cfg.MaxGap,
+		location:            location,
+		logger:              logger,
+		references:          make(map[string]*odometer.Point),
+		vehicleLocks:        make(map[string]*sync.Mutex),
+	}
+}
+
+// lockVehicle locks the odometer of one vehicle and returns its unlock.
+func (s *distanceService) lockVehicle(vehicleID string) func() {
+	s.mu.Lock()
+	lock, ok := s.vehicleLocks[vehicleID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.vehicleLocks[vehicleID] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+func (s *distanceService) setReference(vehicleID string, reference *odometer.Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reference == nil {
+		delete(s.references, vehicleID)
+		return
+	}
+	s.references[vehicleID] = reference
+}
+
+func (s *distanceService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	unlock := s.lockVehicle(location.VehicleID)
+	defer unlock()
+
+	current := pointOf(location)
+
+	s.mu.Lock()
+	reference, ok := s.references[location.VehicleID]
+	s.mu.Unlock()
+	if !ok {
+		// Resume from the persisted reference after a restart
+		stored, err := s.distanceRepo.GetOdometer(ctx, location.VehicleID)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			reference = &odometer.Point{
+				Latitude:  stored.LastLatitude,
+				Longitude: stored.LastLongitude,
+				Timestamp: stored.LastTimestamp,
+			}
+		}
+	}
+
+	meters := 0.0
+	if reference != nil {
+		decision, distance := s.filter.Evaluate(*reference, current)
+		switch decision {
+		case odometer.Hold:
+			held := odometer.Keep(*reference, current)
+			s.setReference(location.VehicleID, &held)
+			return nil
+		case odometer.Outlier:
+			s.setReference(location.VehicleID, reference)
+			s.logger.Warn("Ignoring outlier location for odometer",
+				zap.String("vehicle_id", location.VehicleID),
+				zap.Float64("latitude", location.Latitude),
+				zap.Float64("longitude", location.Longitude))
+			return nil
+		case odometer.Accept:
+			meters = distance
+		}
+	}
+
+	day := s.dayOf(location.Timestamp)
+	if err := s.distanceRepo.RecordDistance(ctx, location.VehicleID, day, meters, location); err != nil {
+		return fmt.Errorf("failed to record distance: %w", err)
+	}
+
+	s.setReference(location.VehicleID, &current)
+	return nil
+}
+
+func (s *distanceService) GetDistance(ctx context.Context, vehicleID string, fromDay, toDay string) (*DistanceReport, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required")
+	}
+
+	if err := validateDayRange(fromDay, toDay); err != nil {
+		return nil, err
+	}
+
+	days, err := s.distanceRepo.GetDailyByVehicleID(ctx, vehicleID, fromDay, toDay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily distances: %w", err)
+	}
+
+	report := &DistanceReport{
+		VehicleID: vehicleID,
+		From:      fromDay,
+		To:        toDay,
+		Days:      days,
+	}
+	for _, day := range days {
+		report.TotalDistanceMeters += day.DistanceMeters
+	}
+
+	stored, err := s.distanceRepo.GetOdometer(ctx, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get odometer: %w", err)
+	}
+	if stored != nil {
+		report.OdometerMeters = stored.TotalMeters
+	}
+
+	return report, nil
+}
+
+func (s *distanceService) GetFleetDailyReport(ctx context.Context, day string) (*FleetDistanceReport, error) {
+	if _, err := time.Parse(dayLayout, day); err != nil {
+		return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", day)
+	}
+
+	vehicles, err := s.distanceRepo.GetDailyByDay(ctx, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fleet daily distances: %w", err)
+	}
+
+	report := &FleetDistanceReport{
+		Date:         day,
+		VehicleCount: len(vehicles),
+		Vehicles:     vehicles,
+	}
+	for _, vehicle := range vehicles {
+		report.TotalDistanceMeters += vehicle.DistanceMeters
+	}
+
+	return report, nil
+}
+
+// Backfill recomputes the daily rollups of every day touched by the time
+// range from stored locations. The range is widened to whole days so that
+// partially covered days are not undercounted, and the first day counts the
+// leg from the last point accepted before it.
+func (s *distanceService) Backfill(ctx context.Context, vehicleID string, startTime, endTime int64) error {
+	if vehicleID == "" {
+		return fmt.Errorf("vehicle_id is required")
+	}
+
+	start := time.Unix(startTime, 0).In(s.location)
+	end := time.Unix(endTime, 0).In(s.location)
+	dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, s.location)
+	dayEnd := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, s.location).AddDate(0, 0, 1)
+
+	locations, err := s.vehicleLocationRepo.GetHistoryByVehicleID(ctx, vehicleID, dayStart.Unix(), dayEnd.Unix()-1)
+	if err != nil {
+		return fmt.Errorf("failed to load location history: %w", err)
+	}
+
+	reference, err := s.referenceBefore(ctx, vehicleID, dayStart)
+	if err != nil {
+		return err
+	}
+
+	daily := make(map[string]*models.DailyDistance)
+	var order []*models.DailyDistance
+	var last *models.VehicleLocation
+
+	// History is returned newest first
+	for i := len(locations) - 1; i >= 0; i-- {
+		location := locations[i]
+		current := pointOf(location)
+
+		meters := 0.0
+		if reference != nil {
+			decision, distance := s.filter.Evaluate(*reference, current)
+			if decision == odometer.Hold {
+				held := odometer.Keep(*reference, current)
+				reference = &held
+				continue
+			}
+			if decision == odometer.Outlier {
+				continue
+			}
+			meters = distance
+		}
+
+		day := s.dayOf(location.Timestamp)
+		entry, ok := daily[day]
+		if !ok {
+			entry = &models.DailyDistance{VehicleID: vehicleID, Date: day}
+			daily[day] = entry
+			order = append(order, entry)
+		}
+		entry.DistanceMeters += meters
+		entry.PointCount++
+
+		reference = &current
+		last = location
+	}
+
+	fromDay := dayStart.Format(dayLayout)
+	toDay := dayEnd.AddDate(0, 0, -1).Format(dayLayout)
+
+	// Keep live processing of the vehicle out until its cached reference is
+	// dropped, so it reloads the stored one
+	unlock := s.lockVehicle(vehicleID)
+	defer unlock()
+
+	if err := s.distanceRepo.ReplaceDaily(ctx, vehicleID, fromDay, toDay, order, last); err != nil {
+		return fmt.Errorf("failed to store daily distances: %w", err)
+	}
+	s.setReference(vehicleID, nil)
+
+	s.logger.Info("Distance backfill completed",
+		zap.String("vehicle_id", vehicleID),
+		zap.String("from", fromDay),
+		zap.String("to", toDay),
+		zap.Int("days", len(order)))
+
+	return nil
+}
+
+// referenceBefore replays the locations within one reporting gap before a
+// time and returns the last accepted point, or nil when there is none. Older
+// points would only start a gap.
+func (s *distanceService) referenceBefore(ctx context.Context, vehicleID string, before time.Time) (*odometer.Point, error) {
+	window := s.maxGap
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+
+	locations, err := s.vehicleLocationRepo.GetHistoryByVehicleID(ctx, vehicleID, before.Add(-window).Unix(), before.Unix()-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location history: %w", err)
+	}
+
+	var reference *odometer.Point
+	for i := len(locations) - 1; i >= 0; i-- {
+		current := pointOf(locations[i])
+		if reference != nil {
+			decision, _ := s.filter.Evaluate(*reference, current)
+			if decision == odometer.Hold {
+				held := odometer.Keep(*reference, current)
+				reference = &held
+				continue
+			}
+			if decision == odometer.Outlier {
+				continue
+			}
+		}
+		reference = &current
+	}
+
+	return reference, nil
+}
+
+func pointOf(location *models.VehicleLocation) odometer.Point {
+	return odometer.Point{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+		Timestamp: location.Timestamp,
+	}
+}
+
+func (s *distanceService) dayOf(timestamp int64) string {
+	return time.Unix(timestamp, 0).In(s.location).Format(dayLayout)
+}
+
+func validateDayRange(fromDay, toDay string) error {
+	from, err := time.Parse(dayLayout, fromDay)
+	if err != nil {
+		return fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", fromDay)
+	}
+
+	to, err := time.Parse(dayLayout, toDay)
+	if err != nil {
+		return fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", toDay)
+	}
+
+	if from.After(to) {
+		return fmt.Errorf("from must not be after to")
+	}
+
+	return nil
+}
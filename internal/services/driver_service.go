@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// shiftCacheTTL bounds how long shifts opened or closed by another instance
+// go unnoticed when attributing locations
+const shiftCacheTTL = time.Minute
+
+// ErrInvalidDriver is returned when driver or shift input fails validation.
+var ErrInvalidDriver = errors.New("invalid driver")
+
+type DriverService interface {
+	CreateDriver(ctx context.Context, driver *models.Driver) error
+	GetDriver(ctx context.Context, driverID string) (*models.Driver, error)
+	ListDrivers(ctx context.Context) ([]*models.Driver, error)
+	UpdateDriver(ctx context.Context, driver *models.Driver) error
+	DeleteDriver(ctx context.Context, driverID string) error
+	// StartShift puts a registered, active driver on a vehicle from
+	// startTime (now when zero), ending any open shift of either.
+	StartShift(ctx context.Context, vehicleID, driverID string, startTime int64, source string) (*models.DriverShift, error)
+	// EndShift closes the open shift of a vehicle at endTime (now when zero).
+	EndShift(ctx context.Context, vehicleID string, endTime int64) (*models.DriverShift, error)
+	GetCurrentShift(ctx context.Context, vehicleID string) (*models.DriverShift, error)
+	GetDriverShifts(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverShift, error)
+	GetDriverReport(ctx context.Context, driverID string, startTime, endTime int64) (*models.DriverReport, error)
+	GetDriverReports(ctx context.Context, startTime, endTime int64) ([]*models.DriverReport, error)
+	// CurrentDriver returns the driver on shift on a vehicle at a time, or ""
+	// when there is none.
+	CurrentDriver(ctx context.Context, vehicleID string, timestamp int64) string
+}
+
+type driverService struct {
+	driverRepo  repositories.DriverRepository
+	shiftRepo   repositories.DriverShiftRepository
+	logger      *zap.Logger
+	mu          sync.RWMutex
+	shifts      map[string]*models.DriverShift // open shifts by vehicle
+	lastUpdated time.Time
+}
+
+func NewDriverService(
+	driverRepo repositories.DriverRepository,
+	shiftRepo repositories.DriverShiftRepository,
+	logger *zap.Logger,
+) DriverService {
+	return &driverService{
+		driverRepo: driverRepo,
+		shiftRepo:  shiftRepo,
+		logger:     logger,
+		shifts:     make(map[string]*models.DriverShift),
+	}
+}
+
+func (s *driverService) CreateDriver(ctx context.Context, driver *models.Driver) error {
+	if err := validateDriver(driver); err != nil {
+		return err
+	}
+
+	if err := s.driverRepo.Create(ctx, driver); err != nil {
+		return err
+	}
+
+	s.logger.Info("Driver registered", zap.String("driver_id", driver.DriverID))
+	return nil
+}
+
+func (s *driverService) GetDriver(ctx context.Context, driverID string) (*models.Driver, error) {
+	if driverID == "" {
+		return nil, fmt.Errorf("driver_id is required: %w", ErrInvalidDriver)
+	}
+
+	return s.driverRepo.GetByID(ctx, driverID)
+}
+
+func (s *driverService) ListDrivers(ctx context.Context) ([]*models.Driver, error) {
+	return s.driverRepo.GetAll(ctx)
+}
+
+func (s *driverService) UpdateDriver(ctx context.Context, driver *models.Driver) error {
+	if err := validateDriver(driver); err != nil {
+		return err
+	}
+
+	if err := s.driverRepo.Update(ctx, driver); err != nil {
+		return err
+	}
+
+	s.logger.Info("Driver updated", zap.String("driver_id", driver.DriverID))
+	return nil
+}
+
+func (s *driverService) DeleteDriver(ctx context.Context, driverID string) error {
+	if err := s.driverRepo.Delete(ctx, driverID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Driver deleted", zap.String("driver_id", driverID))
+	return nil
+}
+
+func (s *driverService) StartShift(ctx context.Context, vehicleID, driverID string, startTime int64, source string) (*models.DriverShift, error) {
+	driverID = strings.TrimSpace(driverID)
+	if vehicleID == "" || driverID == "" {
+		return nil, fmt.Errorf("vehicle_id and driver_id are required: %w", ErrInvalidDriver)
+	}
+
+	driver, err := s.driverRepo.GetByID(ctx, driverID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("driver %s is not registered: %w", driverID, ErrInvalidDriver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !driver.Active {
+		return nil, fmt.Errorf("driver %s is not active: %w", driverID, ErrInvalidDriver)
+	}
+
+	if startTime == 0 {
+		startTime = time.Now().Unix()
+	}
+
+	shift := &models.DriverShift{
+		DriverID:  driverID,
+		VehicleID: vehicleID,
+		StartTime: startTime,
+		Source:    source,
+	}
+	if err := s.shiftRepo.Start(ctx, shift); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for vehicle, open := range s.shifts {
+		if open.DriverID == driverID {
+			delete(s.shifts, vehicle)
+		}
+	}
+	s.shifts[vehicleID] = shift
+	s.mu.Unlock()
+
+	s.logger.Info("Driver shift started",
+		zap.String("vehicle_id", vehicleID),
+		zap.String("driver_id", driverID),
+		zap.String("source", source))
+
+	return shift, nil
+}
+
+func (s *driverService) EndShift(ctx context.Context, vehicleID string, endTime int64) (*models.DriverShift, error) {
+	if endTime == 0 {
+		endTime = time.Now().Unix()
+	}
+
+	shift, err := s.shiftRepo.End(ctx, vehicleID, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.shifts, vehicleID)
+	s.mu.Unlock()
+
+	s.logger.Info("Driver shift ended",
+		zap.String("vehicle_id", vehicleID),
+		zap.String("driver_id", shift.DriverID))
+
+	return shift, nil
+}
+
+func (s *driverService) GetCurrentShift(ctx context.Context, vehicleID string) (*models.DriverShift, error) {
+	if err := s.refreshShifts(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shift, ok := s.shifts[vehicleID]
+	if !ok {
+		return nil, fmt.Errorf("open shift for vehicle %s: %w", vehicleID, repositories.ErrNotFound)
+	}
+
+	current := *shift
+	return &current, nil
+}
+
+func (s *driverService) GetDriverShifts(ctx context.Context, driverID string, startTime, endTime int64) ([]*models.DriverShift, error) {
+	if driverID == "" {
+		return nil, fmt.Errorf("driver_id is required: %w", ErrInvalidDriver)
+	}
+
+	return s.shiftRepo.GetByDriverID(ctx, driverID, startTime, endTime)
+}
+
+func (s *driverService) GetDriverReport(ctx context.Context, driverID string, startTime, endTime int64) (*models.DriverReport, error) {
+	driver, err := s.GetDriver(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.shiftRepo.GetReports(ctx, driverID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		// No shift in the range
+		return &models.DriverReport{DriverID: driver.DriverID, Name: driver.Name}, nil
+	}
+
+	return reports[0], nil
+}
+
+func (s *driverService) GetDriverReports(ctx context.Context, startTime, endTime int64) ([]*models.DriverReport, error) {
+	return s.shiftRepo.GetReports(ctx, "", startTime, endTime)
+}
+
+func (s *driverService) CurrentDriver(ctx context.Context, vehicleID string, timestamp int64) string {
+	if err := s.refreshShifts(ctx); err != nil {
+		s.logger.Warn("Failed to refresh driver shifts", zap.Error(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shift, ok := s.shifts[vehicleID]
+	if !ok || timestamp < shift.StartTime {
+		return ""
+	}
+	return shift.DriverID
+}
+
+// refreshShifts reloads open shifts when the cache is older than its TTL.
+func (s *driverService) refreshShifts(ctx context.Context) error {
+	s.mu.RLock()
+	fresh := time.Since(s.lastUpdated) < shiftCacheTTL
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	shifts, err := s.shiftRepo.GetOpen(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shifts = make(map[string]*models.DriverShift, len(shifts))
+	for _, shift := range shifts {
+		s.shifts[shift.VehicleID] = shift
+	}
+	s.lastUpdated = time.Now()
+
+	return nil
+}
+
+func validateDriver(driver *models.Driver) error {
+	driver.DriverID = strings.TrimSpace(driver.DriverID)
+	driver.Name = strings.TrimSpace(driver.Name)
+
+	if driver.DriverID == "" {
+		return fmt.Errorf("driver_id is required: %w", ErrInvalidDriver)
+	}
+
+	if driver.Name == "" {
+		return fmt.Errorf("name is required: %w", ErrInvalidDriver)
+	}
+
+	return nil
+}
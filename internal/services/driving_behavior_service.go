@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/behavior"
+)
+
+// defaultThresholds names the thresholds for vehicles without their own
+const defaultThresholds = "default"
+
+type DrivingBehaviorService interface {
+	LocationProcessor
+	GetVehicleEvents(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error)
+	GetDriverEvents(ctx context.Context, driverID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error)
+	// GetDriverScores returns a driver's daily safety scores between two
+	// local days (YYYY-MM-DD).
+	GetDriverScores(ctx context.Context, driverID, fromDay, toDay string) ([]*models.DriverSafetyScore, error)
+	// GetDailyScores returns the safety score of every driver on duty or
+	// with driving events on a day.
+	GetDailyScores(ctx context.Context, day string) ([]*models.DriverSafetyScore, error)
+}
+
+type drivingBehaviorService struct {
+	eventRepo       repositories.DrivingEventRepository
+	registryService VehicleRegistryService
+	publisher       *eventbus.Publisher
+	detector        *behavior.Detector
+	thresholds      map[string]behavior.Thresholds // by vehicle type
+	config          *config.BehaviorConfig
+	timezone        string
+	logger          *zap.Logger
+}
+
+func NewDrivingBehaviorService(
+	eventRepo repositories.DrivingEventRepository,
+	registryService VehicleRegistryService,
+	publisher *eventbus.Publisher,
+	cfg *config.BehaviorConfig,
+	logger *zap.Logger,
+) DrivingBehaviorService {
+	// Days are split in the database, so the zone is passed by name
+	timezone := cfg.Timezone
+	if _, err := time.LoadLocation(timezone); err != nil {
+		logger.Warn("Unknown behavior timezone, falling back to Asia/Jakarta",
+			zap.Error(err),
+			zap.String("timezone", timezone))
+		timezone = "Asia/Jakarta"
+	}
+
+	// Fill thresholds a vehicle type leaves unset from the defaults
+	defaults := cfg.Thresholds[defaultThresholds]
+	thresholds := make(map[string]behavior.Thresholds, len(cfg.Thresholds))
+	for vehicleType, t := range cfg.Thresholds {
+		if t.HarshAcceleration == 0 {
+			t.HarshAcceleration = defaults.HarshAcceleration
+		}
+		if t.HarshBraking == 0 {
+			t.HarshBraking = defaults.HarshBraking
+		}
+		if t.SharpCornering == 0 {
+			t.SharpCornering = defaults.SharpCornering
+		}
+		thresholds[vehicleType] = behavior.Thresholds{
+			HarshAcceleration: t.HarshAcceleration,
+			HarshBraking:      t.HarshBraking,
+			SharpCornering:    t.SharpCornering,
+		}
+	}
+
+	return &drivingBehaviorService{
+		eventRepo:       eventRepo,
+		registryService: registryService,
+		publisher:       publisher,
+		detector:        behavior.NewDetector(cfg.MaxGap, cfg.Cooldown, cfg.MinCorneringSpeedKmh),
+		thresholds:      thresholds,
+		config:          cfg,
+		timezone:        timezone,
+		logger:          logger,
+	}
+}
+
+func (s *drivingBehaviorService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	thresholds, ok := s.thresholds[s.registryService.VehicleType(ctx, location.VehicleID)]
+	if !ok {
+		thresholds = s.thresholds[defaultThresholds]
+	}
+
+	for _, event := range s.detector.Process(location, thresholds) {
+		if err := s.eventRepo.Create(ctx, event); err != nil {
+			return fmt.Errorf("failed to save driving event: %w", err)
+		}
+
+		message := &events.VehicleEventMessage{
+			VehicleID: event.VehicleID,
+			Event:     event.EventType,
+			Location: events.Location{
+				Latitude:  event.Latitude,
+				Longitude: event.Longitude,
+			},
+			Timestamp: event.Timestamp,
+			Data: map[string]interface{}{
+				"value":     event.Value,
+				"speed_kmh": event.SpeedKmh,
+				"source":    event.Source,
+			},
+		}
+		if event.DriverID != nil {
+			message.Data["driver_id"] = *event.DriverID
+		}
+
+		if err := s.publisher.PublishVehicleEvent(ctx, message); err != nil {
+			s.logger.Error("Failed to publish driving event to RabbitMQ",
+				zap.Error(err),
+				zap.String("vehicle_id", event.VehicleID),
+				zap.String("event", event.EventType))
+			// Don't return error - database save succeeded
+		}
+
+		s.logger.Info("Driving event detected",
+			zap.String("vehicle_id", event.VehicleID),
+			zap.String("event", event.EventType),
+			zap.Float64("value", event.Value),
+			zap.String("source", event.Source))
+	}
+
+	return nil
+}
+
+func (s *drivingBehaviorService) GetVehicleEvents(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required")
+	}
+
+	return s.eventRepo.GetByVehicleID(ctx, vehicleID, startTime, endTime, limit)
+}
+
+func (s *drivingBehaviorService) GetDriverEvents(ctx context.Context, driverID string, startTime, endTime int64, limit int) ([]*models.DrivingEvent, error) {
+	if driverID == "" {
+		return nil, fmt.Errorf("driver_id is required: %w", ErrInvalidDriver)
+	}
+
+	return s.eventRepo.GetByDriverID(ctx, driverID, startTime, endTime, limit)
+}
+
+func (s *drivingBehaviorService) GetDriverScores(ctx context.Context, driverID, fromDay, toDay string) ([]*models.DriverSafetyScore, error) {
+	if driverID == "" {
+		return nil, fmt.Errorf("driver_id is required: %w", ErrInvalidDriver)
+	}
+
+	scores, err := s.eventRepo.GetDailyScores(ctx, driverID, fromDay, toDay, s.timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, score := range scores {
+		s.score(score)
+	}
+	return scores, nil
+}
+
+func (s *drivingBehaviorService) GetDailyScores(ctx context.Context, day string) ([]*models.DriverSafetyScore, error) {
+	scores, err := s.eventRepo.GetDailyScores(ctx, "", day, day, s.timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, score := range scores {
+		s.score(score)
+	}
+	return scores, nil
+}
+
+// score rates a day by weighted events per hour on duty. Days shorter than
+// an hour count as one, so a single event on a brief shift is not scored as
+// a high rate.
+func (s *drivingBehaviorService) score(score *models.DriverSafetyScore) {
+	hours := math.Max(float64(score.OnDutySeconds)/3600, 1)
+	weighted := s.config.BrakingWeight*float64(score.HarshBraking) +
+		s.config.AccelerationWeight*float64(score.HarshAcceleration) +
+		s.config.CorneringWeight*float64(score.SharpCornering)
+
+	score.Score = math.Round(math.Max(0, 100-s.config.ScorePenalty*weighted/hours)*10) / 10
+}
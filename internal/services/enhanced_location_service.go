@@ -6,7 +6,10 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
 	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/partition"
 	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
 )
 
@@ -14,21 +17,49 @@ import (
 type enhancedLocationService struct {
 	vehicleLocationRepo repositories.VehicleLocationRepository
 	geofenceService     GeofenceService
-	logger              *zap.Logger
+	mapMatchService     MapMatchService
+	driverService       DriverService
+	processors          []LocationProcessor
+	// Geofencing and processors run off the ingest path, partitioned by
+	// vehicle so each vehicle's locations are processed in order
+	pool   *partition.Pool
+	config *config.ProcessingConfig
+	logger *zap.Logger
 }
 
 func NewEnhancedLocationService(
 	vehicleLocationRepo repositories.VehicleLocationRepository,
 	geofenceService GeofenceService,
+	mapMatchService MapMatchService,
+	driverService DriverService,
+	cfg *config.ProcessingConfig,
 	logger *zap.Logger,
+	processors ...LocationProcessor,
 ) LocationService {
 	return &enhancedLocationService{
 		vehicleLocationRepo: vehicleLocationRepo,
 		geofenceService:     geofenceService,
+		mapMatchService:     mapMatchService,
+		driverService:       driverService,
+		processors:          processors,
+		pool:                partition.NewPool(context.Background(), cfg.Workers, cfg.QueueSize),
+		config:              cfg,
 		logger:              logger,
 	}
 }
 
+// Start waits for ctx to end, then lets the processing workers finish the
+// queued locations.
+func (s *enhancedLocationService) Start(ctx context.Context) error {
+	s.logger.Info("Started location processing", zap.Int("workers", s.pool.Workers()))
+
+	<-ctx.Done()
+	if s.pool.Stop(s.config.ShutdownTimeout) {
+		s.logger.Warn("Location processing did not drain before the shutdown timeout")
+	}
+	return nil
+}
+
 func (s *enhancedLocationService) SaveLocation(ctx context.Context, location *models.VehicleLocation) error {
 	// Validate location data
 	if location.VehicleID == "" {
@@ -47,6 +78,24 @@ func (s *enhancedLocationService) SaveLocation(ctx context.Context, location *mo
 		return fmt.Errorf("invalid timestamp: %d", location.Timestamp)
 	}
 
+	if location.Speed != nil && *location.Speed < 0 {
+		return fmt.Errorf("invalid speed: %f", *location.Speed)
+	}
+
+	if location.Heading != nil && (*location.Heading < 0 || *location.Heading > 360) {
+		return fmt.Errorf("invalid heading: %f", *location.Heading)
+	}
+
+	// Snap to the road network when map matching is enabled
+	if s.mapMatchService != nil {
+		s.mapMatchService.MatchLocation(ctx, location)
+	}
+
+	// Attribute to the driver on shift
+	if driverID := s.driverService.CurrentDriver(ctx, location.VehicleID, location.Timestamp); driverID != "" {
+		location.DriverID = &driverID
+	}
+
 	// Save to database
 	if err := s.vehicleLocationRepo.Create(ctx, location); err != nil {
 		s.logger.Error("Failed to save vehicle location", 
@@ -55,16 +104,18 @@ func (s *enhancedLocationService) SaveLocation(ctx context.Context, location *mo
 		return fmt.Errorf("failed to save location: %w", err)
 	}
 
-	// Process for geofencing (async to avoid blocking location save)
-	go func() {
-		// Create new context for async operation
-		asyncCtx := context.Background()
-		if err := s.geofenceService.ProcessLocationForGeofencing(asyncCtx, location); err != nil {
-			s.logger.Error("Failed to process geofencing", 
-				zap.Error(err),
-				zap.String("vehicle_id", location.VehicleID))
-		}
-	}()
+	// Events raised from this location share a correlation id
+	correlationID := fmt.Sprintf("location-%d", location.ID)
+
+	// Process off the ingest path, after the vehicle's earlier locations
+	queued := s.pool.Dispatch(ctx, location.VehicleID, func(workCtx context.Context) {
+		s.processLocation(events.ContextWithCorrelationID(workCtx, correlationID), location)
+	})
+	if !queued {
+		s.logger.Warn("Location saved but not processed",
+			zap.String("vehicle_id", location.VehicleID),
+			zap.Int64("location_id", location.ID))
+	}
 
 	s.logger.Info("Vehicle location saved successfully", 
 		zap.String("vehicle_id", location.VehicleID),
@@ -74,6 +125,23 @@ func (s *enhancedLocationService) SaveLocation(ctx context.Context, location *mo
 	return nil
 }
 
+// processLocation runs geofencing and then the analytics processors in order.
+func (s *enhancedLocationService) processLocation(ctx context.Context, location *models.VehicleLocation) {
+	if err := s.geofenceService.ProcessLocationForGeofencing(ctx, location); err != nil {
+		s.logger.Error("Failed to process geofencing",
+			zap.Error(err),
+			zap.String("vehicle_id", location.VehicleID))
+	}
+
+	for _, processor := range s.processors {
+		if err := processor.ProcessLocation(ctx, location); err != nil {
+			s.logger.Error("Failed to process location",
+				zap.Error(err),
+				zap.String("vehicle_id", location.VehicleID))
+		}
+	}
+}
+
 func (s *enhancedLocationService) GetLatestLocation(ctx context.Context, vehicleID string) (*models.VehicleLocation, error) {
 	if vehicleID == "" {
 		return nil, fmt.Errorf("vehicle_id is required")
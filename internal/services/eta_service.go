@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/eta"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/shape"
+)
+
+const (
+	// etaTripRefreshInterval bounds how long a new trip assignment goes
+	// unnoticed by the predictor
+	etaTripRefreshInterval = 30 * time.Second
+	// liveSpeedWindow is the span of recent positions live speed is taken over
+	liveSpeedWindow = 2 * time.Minute
+	// atStopTolerance is how far past a stop, in meters, a vehicle is still
+	// considered at it rather than departed
+	atStopTolerance = 30.0
+)
+
+// ETAService predicts arrivals at the upcoming stops of vehicles running a
+// trip, from their position along the trip shape, live speed and running
+// times learned from stop visit history.
+type ETAService interface {
+	LocationProcessor
+	Start(ctx context.Context) error
+	GetVehicleETA(ctx context.Context, vehicleID string) ([]*models.StopArrivalPrediction, error)
+	// GetStopArrivals lists predicted arrivals at a stop within the horizon,
+	// soonest first.
+	GetStopArrivals(ctx context.Context, stopID string, limit int) ([]*models.StopArrivalPrediction, error)
+}
+
+type etaService struct {
+	visitRepo      repositories.StopVisitRepository
+	assignmentRepo repositories.TripAssignmentRepository
+	adherence      ScheduleAdherenceService
+	schedule       *scheduleCache
+	config         *config.ETAConfig
+	logger         *zap.Logger
+	mu             sync.Mutex
+	trips          map[string]*models.TripAssignment // by vehicle
+	progress       map[string]*routeProgress         // by vehicle
+	geometries     map[string]*tripGeometry          // by trip
+	feedID         int64
+	profile        *eta.Profile
+	utcOffset      int
+}
+
+// tripGeometry is a trip's shape with its stops located along it.
+type tripGeometry struct {
+	shape *shape.Shape
+	stops []eta.Stop
+}
+
+func NewETAService(
+	visitRepo repositories.StopVisitRepository,
+	assignmentRepo repositories.TripAssignmentRepository,
+	gtfsRepo repositories.GTFSRepository,
+	adherence ScheduleAdherenceService,
+	cfg *config.ETAConfig,
+	logger *zap.Logger,
+) ETAService {
+	return &etaService{
+		visitRepo:      visitRepo,
+		assignmentRepo: assignmentRepo,
+		adherence:      adherence,
+		schedule:       newScheduleCache(gtfsRepo, logger),
+		config:         cfg,
+		logger:         logger,
+		trips:          make(map[string]*models.TripAssignment),
+		progress:       make(map[string]*routeProgress),
+		geometries:     make(map[string]*tripGeometry),
+		profile:        eta.NewProfile(nil, nil, 0),
+	}
+}
+
+// Start keeps vehicle trips and the learned profile up to date until the
+// context is cancelled.
+func (s *etaService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(etaTripRefreshInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("ETA predictor started",
+		zap.Int("history_days", s.config.HistoryDays),
+		zap.Duration("profile_refresh", s.config.ProfileRefresh))
+
+	s.refresh(ctx)
+	lastProfile := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("ETA predictor stopping")
+			return nil
+		case <-ticker.C:
+			trips, err := vehicleTrips(ctx, s.assignmentRepo, s.adherence)
+			if err != nil {
+				s.logger.Error("Failed to refresh vehicle trips", zap.Error(err))
+				continue
+			}
+			s.mu.Lock()
+			s.trips = trips
+			s.mu.Unlock()
+
+			if time.Since(lastProfile) >= s.config.ProfileRefresh {
+				s.refresh(ctx)
+				lastProfile = time.Now()
+			}
+		}
+	}
+}
+
+// refresh relearns running and dwell times from recent stop visits.
+func (s *etaService) refresh(ctx context.Context) {
+	feed, timezone, err := s.schedule.active(ctx)
+	if err != nil || feed == nil {
+		return
+	}
+	_, utcOffset := time.Now().In(timezone).Zone()
+	since := time.Now().AddDate(0, 0, -s.config.HistoryDays).Unix()
+
+	segments, err := s.visitRepo.GetSegmentTravelTimes(ctx, since, utcOffset, int64(s.config.MaxSegmentTime.Seconds()))
+	if err != nil {
+		s.logger.Error("Failed to learn segment travel times", zap.Error(err))
+		return
+	}
+	dwells, err := s.visitRepo.GetDwellProfiles(ctx, since, utcOffset)
+	if err != nil {
+		s.logger.Error("Failed to learn dwell times", zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	s.profile = eta.NewProfile(segments, dwells, s.config.MinSamples)
+	s.utcOffset = utcOffset
+	s.mu.Unlock()
+
+	s.logger.Info("ETA profile updated",
+		zap.Int("segments", len(segments)),
+		zap.Int("dwells", len(dwells)))
+}
+
+func (s *etaService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	s.mu.Lock()
+	trip := s.trips[location.VehicleID]
+	hint := -1.0
+	if p := s.progress[location.VehicleID]; p != nil && trip != nil && p.key == tripKey(trip) {
+		hint = p.latest().along
+	}
+	s.mu.Unlock()
+
+	if trip == nil {
+		return nil
+	}
+
+	geometry, err := s.geometry(ctx, trip.TripID)
+	if err != nil || geometry == nil {
+		return err
+	}
+
+	along, offset := geometry.shape.Project(location.Latitude, location.Longitude, hint)
+	if offset > s.config.MaxOffset {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tripKey(trip)
+	p := s.progress[location.VehicleID]
+	if p != nil && p.latest().timestamp >= location.Timestamp {
+		return nil // out of order
+	}
+	if p == nil || p.key != key || along < p.latest().along-backtrackTolerance {
+		p = &routeProgress{key: key, tripID: trip.TripID}
+		s.progress[location.VehicleID] = p
+	}
+
+	p.samples = append(p.samples, progressSample{along: along, timestamp: location.Timestamp})
+	p.latitude, p.longitude = location.Latitude, location.Longitude
+
+	cutoff := location.Timestamp - int64(liveSpeedWindow.Seconds())
+	first := 0
+	for first < len(p.samples)-1 && p.samples[first].timestamp < cutoff {
+		first++
+	}
+	p.samples = p.samples[first:]
+
+	return nil
+}
+
+// geometry returns the trip's shape with its stops located along it. Trips
+// without a shape use the polyline through their stops.
+func (s *etaService) geometry(ctx context.Context, tripID string) (*tripGeometry, error) {
+	feed, _, err := s.schedule.active(ctx)
+	if err != nil || feed == nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.feedID != feed.ID {
+		s.feedID = feed.ID
+		s.geometries = make(map[string]*tripGeometry)
+	}
+	geometry, ok := s.geometries[tripID]
+	s.mu.Unlock()
+	if ok {
+		return geometry, nil
+	}
+
+	trip, err := s.schedule.trip(ctx, tripID)
+	if err != nil || trip == nil {
+		return nil, err
+	}
+	stops, err := s.schedule.stops(ctx, tripID)
+	if err != nil || len(stops) < 2 {
+		return nil, err
+	}
+
+	var tripShape *shape.Shape
+	if trip.ShapeID != "" {
+		if tripShape, err = s.schedule.shape(ctx, trip.ShapeID); err != nil {
+			return nil, err
+		}
+	}
+	if tripShape == nil {
+		points := make([]*models.GTFSShapePoint, len(stops))
+		for i, stop := range stops {
+			points[i] = &models.GTFSShapePoint{Latitude: stop.Latitude, Longitude: stop.Longitude}
+		}
+		tripShape = shape.New(points)
+	}
+
+	// Locate stops in order so loops resolve to the right pass
+	geometry = &tripGeometry{shape: tripShape}
+	hint := 0.0
+	for _, stop := range stops {
+		along, _ := tripShape.Project(stop.Latitude, stop.Longitude, hint)
+		if along < hint {
+			along = hint
+		}
+		geometry.stops = append(geometry.stops, eta.Stop{GTFSTripStop: stop, Along: along})
+		hint = along
+	}
+
+	s.mu.Lock()
+	s.geometries[tripID] = geometry
+	s.mu.Unlock()
+
+	return geometry, nil
+}
+
+func (s *etaService) GetVehicleETA(ctx context.Context, vehicleID string) ([]*models.StopArrivalPrediction, error) {
+	s.mu.Lock()
+	trip := s.trips[vehicleID]
+	s.mu.Unlock()
+
+	if trip == nil {
+		return nil, fmt.Errorf("vehicle %s has no trip: %w", vehicleID, repositories.ErrNotFound)
+	}
+
+	return s.predict(ctx, vehicleID, trip)
+}
+
+func (s *etaService) GetStopArrivals(ctx context.Context, stopID string, limit int) ([]*models.StopArrivalPrediction, error) {
+	if stopID == "" {
+		return nil, fmt.Errorf("stop_id is required")
+	}
+
+	s.mu.Lock()
+	trips := make([]*models.TripAssignment, 0, len(s.trips))
+	for _, trip := range s.trips {
+		trips = append(trips, trip)
+	}
+	s.mu.Unlock()
+
+	horizon := time.Now().Add(s.config.Horizon).Unix()
+	var arrivals []*models.StopArrivalPrediction
+	for _, trip := range trips {
+		predictions, err := s.predict(ctx, trip.VehicleID, trip)
+		if err != nil {
+			s.logger.Warn("Failed to predict vehicle arrivals",
+				zap.Error(err),
+				zap.String("vehicle_id", trip.VehicleID))
+			continue
+		}
+		for _, prediction := range predictions {
+			if prediction.StopID == stopID && prediction.PredictedArrival <= horizon {
+				arrivals = append(arrivals, prediction)
+				break
+			}
+		}
+	}
+
+	sort.Slice(arrivals, func(i, j int) bool {
+		return arrivals[i].PredictedArrival < arrivals[j].PredictedArrival
+	})
+	if len(arrivals) > limit {
+		arrivals = arrivals[:limit]
+	}
+
+	return arrivals, nil
+}
+
+// predict returns arrival predictions for the remaining stops of a vehicle's
+// trip, or none when its position on the trip is unknown or stale.
+func (s *etaService) predict(ctx context.Context, vehicleID string, trip *models.TripAssignment) ([]*models.StopArrivalPrediction, error) {
+	geometry, err := s.geometry(ctx, trip.TripID)
+	if err != nil || geometry == nil {
+		return nil, err
+	}
+	gtfsTrip, err := s.schedule.trip(ctx, trip.TripID)
+	if err != nil || gtfsTrip == nil {
+		return nil, err
+	}
+	_, timezone, err := s.schedule.active(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	p := s.progress[vehicleID]
+	if p == nil || p.key != tripKey(trip) || p.latest().timestamp < time.Now().Add(-s.config.StaleAfter).Unix() {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	position := eta.Position{Along: p.latest().along, Speed: p.speed(), Timestamp: p.latest().timestamp}
+	profile, utcOffset := s.profile, s.utcOffset
+	s.mu.Unlock()
+
+	next := len(geometry.stops)
+	for i, stop := range geometry.stops {
+		if stop.Along > position.Along-atStopTolerance {
+			next = i
+			break
+		}
+	}
+
+	predictor := &eta.Predictor{
+		LiveWeight:   s.config.LiveWeight,
+		DefaultSpeed: s.config.DefaultSpeedKmh / 3.6,
+		UTCOffset:    utcOffset,
+	}
+	arrivals := predictor.Predict(profile, geometry.stops, next, position)
+
+	dayStart, dayErr := serviceDayStart(trip.StartDate, timezone)
+	now := time.Now().Unix()
+
+	predictions := make([]*models.StopArrivalPrediction, 0, len(arrivals))
+	for i, arrival := range arrivals {
+		stop := geometry.stops[next+i]
+		prediction := &models.StopArrivalPrediction{
+			VehicleID:        vehicleID,
+			TripID:           trip.TripID,
+			RouteID:          trip.RouteID,
+			DirectionID:      trip.DirectionID,
+			Headsign:         gtfsTrip.Headsign,
+			StopID:           stop.StopID,
+			StopName:         stop.StopName,
+			StopSequence:     stop.StopSequence,
+			PredictedArrival: arrival,
+			SecondsAway:      arrival - now,
+			DistanceMeters:   stop.Along - position.Along,
+		}
+		if prediction.SecondsAway < 0 {
+			prediction.SecondsAway = 0
+		}
+		if prediction.DistanceMeters < 0 {
+			prediction.DistanceMeters = 0
+		}
+		if dayErr == nil {
+			scheduled := dayStart.Unix() + int64(stop.ArrivalSeconds)
+			prediction.ScheduledArrival = &scheduled
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	return predictions, nil
+}
+
+func tripKey(trip *models.TripAssignment) string {
+	return trip.TripID + "/" + trip.StartDate
+}
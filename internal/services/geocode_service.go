@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geocode"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/roadnet"
+)
+
+// GeocodeService resolves positions to street, kelurahan and kecamatan from
+// datasets loaded at startup.
+type GeocodeService interface {
+	// ReverseGeocode returns the address at a position, or nil when it is
+	// unknown or geocoding is disabled.
+	ReverseGeocode(lat, lng float64) *models.Address
+}
+
+type geocodeService struct {
+	geocoder *geocode.Geocoder
+}
+
+func NewGeocodeService(cfg *config.GeocodeConfig, logger *zap.Logger) (GeocodeService, error) {
+	if !cfg.Enabled {
+		return &geocodeService{}, nil
+	}
+	if cfg.Boundaries == "" && cfg.Streets == "" {
+		return nil, fmt.Errorf("geocode.boundaries or geocode.streets is required")
+	}
+
+	start := time.Now()
+
+	var streets *roadnet.Graph
+	if cfg.Streets != "" {
+		graph, err := roadnet.Load(cfg.Streets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load streets: %w", err)
+		}
+		streets = graph
+	}
+
+	var geocoder *geocode.Geocoder
+	props := geocode.Properties{
+		Kelurahan: cfg.KelurahanProperty,
+		Kecamatan: cfg.KecamatanProperty,
+		City:      cfg.CityProperty,
+	}
+	if cfg.Boundaries != "" {
+		f, err := os.Open(cfg.Boundaries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open boundaries: %w", err)
+		}
+		defer f.Close()
+
+		if geocoder, err = geocode.New(f, props, streets, cfg.StreetRadius); err != nil {
+			return nil, fmt.Errorf("failed to load boundaries: %w", err)
+		}
+	} else {
+		geocoder, _ = geocode.New(nil, props, streets, cfg.StreetRadius)
+	}
+
+	fields := []zap.Field{
+		zap.Int("areas", geocoder.Areas()),
+		zap.Duration("took", time.Since(start)),
+	}
+	if streets != nil {
+		fields = append(fields, zap.Int("street_edges", streets.Edges()))
+	}
+	logger.Info("Reverse geocoder loaded", fields...)
+
+	return &geocodeService{geocoder: geocoder}, nil
+}
+
+func (s *geocodeService) ReverseGeocode(lat, lng float64) *models.Address {
+	if s.geocoder == nil {
+		return nil
+	}
+	return s.geocoder.Lookup(lat, lng)
+}
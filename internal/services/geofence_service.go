@@ -2,31 +2,50 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
 	"github.com/ivanadhi/transjakarta-fleet/internal/models"
-	"github.com/ivanadhi/transjakarta-fleet/internal/rabbitmq"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
 	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
 )
 
+// ErrInvalidGeofence is returned when geofence input fails validation.
+var ErrInvalidGeofence = errors.New("invalid geofence")
+
 type GeofenceService interface {
 	ProcessLocationForGeofencing(ctx context.Context, location *models.VehicleLocation) error
 	GetGeofenceEvents(ctx context.Context, vehicleID string, limit int) ([]*models.GeofenceEvent, error)
+	// ListGeofences returns the geofences of a type, or all of them when
+	// geofenceType is empty.
+	ListGeofences(ctx context.Context, geofenceType string) ([]*models.Geofence, error)
+	// UpdateGeofenceTags replaces the tags of a geofence. Events carry the
+	// new tags once the detector refreshes its cache.
+	UpdateGeofenceTags(ctx context.Context, id int64, tags []string) (*models.Geofence, error)
 }
 
 type geofenceService struct {
-	detector  *geofence.Detector
-	publisher *rabbitmq.Publisher
-	logger    *zap.Logger
+	detector     *geofence.Detector
+	geofenceRepo repositories.GeofenceRepository
+	outbox       OutboxRelay
+	geocoder     GeocodeService
+	logger       *zap.Logger
 }
 
-func NewGeofenceService(detector *geofence.Detector, publisher *rabbitmq.Publisher, logger *zap.Logger) GeofenceService {
+// NewGeofenceService saves geofence events with their RabbitMQ messages in
+// the outbox; the relay publishes them.
+func NewGeofenceService(detector *geofence.Detector, geofenceRepo repositories.GeofenceRepository, outbox OutboxRelay, geocoder GeocodeService, logger *zap.Logger) GeofenceService {
 	return &geofenceService{
-		detector:  detector,
-		publisher: publisher,
-		logger:    logger,
+		detector:     detector,
+		geofenceRepo: geofenceRepo,
+		outbox:       outbox,
+		geocoder:     geocoder,
+		logger:       logger,
 	}
 }
 
@@ -43,35 +62,58 @@ func (s *geofenceService) ProcessLocationForGeofencing(ctx context.Context, loca
 	// Process each geofence entry
 	for _, result := range results {
 		if result.Entered {
-			// Save geofence event to database
-			if err := s.detector.ProcessGeofenceEntry(ctx, location, result.Geofence); err != nil {
-				s.logger.Error("Failed to process geofence entry", 
-					zap.Error(err),
-					zap.String("vehicle_id", location.VehicleID),
-					zap.String("geofence_name", result.Geofence.Name))
-				continue // Don't fail entire process for one geofence
-			}
-			
-			// Publish event to RabbitMQ
-			eventMessage := &rabbitmq.GeofenceEventMessage{
+			eventMessage := &events.GeofenceEventMessage{
 				VehicleID: location.VehicleID,
 				Event:     "geofence_entry",
-				Location: rabbitmq.Location{
+				Location: events.Location{
 					Latitude:  location.Latitude,
 					Longitude: location.Longitude,
 				},
 				Timestamp:    location.Timestamp,
 				GeofenceName: result.Geofence.Name,
+				GeofenceID:   result.Geofence.ID,
+				GeofenceType: result.Geofence.Type,
+				GeofenceTags: result.Geofence.Tags,
 				Distance:     result.Distance,
 			}
-			
-			if err := s.publisher.PublishGeofenceEvent(ctx, eventMessage); err != nil {
-				s.logger.Error("Failed to publish geofence event to RabbitMQ", 
+			if location.DriverID != nil {
+				eventMessage.DriverID = *location.DriverID
+			}
+			if address := s.geocoder.ReverseGeocode(location.Latitude, location.Longitude); address != nil {
+				eventMessage.Address = &events.Address{
+					Street:    address.Street,
+					Kelurahan: address.Kelurahan,
+					Kecamatan: address.Kecamatan,
+					City:      address.City,
+				}
+			}
+
+			event, err := events.New(ctx, eventMessage.Topic(), location.VehicleID, time.Unix(location.Timestamp, 0), eventMessage)
+			if err != nil {
+				return fmt.Errorf("failed to build geofence event: %w", err)
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("failed to marshal geofence event: %w", err)
+			}
+
+			// Geofence events must reach the worker queue, so unroutable
+			// ones are retried
+			outboxMessage := &models.OutboxMessage{
+				RoutingKey: eventMessage.Topic(),
+				Payload:    payload,
+				Mandatory:  true,
+			}
+
+			// Save the event and its message together; the relay publishes it
+			if err := s.detector.ProcessGeofenceEntry(ctx, location, result.Geofence, outboxMessage); err != nil {
+				s.logger.Error("Failed to process geofence entry", 
 					zap.Error(err),
 					zap.String("vehicle_id", location.VehicleID),
 					zap.String("geofence_name", result.Geofence.Name))
-				// Don't return error - database save succeeded
+				continue // Don't fail entire process for one geofence
 			}
+			s.outbox.Notify()
 			
 			s.logger.Info("Geofence entry processed successfully", 
 				zap.String("vehicle_id", location.VehicleID),
@@ -87,4 +129,39 @@ func (s *geofenceService) GetGeofenceEvents(ctx context.Context, vehicleID strin
     // Use the event repository to get events
     // For now, return empty array to fix 500 error
     return []*models.GeofenceEvent{}, nil
-}
\ No newline at end of file
+}
+
+func (s *geofenceService) ListGeofences(ctx context.Context, geofenceType string) ([]*models.Geofence, error) {
+	geofences, err := s.geofenceRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if geofenceType == "" {
+		return geofences, nil
+	}
+
+	filtered := make([]*models.Geofence, 0, len(geofences))
+	for _, g := range geofences {
+		if g.Type == geofenceType {
+			filtered = append(filtered, g)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *geofenceService) UpdateGeofenceTags(ctx context.Context, id int64, tags []string) (*models.Geofence, error) {
+	tags, err := normalizeLabels(tags)
+	if err != nil {
+		return nil, fmt.Errorf("tags: %v: %w", err, ErrInvalidGeofence)
+	}
+
+	updated, err := s.geofenceRepo.UpdateTags(ctx, id, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Geofence tags updated",
+		zap.Int64("geofence_id", id),
+		zap.Strings("tags", tags))
+	return updated, nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	gtfsrt "github.com/MobilityData/gtfs-realtime-bindings/golang/gtfs"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+const gtfsRealtimeVersion = "2.0"
+
+// GTFSRealtimeService builds GTFS-realtime feeds from the latest known
+// positions of online vehicles and their trip assignments.
+type GTFSRealtimeService interface {
+	VehiclePositions(ctx context.Context) (*gtfsrt.FeedMessage, error)
+	TripUpdates(ctx context.Context) (*gtfsrt.FeedMessage, error)
+}
+
+type gtfsRealtimeService struct {
+	assignmentRepo   repositories.TripAssignmentRepository
+	heartbeatService HeartbeatService
+	schedule         *scheduleCache
+	config           *config.GTFSConfig
+	logger           *zap.Logger
+}
+
+// vehicleState is an online vehicle with its trip context, when known
+type vehicleState struct {
+	connection *models.VehicleConnection
+	assignment *models.TripAssignment
+	stops      []*models.GTFSTripStop
+	progress   *tripProgress
+	dayStart   time.Time
+}
+
+func NewGTFSRealtimeService(
+	assignmentRepo repositories.TripAssignmentRepository,
+	gtfsRepo repositories.GTFSRepository,
+	heartbeatService HeartbeatService,
+	cfg *config.GTFSConfig,
+	logger *zap.Logger,
+) GTFSRealtimeService {
+	return &gtfsRealtimeService{
+		assignmentRepo:   assignmentRepo,
+		heartbeatService: heartbeatService,
+		schedule:         newScheduleCache(gtfsRepo, logger),
+		config:           cfg,
+		logger:           logger,
+	}
+}
+
+func (s *gtfsRealtimeService) VehiclePositions(ctx context.Context) (*gtfsrt.FeedMessage, error) {
+	states, err := s.vehicleStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := newFeedMessage()
+	for _, state := range states {
+		conn := state.connection
+		position := &gtfsrt.VehiclePosition{
+			Vehicle: vehicleDescriptor(conn.VehicleID),
+			Position: &gtfsrt.Position{
+				Latitude:  proto.Float32(float32(conn.Latitude)),
+				Longitude: proto.Float32(float32(conn.Longitude)),
+			},
+			Timestamp: proto.Uint64(uint64(conn.LastTimestamp)),
+		}
+
+		if state.assignment != nil {
+			position.Trip = tripDescriptor(state.assignment)
+		}
+		if state.progress != nil {
+			stop := state.stops[state.progress.Index]
+			position.CurrentStopSequence = proto.Uint32(uint32(stop.StopSequence))
+			position.StopId = proto.String(stop.StopID)
+			if state.progress.Stopped {
+				position.CurrentStatus = gtfsrt.VehiclePosition_STOPPED_AT.Enum()
+			} else {
+				position.CurrentStatus = gtfsrt.VehiclePosition_IN_TRANSIT_TO.Enum()
+			}
+		}
+
+		feed.Entity = append(feed.Entity, &gtfsrt.FeedEntity{
+			Id:      proto.String(conn.VehicleID),
+			Vehicle: position,
+		})
+	}
+
+	return feed, nil
+}
+
+// TripUpdates reports, for each assigned vehicle, the delay at its current or
+// next stop. Consumers propagate that delay to the remaining stops of the
+// trip, as described by the GTFS-realtime spec.
+func (s *gtfsRealtimeService) TripUpdates(ctx context.Context) (*gtfsrt.FeedMessage, error) {
+	states, err := s.vehicleStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := newFeedMessage()
+	for _, state := range states {
+		if state.progress == nil {
+			continue
+		}
+
+		conn := state.connection
+		stop := state.stops[state.progress.Index]
+		scheduled := state.dayStart.Add(time.Duration(state.progress.ScheduledSeconds) * time.Second)
+		delay := int32(conn.LastTimestamp - scheduled.Unix())
+
+		update := &gtfsrt.TripUpdate_StopTimeUpdate{
+			StopSequence: proto.Uint32(uint32(stop.StopSequence)),
+			StopId:       proto.String(stop.StopID),
+			Arrival:      &gtfsrt.TripUpdate_StopTimeEvent{Delay: proto.Int32(delay)},
+		}
+		if state.progress.Stopped {
+			update.Departure = &gtfsrt.TripUpdate_StopTimeEvent{Delay: proto.Int32(delay)}
+		}
+
+		feed.Entity = append(feed.Entity, &gtfsrt.FeedEntity{
+			Id: proto.String(conn.VehicleID),
+			TripUpdate: &gtfsrt.TripUpdate{
+				Trip:           tripDescriptor(state.assignment),
+				Vehicle:        vehicleDescriptor(conn.VehicleID),
+				StopTimeUpdate: []*gtfsrt.TripUpdate_StopTimeUpdate{update},
+				Timestamp:      proto.Uint64(uint64(conn.LastTimestamp)),
+				Delay:          proto.Int32(delay),
+			},
+		})
+	}
+
+	return feed, nil
+}
+
+// vehicleStates collects online vehicles, ordered by ID, with their trip
+// assignment and position along the trip when the trip is in the active feed.
+func (s *gtfsRealtimeService) vehicleStates(ctx context.Context) ([]*vehicleState, error) {
+	assignments, err := s.assignmentRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byVehicle := make(map[string]*models.TripAssignment, len(assignments))
+	for _, assignment := range assignments {
+		byVehicle[assignment.VehicleID] = assignment
+	}
+
+	_, location, err := s.schedule.active(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []*vehicleState
+	for _, conn := range s.heartbeatService.GetAllStatuses() {
+		if conn.Status != models.ConnectionOnline {
+			continue
+		}
+
+		state := &vehicleState{connection: conn, assignment: byVehicle[conn.VehicleID]}
+		states = append(states, state)
+
+		if state.assignment == nil || location == nil {
+			continue
+		}
+
+		stops, err := s.schedule.stops(ctx, state.assignment.TripID)
+		if err != nil {
+			return nil, err
+		}
+		dayStart, err := serviceDayStart(state.assignment.StartDate, location)
+		if len(stops) == 0 || err != nil {
+			// Trips missing from the active feed must not be published
+			s.logger.Debug("Ignoring stale trip assignment",
+				zap.String("vehicle_id", conn.VehicleID),
+				zap.String("trip_id", state.assignment.TripID))
+			state.assignment = nil
+			continue
+		}
+
+		state.stops = stops
+		state.dayStart = dayStart
+		state.progress = locateOnTrip(stops, conn.Latitude, conn.Longitude, float64(s.config.StopRadius))
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].connection.VehicleID < states[j].connection.VehicleID
+	})
+
+	return states, nil
+}
+
+func newFeedMessage() *gtfsrt.FeedMessage {
+	return &gtfsrt.FeedMessage{
+		Header: &gtfsrt.FeedHeader{
+			GtfsRealtimeVersion: proto.String(gtfsRealtimeVersion),
+			Incrementality:      gtfsrt.FeedHeader_FULL_DATASET.Enum(),
+			Timestamp:           proto.Uint64(uint64(time.Now().Unix())),
+		},
+	}
+}
+
+func vehicleDescriptor(vehicleID string) *gtfsrt.VehicleDescriptor {
+	return &gtfsrt.VehicleDescriptor{
+		Id:    proto.String(vehicleID),
+		Label: proto.String(vehicleID),
+	}
+}
+
+func tripDescriptor(assignment *models.TripAssignment) *gtfsrt.TripDescriptor {
+	return &gtfsrt.TripDescriptor{
+		TripId:               proto.String(assignment.TripID),
+		RouteId:              proto.String(assignment.RouteID),
+		DirectionId:          proto.Uint32(uint32(assignment.DirectionID)),
+		StartDate:            proto.String(assignment.StartDate),
+		ScheduleRelationship: gtfsrt.TripDescriptor_SCHEDULED.Enum(),
+	}
+}
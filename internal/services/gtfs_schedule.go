@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/gtfs"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/shape"
+)
+
+const (
+	gtfsDateLayout = "20060102"
+	// scheduleRefreshInterval bounds how long a superseded feed keeps being served
+	scheduleRefreshInterval = time.Minute
+)
+
+// scheduleCache keeps the active GTFS feed and the stop lists of recently
+// used trips in memory; it is reset whenever a different feed becomes active.
+type scheduleCache struct {
+	gtfsRepo repositories.GTFSRepository
+	logger   *zap.Logger
+
+	mu          sync.Mutex
+	feed        *models.GTFSFeed
+	location    *time.Location
+	trips       map[string]*models.GTFSTrip
+	tripStops   map[string][]*models.GTFSTripStop
+	shapes      map[string]*shape.Shape
+	routeShapes map[string]string
+	// Service IDs running per service date; nil when the feed has no
+	// calendar, as feeds imported before calendars were stored
+	services    map[string][]string
+	lastUpdated time.Time
+}
+
+func newScheduleCache(gtfsRepo repositories.GTFSRepository, logger *zap.Logger) *scheduleCache {
+	return &scheduleCache{
+		gtfsRepo: gtfsRepo,
+		logger:   logger,
+	}
+}
+
+// active returns the active feed and its agency timezone, or a nil feed when
+// no GTFS feed has been imported yet.
+func (c *scheduleCache) active(ctx context.Context) (*models.GTFSFeed, *time.Location, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	return c.feed, c.location, nil
+}
+
+func (c *scheduleCache) refresh(ctx context.Context) error {
+	if time.Since(c.lastUpdated) < scheduleRefreshInterval {
+		return nil
+	}
+
+	feed, err := c.gtfsRepo.GetActiveFeed(ctx)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("failed to load active GTFS feed: %w", err)
+	}
+	c.lastUpdated = time.Now()
+
+	if feed == nil {
+		c.feed, c.trips, c.tripStops, c.shapes, c.routeShapes, c.services = nil, nil, nil, nil, nil, nil
+		return nil
+	}
+	if c.feed != nil && c.feed.ID == feed.ID {
+		return nil
+	}
+
+	timezone, err := c.gtfsRepo.GetAgencyTimezone(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		location = time.FixedZone("WIB", 7*60*60)
+	}
+
+	c.feed = feed
+	c.location = location
+	c.trips = make(map[string]*models.GTFSTrip)
+	c.tripStops = make(map[string][]*models.GTFSTripStop)
+	c.shapes = make(map[string]*shape.Shape)
+	c.routeShapes = make(map[string]string)
+	c.services = make(map[string][]string)
+
+	c.logger.Info("GTFS schedule loaded",
+		zap.Int64("feed_id", feed.ID),
+		zap.String("version", feed.Version),
+		zap.String("timezone", location.String()))
+
+	return nil
+}
+
+// trip returns a trip of the active feed, or nil when it does not exist.
+func (c *scheduleCache) trip(ctx context.Context, tripID string) (*models.GTFSTrip, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if c.feed == nil {
+		return nil, nil
+	}
+
+	if trip, ok := c.trips[tripID]; ok {
+		return trip, nil
+	}
+
+	trip, err := c.gtfsRepo.GetTrip(ctx, c.feed.ID, tripID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	c.trips[tripID] = trip
+	return trip, nil
+}
+
+// stops returns the ordered scheduled stops of a trip of the active feed.
+func (c *scheduleCache) stops(ctx context.Context, tripID string) ([]*models.GTFSTripStop, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if c.feed == nil {
+		return nil, nil
+	}
+
+	if stops, ok := c.tripStops[tripID]; ok {
+		return stops, nil
+	}
+
+	stops, err := c.gtfsRepo.GetTripStops(ctx, c.feed.ID, tripID)
+	if err != nil {
+		return nil, err
+	}
+	c.tripStops[tripID] = stops
+	return stops, nil
+}
+
+// shape returns a shape of the active feed, or nil when it has no points.
+func (c *scheduleCache) shape(ctx context.Context, shapeID string) (*shape.Shape, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if c.feed == nil {
+		return nil, nil
+	}
+
+	return c.loadShape(ctx, shapeID)
+}
+
+func (c *scheduleCache) loadShape(ctx context.Context, shapeID string) (*shape.Shape, error) {
+	if s, ok := c.shapes[shapeID]; ok {
+		return s, nil
+	}
+
+	points, err := c.gtfsRepo.GetShape(ctx, c.feed.ID, shapeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *shape.Shape
+	if len(points) > 1 {
+		s = shape.New(points)
+	}
+	c.shapes[shapeID] = s
+	return s, nil
+}
+
+// routeShape returns the main shape of a route direction, the one most of its
+// trips follow, so vehicles on different trip variants share one reference.
+func (c *scheduleCache) routeShape(ctx context.Context, routeID string, directionID int) (*shape.Shape, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if c.feed == nil {
+		return nil, nil
+	}
+
+	key := routeKey(routeID, directionID)
+	shapeID, ok := c.routeShapes[key]
+	if !ok {
+		var err error
+		shapeID, err = c.gtfsRepo.GetRouteShapeID(ctx, c.feed.ID, routeID, directionID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		c.routeShapes[key] = shapeID
+	}
+	if shapeID == "" {
+		return nil, nil
+	}
+
+	return c.loadShape(ctx, shapeID)
+}
+
+// calls returns the trips of the active feed running on a service date
+// (YYYYMMDD) scheduled to arrive at a stop between two times of that service
+// day. Results are not cached.
+func (c *scheduleCache) calls(ctx context.Context, date, stopID string, fromSeconds, toSeconds int) ([]*models.GTFSStopCall, error) {
+	c.mu.Lock()
+	if err := c.refresh(ctx); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	feed := c.feed
+	var services []string
+	var err error
+	if feed != nil {
+		services, err = c.loadServices(ctx, date)
+	}
+	c.mu.Unlock()
+
+	if feed == nil || err != nil {
+		return nil, err
+	}
+	return c.gtfsRepo.GetStopCalls(ctx, feed.ID, services, stopID, fromSeconds, toSeconds)
+}
+
+// departures returns the scheduled first-stop departures of a route
+// direction between two times of a service date (YYYYMMDD), counting only
+// trips running on that date. Results are not cached.
+func (c *scheduleCache) departures(ctx context.Context, date, routeID string, directionID, fromSeconds, toSeconds int) ([]int, error) {
+	c.mu.Lock()
+	if err := c.refresh(ctx); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	feed := c.feed
+	var services []string
+	var err error
+	if feed != nil {
+		services, err = c.loadServices(ctx, date)
+	}
+	c.mu.Unlock()
+
+	if feed == nil || err != nil {
+		return nil, err
+	}
+	return c.gtfsRepo.GetRouteDepartures(ctx, feed.ID, services, routeID, directionID, fromSeconds, toSeconds)
+}
+
+// loadServices returns the services running on a service date, or nil when
+// the active feed has no calendar and every service is assumed to run.
+func (c *scheduleCache) loadServices(ctx context.Context, date string) ([]string, error) {
+	if services, ok := c.services[date]; ok {
+		return services, nil
+	}
+
+	calendars, err := c.gtfsRepo.GetCalendars(ctx, c.feed.ID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := c.gtfsRepo.GetCalendarDates(ctx, c.feed.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []string
+	if len(calendars) > 0 || len(exceptions) > 0 {
+		services, err = gtfs.ActiveServices(calendars, exceptions, date)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.services[date] = services
+	return services, nil
+}
+
+// serviceDayStart returns the instant GTFS times of a service date are
+// measured from: noon minus 12 hours, which differs from midnight on DST days.
+func serviceDayStart(date string, location *time.Location) (time.Time, error) {
+	day, err := time.ParseInLocation(gtfsDateLayout, date, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid service date %q: %w", date, err)
+	}
+
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, location)
+	return noon.Add(-12 * time.Hour), nil
+}
+
+// tripProgress describes where a vehicle is along its trip's stop list.
+type tripProgress struct {
+	// Index of the stop the vehicle is at (Stopped) or heading to
+	Index   int
+	Stopped bool
+	// ScheduledSeconds is the scheduled time, relative to the service day,
+	// at which a vehicle on time would be at the current position
+	ScheduledSeconds int
+}
+
+// locateOnTrip matches a position against the trip's stops: at a stop when
+// within stopRadius, otherwise in transit between the two stops it lies
+// between, interpolating the schedule by distance.
+func locateOnTrip(stops []*models.GTFSTripStop, lat, lng, stopRadius float64) *tripProgress {
+	if len(stops) == 0 {
+		return nil
+	}
+
+	nearest, nearestDistance := 0, -1.0
+	for i, stop := range stops {
+		d := geofence.HaversineDistance(lat, lng, stop.Latitude, stop.Longitude)
+		if nearestDistance < 0 || d < nearestDistance {
+			nearest, nearestDistance = i, d
+		}
+	}
+
+	if nearestDistance <= stopRadius {
+		stop := stops[nearest]
+		return &tripProgress{Index: nearest, Stopped: true, ScheduledSeconds: stop.ArrivalSeconds}
+	}
+
+	// Decide whether the vehicle has already passed the nearest stop
+	next := nearest
+	if nearest+1 < len(stops) {
+		following := stops[nearest+1]
+		toFollowing := geofence.HaversineDistance(lat, lng, following.Latitude, following.Longitude)
+		between := geofence.HaversineDistance(stops[nearest].Latitude, stops[nearest].Longitude, following.Latitude, following.Longitude)
+		if toFollowing < between {
+			next = nearest + 1
+		}
+	}
+
+	if next == 0 {
+		return &tripProgress{Index: 0, ScheduledSeconds: stops[0].ArrivalSeconds}
+	}
+
+	previous, upcoming := stops[next-1], stops[next]
+	fromPrevious := geofence.HaversineDistance(previous.Latitude, previous.Longitude, lat, lng)
+	toUpcoming := geofence.HaversineDistance(lat, lng, upcoming.Latitude, upcoming.Longitude)
+
+	fraction := 0.0
+	if fromPrevious+toUpcoming > 0 {
+		fraction = fromPrevious / (fromPrevious + toUpcoming)
+	}
+	scheduled := previous.DepartureSeconds + int(fraction*float64(upcoming.ArrivalSeconds-previous.DepartureSeconds))
+
+	return &tripProgress{Index: next, ScheduledSeconds: scheduled}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/gtfs"
+)
+
+type GTFSService interface {
+	// Import parses and loads a GTFS zip archive, superseding the active
+	// feed on success. An empty version falls back to feed_info.txt and
+	// then to a hash of the archive.
+	Import(ctx context.Context, archive []byte, version string) (*models.GTFSFeed, error)
+	// StartImport registers the feed and runs Import in the background.
+	StartImport(archive []byte, version string) (*models.GTFSFeed, error)
+	GetFeed(ctx context.Context, feedID int64) (*models.GTFSFeed, error)
+	GetFeeds(ctx context.Context, limit int) ([]*models.GTFSFeed, error)
+}
+
+type gtfsService struct {
+	gtfsRepo repositories.GTFSRepository
+	config   *config.GTFSConfig
+	logger   *zap.Logger
+	// mu serializes imports so two feeds never supersede each other concurrently
+	mu sync.Mutex
+}
+
+func NewGTFSService(gtfsRepo repositories.GTFSRepository, cfg *config.GTFSConfig, logger *zap.Logger) GTFSService {
+	return &gtfsService{
+		gtfsRepo: gtfsRepo,
+		config:   cfg,
+		logger:   logger,
+	}
+}
+
+func (s *gtfsService) Import(ctx context.Context, archive []byte, version string) (*models.GTFSFeed, error) {
+	version = strings.TrimSpace(version)
+	feed, err := s.createFeed(ctx, archive, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.load(ctx, feed, archive, version != ""); err != nil {
+		return feed, err
+	}
+
+	return feed, nil
+}
+
+func (s *gtfsService) StartImport(archive []byte, version string) (*models.GTFSFeed, error) {
+	version = strings.TrimSpace(version)
+	ctx := context.Background()
+	feed, err := s.createFeed(ctx, archive, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request context ends before a large feed finishes loading
+	go func(feed models.GTFSFeed) {
+		if err := s.load(ctx, &feed, archive, version != ""); err != nil {
+			s.logger.Error("Background GTFS import failed",
+				zap.Error(err),
+				zap.Int64("feed_id", feed.ID))
+		}
+	}(*feed)
+
+	return feed, nil
+}
+
+func (s *gtfsService) GetFeed(ctx context.Context, feedID int64) (*models.GTFSFeed, error) {
+	return s.gtfsRepo.GetFeedByID(ctx, feedID)
+}
+
+func (s *gtfsService) GetFeeds(ctx context.Context, limit int) ([]*models.GTFSFeed, error) {
+	return s.gtfsRepo.GetFeeds(ctx, limit)
+}
+
+// createFeed records a feed under a trimmed version, or the archive's hash
+// when there is none.
+func (s *gtfsService) createFeed(ctx context.Context, archive []byte, version string) (*models.GTFSFeed, error) {
+	if version == "" {
+		sum := sha256.Sum256(archive)
+		version = "sha256:" + hex.EncodeToString(sum[:6])
+	}
+
+	feed := &models.GTFSFeed{
+		Version: version,
+		Status:  models.GTFSFeedImporting,
+	}
+	if err := s.gtfsRepo.CreateFeed(ctx, feed); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// load parses the archive and activates the feed, recording the failure
+// reason on the feed row when anything goes wrong.
+func (s *gtfsService) load(ctx context.Context, feed *models.GTFSFeed, archive []byte, explicitVersion bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fail := func(err error) error {
+		feed.Status = models.GTFSFeedFailed
+		feed.Error = err.Error()
+		if markErr := s.gtfsRepo.MarkFailed(ctx, feed.ID, err.Error()); markErr != nil {
+			s.logger.Error("Failed to record GTFS import failure", zap.Error(markErr))
+		}
+		return err
+	}
+
+	data, err := gtfs.Parse(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fail(err)
+	}
+
+	if !explicitVersion && data.Version != "" {
+		feed.Version = data.Version
+	}
+	feed.AgencyCount = len(data.Agencies)
+	feed.RouteCount = len(data.Routes)
+	feed.StopCount = len(data.Stops)
+	feed.TripCount = len(data.Trips)
+	feed.StopTimeCount = len(data.StopTimes)
+	feed.ShapeCount = countShapes(data.Shapes)
+
+	geofences := append(s.stopGeofences(data), s.corridorGeofences(data)...)
+
+	if err := s.gtfsRepo.Activate(ctx, feed, data, geofences); err != nil {
+		return fail(err)
+	}
+
+	s.logger.Info("GTFS feed imported",
+		zap.Int64("feed_id", feed.ID),
+		zap.String("version", feed.Version),
+		zap.Int("routes", feed.RouteCount),
+		zap.Int("stops", feed.StopCount),
+		zap.Int("trips", feed.TripCount),
+		zap.Int("geofences", len(geofences)))
+
+	return nil
+}
+
+// stopGeofences creates a circular geofence for every boarding stop;
+// stations and entrances (location_type > 0) are skipped.
+func (s *gtfsService) stopGeofences(data *gtfs.Feed) []*models.Geofence {
+	var geofences []*models.Geofence
+	for _, stop := range data.Stops {
+		if stop.LocationType != 0 {
+			continue
+		}
+
+		name := stop.Name
+		if name == "" {
+			name = stop.StopID
+		}
+
+		geofences = append(geofences, &models.Geofence{
+			Name:       name,
+			Type:       models.GeofenceTypeStop,
+			Latitude:   stop.Latitude,
+			Longitude:  stop.Longitude,
+			Radius:     s.config.StopRadius,
+			ExternalID: stop.StopID,
+		})
+	}
+
+	return geofences
+}
+
+// corridorGeofences creates a polyline geofence for every shape, named after
+// the route that uses it.
+func (s *gtfsService) corridorGeofences(data *gtfs.Feed) []*models.Geofence {
+	routes := make(map[string]*models.GTFSRoute, len(data.Routes))
+	for _, route := range data.Routes {
+		routes[route.RouteID] = route
+	}
+
+	shapeRoutes := make(map[string]*models.GTFSRoute)
+	for _, trip := range data.Trips {
+		if trip.ShapeID == "" {
+			continue
+		}
+		if _, ok := shapeRoutes[trip.ShapeID]; !ok {
+			shapeRoutes[trip.ShapeID] = routes[trip.RouteID]
+		}
+	}
+
+	points := make(map[string][]*models.GTFSShapePoint)
+	for _, point := range data.Shapes {
+		points[point.ShapeID] = append(points[point.ShapeID], point)
+	}
+
+	shapeIDs := make([]string, 0, len(points))
+	for shapeID := range points {
+		shapeIDs = append(shapeIDs, shapeID)
+	}
+	sort.Strings(shapeIDs)
+
+	var geofences []*models.Geofence
+	for _, shapeID := range shapeIDs {
+		shape := points[shapeID]
+		if len(shape) < 2 {
+			continue
+		}
+		sort.Slice(shape, func(i, j int) bool { return shape[i].Sequence < shape[j].Sequence })
+
+		path := make([][]float64, len(shape))
+		for i, point := range shape {
+			path[i] = []float64{point.Latitude, point.Longitude}
+		}
+
+		name := "Shape " + shapeID
+		if route := shapeRoutes[shapeID]; route != nil {
+			name = fmt.Sprintf("Corridor %s (%s)", routeName(route), shapeID)
+		}
+
+		middle := shape[len(shape)/2]
+		geofences = append(geofences, &models.Geofence{
+			Name:       name,
+			Type:       models.GeofenceTypeCorridor,
+			Latitude:   middle.Latitude,
+			Longitude:  middle.Longitude,
+			Radius:     s.config.CorridorWidth,
+			Path:       path,
+			ExternalID: shapeID,
+		})
+	}
+
+	return geofences
+}
+
+func routeName(route *models.GTFSRoute) string {
+	if route.ShortName != "" {
+		return route.ShortName
+	}
+	if route.LongName != "" {
+		return route.LongName
+	}
+	return route.RouteID
+}
+
+func countShapes(points []*models.GTFSShapePoint) int {
+	shapes := make(map[string]struct{})
+	for _, point := range points {
+		shapes[point.ShapeID] = struct{}{}
+	}
+	return len(shapes)
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+const (
+	// backtrackTolerance is how far a vehicle may appear to move backwards
+	// along the route, through GPS noise, before its history is reset
+	backtrackTolerance = 200.0
+	// plannedHeadwayTTL bounds how long a planned headway is reused
+	plannedHeadwayTTL = 10 * time.Minute
+)
+
+// HeadwayService tracks vehicles along their route shape and periodically
+// computes headways between consecutive vehicles of each route direction,
+// raising bunching and gap events against the planned headway.
+type HeadwayService interface {
+	LocationProcessor
+	Start(ctx context.Context) error
+	// GetCorridorHeadways returns the latest snapshot of each direction of
+	// a route.
+	GetCorridorHeadways(routeID string) []*models.CorridorHeadways
+}
+
+type headwayService struct {
+	assignmentRepo repositories.TripAssignmentRepository
+	adherence      ScheduleAdherenceService
+	publisher      *eventbus.Publisher
+	schedule       *scheduleCache
+	config         *config.HeadwayConfig
+	logger         *zap.Logger
+	mu             sync.Mutex
+	trips          map[string]*models.TripAssignment // by vehicle
+	progress       map[string]*routeProgress         // by vehicle
+	statuses       map[string]string                 // last headway status, by vehicle
+	planned        map[string]*plannedHeadway        // by route direction
+	snapshots      map[string]*models.CorridorHeadways
+}
+
+// routeProgress is a vehicle's recent positions along a route direction's
+// shape, oldest first.
+type routeProgress struct {
+	key       string
+	tripID    string
+	samples   []progressSample
+	latitude  float64
+	longitude float64
+}
+
+type progressSample struct {
+	along     float64
+	timestamp int64
+}
+
+type plannedHeadway struct {
+	seconds    int64
+	computedAt time.Time
+}
+
+func (p *routeProgress) latest() progressSample {
+	return p.samples[len(p.samples)-1]
+}
+
+// passedAt returns when the vehicle was at the given distance along the
+// route, interpolated between samples, if its history covers it.
+func (p *routeProgress) passedAt(along float64) (int64, bool) {
+	for i := 1; i < len(p.samples); i++ {
+		a, b := p.samples[i-1], p.samples[i]
+		if a.along <= along && along <= b.along {
+			if b.along == a.along {
+				return a.timestamp, true
+			}
+			fraction := (along - a.along) / (b.along - a.along)
+			return a.timestamp + int64(fraction*float64(b.timestamp-a.timestamp)), true
+		}
+	}
+	return 0, false
+}
+
+// speed returns the average speed over the history, in meters per second.
+func (p *routeProgress) speed() float64 {
+	first, last := p.samples[0], p.latest()
+	if last.timestamp <= first.timestamp {
+		return 0
+	}
+	return (last.along - first.along) / float64(last.timestamp-first.timestamp)
+}
+
+func NewHeadwayService(
+	assignmentRepo repositories.TripAssignmentRepository,
+	gtfsRepo repositories.GTFSRepository,
+	adherence ScheduleAdherenceService,
+	publisher *eventbus.Publisher,
+	cfg *config.HeadwayConfig,
+	logger *zap.Logger,
+) HeadwayService {
+	return &headwayService{
+		assignmentRepo: assignmentRepo,
+		adherence:      adherence,
+		publisher:      publisher,
+		schedule:       newScheduleCache(gtfsRepo, logger),
+		config:         cfg,
+		logger:         logger,
+		trips:          make(map[string]*models.TripAssignment),
+		progress:       make(map[string]*routeProgress),
+		statuses:       make(map[string]string),
+		planned:        make(map[string]*plannedHeadway),
+		snapshots:      make(map[string]*models.CorridorHeadways),
+	}
+}
+
+// Start computes headways every interval until the context is cancelled.
+func (s *headwayService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Headway monitor started",
+		zap.Duration("interval", s.config.Interval),
+		zap.Float64("bunching_ratio", s.config.BunchingRatio),
+		zap.Float64("gap_ratio", s.config.GapRatio))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Headway monitor stopping")
+			return nil
+		case <-ticker.C:
+			if err := s.refreshTrips(ctx); err != nil {
+				s.logger.Error("Failed to refresh vehicle trips", zap.Error(err))
+				continue
+			}
+			s.evaluate(ctx)
+		}
+	}
+}
+
+func (s *headwayService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	s.mu.Lock()
+	trip := s.trips[location.VehicleID]
+	hint := -1.0
+	if p := s.progress[location.VehicleID]; p != nil && trip != nil && p.tripID == trip.TripID {
+		hint = p.latest().along
+	}
+	s.mu.Unlock()
+
+	if trip == nil {
+		return nil
+	}
+
+	routeShape, err := s.schedule.routeShape(ctx, trip.RouteID, trip.DirectionID)
+	if err != nil || routeShape == nil {
+		return err
+	}
+
+	along, offset := routeShape.Project(location.Latitude, location.Longitude, hint)
+	if offset > s.config.MaxOffset {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := routeKey(trip.RouteID, trip.DirectionID)
+	p := s.progress[location.VehicleID]
+	if p != nil && p.latest().timestamp >= location.Timestamp {
+		return nil // out of order
+	}
+	if p == nil || p.key != key || p.tripID != trip.TripID || along < p.latest().along-backtrackTolerance {
+		p = &routeProgress{key: key, tripID: trip.TripID}
+		s.progress[location.VehicleID] = p
+	}
+
+	p.samples = append(p.samples, progressSample{along: along, timestamp: location.Timestamp})
+	p.latitude, p.longitude = location.Latitude, location.Longitude
+
+	cutoff := location.Timestamp - int64(s.config.History.Seconds())
+	first := 0
+	for first < len(p.samples)-1 && p.samples[first].timestamp < cutoff {
+		first++
+	}
+	p.samples = p.samples[first:]
+
+	return nil
+}
+
+func (s *headwayService) refreshTrips(ctx context.Context) error {
+	trips, err := vehicleTrips(ctx, s.assignmentRepo, s.adherence)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.trips = trips
+	s.mu.Unlock()
+
+	return nil
+}
+
+// headwayCandidate is a vehicle taking part in one headway evaluation
+type headwayCandidate struct {
+	vehicleID string
+	progress  *routeProgress
+}
+
+func (s *headwayService) evaluate(ctx context.Context) {
+	now := time.Now()
+	staleBefore := now.Add(-s.config.StaleAfter).Unix()
+
+	s.mu.Lock()
+	groups := make(map[string][]headwayCandidate)
+	routes := make(map[string]*models.TripAssignment)
+	for vehicleID, p := range s.progress {
+		trip := s.trips[vehicleID]
+		if trip == nil || trip.TripID != p.tripID || p.latest().timestamp < staleBefore {
+			delete(s.progress, vehicleID)
+			delete(s.statuses, vehicleID)
+			continue
+		}
+		groups[p.key] = append(groups[p.key], headwayCandidate{vehicleID: vehicleID, progress: p})
+		routes[p.key] = trip
+	}
+	s.mu.Unlock()
+
+	snapshots := make(map[string]*models.CorridorHeadways)
+	for key, candidates := range groups {
+		trip := routes[key]
+		planned, err := s.plannedHeadway(ctx, trip.RouteID, trip.DirectionID)
+		if err != nil {
+			s.logger.Warn("Failed to compute planned headway",
+				zap.Error(err),
+				zap.String("route_id", trip.RouteID))
+		}
+
+		snapshot := &models.CorridorHeadways{
+			RouteID:     trip.RouteID,
+			DirectionID: trip.DirectionID,
+			UpdatedAt:   now,
+		}
+		if planned > 0 {
+			snapshot.PlannedHeadwaySeconds = &planned
+		}
+
+		s.mu.Lock()
+		snapshot.Vehicles = s.headways(candidates, planned)
+		s.mu.Unlock()
+
+		for _, headway := range snapshot.Vehicles {
+			s.updateStatus(ctx, snapshot, headway)
+		}
+		snapshots[key] = snapshot
+	}
+
+	s.mu.Lock()
+	s.snapshots = snapshots
+	s.mu.Unlock()
+}
+
+// headways orders the vehicles of a route direction from the front and
+// measures each one against the vehicle directly ahead.
+func (s *headwayService) headways(candidates []headwayCandidate, planned int64) []*models.VehicleHeadway {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].progress.latest().along > candidates[j].progress.latest().along
+	})
+
+	defaultSpeed := s.config.DefaultSpeedKmh / 3.6
+	headways := make([]*models.VehicleHeadway, 0, len(candidates))
+	for i, c := range candidates {
+		latest := c.progress.latest()
+		headway := &models.VehicleHeadway{
+			VehicleID:     c.vehicleID,
+			TripID:        c.progress.tripID,
+			Latitude:      c.progress.latitude,
+			Longitude:     c.progress.longitude,
+			DistanceAlong: latest.along,
+			Timestamp:     latest.timestamp,
+		}
+		headways = append(headways, headway)
+
+		if i == 0 {
+			continue
+		}
+
+		leader := candidates[i-1]
+		headway.LeaderVehicleID = leader.vehicleID
+		headway.GapMeters = leader.progress.latest().along - latest.along
+
+		var seconds int64
+		if passed, ok := leader.progress.passedAt(latest.along); ok {
+			seconds = latest.timestamp - passed
+			if seconds < 0 {
+				seconds = 0
+			}
+		} else {
+			speed := c.progress.speed()
+			if speed < 1 {
+				speed = defaultSpeed
+			}
+			seconds = int64(headway.GapMeters / speed)
+			headway.Estimated = true
+		}
+		headway.HeadwaySeconds = &seconds
+
+		if planned > 0 {
+			ratio := float64(seconds) / float64(planned)
+			headway.Ratio = &ratio
+			switch {
+			case ratio < s.config.BunchingRatio:
+				headway.Status = models.HeadwayBunching
+			case ratio > s.config.GapRatio:
+				headway.Status = models.HeadwayGap
+			default:
+				headway.Status = models.HeadwayNormal
+			}
+		}
+	}
+
+	return headways
+}
+
+// updateStatus publishes an event when a vehicle enters the bunching or gap
+// state; staying in a state does not repeat the event.
+func (s *headwayService) updateStatus(ctx context.Context, snapshot *models.CorridorHeadways, headway *models.VehicleHeadway) {
+	s.mu.Lock()
+	previous := s.statuses[headway.VehicleID]
+	s.statuses[headway.VehicleID] = headway.Status
+	s.mu.Unlock()
+
+	if headway.Status == previous || (headway.Status != models.HeadwayBunching && headway.Status != models.HeadwayGap) {
+		return
+	}
+
+	event := &events.VehicleEventMessage{
+		VehicleID: headway.VehicleID,
+		Event:     headway.Status,
+		Location: events.Location{
+			Latitude:  headway.Latitude,
+			Longitude: headway.Longitude,
+		},
+		Timestamp: headway.Timestamp,
+		Data: map[string]interface{}{
+			"route_id":                snapshot.RouteID,
+			"direction_id":            snapshot.DirectionID,
+			"trip_id":                 headway.TripID,
+			"leader_vehicle_id":       headway.LeaderVehicleID,
+			"gap_meters":              headway.GapMeters,
+			"headway_seconds":         *headway.HeadwaySeconds,
+			"planned_headway_seconds": *snapshot.PlannedHeadwaySeconds,
+			"ratio":                   *headway.Ratio,
+		},
+	}
+
+	if err := s.publisher.PublishVehicleEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to publish headway event to RabbitMQ",
+			zap.Error(err),
+			zap.String("vehicle_id", headway.VehicleID),
+			zap.String("event", event.Event))
+		return
+	}
+
+	s.logger.Info("Headway state changed",
+		zap.String("vehicle_id", headway.VehicleID),
+		zap.String("route_id", snapshot.RouteID),
+		zap.String("event", event.Event),
+		zap.Int64("headway_seconds", *headway.HeadwaySeconds))
+}
+
+// plannedHeadway returns the median scheduled interval between departures of
+// a route direction in the hour around now, or 0 when unknown.
+func (s *headwayService) plannedHeadway(ctx context.Context, routeID string, directionID int) (int64, error) {
+	key := routeKey(routeID, directionID)
+
+	s.mu.Lock()
+	cached := s.planned[key]
+	s.mu.Unlock()
+	if cached != nil && time.Since(cached.computedAt) < plannedHeadwayTTL {
+		return cached.seconds, nil
+	}
+
+	feed, timezone, err := s.schedule.active(ctx)
+	if err != nil || feed == nil {
+		return 0, err
+	}
+
+	now := time.Now().In(timezone)
+	today, err := serviceDayStart(now.Format(gtfsDateLayout), timezone)
+	if err != nil {
+		return 0, err
+	}
+
+	// Trips running past midnight belong to the previous service date, with
+	// times past 24:00
+	var departures []int
+	for daysBack := 0; daysBack <= 1; daysBack++ {
+		date := now.AddDate(0, 0, -daysBack).Format(gtfsDateLayout)
+		dayStart, err := serviceDayStart(date, timezone)
+		if err != nil {
+			return 0, err
+		}
+		seconds := int(now.Sub(dayStart).Seconds())
+
+		times, err := s.schedule.departures(ctx, date, routeID, directionID, seconds-1800, seconds+1800)
+		if err != nil {
+			return 0, err
+		}
+
+		// Measure every departure from the start of today
+		offset := int(dayStart.Sub(today).Seconds())
+		for _, t := range times {
+			departures = append(departures, t+offset)
+		}
+	}
+	sort.Ints(departures)
+	departures = slices.Compact(departures)
+
+	var intervals []int
+	for i := 1; i < len(departures); i++ {
+		intervals = append(intervals, departures[i]-departures[i-1])
+	}
+
+	var planned int64
+	if len(intervals) > 0 {
+		sort.Ints(intervals)
+		planned = int64(intervals[len(intervals)/2])
+	}
+
+	s.mu.Lock()
+	s.planned[key] = &plannedHeadway{seconds: planned, computedAt: time.Now()}
+	s.mu.Unlock()
+
+	return planned, nil
+}
+
+func (s *headwayService) GetCorridorHeadways(routeID string) []*models.CorridorHeadways {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var corridors []*models.CorridorHeadways
+	for _, snapshot := range s.snapshots {
+		if snapshot.RouteID == routeID {
+			corridors = append(corridors, snapshot)
+		}
+	}
+
+	sort.Slice(corridors, func(i, j int) bool {
+		return corridors[i].DirectionID < corridors[j].DirectionID
+	})
+
+	return corridors
+}
+
+func routeKey(routeID string, directionID int) string {
+	return fmt.Sprintf("%s/%d", routeID, directionID)
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// HeartbeatService tracks when each vehicle last reported and raises
+// vehicle_offline / vehicle_online events when reporting stops or resumes.
+type HeartbeatService interface {
+	LocationProcessor
+	Start(ctx context.Context) error
+	GetStatus(vehicleID string) (*models.VehicleConnection, bool)
+	GetAllStatuses() []*models.VehicleConnection
+}
+
+type heartbeatService struct {
+	vehicleLocationRepo repositories.VehicleLocationRepository
+	publisher           *eventbus.Publisher
+	config              *config.HeartbeatConfig
+	logger              *zap.Logger
+	mu                  sync.RWMutex
+	vehicles            map[string]*models.VehicleConnection
+}
+
+func NewHeartbeatService(
+	vehicleLocationRepo repositories.VehicleLocationRepository,
+	publisher *eventbus.Publisher,
+	cfg *config.HeartbeatConfig,
+	logger *zap.Logger,
+) HeartbeatService {
+	return &heartbeatService{
+		vehicleLocationRepo: vehicleLocationRepo,
+		publisher:           publisher,
+		config:              cfg,
+		logger:              logger,
+		vehicles:            make(map[string]*models.VehicleConnection),
+	}
+}
+
+// Start seeds last-seen state from stored locations and checks for silent
+// vehicles until the context is cancelled.
+func (s *heartbeatService) Start(ctx context.Context) error {
+	if err := s.seed(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Heartbeat monitor started",
+		zap.Duration("offline_after", s.config.OfflineAfter),
+		zap.Duration("check_interval", s.config.CheckInterval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Heartbeat monitor stopping")
+			return nil
+		case <-ticker.C:
+			s.checkOffline(ctx)
+		}
+	}
+}
+
+func (s *heartbeatService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	connection, ok := s.vehicles[location.VehicleID]
+	if !ok {
+		connection = &models.VehicleConnection{VehicleID: location.VehicleID}
+		s.vehicles[location.VehicleID] = connection
+	}
+
+	wasOffline := connection.Status == models.ConnectionOffline
+	offlineSince := connection.OfflineSince
+
+	connection.Status = models.ConnectionOnline
+	connection.LastSeen = now
+	connection.LastTimestamp = location.Timestamp
+	connection.Latitude = location.Latitude
+	connection.Longitude = location.Longitude
+	connection.OfflineSince = nil
+	s.mu.Unlock()
+
+	if !wasOffline {
+		return nil
+	}
+
+	data := map[string]interface{}{}
+	if offlineSince != nil {
+		data["offline_seconds"] = int64(now.Sub(*offlineSince).Seconds())
+	}
+
+	s.logger.Info("Vehicle back online", zap.String("vehicle_id", location.VehicleID))
+
+	return s.publish(ctx, "vehicle_online", location.VehicleID, location.Latitude, location.Longitude, data)
+}
+
+func (s *heartbeatService) GetStatus(vehicleID string) (*models.VehicleConnection, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	connection, ok := s.vehicles[vehicleID]
+	if !ok {
+		return nil, false
+	}
+
+	status := *connection
+	return &status, true
+}
+
+func (s *heartbeatService) GetAllStatuses() []*models.VehicleConnection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]*models.VehicleConnection, 0, len(s.vehicles))
+	for _, connection := range s.vehicles {
+		status := *connection
+		statuses = append(statuses, &status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].VehicleID < statuses[j].VehicleID
+	})
+
+	return statuses
+}
+
+func (s *heartbeatService) seed(ctx context.Context) error {
+	locations, err := s.vehicleLocationRepo.GetLatestForAllVehicles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load last seen vehicles: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, location := range locations {
+		if _, ok := s.vehicles[location.VehicleID]; ok {
+			continue
+		}
+
+		lastSeen := location.CreatedAt.UTC()
+		connection := &models.VehicleConnection{
+			VehicleID:     location.VehicleID,
+			Status:        models.ConnectionOnline,
+			LastSeen:      lastSeen,
+			LastTimestamp: location.Timestamp,
+			Latitude:      location.Latitude,
+			Longitude:     location.Longitude,
+		}
+
+		// Vehicles already silent at startup are marked offline without an event
+		if now.Sub(lastSeen) > s.config.OfflineAfter {
+			connection.Status = models.ConnectionOffline
+			connection.OfflineSince = &lastSeen
+		}
+
+		s.vehicles[location.VehicleID] = connection
+	}
+
+	s.logger.Info("Heartbeat state seeded", zap.Int("vehicle_count", len(locations)))
+	return nil
+}
+
+func (s *heartbeatService) checkOffline(ctx context.Context) {
+	now := time.Now().UTC()
+
+	var silent []models.VehicleConnection
+
+	s.mu.Lock()
+	for _, connection := range s.vehicles {
+		if connection.Status == models.ConnectionOffline {
+			continue
+		}
+		if now.Sub(connection.LastSeen) > s.config.OfflineAfter {
+			offlineSince := now
+			connection.Status = models.ConnectionOffline
+			connection.OfflineSince = &offlineSince
+			silent = append(silent, *connection)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, connection := range silent {
+		s.logger.Warn("Vehicle went offline",
+			zap.String("vehicle_id", connection.VehicleID),
+			zap.Time("last_seen", connection.LastSeen))
+
+		data := map[string]interface{}{
+			"last_seen":       connection.LastSeen.Unix(),
+			"silence_seconds": int64(now.Sub(connection.LastSeen).Seconds()),
+		}
+		if err := s.publish(ctx, "vehicle_offline", connection.VehicleID, connection.Latitude, connection.Longitude, data); err != nil {
+			s.logger.Error("Failed to publish offline event", zap.Error(err))
+		}
+	}
+}
+
+func (s *heartbeatService) publish(ctx context.Context, event, vehicleID string, latitude, longitude float64, data map[string]interface{}) error {
+	message := &events.VehicleEventMessage{
+		VehicleID: vehicleID,
+		Event:     event,
+		Location: events.Location{
+			Latitude:  latitude,
+			Longitude: longitude,
+		},
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	}
+
+	return s.publisher.PublishVehicleEvent(ctx, message)
+}
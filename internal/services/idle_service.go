@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/idle"
+)
+
+type IdleService interface {
+	LocationProcessor
+	GetIdleIntervals(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.IdleInterval, error)
+	GetIdleSummary(ctx context.Context, startTime, endTime int64) ([]*models.IdleSummary, error)
+}
+
+type idleService struct {
+	idleRepo     repositories.IdleRepository
+	geofenceRepo repositories.GeofenceRepository
+	publisher    *eventbus.Publisher
+	detector     *idle.Detector
+	logger       *zap.Logger
+	mu           sync.Mutex
+	depots       []*models.Geofence
+	lastUpdated  time.Time
+}
+
+func NewIdleService(
+	idleRepo repositories.IdleRepository,
+	geofenceRepo repositories.GeofenceRepository,
+	publisher *eventbus.Publisher,
+	cfg *config.IdleConfig,
+	logger *zap.Logger,
+) IdleService {
+	return &idleService{
+		idleRepo:     idleRepo,
+		geofenceRepo: geofenceRepo,
+		publisher:    publisher,
+		detector:     idle.NewDetector(cfg.Threshold, cfg.StationaryRadius, cfg.RequireIgnition),
+		logger:       logger,
+	}
+}
+
+func (s *idleService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	inDepot, err := s.isInDepot(ctx, location)
+	if err != nil {
+		return err
+	}
+
+	transition := s.detector.Process(location, inDepot)
+	if transition == nil {
+		return nil
+	}
+
+	event := &events.VehicleEventMessage{
+		VehicleID: transition.VehicleID,
+		Location: events.Location{
+			Latitude:  transition.Latitude,
+			Longitude: transition.Longitude,
+		},
+		Data: map[string]interface{}{
+			"idle_start": transition.StartTime,
+		},
+	}
+	if location.DriverID != nil {
+		event.Data["driver_id"] = *location.DriverID
+	}
+
+	if transition.Started {
+		interval := &models.IdleInterval{
+			VehicleID: transition.VehicleID,
+			StartTime: transition.StartTime,
+			Latitude:  transition.Latitude,
+			Longitude: transition.Longitude,
+			DriverID:  location.DriverID,
+		}
+		if err := s.idleRepo.Start(ctx, interval); err != nil {
+			return fmt.Errorf("failed to save idle start: %w", err)
+		}
+
+		event.Event = "idle_start"
+		event.Timestamp = location.Timestamp
+	} else {
+		if err := s.idleRepo.End(ctx, transition.VehicleID, transition.StartTime, transition.EndTime); err != nil {
+			return fmt.Errorf("failed to save idle end: %w", err)
+		}
+
+		event.Event = "idle_end"
+		event.Timestamp = transition.EndTime
+		event.Data["idle_seconds"] = transition.EndTime - transition.StartTime
+	}
+
+	if err := s.publisher.PublishVehicleEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to publish idle event to RabbitMQ",
+			zap.Error(err),
+			zap.String("vehicle_id", transition.VehicleID),
+			zap.String("event", event.Event))
+		// Don't return error - database save succeeded
+	}
+
+	s.logger.Info("Idle state changed",
+		zap.String("vehicle_id", transition.VehicleID),
+		zap.String("event", event.Event))
+
+	return nil
+}
+
+func (s *idleService) GetIdleIntervals(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.IdleInterval, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required")
+	}
+
+	if startTime > endTime {
+		return nil, fmt.Errorf("start_time must be less than end_time")
+	}
+
+	return s.idleRepo.GetByVehicleID(ctx, vehicleID, startTime, endTime)
+}
+
+func (s *idleService) GetIdleSummary(ctx context.Context, startTime, endTime int64) ([]*models.IdleSummary, error) {
+	if startTime > endTime {
+		return nil, fmt.Errorf("start_time must be less than end_time")
+	}
+
+	return s.idleRepo.GetSummary(ctx, startTime, endTime)
+}
+
+func (s *idleService) isInDepot(ctx context.Context, location *models.VehicleLocation) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Refresh depot cache if needed (every 5 minutes)
+	if time.Since(s.lastUpdated) > 5*time.Minute {
+		geofences, err := s.geofenceRepo.GetAll(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to load depot geofences: %w", err)
+		}
+
+		var depots []*models.Geofence
+		for _, g := range geofences {
+			if g.Type == models.GeofenceTypeDepot {
+				depots = append(depots, g)
+			}
+		}
+		s.depots = depots
+		s.lastUpdated = time.Now()
+	}
+
+	for _, depot := range s.depots {
+		if geofence.Contains(depot, location.Latitude, location.Longitude) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
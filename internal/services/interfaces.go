@@ -7,7 +7,18 @@ import (
 )
 
 type LocationService interface {
+	// Start runs until ctx ends, then drains the locations still queued for
+	// processing
+	Start(ctx context.Context) error
 	SaveLocation(ctx context.Context, location *models.VehicleLocation) error
 	GetLatestLocation(ctx context.Context, vehicleID string) (*models.VehicleLocation, error)
 	GetLocationHistory(ctx context.Context, vehicleID string, startTime, endTime int64) ([]*models.VehicleLocation, error)
-}
\ No newline at end of file
+}
+
+// LocationProcessor consumes saved locations for derived analytics
+// (trips, distance, ...). After a location is stored, the processors are
+// called one after another; a vehicle's locations reach them one at a time,
+// in the order they were saved.
+type LocationProcessor interface {
+	ProcessLocation(ctx context.Context, location *models.VehicleLocation) error
+}
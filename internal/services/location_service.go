@@ -22,6 +22,12 @@ func NewLocationService(vehicleLocationRepo repositories.VehicleLocationReposito
 	}
 }
 
+// Start only waits for ctx to end; locations are not processed further.
+func (s *locationService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
 func (s *locationService) SaveLocation(ctx context.Context, location *models.VehicleLocation) error {
 	// Validate location data
 	if location.VehicleID == "" {
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/mapmatch"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/roadnet"
+)
+
+// MapMatchService snaps vehicle positions to the road network.
+type MapMatchService interface {
+	// MatchLocation sets the matched position and road of a location before
+	// it is stored, and corrects the vehicle's recently stored locations when
+	// the new position changes the most likely path.
+	MatchLocation(ctx context.Context, location *models.VehicleLocation)
+}
+
+type mapMatchService struct {
+	vehicleLocationRepo repositories.VehicleLocationRepository
+	matcher             *mapmatch.Matcher
+	logger              *zap.Logger
+	mu                  sync.Mutex
+	tracks              map[string]*vehicleTrack
+}
+
+// vehicleTrack pairs a vehicle's matching window with the locations in it and
+// the match stored for each.
+type vehicleTrack struct {
+	track     mapmatch.Track
+	locations []*models.VehicleLocation
+	matches   []mapmatch.Match
+}
+
+func NewMapMatchService(
+	vehicleLocationRepo repositories.VehicleLocationRepository,
+	cfg *config.MapMatchConfig,
+	logger *zap.Logger,
+) (MapMatchService, error) {
+	if cfg.RoadNetwork == "" {
+		return nil, fmt.Errorf("map_match.road_network is required")
+	}
+
+	start := time.Now()
+	graph, err := roadnet.Load(cfg.RoadNetwork)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load road network: %w", err)
+	}
+	if graph.Edges() == 0 {
+		return nil, fmt.Errorf("road network %s has no roads", cfg.RoadNetwork)
+	}
+
+	logger.Info("Road network loaded",
+		zap.String("path", cfg.RoadNetwork),
+		zap.Int("nodes", graph.Nodes()),
+		zap.Int("edges", graph.Edges()),
+		zap.Duration("took", time.Since(start)))
+
+	return &mapMatchService{
+		vehicleLocationRepo: vehicleLocationRepo,
+		matcher: &mapmatch.Matcher{
+			Graph:          graph,
+			SearchRadius:   cfg.SearchRadius,
+			MaxCandidates:  cfg.MaxCandidates,
+			Sigma:          cfg.GPSSigma,
+			Beta:           cfg.TransitionBeta,
+			MaxRouteFactor: cfg.MaxRouteFactor,
+			Window:         cfg.Window,
+			MaxGap:         int64(cfg.MaxGap.Seconds()),
+		},
+		logger: logger,
+		tracks: make(map[string]*vehicleTrack),
+	}, nil
+}
+
+func (s *mapMatchService) MatchLocation(ctx context.Context, location *models.VehicleLocation) {
+	s.mu.Lock()
+
+	t := s.tracks[location.VehicleID]
+	if t == nil {
+		t = &vehicleTrack{}
+		s.tracks[location.VehicleID] = t
+	}
+	if n := len(t.locations); n > 0 && location.Timestamp <= t.locations[n-1].Timestamp {
+		s.mu.Unlock()
+		return // out of order; left unmatched
+	}
+
+	match, ok := s.matcher.Match(&t.track, location.Latitude, location.Longitude, location.Timestamp)
+	if !ok {
+		t.locations, t.matches = nil, nil
+		s.mu.Unlock()
+		return
+	}
+	setMatch(location, match)
+
+	t.locations = append(t.locations, location)
+	t.matches = append(t.matches, match)
+	if drop := len(t.locations) - t.track.Len(); drop > 0 {
+		t.locations = t.locations[drop:]
+		t.matches = t.matches[drop:]
+	}
+
+	// Earlier positions are decided again with the new one known
+	var corrections []*models.VehicleLocation
+	path := s.matcher.Path(&t.track)
+	offset := len(t.locations) - len(path)
+	for i, m := range path[:len(path)-1] {
+		k := offset + i
+		if m == t.matches[k] || t.locations[k].ID == 0 {
+			continue
+		}
+		t.matches[k] = m
+		corrected := *t.locations[k]
+		setMatch(&corrected, m)
+		corrections = append(corrections, &corrected)
+	}
+	s.mu.Unlock()
+
+	for _, corrected := range corrections {
+		if err := s.vehicleLocationRepo.UpdateMatch(ctx, corrected); err != nil {
+			s.logger.Error("Failed to correct matched location",
+				zap.Error(err),
+				zap.String("vehicle_id", corrected.VehicleID),
+				zap.Int64("location_id", corrected.ID))
+		}
+	}
+}
+
+func setMatch(location *models.VehicleLocation, match mapmatch.Match) {
+	lat, lng, roadID := match.Latitude, match.Longitude, match.RoadID
+	location.MatchedLatitude = &lat
+	location.MatchedLongitude = &lng
+	location.RoadID = &roadID
+}
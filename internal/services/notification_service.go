@@ -0,0 +1,630 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// Notification channel types
+const (
+	NotificationChannelLog  = "log"
+	NotificationChannelHTTP = "http"
+)
+
+// defaultNotificationTemplate is used by rules created without a template
+const defaultNotificationTemplate = `{{.Rule}}: vehicle {{.VehicleID}} {{.Event}}{{with .Geofence}} at {{.Name}}{{end}} ({{.Time.Format "15:04"}})`
+
+// ErrInvalidNotificationRule is returned when a rule fails validation.
+var ErrInvalidNotificationRule = errors.New("invalid notification rule")
+
+// NotificationService manages notification rules and evaluates them against
+// the events handled by the worker.
+type NotificationService interface {
+	CreateRule(ctx context.Context, rule *models.NotificationRule) error
+	GetRule(ctx context.Context, id int64) (*models.NotificationRule, error)
+	ListRules(ctx context.Context) ([]*models.NotificationRule, error)
+	UpdateRule(ctx context.Context, rule *models.NotificationRule) error
+	DeleteRule(ctx context.Context, id int64) error
+	GetNotifications(ctx context.Context, ruleID int64, limit int) ([]*models.Notification, error)
+	// ListChannels returns the type of each configured channel by name.
+	ListChannels() map[string]string
+	// Evaluate notifies the channels of the enabled rules an event matches,
+	// once per rule and event. It fails only when the rules cannot be
+	// evaluated; a channel that cannot be reached fails the notification.
+	Evaluate(ctx context.Context, event *events.Event) error
+	// Start deletes old notifications until the context ends.
+	Start(ctx context.Context) error
+}
+
+// compiledRule is an enabled rule ready to be matched and rendered.
+type compiledRule struct {
+	rule     *models.NotificationRule
+	template *template.Template
+	// Time window in minutes since midnight, or -1 when unbounded
+	timeFrom int
+	timeTo   int
+}
+
+// notificationData is what rule templates are rendered with, e.g.
+// {{.VehicleID}} or {{.Geofence.Name}}.
+type notificationData struct {
+	Rule      string
+	Event     string
+	Type      string
+	VehicleID string
+	DriverID  string
+	Time      time.Time
+	Location  events.Location
+	Address   *events.Address
+	Geofence  *notificationGeofence
+	Vehicle   *models.Vehicle
+	Data      map[string]interface{}
+}
+
+type notificationGeofence struct {
+	ID       int64
+	Name     string
+	Type     string
+	Tags     []string
+	Distance float64
+}
+
+type notificationService struct {
+	notificationRepo repositories.NotificationRepository
+	registryService  VehicleRegistryService
+	config           *config.NotificationsConfig
+	channels         map[string]config.NotificationChannelConfig
+	location         *time.Location
+	client           *http.Client
+	logger           *zap.Logger
+
+	mu          sync.Mutex
+	rules       []*compiledRule
+	rulesLoaded time.Time
+}
+
+func NewNotificationService(
+	notificationRepo repositories.NotificationRepository,
+	registryService VehicleRegistryService,
+	cfg *config.NotificationsConfig,
+	logger *zap.Logger,
+) NotificationService {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		logger.Warn("Unknown notifications timezone, falling back to WIB",
+			zap.Error(err),
+			zap.String("timezone", cfg.Timezone))
+		location = time.FixedZone("WIB", 7*60*60)
+	}
+
+	// Rules naming a misconfigured channel are rejected, and fail to send
+	// if the channel is removed later
+	channels := make(map[string]config.NotificationChannelConfig, len(cfg.Channels))
+	for name, channel := range cfg.Channels {
+		switch {
+		case channel.Type == NotificationChannelLog:
+		case channel.Type == NotificationChannelHTTP && channel.URL != "":
+		default:
+			logger.Warn("Ignoring misconfigured notification channel",
+				zap.String("channel", name),
+				zap.String("type", channel.Type))
+			continue
+		}
+		channels[name] = channel
+	}
+
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		registryService:  registryService,
+		config:           cfg,
+		channels:         channels,
+		location:         location,
+		client:           &http.Client{Timeout: cfg.Timeout},
+		logger:           logger,
+	}
+}
+
+func (s *notificationService) CreateRule(ctx context.Context, rule *models.NotificationRule) error {
+	if err := s.validateRule(rule); err != nil {
+		return err
+	}
+
+	if err := s.notificationRepo.CreateRule(ctx, rule); err != nil {
+		return err
+	}
+	s.invalidateRules()
+
+	s.logger.Info("Notification rule created",
+		zap.Int64("rule_id", rule.ID),
+		zap.String("name", rule.Name),
+		zap.String("channel", rule.Channel))
+	return nil
+}
+
+func (s *notificationService) GetRule(ctx context.Context, id int64) (*models.NotificationRule, error) {
+	return s.notificationRepo.GetRule(ctx, id)
+}
+
+func (s *notificationService) ListRules(ctx context.Context) ([]*models.NotificationRule, error) {
+	return s.notificationRepo.ListRules(ctx)
+}
+
+func (s *notificationService) UpdateRule(ctx context.Context, rule *models.NotificationRule) error {
+	if err := s.validateRule(rule); err != nil {
+		return err
+	}
+
+	if err := s.notificationRepo.UpdateRule(ctx, rule); err != nil {
+		return err
+	}
+	s.invalidateRules()
+
+	s.logger.Info("Notification rule updated",
+		zap.Int64("rule_id", rule.ID),
+		zap.Bool("enabled", rule.Enabled))
+	return nil
+}
+
+func (s *notificationService) DeleteRule(ctx context.Context, id int64) error {
+	if err := s.notificationRepo.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateRules()
+
+	s.logger.Info("Notification rule deleted", zap.Int64("rule_id", id))
+	return nil
+}
+
+func (s *notificationService) GetNotifications(ctx context.Context, ruleID int64, limit int) ([]*models.Notification, error) {
+	// Report a missing rule rather than an empty history
+	if _, err := s.notificationRepo.GetRule(ctx, ruleID); err != nil {
+		return nil, err
+	}
+	return s.notificationRepo.GetNotifications(ctx, ruleID, limit)
+}
+
+func (s *notificationService) ListChannels() map[string]string {
+	channels := make(map[string]string, len(s.channels))
+	for name, channel := range s.channels {
+		channels[name] = channel.Type
+	}
+	return channels
+}
+
+func (s *notificationService) Evaluate(ctx context.Context, event *events.Event) error {
+	rules, err := s.enabledRules(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	if event.ID == "" {
+		// Without an id, a redelivered event would notify again
+		s.logger.Warn("Skipping notification rules for event without id", zap.String("event_type", event.Type))
+		return nil
+	}
+
+	data, err := s.notificationData(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if !rule.matches(event, data) {
+			continue
+		}
+		if err := s.notify(ctx, rule, event, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *notificationService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := s.notificationRepo.DeleteNotificationsBefore(ctx, time.Now().Add(-s.config.Retention))
+			if err != nil {
+				s.logger.Error("Failed to clean up notifications", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				s.logger.Info("Old notifications deleted", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// notify records and sends the notification of a matching rule, unless the
+// rule already notified about the event or is throttled.
+func (s *notificationService) notify(ctx context.Context, rule *compiledRule, event *events.Event, data *notificationData) error {
+	data.Rule = rule.rule.Name
+	var message bytes.Buffer
+	if err := rule.template.Execute(&message, data); err != nil {
+		// Not worth retrying the event for; the rule needs fixing
+		s.logger.Error("Failed to render notification template",
+			zap.Error(err),
+			zap.Int64("rule_id", rule.rule.ID),
+			zap.String("event_id", event.ID))
+		return nil
+	}
+
+	notification := &models.Notification{
+		RuleID:    rule.rule.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		VehicleID: data.VehicleID,
+		Channel:   rule.rule.Channel,
+		Message:   message.String(),
+	}
+
+	throttleKey := data.VehicleID
+	if rule.rule.ThrottleScope == models.ThrottlePerRule {
+		throttleKey = "*"
+	}
+	throttle := time.Duration(rule.rule.ThrottleSeconds) * time.Second
+
+	claimed, err := s.notificationRepo.ClaimNotification(ctx, notification, throttleKey, throttle, event.Time)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		s.logger.Debug("Notification skipped: duplicate or throttled",
+			zap.Int64("rule_id", rule.rule.ID),
+			zap.String("event_id", event.ID))
+		return nil
+	}
+
+	var sendError *string
+	if err := s.send(ctx, rule.rule, notification); err != nil {
+		message := err.Error()
+		sendError = &message
+		s.logger.Error("Failed to send notification",
+			zap.Error(err),
+			zap.Int64("rule_id", rule.rule.ID),
+			zap.String("channel", rule.rule.Channel),
+			zap.String("event_id", event.ID))
+	}
+
+	// The result is saved even when shutting down, as the message went out
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	return s.notificationRepo.CompleteNotification(saveCtx, notification.ID, sendError)
+}
+
+func (s *notificationService) send(ctx context.Context, rule *models.NotificationRule, notification *models.Notification) error {
+	channel, ok := s.channels[rule.Channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not configured", rule.Channel)
+	}
+
+	switch channel.Type {
+	case NotificationChannelLog:
+		s.logger.Info("🔔 "+notification.Message,
+			zap.String("rule", rule.Name),
+			zap.String("channel", rule.Channel),
+			zap.String("vehicle_id", notification.VehicleID),
+			zap.String("event_id", notification.EventID))
+		return nil
+	case NotificationChannelHTTP:
+		return s.post(ctx, channel.URL, rule, notification)
+	default:
+		return fmt.Errorf("channel %q has unsupported type %q", rule.Channel, channel.Type)
+	}
+}
+
+// post sends a notification as JSON. Slack-compatible endpoints read the
+// text field and ignore the rest.
+func (s *notificationService) post(ctx context.Context, url string, rule *models.NotificationRule, notification *models.Notification) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"text":       notification.Message,
+		"rule":       rule.Name,
+		"event_id":   notification.EventID,
+		"event_type": notification.EventType,
+		"vehicle_id": notification.VehicleID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Drain a bounded amount so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("channel responded %s", response.Status)
+	}
+	return nil
+}
+
+// notificationData gathers what rules match on and templates render from
+// an event.
+func (s *notificationService) notificationData(ctx context.Context, event *events.Event) (*notificationData, error) {
+	data := &notificationData{
+		Event:     event.Name(),
+		Type:      event.Type,
+		VehicleID: event.Subject,
+		Time:      event.Time.In(s.location),
+	}
+
+	switch {
+	case event.IsCategory("geofence"):
+		message, err := events.DecodeGeofenceEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		data.VehicleID = message.VehicleID
+		data.DriverID = message.DriverID
+		data.Location = message.Location
+		data.Address = message.Address
+		data.Geofence = &notificationGeofence{
+			ID:       message.GeofenceID,
+			Name:     message.GeofenceName,
+			Type:     message.GeofenceType,
+			Tags:     message.GeofenceTags,
+			Distance: message.Distance,
+		}
+	case event.IsCategory("vehicle"):
+		message, err := events.DecodeVehicleEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		data.VehicleID = message.VehicleID
+		data.Location = message.Location
+		data.Data = message.Data
+		if driverID, ok := message.Data["driver_id"].(string); ok {
+			data.DriverID = driverID
+		}
+	}
+
+	if data.VehicleID != "" {
+		data.Vehicle = s.registryService.LookupVehicle(ctx, data.VehicleID)
+	}
+	return data, nil
+}
+
+// matches reports whether an event meets all of the rule's conditions.
+func (r *compiledRule) matches(event *events.Event, data *notificationData) bool {
+	conditions := &r.rule.Conditions
+
+	if !matchesEventTypes(conditions.EventTypes, event) {
+		return false
+	}
+
+	if len(conditions.GeofenceNames) > 0 || len(conditions.GeofenceTypes) > 0 || len(conditions.GeofenceTags) > 0 {
+		geofence := data.Geofence
+		if geofence == nil {
+			return false
+		}
+		if len(conditions.GeofenceNames) > 0 && !slices.Contains(conditions.GeofenceNames, geofence.Name) {
+			return false
+		}
+		if len(conditions.GeofenceTypes) > 0 && !slices.Contains(conditions.GeofenceTypes, geofence.Type) {
+			return false
+		}
+		if len(conditions.GeofenceTags) > 0 && !slices.ContainsFunc(conditions.GeofenceTags, func(tag string) bool {
+			return slices.Contains(geofence.Tags, tag)
+		}) {
+			return false
+		}
+	}
+
+	if len(conditions.VehicleIDs) > 0 && !slices.Contains(conditions.VehicleIDs, data.VehicleID) {
+		return false
+	}
+
+	// Unregistered vehicles have no attributes to match
+	if len(conditions.VehicleGroups) > 0 || len(conditions.VehicleTypes) > 0 ||
+		len(conditions.Operators) > 0 || len(conditions.Corridors) > 0 {
+		vehicle := data.Vehicle
+		if vehicle == nil {
+			return false
+		}
+		if len(conditions.VehicleGroups) > 0 && !slices.ContainsFunc(conditions.VehicleGroups, func(group string) bool {
+			return slices.Contains(vehicle.Groups, group)
+		}) {
+			return false
+		}
+		if len(conditions.VehicleTypes) > 0 && !slices.Contains(conditions.VehicleTypes, vehicle.VehicleType) {
+			return false
+		}
+		if len(conditions.Operators) > 0 && !slices.Contains(conditions.Operators, vehicle.Operator) {
+			return false
+		}
+		if len(conditions.Corridors) > 0 && !slices.Contains(conditions.Corridors, vehicle.Corridor) {
+			return false
+		}
+	}
+
+	if r.timeFrom >= 0 {
+		minute := data.Time.Hour()*60 + data.Time.Minute()
+		if r.timeFrom < r.timeTo {
+			if minute < r.timeFrom || minute >= r.timeTo {
+				return false
+			}
+		} else if minute < r.timeFrom && minute >= r.timeTo {
+			// The window spans midnight
+			return false
+		}
+	}
+
+	return true
+}
+
+// enabledRules returns the enabled rules, reloading them every
+// RuleCacheTTL so changes made through another process are picked up.
+func (s *notificationService) enabledRules(ctx context.Context) ([]*compiledRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rules != nil && time.Since(s.rulesLoaded) < s.config.RuleCacheTTL {
+		return s.rules, nil
+	}
+
+	rules, err := s.notificationRepo.GetEnabledRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification rules: %w", err)
+	}
+
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			// Only rules edited outside the API can get here
+			s.logger.Error("Skipping invalid notification rule",
+				zap.Error(err),
+				zap.Int64("rule_id", rule.ID))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.rules = compiled
+	s.rulesLoaded = time.Now()
+	return compiled, nil
+}
+
+func (s *notificationService) invalidateRules() {
+	s.mu.Lock()
+	s.rules = nil
+	s.mu.Unlock()
+}
+
+func (s *notificationService) validateRule(rule *models.NotificationRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || len(rule.Name) > 100 {
+		return fmt.Errorf("name is required and must be at most 100 characters: %w", ErrInvalidNotificationRule)
+	}
+
+	if _, ok := s.channels[rule.Channel]; !ok {
+		names := make([]string, 0, len(s.channels))
+		for name := range s.channels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("channel must be one of %s: %w", strings.Join(names, ", "), ErrInvalidNotificationRule)
+	}
+
+	if strings.TrimSpace(rule.Template) == "" {
+		rule.Template = defaultNotificationTemplate
+	}
+
+	if rule.ThrottleSeconds < 0 {
+		return fmt.Errorf("throttle_seconds must not be negative: %w", ErrInvalidNotificationRule)
+	}
+	if rule.ThrottleScope == "" {
+		rule.ThrottleScope = models.ThrottlePerVehicle
+	}
+	if rule.ThrottleScope != models.ThrottlePerVehicle && rule.ThrottleScope != models.ThrottlePerRule {
+		return fmt.Errorf("throttle_scope must be vehicle or rule: %w", ErrInvalidNotificationRule)
+	}
+
+	conditions := &rule.Conditions
+	for _, list := range []*[]string{
+		&conditions.EventTypes,
+		&conditions.GeofenceNames,
+		&conditions.GeofenceTypes,
+		&conditions.GeofenceTags,
+		&conditions.VehicleIDs,
+		&conditions.VehicleGroups,
+		&conditions.VehicleTypes,
+		&conditions.Operators,
+		&conditions.Corridors,
+	} {
+		if len(*list) == 0 {
+			*list = nil
+			continue
+		}
+		values, err := normalizeLabels(*list)
+		if err != nil {
+			return fmt.Errorf("conditions: %v: %w", err, ErrInvalidNotificationRule)
+		}
+		*list = values
+	}
+	for _, pattern := range conditions.EventTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid event type condition %q: %w", pattern, ErrInvalidNotificationRule)
+		}
+	}
+
+	if _, err := compileRule(rule); err != nil {
+		return fmt.Errorf("%v: %w", err, ErrInvalidNotificationRule)
+	}
+	return nil
+}
+
+// compileRule parses the template and time window of a rule.
+func compileRule(rule *models.NotificationRule) (*compiledRule, error) {
+	tmpl, err := template.New(rule.Name).Option("missingkey=zero").Parse(rule.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+
+	compiled := &compiledRule{
+		rule:     rule,
+		template: tmpl,
+		timeFrom: -1,
+		timeTo:   -1,
+	}
+
+	from, to := rule.Conditions.TimeFrom, rule.Conditions.TimeTo
+	if from == "" && to == "" {
+		return compiled, nil
+	}
+	if compiled.timeFrom, err = parseClock(from); err != nil {
+		return nil, fmt.Errorf("invalid time_from: %v", err)
+	}
+	if compiled.timeTo, err = parseClock(to); err != nil {
+		return nil, fmt.Errorf("invalid time_to: %v", err)
+	}
+	if compiled.timeFrom == compiled.timeTo {
+		return nil, errors.New("time_from and time_to must differ")
+	}
+	return compiled, nil
+}
+
+// parseClock returns the minutes since midnight of an HH:MM time.
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an HH:MM time", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
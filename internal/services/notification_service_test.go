@@ -0,0 +1,242 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+func TestCompiledRuleMatches(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, jakarta)
+	}
+
+	entry := &events.Event{Type: events.Type("geofence.geofence_entry")}
+	overspeed := &events.Event{Type: events.Type("vehicle.vehicle_overspeed")}
+
+	terminal := func(hour, minute int) *notificationData {
+		return &notificationData{
+			VehicleID: "B1234ABC",
+			Time:      at(hour, minute),
+			Geofence: &notificationGeofence{
+				Name: "Blok M",
+				Type: "terminal",
+				Tags: []string{"corridor-1", "interchange"},
+			},
+			Vehicle: &models.Vehicle{
+				VehicleID:   "B1234ABC",
+				Operator:    "TJ",
+				Corridor:    "1",
+				VehicleType: "articulated",
+				Groups:      []string{"night"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		conditions models.RuleConditions
+		event      *events.Event
+		data       *notificationData
+		want       bool
+	}{
+		{
+			name:  "no conditions",
+			event: overspeed,
+			data:  &notificationData{Time: at(12, 0)},
+			want:  true,
+		},
+		{
+			name:       "exact event name",
+			conditions: models.RuleConditions{EventTypes: []string{"geofence.entry"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       true,
+		},
+		{
+			name:       "full event type",
+			conditions: models.RuleConditions{EventTypes: []string{entry.Type}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       true,
+		},
+		{
+			name:       "event type pattern",
+			conditions: models.RuleConditions{EventTypes: []string{"vehicle.*"}},
+			event:      overspeed,
+			data:       terminal(12, 0),
+			want:       true,
+		},
+		{
+			name:       "event type pattern of another category",
+			conditions: models.RuleConditions{EventTypes: []string{"vehicle.*"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       false,
+		},
+		{
+			name:       "pattern must match the whole name",
+			conditions: models.RuleConditions{EventTypes: []string{"geofence"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       false,
+		},
+		{
+			name:       "geofence tag",
+			conditions: models.RuleConditions{GeofenceTags: []string{"depot", "interchange"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       true,
+		},
+		{
+			name:       "geofence type mismatch",
+			conditions: models.RuleConditions{GeofenceTypes: []string{"depot"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       false,
+		},
+		{
+			name:       "geofence condition on a vehicle event",
+			conditions: models.RuleConditions{GeofenceNames: []string{"Blok M"}},
+			event:      overspeed,
+			data:       &notificationData{VehicleID: "B1234ABC", Time: at(12, 0)},
+			want:       false,
+		},
+		{
+			name:       "vehicle id",
+			conditions: models.RuleConditions{VehicleIDs: []string{"B5678DEF", "B1234ABC"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       true,
+		},
+		{
+			name: "vehicle attributes",
+			conditions: models.RuleConditions{
+				VehicleGroups: []string{"night"},
+				VehicleTypes:  []string{"articulated"},
+				Operators:     []string{"TJ"},
+				Corridors:     []string{"1"},
+			},
+			event: entry,
+			data:  terminal(12, 0),
+			want:  true,
+		},
+		{
+			name:       "vehicle corridor mismatch",
+			conditions: models.RuleConditions{Corridors: []string{"13"}},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       false,
+		},
+		{
+			name:       "unregistered vehicle",
+			conditions: models.RuleConditions{Operators: []string{"TJ"}},
+			event:      overspeed,
+			data:       &notificationData{VehicleID: "B9999XYZ", Time: at(12, 0)},
+			want:       false,
+		},
+		{
+			name:       "inside daytime window",
+			conditions: models.RuleConditions{TimeFrom: "06:00", TimeTo: "09:00"},
+			event:      entry,
+			data:       terminal(6, 0),
+			want:       true,
+		},
+		{
+			name:       "daytime window end is exclusive",
+			conditions: models.RuleConditions{TimeFrom: "06:00", TimeTo: "09:00"},
+			event:      entry,
+			data:       terminal(9, 0),
+			want:       false,
+		},
+		{
+			name:       "overnight window before midnight",
+			conditions: models.RuleConditions{TimeFrom: "22:00", TimeTo: "05:00"},
+			event:      entry,
+			data:       terminal(23, 30),
+			want:       true,
+		},
+		{
+			name:       "overnight window after midnight",
+			conditions: models.RuleConditions{TimeFrom: "22:00", TimeTo: "05:00"},
+			event:      entry,
+			data:       terminal(4, 59),
+			want:       true,
+		},
+		{
+			name:       "outside overnight window",
+			conditions: models.RuleConditions{TimeFrom: "22:00", TimeTo: "05:00"},
+			event:      entry,
+			data:       terminal(12, 0),
+			want:       false,
+		},
+		{
+			name:       "overnight window end is exclusive",
+			conditions: models.RuleConditions{TimeFrom: "22:00", TimeTo: "05:00"},
+			event:      entry,
+			data:       terminal(5, 0),
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := compileRule(&models.NotificationRule{
+				Name:       tt.name,
+				Template:   defaultNotificationTemplate,
+				Conditions: tt.conditions,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rule.matches(tt.event, tt.data); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRuleTimeWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom int
+		wantTo   int
+		wantErr  bool
+	}{
+		{name: "unbounded", wantFrom: -1, wantTo: -1},
+		{name: "daytime", from: "06:30", to: "09:00", wantFrom: 390, wantTo: 540},
+		{name: "overnight", from: "22:00", to: "05:00", wantFrom: 1320, wantTo: 300},
+		{name: "missing end", from: "22:00", wantErr: true},
+		{name: "not a clock", from: "10pm", to: "05:00", wantErr: true},
+		{name: "past midnight", from: "24:00", to: "05:00", wantErr: true},
+		{name: "empty window", from: "08:00", to: "08:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := compileRule(&models.NotificationRule{
+				Name:       tt.name,
+				Template:   defaultNotificationTemplate,
+				Conditions: models.RuleConditions{TimeFrom: tt.from, TimeTo: tt.to},
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("compileRule() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rule.timeFrom != tt.wantFrom || rule.timeTo != tt.wantTo {
+				t.Errorf("window = %d-%d, want %d-%d", rule.timeFrom, rule.timeTo, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// OutboxRelay publishes messages written to the outbox table. Each replica
+// may run one: pending rows are locked while they are published, so every
+// message is claimed by a single relay at a time.
+type OutboxRelay interface {
+	Start(ctx context.Context) error
+	// Notify wakes the relay after a message is queued, instead of waiting
+	// for the next poll.
+	Notify()
+	// Pending counts messages not yet published.
+	Pending(ctx context.Context) (int, error)
+}
+
+type outboxRelay struct {
+	outboxRepo repositories.OutboxRepository
+	publisher  *eventbus.Publisher
+	config     *config.OutboxConfig
+	logger     *zap.Logger
+	wake       chan struct{}
+}
+
+func NewOutboxRelay(outboxRepo repositories.OutboxRepository, publisher *eventbus.Publisher, cfg *config.OutboxConfig, logger *zap.Logger) OutboxRelay {
+	return &outboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		config:     cfg,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (s *outboxRelay) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	s.logger.Info("Outbox relay started",
+		zap.Duration("poll_interval", s.config.PollInterval),
+		zap.Int("batch_size", s.config.BatchSize))
+
+	// Publish what earlier runs left behind
+	s.relay(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Outbox relay stopping")
+			return nil
+		case <-ticker.C:
+			s.relay(ctx)
+		case <-s.wake:
+			s.relay(ctx)
+		case <-cleanup.C:
+			s.cleanup(ctx)
+		}
+	}
+}
+
+func (s *outboxRelay) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+func (s *outboxRelay) Pending(ctx context.Context) (int, error) {
+	return s.outboxRepo.CountPending(ctx)
+}
+
+// relay publishes due messages in batches until fewer than a full batch is
+// sent, which means the backlog is drained or the broker is failing.
+func (s *outboxRelay) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		sent, err := s.outboxRepo.ProcessPending(ctx, s.config.BatchSize, s.retryDelay, func(message *models.OutboxMessage) error {
+			return s.publish(ctx, message)
+		})
+		if err != nil {
+			s.logger.Error("Failed to relay outbox messages", zap.Error(err))
+			return
+		}
+		if sent > 0 {
+			s.logger.Debug("Outbox messages relayed", zap.Int("count", sent))
+		}
+		if sent < s.config.BatchSize {
+			return
+		}
+	}
+}
+
+// retryDelay is the delay before a message that failed to publish the
+// given number of times is tried again, doubling from RetryBackoff up to
+// MaxBackoff.
+func (s *outboxRelay) retryDelay(failed int) time.Duration {
+	return eventbus.RetryDelay(s.config.RetryBackoff, s.config.MaxBackoff, failed)
+}
+
+func (s *outboxRelay) publish(ctx context.Context, message *models.OutboxMessage) error {
+	// The payload is the event envelope, so its id is stable across attempts
+	// and replicas and consumers can recognize a message published twice
+	var event events.Event
+	if err := json.Unmarshal(message.Payload, &event); err != nil {
+		return fmt.Errorf("invalid outbox payload: %w", err)
+	}
+	if event.SpecVersion == "" {
+		// Queued before events were enveloped; the payload is the data
+		legacy, err := events.New(ctx, message.RoutingKey, "", message.CreatedAt, json.RawMessage(message.Payload))
+		if err != nil {
+			return err
+		}
+		legacy.ID = fmt.Sprintf("outbox-%d", message.ID)
+		event = *legacy
+	}
+
+	if err := s.publisher.PublishEvent(ctx, message.RoutingKey, &event, message.Mandatory); err != nil {
+		s.logger.Warn("Failed to publish outbox message",
+			zap.Error(err),
+			zap.Int64("outbox_id", message.ID),
+			zap.String("routing_key", message.RoutingKey),
+			zap.Int("attempts", message.Attempts+1))
+		return err
+	}
+	return nil
+}
+
+func (s *outboxRelay) cleanup(ctx context.Context) {
+	deleted, err := s.outboxRepo.DeleteSent(ctx, time.Now().Add(-s.config.Retention))
+	if err != nil {
+		s.logger.Error("Failed to clean up outbox", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("Sent outbox messages deleted", zap.Int64("count", deleted))
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// fakeOutboxRepository keeps messages in memory, marking them sent or
+// rescheduling them the way the outbox table does.
+type fakeOutboxRepository struct {
+	pending []*models.OutboxMessage
+	sent    []int64
+	// Delay each failed message was rescheduled with
+	delays map[int64]time.Duration
+	calls  int
+}
+
+func (r *fakeOutboxRepository) ProcessPending(ctx context.Context, limit int, backoff func(failed int) time.Duration, publish func(*models.OutboxMessage) error) (int, error) {
+	r.calls++
+	batch := r.pending[:min(limit, len(r.pending))]
+
+	sent := 0
+	for _, message := range batch {
+		if err := publish(message); err != nil {
+			r.delays[message.ID] = backoff(message.Attempts + 1)
+			message.Attempts++
+			break
+		}
+		r.sent = append(r.sent, message.ID)
+		sent++
+	}
+	r.pending = r.pending[sent:]
+	return sent, nil
+}
+
+func (r *fakeOutboxRepository) CountPending(ctx context.Context) (int, error) {
+	return len(r.pending), nil
+}
+
+func (r *fakeOutboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeBus confirms every publish except those of the event ids in fail.
+type fakeBus struct {
+	fail      map[string]bool
+	published []*events.Event
+	topics    []string
+}
+
+func (b *fakeBus) Publish(ctx context.Context, topic string, event *events.Event, mandatory bool) error {
+	if b.fail[event.ID] {
+		return errors.New("publish not confirmed")
+	}
+	b.published = append(b.published, event)
+	b.topics = append(b.topics, topic)
+	return nil
+}
+
+func (b *fakeBus) Subscribe(ctx context.Context, handler eventbus.Handler) error { return nil }
+func (b *fakeBus) IsConnected() bool                                             { return true }
+func (b *fakeBus) Close()                                                        {}
+
+func outboxMessages(t *testing.T) []*models.OutboxMessage {
+	t.Helper()
+
+	enveloped := func(id int64, eventID string) *models.OutboxMessage {
+		event, err := events.New(context.Background(), "geofence.geofence_entry", "B1234ABC", time.Unix(1760000000, 0), map[string]string{"vehicle_id": "B1234ABC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		event.ID = eventID
+		payload, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &models.OutboxMessage{ID: id, RoutingKey: "geofence.geofence_entry", Payload: payload, Mandatory: true}
+	}
+
+	return []*models.OutboxMessage{
+		enveloped(1, "event-1"),
+		// Queued before events were enveloped
+		{ID: 2, RoutingKey: "geofence.geofence_exit", Payload: []byte(`{"vehicle_id":"B1234ABC"}`), CreatedAt: time.Unix(1760000060, 0)},
+		enveloped(3, "event-3"),
+	}
+}
+
+func TestOutboxRelayRelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		fail        map[string]bool
+		wantSent    []int64
+		wantPending int
+		wantEvents  []string
+		// Reschedule delay per failed message
+		wantDelays map[int64]time.Duration
+		wantCalls  int
+	}{
+		{
+			name:       "confirmed messages are sent in batches",
+			wantSent:   []int64{1, 2, 3},
+			wantEvents: []string{"event-1", "outbox-2", "event-3"},
+			wantDelays: map[int64]time.Duration{},
+			// A full batch, then the rest
+			wantCalls: 2,
+		},
+		{
+			name:        "unconfirmed message stays pending and backs off",
+			fail:        map[string]bool{"event-3": true},
+			wantSent:    []int64{1, 2},
+			wantPending: 1,
+			wantEvents:  []string{"event-1", "outbox-2"},
+			wantDelays:  map[int64]time.Duration{3: 5 * time.Second},
+			wantCalls:   2,
+		},
+		{
+			name:        "failure holds back later messages",
+			fail:        map[string]bool{"event-1": true},
+			wantPending: 3,
+			wantDelays:  map[int64]time.Duration{1: 5 * time.Second},
+			wantCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOutboxRepository{pending: outboxMessages(t), delays: make(map[int64]time.Duration)}
+			bus := &fakeBus{fail: tt.fail}
+			relay := NewOutboxRelay(repo, eventbus.NewPublisher(bus, "/transjakarta-fleet", zap.NewNop()), &config.OutboxConfig{
+				BatchSize:    2,
+				RetryBackoff: 5 * time.Second,
+				MaxBackoff:   time.Minute,
+			}, zap.NewNop()).(*outboxRelay)
+
+			relay.relay(context.Background())
+
+			if !slices.Equal(repo.sent, tt.wantSent) {
+				t.Fatalf("sent %v, want %v", repo.sent, tt.wantSent)
+			}
+			if len(repo.pending) != tt.wantPending {
+				t.Errorf("%d pending, want %d", len(repo.pending), tt.wantPending)
+			}
+			if repo.calls != tt.wantCalls {
+				t.Errorf("%d batches, want %d", repo.calls, tt.wantCalls)
+			}
+
+			if len(bus.published) != len(tt.wantEvents) {
+				t.Fatalf("published %d events, want %d", len(bus.published), len(tt.wantEvents))
+			}
+			for i, event := range bus.published {
+				if event.ID != tt.wantEvents[i] {
+					t.Errorf("event %d id = %q, want %q", i, event.ID, tt.wantEvents[i])
+				}
+				if event.Source != "/transjakarta-fleet" {
+					t.Errorf("event %d source = %q", i, event.Source)
+				}
+			}
+
+			if len(repo.delays) != len(tt.wantDelays) {
+				t.Fatalf("rescheduled %v, want %v", repo.delays, tt.wantDelays)
+			}
+			for id, want := range tt.wantDelays {
+				if repo.delays[id] != want {
+					t.Errorf("message %d delay = %v, want %v", id, repo.delays[id], want)
+				}
+			}
+		})
+	}
+}
+
+func TestOutboxRelayLegacyPayload(t *testing.T) {
+	bus := &fakeBus{}
+	relay := NewOutboxRelay(&fakeOutboxRepository{}, eventbus.NewPublisher(bus, "/transjakarta-fleet", zap.NewNop()), &config.OutboxConfig{}, zap.NewNop()).(*outboxRelay)
+
+	message := outboxMessages(t)[1]
+	if err := relay.publish(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+
+	event := bus.published[0]
+	if event.Type != events.Type("geofence.geofence_exit") || bus.topics[0] != "geofence.geofence_exit" {
+		t.Errorf("type = %q on topic %q", event.Type, bus.topics[0])
+	}
+	if !event.Time.Equal(message.CreatedAt) {
+		t.Errorf("time = %v, want %v", event.Time, message.CreatedAt)
+	}
+	if string(event.Data) != string(message.Payload) {
+		t.Errorf("data = %s, want %s", event.Data, message.Payload)
+	}
+
+	// Not JSON: never published, so it stays pending for inspection
+	if err := relay.publish(context.Background(), &models.OutboxMessage{ID: 4, Payload: []byte("{")}); err == nil {
+		t.Error("invalid payload published")
+	}
+	if len(bus.published) != 1 {
+		t.Errorf("published %d events, want 1", len(bus.published))
+	}
+}
+
+func TestOutboxRelayRetryDelay(t *testing.T) {
+	relay := &outboxRelay{config: &config.OutboxConfig{RetryBackoff: 5 * time.Second, MaxBackoff: time.Minute}}
+
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{failed: 1, want: 5 * time.Second},
+		{failed: 2, want: 10 * time.Second},
+		{failed: 4, want: 40 * time.Second},
+		{failed: 5, want: time.Minute},
+		{failed: 30, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := relay.retryDelay(tt.failed); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.failed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// ScheduleAdherenceService compares stop arrivals against the GTFS schedule
+// of the trip a vehicle runs, either assigned explicitly or matched from its
+// recent stop visits.
+type ScheduleAdherenceService interface {
+	// Link fills the trip and schedule fields of a new stop visit and raises
+	// a schedule_deviation event when the delay crosses the threshold.
+	Link(ctx context.Context, visit *models.StopVisit, location *models.VehicleLocation) error
+	// GetTripAdherence returns the visits of one trip run with their delays.
+	// An empty start date defaults to today in the agency timezone.
+	GetTripAdherence(ctx context.Context, tripID, startDate string) (*models.OnTimePerformance, []*models.StopVisit, error)
+	GetRouteOnTimePerformance(ctx context.Context, routeID string, startTime, endTime int64) ([]*models.OnTimePerformance, error)
+	// MatchedTrips returns the trips currently matched to unassigned vehicles.
+	MatchedTrips() []*models.TripAssignment
+}
+
+type scheduleAdherenceService struct {
+	visitRepo      repositories.StopVisitRepository
+	assignmentRepo repositories.TripAssignmentRepository
+	vehicleRepo    repositories.VehicleRepository
+	publisher      *eventbus.Publisher
+	schedule       *scheduleCache
+	config         *config.AdherenceConfig
+	logger         *zap.Logger
+	mu             sync.Mutex
+	trips          map[string]*vehicleTrip      // by vehicle
+	lastVisits     map[string]*models.StopVisit // by vehicle
+}
+
+// vehicleTrip is the trip run a vehicle is currently on.
+type vehicleTrip struct {
+	tripID       string
+	routeID      string
+	directionID  int
+	startDate    string
+	matched      bool
+	stops        []*models.GTFSTripStop
+	dayStart     time.Time
+	lastSequence int
+	deviating    bool
+}
+
+// next returns the first scheduled call at stopID after the last visited stop.
+// Loop routes may serve the same stop more than once.
+func (t *vehicleTrip) next(stopID string) *models.GTFSTripStop {
+	for _, stop := range t.stops {
+		if stop.StopID == stopID && stop.StopSequence > t.lastSequence {
+			return stop
+		}
+	}
+	return nil
+}
+
+// apply sets the trip context and schedule of a visit at the given stop.
+func (t *vehicleTrip) apply(visit *models.StopVisit, stop *models.GTFSTripStop) {
+	directionID := t.directionID
+	visit.RouteID = t.routeID
+	visit.TripID = t.tripID
+	visit.DirectionID = &directionID
+	visit.StartDate = t.startDate
+	visit.TripMatched = t.matched
+
+	if stop == nil {
+		return
+	}
+
+	sequence := stop.StopSequence
+	scheduledArrival := t.dayStart.Unix() + int64(stop.ArrivalSeconds)
+	scheduledDeparture := t.dayStart.Unix() + int64(stop.DepartureSeconds)
+	delay := visit.ArrivalTime - scheduledArrival
+	visit.StopSequence = &sequence
+	visit.ScheduledArrival = &scheduledArrival
+	visit.ScheduledDeparture = &scheduledDeparture
+	visit.ArrivalDelay = &delay
+	t.lastSequence = sequence
+}
+
+func NewScheduleAdherenceService(
+	visitRepo repositories.StopVisitRepository,
+	assignmentRepo repositories.TripAssignmentRepository,
+	vehicleRepo repositories.VehicleRepository,
+	gtfsRepo repositories.GTFSRepository,
+	publisher *eventbus.Publisher,
+	cfg *config.AdherenceConfig,
+	logger *zap.Logger,
+) ScheduleAdherenceService {
+	return &scheduleAdherenceService{
+		visitRepo:      visitRepo,
+		assignmentRepo: assignmentRepo,
+		vehicleRepo:    vehicleRepo,
+		publisher:      publisher,
+		schedule:       newScheduleCache(gtfsRepo, logger),
+		config:         cfg,
+		logger:         logger,
+		trips:          make(map[string]*vehicleTrip),
+		lastVisits:     make(map[string]*models.StopVisit),
+	}
+}
+
+func (s *scheduleAdherenceService) Link(ctx context.Context, visit *models.StopVisit, location *models.VehicleLocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.lastVisits[visit.VehicleID]
+	s.lastVisits[visit.VehicleID] = visit
+
+	feed, timezone, err := s.schedule.active(ctx)
+	if err != nil || feed == nil {
+		return err
+	}
+
+	trip, err := s.currentTrip(ctx, visit)
+	if err != nil {
+		return err
+	}
+
+	// A matched trip is dropped once the vehicle leaves its stop pattern,
+	// typically because it started its next trip
+	if trip == nil || (trip.matched && trip.next(visit.StopID) == nil) {
+		delete(s.trips, visit.VehicleID)
+		trip, err = s.matchTrip(ctx, visit, previous, timezone)
+		if err != nil {
+			return err
+		}
+		if trip == nil {
+			return nil
+		}
+		s.trips[visit.VehicleID] = trip
+	}
+
+	stop := trip.next(visit.StopID)
+	trip.apply(visit, stop)
+	if stop == nil {
+		return nil
+	}
+
+	if trip.matched && stop == trip.stops[len(trip.stops)-1] {
+		delete(s.trips, visit.VehicleID)
+	}
+
+	s.checkDeviation(ctx, trip, visit, location)
+	return nil
+}
+
+// currentTrip returns the vehicle's assigned trip run, or its matched trip
+// while that is still scheduled to be running.
+func (s *scheduleAdherenceService) currentTrip(ctx context.Context, visit *models.StopVisit) (*vehicleTrip, error) {
+	state := s.trips[visit.VehicleID]
+
+	assignment, err := s.assignmentRepo.GetByVehicleID(ctx, visit.VehicleID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		if state == nil || !state.matched {
+			return nil, nil
+		}
+		end := state.dayStart.Unix() + int64(state.stops[len(state.stops)-1].ArrivalSeconds)
+		if visit.ArrivalTime > end+int64(s.config.MatchMaxGap.Seconds()) {
+			return nil, nil
+		}
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil && !state.matched && state.tripID == assignment.TripID && state.startDate == assignment.StartDate {
+		return state, nil
+	}
+
+	state = &vehicleTrip{
+		tripID:       assignment.TripID,
+		routeID:      assignment.RouteID,
+		directionID:  assignment.DirectionID,
+		startDate:    assignment.StartDate,
+		lastSequence: -1,
+	}
+	s.trips[visit.VehicleID] = state
+
+	stops, err := s.schedule.stops(ctx, assignment.TripID)
+	if err != nil {
+		return nil, err
+	}
+	_, timezone, err := s.schedule.active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dayStart, err := serviceDayStart(assignment.StartDate, timezone)
+	if err != nil {
+		// Keep the trip context; without a valid date there is no schedule
+		return state, nil
+	}
+
+	state.stops = stops
+	state.dayStart = dayStart
+	return state, nil
+}
+
+// matchTrip infers the trip of an unassigned vehicle from its last two stop
+// visits. Candidates are trips scheduled at the current stop within the match
+// window that serve the previous stop earlier in their pattern and are not run
+// by another vehicle. The candidate with the smallest delay, and the most
+// consistent delay between both stops, wins. A matched trip also links an
+// unlinked previous visit retroactively.
+func (s *scheduleAdherenceService) matchTrip(ctx context.Context, visit, previous *models.StopVisit, timezone *time.Location) (*vehicleTrip, error) {
+	if previous == nil || previous.StopID == visit.StopID ||
+		visit.ArrivalTime-previous.ArrivalTime > int64(s.config.MatchMaxGap.Seconds()) {
+		return nil, nil
+	}
+
+	claimed, err := s.claimedTrips(ctx, visit.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	corridor := s.vehicleCorridor(ctx, visit.VehicleID)
+	window := int(s.config.MatchWindow.Seconds())
+	arrival := time.Unix(visit.ArrivalTime, 0).In(timezone)
+
+	var best *vehicleTrip
+	var bestPrevious *models.GTFSTripStop
+	bestScore := int64(-1)
+
+	// Trips running past midnight belong to the previous service date
+	for daysBack := 0; daysBack <= 1; daysBack++ {
+		date := arrival.AddDate(0, 0, -daysBack).Format(gtfsDateLayout)
+		dayStart, err := serviceDayStart(date, timezone)
+		if err != nil {
+			return nil, err
+		}
+		seconds := int(visit.ArrivalTime - dayStart.Unix())
+
+		calls, err := s.schedule.calls(ctx, date, visit.StopID, seconds-window, seconds+window)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, call := range calls {
+			if claimed[call.TripID+"/"+date] {
+				continue
+			}
+
+			stops, err := s.schedule.stops(ctx, call.TripID)
+			if err != nil {
+				return nil, err
+			}
+			var previousStop *models.GTFSTripStop
+			for _, stop := range stops {
+				if stop.StopSequence >= call.StopSequence {
+					break
+				}
+				if stop.StopID == previous.StopID {
+					previousStop = stop
+				}
+			}
+			if previousStop == nil {
+				continue
+			}
+
+			delay := visit.ArrivalTime - (dayStart.Unix() + int64(call.ArrivalSeconds))
+			previousDelay := previous.ArrivalTime - (dayStart.Unix() + int64(previousStop.ArrivalSeconds))
+			drift := abs64(delay - previousDelay)
+			if drift > int64(window) {
+				continue
+			}
+
+			score := abs64(delay) + 2*drift
+			if corridor != "" && call.RouteID != corridor && call.RouteShortName != corridor {
+				score += int64(window)
+			}
+			if bestScore >= 0 && score >= bestScore {
+				continue
+			}
+
+			bestScore = score
+			bestPrevious = previousStop
+			best = &vehicleTrip{
+				tripID:       call.TripID,
+				routeID:      call.RouteID,
+				directionID:  call.DirectionID,
+				startDate:    date,
+				matched:      true,
+				stops:        stops,
+				dayStart:     dayStart,
+				lastSequence: -1,
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, nil
+	}
+
+	// A previous visit already linked, such as the terminal where the last
+	// trip ended, keeps its trip
+	if previous.TripID == "" {
+		best.apply(previous, bestPrevious)
+		if previous.ID != 0 {
+			if err := s.visitRepo.LinkTrip(ctx, previous); err != nil {
+				return nil, err
+			}
+		}
+	}
+	best.lastSequence = bestPrevious.StopSequence
+
+	s.logger.Info("Vehicle matched to trip",
+		zap.String("vehicle_id", visit.VehicleID),
+		zap.String("trip_id", best.tripID),
+		zap.String("route_id", best.routeID),
+		zap.String("start_date", best.startDate))
+
+	return best, nil
+}
+
+// claimedTrips returns the trip runs, keyed trip_id/start_date, assigned to or
+// matched by vehicles other than vehicleID.
+func (s *scheduleAdherenceService) claimedTrips(ctx context.Context, vehicleID string) (map[string]bool, error) {
+	assignments, err := s.assignmentRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make(map[string]bool)
+	for _, assignment := range assignments {
+		if assignment.VehicleID != vehicleID {
+			claimed[assignment.TripID+"/"+assignment.StartDate] = true
+		}
+	}
+	for id, trip := range s.trips {
+		if id != vehicleID {
+			claimed[trip.tripID+"/"+trip.startDate] = true
+		}
+	}
+
+	return claimed, nil
+}
+
+// vehicleCorridor returns the corridor a registered vehicle serves, used to
+// prefer trips of that route when matching.
+func (s *scheduleAdherenceService) vehicleCorridor(ctx context.Context, vehicleID string) string {
+	vehicle, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return ""
+	}
+	return vehicle.Corridor
+}
+
+func (s *scheduleAdherenceService) checkDeviation(ctx context.Context, trip *vehicleTrip, visit *models.StopVisit, location *models.VehicleLocation) {
+	delay := *visit.ArrivalDelay
+	exceeded := abs64(delay) >= int64(s.config.DeviationThreshold.Seconds())
+	wasDeviating := trip.deviating
+	trip.deviating = exceeded
+
+	// Publish once when the threshold is crossed, not at every stop after
+	if !exceeded || wasDeviating {
+		return
+	}
+
+	status := "late"
+	if delay < 0 {
+		status = "early"
+	}
+
+	event := &events.VehicleEventMessage{
+		VehicleID: visit.VehicleID,
+		Event:     "schedule_deviation",
+		Location: events.Location{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+		},
+		Timestamp: visit.ArrivalTime,
+		Data: map[string]interface{}{
+			"trip_id":           visit.TripID,
+			"route_id":          visit.RouteID,
+			"start_date":        visit.StartDate,
+			"trip_matched":      visit.TripMatched,
+			"stop_id":           visit.StopID,
+			"stop_sequence":     *visit.StopSequence,
+			"scheduled_arrival": *visit.ScheduledArrival,
+			"delay_seconds":     delay,
+			"status":            status,
+		},
+	}
+
+	if err := s.publisher.PublishVehicleEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to publish schedule deviation event to RabbitMQ",
+			zap.Error(err),
+			zap.String("vehicle_id", visit.VehicleID))
+		return
+	}
+
+	s.logger.Info("Vehicle deviating from schedule",
+		zap.String("vehicle_id", visit.VehicleID),
+		zap.String("trip_id", visit.TripID),
+		zap.Int64("delay_seconds", delay))
+}
+
+func (s *scheduleAdherenceService) GetTripAdherence(ctx context.Context, tripID, startDate string) (*models.OnTimePerformance, []*models.StopVisit, error) {
+	if tripID == "" {
+		return nil, nil, fmt.Errorf("trip_id is required")
+	}
+
+	if startDate == "" {
+		_, timezone, err := s.schedule.active(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if timezone == nil {
+			timezone = time.Local
+		}
+		startDate = time.Now().In(timezone).Format(gtfsDateLayout)
+	}
+
+	visits, err := s.visitRepo.GetByTrip(ctx, tripID, startDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	performance := &models.OnTimePerformance{TripID: tripID, StartDate: startDate}
+	early := -int64(s.config.EarlyTolerance.Seconds())
+	late := int64(s.config.LateTolerance.Seconds())
+	var totalDelay int64
+	for _, visit := range visits {
+		if performance.RouteID == "" {
+			performance.RouteID = visit.RouteID
+		}
+		if visit.ArrivalDelay == nil {
+			continue
+		}
+
+		delay := *visit.ArrivalDelay
+		performance.Observations++
+		totalDelay += delay
+		switch {
+		case delay < early:
+			performance.Early++
+		case delay > late:
+			performance.Late++
+		default:
+			performance.OnTime++
+		}
+	}
+
+	if performance.Observations > 0 {
+		performance.OnTimePercent = 100 * float64(performance.OnTime) / float64(performance.Observations)
+		performance.AvgDelaySeconds = float64(totalDelay) / float64(performance.Observations)
+	}
+
+	return performance, visits, nil
+}
+
+func (s *scheduleAdherenceService) GetRouteOnTimePerformance(ctx context.Context, routeID string, startTime, endTime int64) ([]*models.OnTimePerformance, error) {
+	return s.visitRepo.GetOnTimePerformance(ctx, routeID, startTime, endTime,
+		int64(s.config.EarlyTolerance.Seconds()), int64(s.config.LateTolerance.Seconds()))
+}
+
+func (s *scheduleAdherenceService) MatchedTrips() []*models.TripAssignment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var trips []*models.TripAssignment
+	for vehicleID, trip := range s.trips {
+		if !trip.matched {
+			continue
+		}
+		trips = append(trips, &models.TripAssignment{
+			VehicleID:   vehicleID,
+			TripID:      trip.tripID,
+			RouteID:     trip.routeID,
+			DirectionID: trip.directionID,
+			StartDate:   trip.startDate,
+		})
+	}
+
+	return trips
+}
+
+// vehicleTrips returns the trip each vehicle runs, by vehicle; explicit
+// assignments take precedence over trips matched by schedule adherence.
+func vehicleTrips(ctx context.Context, assignmentRepo repositories.TripAssignmentRepository, adherence ScheduleAdherenceService) (map[string]*models.TripAssignment, error) {
+	assignments, err := assignmentRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trips := make(map[string]*models.TripAssignment)
+	for _, trip := range adherence.MatchedTrips() {
+		trips[trip.VehicleID] = trip
+	}
+	for _, assignment := range assignments {
+		trips[assignment.VehicleID] = assignment
+	}
+
+	return trips, nil
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
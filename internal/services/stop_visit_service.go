@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/stopvisit"
+)
+
+type StopVisitService interface {
+	LocationProcessor
+	GetStopVisits(ctx context.Context, stopID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error)
+	GetVehicleStopVisits(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error)
+	GetStopDwell(ctx context.Context, stopID string, startTime, endTime int64) (*models.StopDwellStats, error)
+	GetDwellReport(ctx context.Context, routeID string, startTime, endTime int64, limit int) ([]*models.StopDwellStats, error)
+}
+
+type stopVisitService struct {
+	visitRepo    repositories.StopVisitRepository
+	geofenceRepo repositories.GeofenceRepository
+	adherence    ScheduleAdherenceService
+	detector     *stopvisit.Detector
+	logger       *zap.Logger
+	mu           sync.Mutex
+	stops        *geofence.Index
+	lastUpdated  time.Time
+	openVisits   map[string]*models.StopVisit // by vehicle
+}
+
+func NewStopVisitService(
+	visitRepo repositories.StopVisitRepository,
+	geofenceRepo repositories.GeofenceRepository,
+	adherence ScheduleAdherenceService,
+	cfg *config.GTFSConfig,
+	logger *zap.Logger,
+) StopVisitService {
+	return &stopVisitService{
+		visitRepo:    visitRepo,
+		geofenceRepo: geofenceRepo,
+		adherence:    adherence,
+		detector:     stopvisit.NewDetector(cfg.StopExitMargin),
+		logger:       logger,
+		openVisits:   make(map[string]*models.StopVisit),
+	}
+}
+
+func (s *stopVisitService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	stops, err := s.stopIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := s.detector.Process(location, stops)
+	if result == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if result.Departed != nil {
+		if open := s.openVisits[location.VehicleID]; open != nil {
+			delete(s.openVisits, location.VehicleID)
+			if err := s.visitRepo.Depart(ctx, open.ID, result.Departed.LastSeen); err != nil {
+				return fmt.Errorf("failed to save stop departure: %w", err)
+			}
+
+			s.logger.Debug("Vehicle departed stop",
+				zap.String("vehicle_id", location.VehicleID),
+				zap.String("stop_id", open.StopID),
+				zap.Int64("dwell_seconds", result.Departed.LastSeen-open.ArrivalTime))
+		}
+	}
+
+	if result.Arrived != nil {
+		visit := &models.StopVisit{
+			VehicleID:   location.VehicleID,
+			StopID:      result.Arrived.Stop.ExternalID,
+			StopName:    result.Arrived.Stop.Name,
+			ArrivalTime: result.Arrived.ArrivalTime,
+		}
+		if err := s.adherence.Link(ctx, visit, location); err != nil {
+			// Still record the visit, just without trip context
+			s.logger.Warn("Failed to link stop visit to trip",
+				zap.Error(err),
+				zap.String("vehicle_id", location.VehicleID))
+		}
+
+		if err := s.visitRepo.Arrive(ctx, visit); err != nil {
+			return fmt.Errorf("failed to save stop arrival: %w", err)
+		}
+		s.openVisits[location.VehicleID] = visit
+
+		s.logger.Debug("Vehicle arrived at stop",
+			zap.String("vehicle_id", location.VehicleID),
+			zap.String("stop_id", visit.StopID),
+			zap.String("trip_id", visit.TripID))
+	}
+
+	return nil
+}
+
+func (s *stopVisitService) stopIndex(ctx context.Context) (*geofence.Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Refresh stop cache if needed (every 5 minutes)
+	if s.stops == nil || time.Since(s.lastUpdated) > 5*time.Minute {
+		geofences, err := s.geofenceRepo.GetAll(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stop geofences: %w", err)
+		}
+
+		var stops []*models.Geofence
+		for _, g := range geofences {
+			if g.Type == models.GeofenceTypeStop {
+				stops = append(stops, g)
+			}
+		}
+		s.stops = geofence.NewIndex(stops)
+		s.lastUpdated = time.Now()
+	}
+
+	return s.stops, nil
+}
+
+func (s *stopVisitService) GetStopVisits(ctx context.Context, stopID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error) {
+	if stopID == "" {
+		return nil, fmt.Errorf("stop_id is required")
+	}
+
+	return s.visitRepo.GetByStopID(ctx, stopID, startTime, endTime, limit)
+}
+
+func (s *stopVisitService) GetVehicleStopVisits(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.StopVisit, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required")
+	}
+
+	return s.visitRepo.GetByVehicleID(ctx, vehicleID, startTime, endTime, limit)
+}
+
+func (s *stopVisitService) GetStopDwell(ctx context.Context, stopID string, startTime, endTime int64) (*models.StopDwellStats, error) {
+	if stopID == "" {
+		return nil, fmt.Errorf("stop_id is required")
+	}
+
+	stats, err := s.visitRepo.GetDwellStats(ctx, stopID, "", startTime, endTime, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return &models.StopDwellStats{StopID: stopID}, nil
+	}
+
+	return stats[0], nil
+}
+
+func (s *stopVisitService) GetDwellReport(ctx context.Context, routeID string, startTime, endTime int64, limit int) ([]*models.StopDwellStats, error) {
+	return s.visitRepo.GetDwellStats(ctx, "", routeID, startTime, endTime, limit)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// ErrInvalidAssignment is returned when a trip assignment fails validation.
+var ErrInvalidAssignment = errors.New("invalid trip assignment")
+
+type TripAssignmentService interface {
+	// Assign links a vehicle to a trip of the active GTFS feed. An empty
+	// start date defaults to today in the agency timezone.
+	Assign(ctx context.Context, vehicleID, tripID, startDate string) (*models.TripAssignment, error)
+	GetAssignment(ctx context.Context, vehicleID string) (*models.TripAssignment, error)
+	GetAssignments(ctx context.Context) ([]*models.TripAssignment, error)
+	Unassign(ctx context.Context, vehicleID string) error
+}
+
+type tripAssignmentService struct {
+	assignmentRepo repositories.TripAssignmentRepository
+	schedule       *scheduleCache
+	logger         *zap.Logger
+}
+
+func NewTripAssignmentService(
+	assignmentRepo repositories.TripAssignmentRepository,
+	gtfsRepo repositories.GTFSRepository,
+	logger *zap.Logger,
+) TripAssignmentService {
+	return &tripAssignmentService{
+		assignmentRepo: assignmentRepo,
+		schedule:       newScheduleCache(gtfsRepo, logger),
+		logger:         logger,
+	}
+}
+
+func (s *tripAssignmentService) Assign(ctx context.Context, vehicleID, tripID, startDate string) (*models.TripAssignment, error) {
+	tripID = strings.TrimSpace(tripID)
+	if vehicleID == "" || tripID == "" {
+		return nil, fmt.Errorf("vehicle_id and trip_id are required: %w", ErrInvalidAssignment)
+	}
+
+	feed, location, err := s.schedule.active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, fmt.Errorf("no active GTFS feed: %w", ErrInvalidAssignment)
+	}
+
+	trip, err := s.schedule.trip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip == nil {
+		return nil, fmt.Errorf("trip %s not in active GTFS feed: %w", tripID, ErrInvalidAssignment)
+	}
+
+	if startDate == "" {
+		startDate = time.Now().In(location).Format(gtfsDateLayout)
+	}
+	if _, err := time.Parse(gtfsDateLayout, startDate); err != nil {
+		return nil, fmt.Errorf("start_date must be YYYYMMDD: %w", ErrInvalidAssignment)
+	}
+
+	assignment := &models.TripAssignment{
+		VehicleID:   vehicleID,
+		TripID:      trip.TripID,
+		RouteID:     trip.RouteID,
+		DirectionID: trip.DirectionID,
+		StartDate:   startDate,
+	}
+	if err := s.assignmentRepo.Upsert(ctx, assignment); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Vehicle assigned to trip",
+		zap.String("vehicle_id", vehicleID),
+		zap.String("trip_id", trip.TripID),
+		zap.String("route_id", trip.RouteID),
+		zap.String("start_date", startDate))
+
+	return assignment, nil
+}
+
+func (s *tripAssignmentService) GetAssignment(ctx context.Context, vehicleID string) (*models.TripAssignment, error) {
+	return s.assignmentRepo.GetByVehicleID(ctx, vehicleID)
+}
+
+func (s *tripAssignmentService) GetAssignments(ctx context.Context) ([]*models.TripAssignment, error) {
+	return s.assignmentRepo.GetAll(ctx)
+}
+
+func (s *tripAssignmentService) Unassign(ctx context.Context, vehicleID string) error {
+	if err := s.assignmentRepo.Delete(ctx, vehicleID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Vehicle trip assignment removed", zap.String("vehicle_id", vehicleID))
+	return nil
+}
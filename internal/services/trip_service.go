@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/trips"
+)
+
+type TripService interface {
+	LocationProcessor
+	GetTrips(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.Trip, error)
+	Backfill(ctx context.Context, vehicleID string, startTime, endTime int64) (int, error)
+}
+
+type tripService struct {
+	tripRepo            repositories.TripRepository
+	vehicleLocationRepo repositories.VehicleLocationRepository
+	segmenter           *trips.Segmenter
+	config              *config.TripsConfig
+	logger              *zap.Logger
+}
+
+func NewTripService(
+	tripRepo repositories.TripRepository,
+	vehicleLocationRepo repositories.VehicleLocationRepository,
+	cfg *config.TripsConfig,
+	logger *zap.Logger,
+) TripService {
+	return &tripService{
+		tripRepo:            tripRepo,
+		vehicleLocationRepo: vehicleLocationRepo,
+		segmenter:           trips.NewSegmenter(cfg.StopRadius, cfg.StopMinDuration),
+		config:              cfg,
+		logger:              logger,
+	}
+}
+
+func (s *tripService) ProcessLocation(ctx context.Context, location *models.VehicleLocation) error {
+	result := s.segmenter.Process(location)
+	if result == nil {
+		return nil
+	}
+
+	return s.saveResult(ctx, result)
+}
+
+func (s *tripService) GetTrips(ctx context.Context, vehicleID string, startTime, endTime int64, limit int) ([]*models.Trip, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required")
+	}
+
+	if startTime > endTime {
+		return nil, fmt.Errorf("start_time must be less than end_time")
+	}
+
+	results, err := s.tripRepo.GetTripsByVehicleID(ctx, vehicleID, startTime, endTime, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trips: %w", err)
+	}
+
+	return results, nil
+}
+
+// Backfill re-segments stored locations of a vehicle within the time range,
+// replacing any trips and stops previously recorded there. It returns the
+// number of trips created.
+func (s *tripService) Backfill(ctx context.Context, vehicleID string, startTime, endTime int64) (int, error) {
+	if vehicleID == "" {
+		return 0, fmt.Errorf("vehicle_id is required")
+	}
+
+	locations, err := s.vehicleLocationRepo.GetHistoryByVehicleID(ctx, vehicleID, startTime, endTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load location history: %w", err)
+	}
+
+	if err := s.tripRepo.DeleteByVehicleID(ctx, vehicleID, startTime, endTime); err != nil {
+		return 0, fmt.Errorf("failed to clear previous trips: %w", err)
+	}
+
+	// Use a dedicated segmenter so live state is left untouched
+	segmenter := trips.NewSegmenter(s.config.StopRadius, s.config.StopMinDuration)
+
+	tripCount := 0
+	// History is returned newest first
+	for i := len(locations) - 1; i >= 0; i-- {
+		result := segmenter.Process(locations[i])
+		if result == nil {
+			continue
+		}
+
+		if err := s.saveResult(ctx, result); err != nil {
+			return tripCount, err
+		}
+		if result.Trip != nil {
+			tripCount++
+		}
+	}
+
+	s.logger.Info("Trip backfill completed",
+		zap.String("vehicle_id", vehicleID),
+		zap.Int("locations", len(locations)),
+		zap.Int("trips", tripCount))
+
+	return tripCount, nil
+}
+
+func (s *tripService) saveResult(ctx context.Context, result *trips.Result) error {
+	if result.Stop != nil {
+		if err := s.tripRepo.CreateStop(ctx, result.Stop); err != nil {
+			return fmt.Errorf("failed to save stop: %w", err)
+		}
+	}
+
+	if result.Trip != nil {
+		if err := s.tripRepo.CreateTrip(ctx, result.Trip); err != nil {
+			return fmt.Errorf("failed to save trip: %w", err)
+		}
+
+		s.logger.Info("Trip recorded",
+			zap.String("vehicle_id", result.Trip.VehicleID),
+			zap.Float64("distance_meters", result.Trip.DistanceMeters),
+			zap.Int64("duration_seconds", result.Trip.DurationSeconds))
+	}
+
+	return nil
+}
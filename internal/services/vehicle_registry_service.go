@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// Policies for locations reported by vehicles missing from the registry
+const (
+	UnknownVehicleAllow      = "allow"
+	UnknownVehicleReject     = "reject"
+	UnknownVehicleQuarantine = "quarantine"
+)
+
+// ErrInvalidVehicle is returned when vehicle input fails validation.
+var ErrInvalidVehicle = errors.New("invalid vehicle")
+
+type VehicleRegistryService interface {
+	CreateVehicle(ctx context.Context, vehicle *models.Vehicle) error
+	GetVehicle(ctx context.Context, vehicleID string) (*models.Vehicle, error)
+	ListVehicles(ctx context.Context) ([]*models.Vehicle, error)
+	UpdateVehicle(ctx context.Context, vehicle *models.Vehicle) error
+	DeleteVehicle(ctx context.Context, vehicleID string) error
+	// AdmitLocation applies the unknown-vehicle policy to an incoming location.
+	// It returns false when the location must not be ingested.
+	AdmitLocation(ctx context.Context, location *models.VehicleLocation) (bool, error)
+	GetQuarantinedLocations(ctx context.Context, limit int) ([]*models.QuarantinedLocation, error)
+	// VehicleType returns the registered type of a vehicle, or "" when it is
+	// not registered or the registry cannot be read.
+	VehicleType(ctx context.Context, vehicleID string) string
+	// LookupVehicle returns a registered vehicle from the cache, or nil when
+	// it is not registered or the registry cannot be read.
+	LookupVehicle(ctx context.Context, vehicleID string) *models.Vehicle
+}
+
+type vehicleRegistryService struct {
+	vehicleRepo    repositories.VehicleRepository
+	quarantineRepo repositories.QuarantineRepository
+	config         *config.RegistryConfig
+	logger         *zap.Logger
+	mu             sync.RWMutex
+	cache          map[string]*models.Vehicle
+	lastUpdated    time.Time
+}
+
+func NewVehicleRegistryService(
+	vehicleRepo repositories.VehicleRepository,
+	quarantineRepo repositories.QuarantineRepository,
+	cfg *config.RegistryConfig,
+	logger *zap.Logger,
+) VehicleRegistryService {
+	switch cfg.UnknownVehiclePolicy {
+	case UnknownVehicleAllow, UnknownVehicleReject, UnknownVehicleQuarantine:
+	default:
+		logger.Warn("Unknown vehicle policy not recognized, allowing all vehicles",
+			zap.String("policy", cfg.UnknownVehiclePolicy))
+		cfg.UnknownVehiclePolicy = UnknownVehicleAllow
+	}
+
+	return &vehicleRegistryService{
+		vehicleRepo:    vehicleRepo,
+		quarantineRepo: quarantineRepo,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+func (s *vehicleRegistryService) CreateVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+
+	if err := s.vehicleRepo.Create(ctx, vehicle); err != nil {
+		return err
+	}
+
+	s.invalidateCache()
+	s.logger.Info("Vehicle registered", zap.String("vehicle_id", vehicle.VehicleID))
+	return nil
+}
+
+func (s *vehicleRegistryService) GetVehicle(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	if vehicleID == "" {
+		return nil, fmt.Errorf("vehicle_id is required: %w", ErrInvalidVehicle)
+	}
+
+	return s.vehicleRepo.GetByID(ctx, vehicleID)
+}
+
+func (s *vehicleRegistryService) ListVehicles(ctx context.Context) ([]*models.Vehicle, error) {
+	return s.vehicleRepo.GetAll(ctx)
+}
+
+func (s *vehicleRegistryService) UpdateVehicle(ctx context.Context, vehicle *models.Vehicle) error {
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+
+	if err := s.vehicleRepo.Update(ctx, vehicle); err != nil {
+		return err
+	}
+
+	s.invalidateCache()
+	s.logger.Info("Vehicle updated", zap.String("vehicle_id", vehicle.VehicleID))
+	return nil
+}
+
+func (s *vehicleRegistryService) DeleteVehicle(ctx context.Context, vehicleID string) error {
+	if err := s.vehicleRepo.Delete(ctx, vehicleID); err != nil {
+		return err
+	}
+
+	s.invalidateCache()
+	s.logger.Info("Vehicle deleted", zap.String("vehicle_id", vehicleID))
+	return nil
+}
+
+func (s *vehicleRegistryService) AdmitLocation(ctx context.Context, location *models.VehicleLocation) (bool, error) {
+	if s.config.UnknownVehiclePolicy == UnknownVehicleAllow {
+		return true, nil
+	}
+
+	vehicle, err := s.lookup(ctx, location.VehicleID)
+	if err != nil {
+		return false, err
+	}
+
+	reason := ""
+	switch {
+	case vehicle == nil:
+		reason = "unknown_vehicle"
+	case !vehicle.Active:
+		reason = "inactive_vehicle"
+	default:
+		return true, nil
+	}
+
+	if s.config.UnknownVehiclePolicy == UnknownVehicleQuarantine {
+		quarantined := &models.QuarantinedLocation{
+			VehicleID: location.VehicleID,
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Timestamp: location.Timestamp,
+			Reason:    reason,
+		}
+		if err := s.quarantineRepo.Create(ctx, quarantined); err != nil {
+			return false, err
+		}
+	}
+
+	s.logger.Warn("Location from unregistered vehicle not ingested",
+		zap.String("vehicle_id", location.VehicleID),
+		zap.String("reason", reason),
+		zap.String("policy", s.config.UnknownVehiclePolicy))
+
+	return false, nil
+}
+
+func (s *vehicleRegistryService) GetQuarantinedLocations(ctx context.Context, limit int) ([]*models.QuarantinedLocation, error) {
+	return s.quarantineRepo.GetRecent(ctx, limit)
+}
+
+func (s *vehicleRegistryService) VehicleType(ctx context.Context, vehicleID string) string {
+	vehicle, err := s.lookup(ctx, vehicleID)
+	if err != nil {
+		s.logger.Warn("Failed to look up vehicle type", zap.Error(err), zap.String("vehicle_id", vehicleID))
+		return ""
+	}
+	if vehicle == nil {
+		return ""
+	}
+	return vehicle.VehicleType
+}
+
+func (s *vehicleRegistryService) LookupVehicle(ctx context.Context, vehicleID string) *models.Vehicle {
+	vehicle, err := s.lookup(ctx, vehicleID)
+	if err != nil {
+		s.logger.Warn("Failed to look up vehicle", zap.Error(err), zap.String("vehicle_id", vehicleID))
+		return nil
+	}
+	return vehicle
+}
+
+// lookup returns the registered vehicle or nil, using a cache refreshed
+// every CacheTTL or whenever the registry changes.
+func (s *vehicleRegistryService) lookup(ctx context.Context, vehicleID string) (*models.Vehicle, error) {
+	s.mu.RLock()
+	fresh := s.cache != nil && time.Since(s.lastUpdated) < s.config.CacheTTL
+	if fresh {
+		vehicle := s.cache[vehicleID]
+		s.mu.RUnlock()
+		return vehicle, nil
+	}
+	s.mu.RUnlock()
+
+	vehicles, err := s.vehicleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh vehicle registry: %w", err)
+	}
+
+	cache := make(map[string]*models.Vehicle, len(vehicles))
+	for _, vehicle := range vehicles {
+		cache[vehicle.VehicleID] = vehicle
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.lastUpdated = time.Now()
+	s.mu.Unlock()
+
+	return cache[vehicleID], nil
+}
+
+func (s *vehicleRegistryService) invalidateCache() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+func validateVehicle(vehicle *models.Vehicle) error {
+	vehicle.VehicleID = strings.TrimSpace(vehicle.VehicleID)
+	vehicle.PlateNumber = strings.TrimSpace(vehicle.PlateNumber)
+
+	if vehicle.VehicleID == "" {
+		return fmt.Errorf("vehicle_id is required: %w", ErrInvalidVehicle)
+	}
+
+	if vehicle.PlateNumber == "" {
+		return fmt.Errorf("plate_number is required: %w", ErrInvalidVehicle)
+	}
+
+	switch vehicle.VehicleType {
+	case models.VehicleTypeArticulated, models.VehicleTypeMaxi, models.VehicleTypeEBus, models.VehicleTypeMikrotrans:
+	default:
+		return fmt.Errorf("vehicle_type must be one of articulated, maxi, e-bus, mikrotrans: %w", ErrInvalidVehicle)
+	}
+
+	if vehicle.Capacity <= 0 {
+		return fmt.Errorf("capacity must be positive: %w", ErrInvalidVehicle)
+	}
+
+	groups, err := normalizeLabels(vehicle.Groups)
+	if err != nil {
+		return fmt.Errorf("groups: %v: %w", err, ErrInvalidVehicle)
+	}
+	vehicle.Groups = groups
+
+	return nil
+}
+
+// normalizeLabels trims and deduplicates group and tag labels, keeping their
+// order. Labels are matched exactly, so blank ones are rejected.
+func normalizeLabels(labels []string) ([]string, error) {
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return nil, errors.New("labels must not be blank")
+		}
+		if len(label) > 50 {
+			return nil, fmt.Errorf("label %q is longer than 50 characters", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
+}
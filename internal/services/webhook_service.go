@@ -0,0 +1,408 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+)
+
+// Headers of webhook requests. The signature is the hex HMAC-SHA256, keyed
+// with the subscription secret, of the timestamp, a dot and the body.
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookEventHeader     = "X-Webhook-Event"
+)
+
+// ErrInvalidWebhook is returned when a subscription fails validation.
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// WebhookService manages webhook subscriptions and sends them the events
+// they subscribe to, at least once each.
+type WebhookService interface {
+	// CreateSubscription registers an endpoint, generating a secret when
+	// none is given. The secret is only returned here and on rotation.
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+	GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*models.WebhookDelivery, error)
+	// Enqueue queues an event for every enabled subscription whose filter
+	// matches it. Queueing the same event again has no effect.
+	Enqueue(ctx context.Context, event *events.Event) error
+	// Start sends due deliveries until the context ends. Each worker may
+	// run one: deliveries are claimed by a single sender at a time.
+	Start(ctx context.Context) error
+}
+
+type webhookService struct {
+	webhookRepo repositories.WebhookRepository
+	config      *config.WebhooksConfig
+	client      *http.Client
+	logger      *zap.Logger
+}
+
+func NewWebhookService(webhookRepo repositories.WebhookRepository, cfg *config.WebhooksConfig, logger *zap.Logger) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		config:      cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect counts as a failure; subscribers register the
+			// final URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+	}
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	if subscription.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return err
+		}
+		subscription.Secret = secret
+	}
+	if err := validateWebhookSubscription(subscription); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		return err
+	}
+
+	s.logger.Info("Webhook subscription created",
+		zap.Int64("subscription_id", subscription.ID),
+		zap.String("url", subscription.URL),
+		zap.Strings("event_types", subscription.EventTypes))
+	return nil
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscription(ctx, id)
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+func (s *webhookService) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	if err := validateWebhookSubscription(subscription); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.UpdateSubscription(ctx, subscription); err != nil {
+		return err
+	}
+
+	s.logger.Info("Webhook subscription updated",
+		zap.Int64("subscription_id", subscription.ID),
+		zap.Bool("enabled", subscription.Enabled))
+	return nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	if err := s.webhookRepo.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("Webhook subscription deleted", zap.Int64("subscription_id", id))
+	return nil
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]*models.WebhookDelivery, error) {
+	// Report a missing subscription rather than an empty log
+	if _, err := s.webhookRepo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.GetDeliveries(ctx, subscriptionID, limit)
+}
+
+func (s *webhookService) Enqueue(ctx context.Context, event *events.Event) error {
+	if event.ID == "" {
+		// Without an id, a redelivered event cannot be told apart
+		s.logger.Warn("Skipping webhooks for event without id", zap.String("event_type", event.Type))
+		return nil
+	}
+
+	subscriptions, err := s.webhookRepo.GetEnabledSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var subscriptionIDs []int64
+	for _, subscription := range subscriptions {
+		if matchesEventTypes(subscription.EventTypes, event) {
+			subscriptionIDs = append(subscriptionIDs, subscription.ID)
+		}
+	}
+	if len(subscriptionIDs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal event: %v", events.ErrRejected, err)
+	}
+
+	if err := s.webhookRepo.CreateDeliveries(ctx, subscriptionIDs, event.ID, event.Type, payload); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Webhook deliveries queued",
+		zap.String("event_id", event.ID),
+		zap.Int("subscriptions", len(subscriptionIDs)))
+	return nil
+}
+
+func (s *webhookService) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	s.logger.Info("Webhook sender started",
+		zap.Duration("poll_interval", s.config.PollInterval),
+		zap.Int("concurrency", s.concurrency()))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Webhook sender stopping")
+			return nil
+		case <-ticker.C:
+			s.sendDue(ctx)
+		case <-cleanup.C:
+			s.cleanup(ctx)
+		}
+	}
+}
+
+// sendDue sends due deliveries in batches until fewer than a full batch is
+// due.
+func (s *webhookService) sendDue(ctx context.Context) {
+	concurrency := s.concurrency()
+	// A claim must outlast the slowest batch: every round of concurrent
+	// requests may take up to the timeout
+	rounds := (s.config.BatchSize + concurrency - 1) / concurrency
+	lease := time.Duration(rounds)*s.config.Timeout + time.Minute
+
+	for ctx.Err() == nil {
+		deliveries, err := s.webhookRepo.ClaimDue(ctx, s.config.BatchSize, lease)
+		if err != nil {
+			s.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+			return
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				s.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < s.config.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *webhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	start := time.Now()
+	statusCode, err := s.post(ctx, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Cut short by shutdown, not the endpoint's fault; the claim expires
+		// and the delivery is sent again
+		return
+	}
+
+	attempt := &models.WebhookDeliveryAttempt{
+		Attempt:    delivery.Attempts,
+		DurationMs: int(time.Since(start).Milliseconds()),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	var nextAttempt *time.Time
+	if err != nil {
+		message := events.TruncateReason(err.Error())
+		attempt.Error = &message
+		if delivery.Attempts < s.config.MaxAttempts {
+			next := time.Now().Add(eventbus.RetryDelay(s.config.RetryBackoff, s.config.MaxBackoff, delivery.Attempts))
+			nextAttempt = &next
+		}
+	}
+
+	// The result is saved even when shutting down, so the attempt counts
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	disabled, saveErr := s.webhookRepo.RecordAttempt(saveCtx, delivery, attempt, err == nil, nextAttempt, s.config.DisableAfter)
+	if saveErr != nil {
+		// The claim expires and the delivery is sent again
+		s.logger.Error("Failed to record webhook attempt",
+			zap.Error(saveErr),
+			zap.Int64("delivery_id", delivery.ID))
+		return
+	}
+
+	fields := []zap.Field{
+		zap.Int64("delivery_id", delivery.ID),
+		zap.Int64("subscription_id", delivery.SubscriptionID),
+		zap.String("event_id", delivery.EventID),
+		zap.Int("attempt", delivery.Attempts),
+		zap.Int("status_code", statusCode),
+	}
+	switch {
+	case err == nil:
+		s.logger.Debug("Webhook delivered", fields...)
+	case nextAttempt != nil:
+		s.logger.Warn("Webhook delivery failed, will retry", append(fields, zap.Error(err), zap.Time("next_attempt_at", *nextAttempt))...)
+	default:
+		s.logger.Error("Webhook delivery failed, giving up", append(fields, zap.Error(err))...)
+	}
+	if disabled {
+		s.logger.Error("Webhook subscription disabled after repeated failures",
+			zap.Int64("subscription_id", delivery.SubscriptionID),
+			zap.String("url", delivery.URL))
+	}
+}
+
+// post sends a delivery and returns the response status, or 0 without a
+// response. Any status outside 2xx is an error.
+func (s *webhookService) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", events.ContentType)
+	request.Header.Set("User-Agent", "transjakarta-fleet-webhooks/1.0")
+	request.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(webhookEventHeader, delivery.EventType)
+	request.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(webhookSignatureHeader, signWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// Drain a bounded amount so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("endpoint responded %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+func (s *webhookService) cleanup(ctx context.Context) {
+	deleted, err := s.webhookRepo.DeleteCompleted(ctx, time.Now().Add(-s.config.Retention))
+	if err != nil {
+		s.logger.Error("Failed to clean up webhook deliveries", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("Completed webhook deliveries deleted", zap.Int64("count", deleted))
+	}
+}
+
+func (s *webhookService) concurrency() int {
+	return max(s.config.Concurrency, 1)
+}
+
+// signWebhook returns the signature header value for a request body.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchesEventTypes reports whether an event passes an event type filter:
+// its name (e.g. geofence.entry) matches one of the patterns, or its full
+// type equals one. An empty filter passes every event.
+func matchesEventTypes(filter []string, event *events.Event) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	name := event.Name()
+	for _, pattern := range filter {
+		if pattern == event.Type {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func validateWebhookSubscription(subscription *models.WebhookSubscription) error {
+	endpoint, err := url.Parse(subscription.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL: %w", ErrInvalidWebhook)
+	}
+	// An empty secret keeps the current one on update
+	if subscription.Secret != "" && len(subscription.Secret) < 16 {
+		return fmt.Errorf("secret must be at least 16 characters: %w", ErrInvalidWebhook)
+	}
+	if len(subscription.Secret) > 128 {
+		return fmt.Errorf("secret must be at most 128 characters: %w", ErrInvalidWebhook)
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+	for _, pattern := range subscription.EventTypes {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid event type filter %q: %w", pattern, ErrInvalidWebhook)
+		}
+	}
+	return nil
+}
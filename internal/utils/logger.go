@@ -2,7 +2,6 @@ package utils
 
 import (
 	"go.uber.org/zap"
-	"go.uber.org/zap"
 )
 
 func NewLogger(development bool) (*zap.Logger, error) {
@@ -0,0 +1,140 @@
+// Package worker handles the events delivered by the event bus: geofence
+// alerts are logged, every event is evaluated against the notification
+// rules and queued for webhooks. It runs in the worker binary, or inside the
+// server with the in-memory bus.
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/config"
+	"github.com/ivanadhi/transjakarta-fleet/internal/eventbus"
+	"github.com/ivanadhi/transjakarta-fleet/internal/events"
+	"github.com/ivanadhi/transjakarta-fleet/internal/repositories"
+	"github.com/ivanadhi/transjakarta-fleet/internal/services"
+)
+
+type Worker struct {
+	bus           eventbus.EventBus
+	processedRepo repositories.ProcessedEventRepository
+	webhooks      services.WebhookService
+	notifications services.NotificationService
+	config        *config.IdempotencyConfig
+	logger        *zap.Logger
+}
+
+func New(bus eventbus.EventBus, processedRepo repositories.ProcessedEventRepository, webhooks services.WebhookService, notifications services.NotificationService, cfg *config.IdempotencyConfig, logger *zap.Logger) *Worker {
+	return &Worker{
+		bus:           bus,
+		processedRepo: processedRepo,
+		webhooks:      webhooks,
+		notifications: notifications,
+		config:        cfg,
+		logger:        logger,
+	}
+}
+
+// Run handles events and sends webhooks until the context ends.
+func (w *Worker) Run(ctx context.Context) error {
+	go func() {
+		if err := w.webhooks.Start(ctx); err != nil {
+			w.logger.Error("Webhook sender error", zap.Error(err))
+		}
+	}()
+	go func() {
+		if err := w.notifications.Start(ctx); err != nil {
+			w.logger.Error("Notification cleanup error", zap.Error(err))
+		}
+	}()
+
+	handler := eventbus.Handler(w.handleEvent)
+	if w.config.Enabled {
+		handler = eventbus.Deduplicate(w.processedRepo, handler, w.logger)
+
+		// Forget processed events once redeliveries are no longer expected
+		go w.cleanupProcessedEvents(ctx)
+	}
+
+	return w.bus.Subscribe(ctx, handler)
+}
+
+func (w *Worker) cleanupProcessedEvents(ctx context.Context) {
+	ticker := time.NewTicker(w.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := w.processedRepo.DeleteBefore(ctx, time.Now().Add(-w.config.Retention))
+			if err != nil {
+				w.logger.Error("Failed to clean up processed events", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				w.logger.Info("Processed events cleaned up", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+func (w *Worker) handleEvent(ctx context.Context, event *events.Event) error {
+	if event.IsCategory("geofence") {
+		if err := w.handleGeofenceEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	// Neither write joins a ProcessOnce transaction: a notification is sent
+	// outside the database. Each is instead claimed under a unique key,
+	// notifications per rule and event id and webhook deliveries per
+	// subscription and event id, so a redelivered event skips the rules and
+	// subscriptions already handled and retries only the rest
+	if err := w.notifications.Evaluate(ctx, event); err != nil {
+		return err
+	}
+
+	// Queued deliveries are sent by the webhook sender, so a slow endpoint
+	// does not hold up the bus
+	return w.webhooks.Enqueue(ctx, event)
+}
+
+// handleGeofenceEvent only logs. Handlers that write to the database should
+// do so through ProcessedEventRepository.ProcessOnce, so the writes and the
+// processed mark commit together.
+func (w *Worker) handleGeofenceEvent(ctx context.Context, event *events.Event) error {
+	message, err := events.DecodeGeofenceEvent(event)
+	if err != nil {
+		return err
+	}
+
+	// Log the geofence event
+	fields := []zap.Field{
+		zap.String("event_id", event.ID),
+		zap.String("vehicle_id", message.VehicleID),
+		zap.String("landmark", message.GeofenceName),
+		zap.String("event_type", message.Event),
+		zap.Float64("latitude", message.Location.Latitude),
+		zap.Float64("longitude", message.Location.Longitude),
+		zap.Float64("distance_meters", message.Distance),
+		zap.Time("timestamp", time.Unix(message.Timestamp, 0)),
+	}
+	if message.DriverID != "" {
+		fields = append(fields, zap.String("driver_id", message.DriverID))
+	}
+	if message.Address != nil {
+		fields = append(fields,
+			zap.String("street", message.Address.Street),
+			zap.String("kelurahan", message.Address.Kelurahan),
+			zap.String("kecamatan", message.Address.Kecamatan))
+	}
+	w.logger.Info("🚌 GEOFENCE ALERT! Vehicle entered landmark area!", fields...)
+
+	// Alerts for specific geofences, vehicles and times are configured as
+	// notification rules
+	return nil
+}
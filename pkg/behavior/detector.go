@@ -0,0 +1,152 @@
+package behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// Thresholds are the accelerations, in m/s², at or beyond which a maneuver
+// counts as harsh. A zero threshold disables that check.
+type Thresholds struct {
+	HarshAcceleration float64
+	HarshBraking      float64
+	SharpCornering    float64
+}
+
+// Detector flags harsh braking, harsh acceleration and sharp cornering.
+// Accelerometer readings are used when a location carries them; otherwise
+// accelerations are derived from the reported speed and heading of
+// consecutive locations no more than maxGap apart.
+type Detector struct {
+	maxGap            int64
+	cooldown          int64
+	minCorneringSpeed float64 // m/s
+	mu                sync.Mutex
+	states            map[string]*vehicleState
+}
+
+type vehicleState struct {
+	timestamp int64
+	speed     *float64 // m/s
+	heading   *float64
+	// lastEvents holds the time of the last event of each type, so a single
+	// maneuver spanning several locations is reported once
+	lastEvents map[string]int64
+}
+
+func NewDetector(maxGap, cooldown time.Duration, minCorneringSpeedKmh float64) *Detector {
+	return &Detector{
+		maxGap:            int64(maxGap.Seconds()),
+		cooldown:          int64(cooldown.Seconds()),
+		minCorneringSpeed: minCorneringSpeedKmh / 3.6,
+		states:            make(map[string]*vehicleState),
+	}
+}
+
+// Process evaluates a location against the thresholds of its vehicle and
+// returns the maneuvers it completes.
+func (d *Detector) Process(location *models.VehicleLocation, thresholds Thresholds) []*models.DrivingEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[location.VehicleID]
+	if !ok {
+		state = &vehicleState{lastEvents: make(map[string]int64)}
+		d.states[location.VehicleID] = state
+	} else if location.Timestamp <= state.timestamp {
+		return nil
+	}
+
+	var speed *float64
+	if location.Speed != nil {
+		v := *location.Speed / 3.6
+		speed = &v
+	}
+
+	dt := float64(location.Timestamp - state.timestamp)
+	continuous := ok && location.Timestamp-state.timestamp <= d.maxGap
+
+	var events []*models.DrivingEvent
+	emit := func(eventType string, value float64, source string) {
+		if last, seen := state.lastEvents[eventType]; seen && location.Timestamp-last < d.cooldown {
+			return
+		}
+		state.lastEvents[eventType] = location.Timestamp
+
+		event := &models.DrivingEvent{
+			VehicleID: location.VehicleID,
+			DriverID:  location.DriverID,
+			EventType: eventType,
+			Value:     value,
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Timestamp: location.Timestamp,
+			Source:    source,
+		}
+		if speed != nil {
+			event.SpeedKmh = *speed * 3.6
+		}
+		events = append(events, event)
+	}
+
+	// Longitudinal: forward positive, braking negative
+	switch {
+	case location.LongitudinalAccel != nil:
+		checkLongitudinal(*location.LongitudinalAccel, models.DrivingSourceAccelerometer, thresholds, emit)
+	case continuous && speed != nil && state.speed != nil:
+		checkLongitudinal((*speed-*state.speed)/dt, models.DrivingSourceGPS, thresholds, emit)
+	}
+
+	// Lateral: centripetal acceleration from speed and turn rate
+	switch {
+	case location.LateralAccel != nil:
+		if thresholds.SharpCornering > 0 && math.Abs(*location.LateralAccel) >= thresholds.SharpCornering {
+			emit(models.DrivingEventSharpCornering, math.Abs(*location.LateralAccel), models.DrivingSourceAccelerometer)
+		}
+	case continuous && speed != nil && state.speed != nil && location.Heading != nil && state.heading != nil:
+		v := (*speed + *state.speed) / 2
+		if v >= d.minCorneringSpeed {
+			turnRate := headingChange(*state.heading, *location.Heading) * math.Pi / 180 / dt
+			lateral := math.Abs(v * turnRate)
+			if thresholds.SharpCornering > 0 && lateral >= thresholds.SharpCornering {
+				emit(models.DrivingEventSharpCornering, lateral, models.DrivingSourceGPS)
+			}
+		}
+	}
+
+	state.timestamp = location.Timestamp
+	state.speed = speed
+	if location.Heading != nil {
+		heading := *location.Heading
+		state.heading = &heading
+	} else {
+		state.heading = nil
+	}
+
+	return events
+}
+
+func checkLongitudinal(accel float64, source string, thresholds Thresholds, emit func(string, float64, string)) {
+	switch {
+	case thresholds.HarshBraking > 0 && accel <= -thresholds.HarshBraking:
+		emit(models.DrivingEventHarshBraking, accel, source)
+	case thresholds.HarshAcceleration > 0 && accel >= thresholds.HarshAcceleration:
+		emit(models.DrivingEventHarshAcceleration, accel, source)
+	}
+}
+
+// headingChange returns the smallest signed difference between two headings
+// in degrees, positive clockwise.
+func headingChange(from, to float64) float64 {
+	delta := math.Mod(to-from, 360)
+	switch {
+	case delta > 180:
+		delta -= 360
+	case delta < -180:
+		delta += 360
+	}
+	return delta
+}
@@ -0,0 +1,164 @@
+package eta
+
+import (
+	"math"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// minLiveSpeed is the speed, in meters per second, below which live speed is
+// ignored; a bus stopped in traffic says little about its arrival time.
+const minLiveSpeed = 1.0
+
+type segmentKey struct {
+	from, to string
+	hour     int
+}
+
+type dwellKey struct {
+	stop string
+	hour int
+}
+
+// Profile holds running times between consecutive stops and dwell times at
+// stops, learned from stop visit history by hour of day.
+type Profile struct {
+	segments map[segmentKey]float64
+	dwells   map[dwellKey]float64
+}
+
+// NewProfile keeps the aggregates backed by at least minSamples visits.
+func NewProfile(segments []*models.SegmentTravelTime, dwells []*models.StopDwellProfile, minSamples int) *Profile {
+	p := &Profile{
+		segments: make(map[segmentKey]float64),
+		dwells:   make(map[dwellKey]float64),
+	}
+
+	for _, s := range segments {
+		if s.Samples >= minSamples {
+			p.segments[segmentKey{s.FromStopID, s.ToStopID, s.Hour}] = s.MedianSeconds
+		}
+	}
+	for _, d := range dwells {
+		if d.Samples >= minSamples {
+			p.dwells[dwellKey{d.StopID, d.Hour}] = d.MedianSeconds
+		}
+	}
+
+	return p
+}
+
+// Segment returns the running time between two stops at an hour of day,
+// falling back to the neighbouring hours.
+func (p *Profile) Segment(from, to string, hour int) (float64, bool) {
+	for _, h := range []int{hour, (hour + 23) % 24, (hour + 1) % 24} {
+		if seconds, ok := p.segments[segmentKey{from, to, h}]; ok {
+			return seconds, true
+		}
+	}
+	return 0, false
+}
+
+// Dwell returns the dwell time at a stop at an hour of day, falling back to
+// the neighbouring hours.
+func (p *Profile) Dwell(stopID string, hour int) (float64, bool) {
+	for _, h := range []int{hour, (hour + 23) % 24, (hour + 1) % 24} {
+		if seconds, ok := p.dwells[dwellKey{stopID, h}]; ok {
+			return seconds, true
+		}
+	}
+	return 0, false
+}
+
+// Stop is a scheduled stop of a trip with its distance along the trip shape.
+type Stop struct {
+	*models.GTFSTripStop
+	Along float64
+}
+
+// Position is a vehicle's distance along its trip shape and its live speed in
+// meters per second (0 when unknown).
+type Position struct {
+	Along     float64
+	Speed     float64
+	Timestamp int64
+}
+
+// Predictor estimates arrival times at upcoming stops.
+type Predictor struct {
+	// LiveWeight is the share of live speed, against history, in the
+	// estimate to the next stop
+	LiveWeight float64
+	// DefaultSpeed, in meters per second, is used when neither history nor
+	// schedule give a running time
+	DefaultSpeed float64
+	// UTCOffset shifts unix times into the local time of the profile hours
+	UTCOffset int
+}
+
+// Predict returns the predicted arrival, as unix time, at stops[next:], for
+// a vehicle that has not yet reached stops[next].
+//
+// The run to the next stop blends the remaining share of the learned
+// segment time with the remaining distance at live speed. Later stops add
+// learned dwell and running times for the hour the vehicle is expected
+// there, falling back to the schedule and then to the default speed.
+func (p *Predictor) Predict(profile *Profile, stops []Stop, next int, position Position) []int64 {
+	if next < 0 || next >= len(stops) {
+		return nil
+	}
+
+	t := float64(position.Timestamp)
+	remaining := math.Max(0, stops[next].Along-position.Along)
+
+	estimate := remaining / p.DefaultSpeed
+	if next > 0 {
+		length := stops[next].Along - stops[next-1].Along
+		fraction := 1.0
+		if length > 0 {
+			fraction = math.Min(1, remaining/length)
+		}
+		estimate = fraction * p.segment(profile, stops, next, p.hour(t))
+	}
+	if position.Speed >= minLiveSpeed {
+		estimate = p.LiveWeight*remaining/position.Speed + (1-p.LiveWeight)*estimate
+	}
+
+	t += estimate
+	arrivals := []int64{int64(t)}
+
+	for k := next + 1; k < len(stops); k++ {
+		t += p.dwell(profile, stops[k-1], p.hour(t))
+		t += p.segment(profile, stops, k, p.hour(t))
+		arrivals = append(arrivals, int64(t))
+	}
+
+	return arrivals
+}
+
+// segment estimates the running time from stops[k-1] to stops[k].
+func (p *Predictor) segment(profile *Profile, stops []Stop, k int, hour int) float64 {
+	from, to := stops[k-1], stops[k]
+	if seconds, ok := profile.Segment(from.StopID, to.StopID, hour); ok {
+		return seconds
+	}
+	if scheduled := to.ArrivalSeconds - from.DepartureSeconds; scheduled > 0 {
+		return float64(scheduled)
+	}
+	return math.Max(0, to.Along-from.Along) / p.DefaultSpeed
+}
+
+func (p *Predictor) dwell(profile *Profile, stop Stop, hour int) float64 {
+	if seconds, ok := profile.Dwell(stop.StopID, hour); ok {
+		return seconds
+	}
+	return float64(stop.DepartureSeconds - stop.ArrivalSeconds)
+}
+
+func (p *Predictor) hour(t float64) int {
+	local := (int64(t) + int64(p.UTCOffset)) % 86400
+	if local < 0 {
+		local += 86400
+	}
+	return int(local / 3600)
+}
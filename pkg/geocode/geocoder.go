@@ -0,0 +1,237 @@
+package geocode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/roadnet"
+)
+
+// cellSize is the side of a boundary index cell, in degrees (about 1.1 km)
+const cellSize = 0.01
+
+// Properties names the GeoJSON properties holding each administrative level.
+// Matching is case-insensitive.
+type Properties struct {
+	Kelurahan string
+	Kecamatan string
+	City      string
+}
+
+// area is an administrative boundary: polygons of rings of [lng, lat] pairs,
+// the first ring of each polygon its outline and the rest holes.
+type area struct {
+	polygons  [][][][2]float64
+	minLat    float64
+	minLng    float64
+	maxLat    float64
+	maxLng    float64
+	kelurahan string
+	kecamatan string
+	city      string
+}
+
+type cell struct{ x, y int }
+
+// Geocoder resolves coordinates to administrative areas and street names
+// from local datasets.
+type Geocoder struct {
+	areas []area
+	grid  map[cell][]int
+	// streets is optional; its road names give the street
+	streets      *roadnet.Graph
+	streetRadius float64
+}
+
+// New builds a geocoder from a GeoJSON FeatureCollection of administrative
+// boundaries and a street network searched within streetRadius meters. Either
+// may be nil.
+func New(boundaries io.Reader, props Properties, streets *roadnet.Graph, streetRadius float64) (*Geocoder, error) {
+	g := &Geocoder{
+		grid:         make(map[cell][]int),
+		streets:      streets,
+		streetRadius: streetRadius,
+	}
+
+	if boundaries != nil {
+		areas, err := loadBoundaries(boundaries, props)
+		if err != nil {
+			return nil, err
+		}
+		g.areas = areas
+	}
+
+	for i, a := range g.areas {
+		minCell, maxCell := toCell(a.minLat, a.minLng), toCell(a.maxLat, a.maxLng)
+		for x := minCell.x; x <= maxCell.x; x++ {
+			for y := minCell.y; y <= maxCell.y; y++ {
+				g.grid[cell{x, y}] = append(g.grid[cell{x, y}], i)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Areas returns the number of boundaries loaded.
+func (g *Geocoder) Areas() int {
+	return len(g.areas)
+}
+
+// Lookup returns the address at a position, or nil when no dataset covers it.
+// Overlapping boundaries, such as separate kelurahan and kecamatan layers,
+// each fill the levels they name.
+func (g *Geocoder) Lookup(lat, lng float64) *models.Address {
+	address := &models.Address{}
+
+	for _, i := range g.grid[toCell(lat, lng)] {
+		a := &g.areas[i]
+		if lat < a.minLat || lat > a.maxLat || lng < a.minLng || lng > a.maxLng || !a.contains(lat, lng) {
+			continue
+		}
+		if address.Kelurahan == "" {
+			address.Kelurahan = a.kelurahan
+		}
+		if address.Kecamatan == "" {
+			address.Kecamatan = a.kecamatan
+		}
+		if address.City == "" {
+			address.City = a.city
+		}
+	}
+
+	if g.streets != nil {
+		for _, c := range g.streets.Candidates(lat, lng, g.streetRadius, 0) {
+			if name := g.streets.Edge(c.Edge).Name; name != "" {
+				address.Street = name
+				break
+			}
+		}
+	}
+
+	if *address == (models.Address{}) {
+		return nil
+	}
+	return address
+}
+
+func (a *area) contains(lat, lng float64) bool {
+	for _, polygon := range a.polygons {
+		if len(polygon) == 0 || !inRing(polygon[0], lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if inRing(hole, lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// inRing tests a point against a ring by ray casting.
+func inRing(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func toCell(lat, lng float64) cell {
+	return cell{int(math.Floor(lng / cellSize)), int(math.Floor(lat / cellSize))}
+}
+
+type featureCollection struct {
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Props map[string]any `json:"properties"`
+	} `json:"features"`
+}
+
+// loadBoundaries reads the Polygon and MultiPolygon features naming at least
+// one administrative level.
+func loadBoundaries(r io.Reader, props Properties) ([]area, error) {
+	var collection featureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	var areas []area
+	for i, f := range collection.Features {
+		var polygons [][][][2]float64
+		switch f.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			polygons = append(polygons, polygon)
+		case "MultiPolygon":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &polygons); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		default:
+			continue
+		}
+
+		a := area{
+			polygons:  polygons,
+			minLat:    math.Inf(1),
+			minLng:    math.Inf(1),
+			maxLat:    math.Inf(-1),
+			maxLng:    math.Inf(-1),
+			kelurahan: property(f.Props, props.Kelurahan),
+			kecamatan: property(f.Props, props.Kecamatan),
+			city:      property(f.Props, props.City),
+		}
+		if a.kelurahan == "" && a.kecamatan == "" && a.city == "" {
+			continue
+		}
+		for _, polygon := range polygons {
+			if len(polygon) == 0 {
+				continue
+			}
+			for _, p := range polygon[0] {
+				a.minLng, a.maxLng = math.Min(a.minLng, p[0]), math.Max(a.maxLng, p[0])
+				a.minLat, a.maxLat = math.Min(a.minLat, p[1]), math.Max(a.maxLat, p[1])
+			}
+		}
+		if math.IsInf(a.minLat, 1) {
+			continue
+		}
+		areas = append(areas, a)
+	}
+
+	return areas, nil
+}
+
+func property(props map[string]any, name string) string {
+	if name == "" {
+		return ""
+	}
+	for key, value := range props {
+		if strings.EqualFold(key, name) {
+			if s, ok := value.(string); ok {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+	return ""
+}
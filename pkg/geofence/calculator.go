@@ -2,6 +2,8 @@ package geofence
 
 import (
 	"math"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
 )
 
 // HaversineDistance calculates the distance between two points on Earth
@@ -60,4 +62,55 @@ func CalculateBearing(lat1, lng1, lat2, lng2 float64) float64 {
 	}
 	
 	return bearing
-}
\ No newline at end of file
+}
+
+// Distance returns the distance in meters from a point to a geofence: to the
+// center for circular geofences, or to the nearest segment of Path for
+// corridor geofences.
+func Distance(g *models.Geofence, lat, lng float64) float64 {
+	if len(g.Path) == 0 {
+		return HaversineDistance(g.Latitude, g.Longitude, lat, lng)
+	}
+
+	if len(g.Path) == 1 {
+		return HaversineDistance(g.Path[0][0], g.Path[0][1], lat, lng)
+	}
+
+	nearest := math.Inf(1)
+	for i := 1; i < len(g.Path); i++ {
+		d := DistanceToSegment(lat, lng, g.Path[i-1][0], g.Path[i-1][1], g.Path[i][0], g.Path[i][1])
+		if d < nearest {
+			nearest = d
+		}
+	}
+
+	return nearest
+}
+
+// Contains checks if a point lies within a geofence's radius or corridor width
+func Contains(g *models.Geofence, lat, lng float64) bool {
+	return Distance(g, lat, lng) <= float64(g.Radius)
+}
+
+// DistanceToSegment returns the distance in meters from a point to the segment
+// between (lat1, lng1) and (lat2, lng2), using an equirectangular projection
+// around the point. Accurate enough for the short segments found in shapes.
+func DistanceToSegment(lat, lng, lat1, lng1, lat2, lng2 float64) float64 {
+	const metersPerDegree = 111320.0
+
+	scale := math.Cos(lat * math.Pi / 180)
+	ax, ay := (lng1-lng)*scale*metersPerDegree, (lat1-lat)*metersPerDegree
+	bx, by := (lng2-lng)*scale*metersPerDegree, (lat2-lat)*metersPerDegree
+
+	dx, dy := bx-ax, by-ay
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return math.Hypot(ax, ay)
+	}
+
+	// Project the origin (the point) onto the segment, clamped to its ends
+	t := -(ax*dx + ay*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
@@ -46,10 +46,13 @@ func (d *Detector) CheckGeofences(ctx context.Context, location *models.VehicleL
 	
 	// Check each geofence
 	for _, geofence := range d.cache {
-		distance := HaversineDistance(
-			location.Latitude, location.Longitude,
-			geofence.Latitude, geofence.Longitude,
-		)
+		// Stop and corridor geofences come from GTFS feeds and are matched
+		// by dedicated services; treating them as entries would flood events
+		if geofence.Type == models.GeofenceTypeStop || geofence.Type == models.GeofenceTypeCorridor {
+			continue
+		}
+
+		distance := Distance(geofence, location.Latitude, location.Longitude)
 		
 		entered := distance <= float64(geofence.Radius)
 		
@@ -72,7 +75,9 @@ func (d *Detector) CheckGeofences(ctx context.Context, location *models.VehicleL
 	return results, nil
 }
 
-func (d *Detector) ProcessGeofenceEntry(ctx context.Context, location *models.VehicleLocation, geofence *models.Geofence) error {
+// ProcessGeofenceEntry saves the entry event together with the outbox
+// message that announces it.
+func (d *Detector) ProcessGeofenceEntry(ctx context.Context, location *models.VehicleLocation, geofence *models.Geofence, message *models.OutboxMessage) error {
 	// Create geofence event
 	event := &models.GeofenceEvent{
 		VehicleID:   location.VehicleID,
@@ -81,10 +86,11 @@ func (d *Detector) ProcessGeofenceEntry(ctx context.Context, location *models.Ve
 		Latitude:    location.Latitude,
 		Longitude:   location.Longitude,
 		Timestamp:   location.Timestamp,
+		DriverID:    location.DriverID,
 	}
 	
 	// Save event to database
-	if err := d.eventRepo.Create(ctx, event); err != nil {
+	if err := d.eventRepo.CreateWithMessage(ctx, event, message); err != nil {
 		d.logger.Error("Failed to create geofence event", 
 			zap.Error(err),
 			zap.String("vehicle_id", location.VehicleID),
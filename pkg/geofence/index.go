@@ -0,0 +1,69 @@
+package geofence
+
+import (
+	"math"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// indexCellDegrees is the grid cell size (~1.1 km); geofences indexed must
+// have a radius smaller than one cell.
+const indexCellDegrees = 0.01
+
+type cellKey struct {
+	lat, lng int
+}
+
+// Index is a grid index over circular geofences for fast point lookups on
+// large sets such as GTFS stops. Geofences with a Path are not indexed.
+type Index struct {
+	cells map[cellKey][]*models.Geofence
+}
+
+func NewIndex(geofences []*models.Geofence) *Index {
+	index := &Index{cells: make(map[cellKey][]*models.Geofence)}
+	for _, g := range geofences {
+		if len(g.Path) > 0 {
+			continue
+		}
+		key := cellOf(g.Latitude, g.Longitude)
+		index.cells[key] = append(index.cells[key], g)
+	}
+	return index
+}
+
+// Nearest returns the closest geofence containing the point, or nil.
+func (idx *Index) Nearest(lat, lng float64) (*models.Geofence, float64) {
+	center := cellOf(lat, lng)
+
+	var nearest *models.Geofence
+	nearestDistance := math.Inf(1)
+	for dLat := -1; dLat <= 1; dLat++ {
+		for dLng := -1; dLng <= 1; dLng++ {
+			for _, g := range idx.cells[cellKey{center.lat + dLat, center.lng + dLng}] {
+				d := HaversineDistance(g.Latitude, g.Longitude, lat, lng)
+				if d <= float64(g.Radius) && d < nearestDistance {
+					nearest, nearestDistance = g, d
+				}
+			}
+		}
+	}
+
+	return nearest, nearestDistance
+}
+
+// Len returns the number of indexed geofences
+func (idx *Index) Len() int {
+	n := 0
+	for _, cell := range idx.cells {
+		n += len(cell)
+	}
+	return n
+}
+
+func cellOf(lat, lng float64) cellKey {
+	return cellKey{
+		lat: int(math.Floor(lat / indexCellDegrees)),
+		lng: int(math.Floor(lng / indexCellDegrees)),
+	}
+}
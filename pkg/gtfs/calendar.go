@@ -0,0 +1,67 @@
+package gtfs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// DateLayout is the GTFS date format, also used for service dates.
+const DateLayout = "20060102"
+
+// ActiveServices returns the sorted IDs of the services running on a service
+// date (YYYYMMDD): those whose calendar covers the date and its weekday, plus
+// the services calendar_dates adds and minus those it removes on that date.
+func ActiveServices(calendars []*models.GTFSCalendar, dates []*models.GTFSCalendarDate, date string) ([]string, error) {
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service date %q", date)
+	}
+
+	active := make(map[string]bool)
+	for _, calendar := range calendars {
+		if date >= calendar.StartDate && date <= calendar.EndDate && runsOn(calendar, day.Weekday()) {
+			active[calendar.ServiceID] = true
+		}
+	}
+
+	for _, exception := range dates {
+		if exception.Date != date {
+			continue
+		}
+		switch exception.ExceptionType {
+		case models.GTFSServiceAdded:
+			active[exception.ServiceID] = true
+		case models.GTFSServiceRemoved:
+			delete(active, exception.ServiceID)
+		}
+	}
+
+	services := make([]string, 0, len(active))
+	for serviceID := range active {
+		services = append(services, serviceID)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+func runsOn(calendar *models.GTFSCalendar, weekday time.Weekday) bool {
+	switch weekday {
+	case time.Monday:
+		return calendar.Monday
+	case time.Tuesday:
+		return calendar.Tuesday
+	case time.Wednesday:
+		return calendar.Wednesday
+	case time.Thursday:
+		return calendar.Thursday
+	case time.Friday:
+		return calendar.Friday
+	case time.Saturday:
+		return calendar.Saturday
+	default:
+		return calendar.Sunday
+	}
+}
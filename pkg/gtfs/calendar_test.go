@@ -0,0 +1,56 @@
+package gtfs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+func TestActiveServices(t *testing.T) {
+	calendars := []*models.GTFSCalendar{
+		{ServiceID: "WD", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: "20260101", EndDate: "20261231"},
+		{ServiceID: "WE", Saturday: true, Sunday: true, StartDate: "20260101", EndDate: "20261231"},
+		{ServiceID: "OLD", Monday: true, Saturday: true, StartDate: "20250101", EndDate: "20251231"},
+	}
+	dates := []*models.GTFSCalendarDate{
+		// Independence Day falls on a Monday: weekend service instead
+		{ServiceID: "WD", Date: "20260817", ExceptionType: models.GTFSServiceRemoved},
+		{ServiceID: "WE", Date: "20260817", ExceptionType: models.GTFSServiceAdded},
+		{ServiceID: "EVENT", Date: "20261017", ExceptionType: models.GTFSServiceAdded},
+	}
+
+	tests := []struct {
+		name    string
+		date    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "weekday", date: "20261016", want: []string{"WD"}},
+		{name: "weekend with added service", date: "20261017", want: []string{"EVENT", "WE"}},
+		{name: "holiday swaps services", date: "20260817", want: []string{"WE"}},
+		{name: "first day of range", date: "20260101", want: []string{"WD"}},
+		{name: "last day of range", date: "20261231", want: []string{"WD"}},
+		{name: "outside every range", date: "20270104", want: []string{}},
+		{name: "expired calendar", date: "20251231", want: []string{}},
+		{name: "invalid date", date: "2026-10-16", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ActiveServices(calendars, dates, tt.date)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ActiveServices(%s) = %v, want error", tt.date, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ActiveServices(%s): %v", tt.date, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ActiveServices(%s) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,518 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// Feed is the content of a GTFS static zip archive.
+type Feed struct {
+	Version   string
+	Agencies  []*models.GTFSAgency
+	Routes    []*models.GTFSRoute
+	Stops     []*models.GTFSStop
+	Trips     []*models.GTFSTrip
+	StopTimes []*models.GTFSStopTime
+	Shapes    []*models.GTFSShapePoint
+
+	Calendars     []*models.GTFSCalendar
+	CalendarDates []*models.GTFSCalendarDate
+}
+
+// Parse reads a GTFS zip archive. agency, routes, stops, trips and
+// stop_times are required, as is at least one of calendar and
+// calendar_dates; shapes and feed_info are optional.
+func Parse(r io.ReaderAt, size int64) (*Feed, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GTFS archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		// Feeds are sometimes zipped with an enclosing folder
+		files[path.Base(file.Name)] = file
+	}
+
+	feed := &Feed{}
+
+	steps := []struct {
+		name     string
+		required bool
+		parse    func(*table) error
+	}{
+		{"agency.txt", true, feed.parseAgencies},
+		{"routes.txt", true, feed.parseRoutes},
+		{"stops.txt", true, feed.parseStops},
+		{"trips.txt", true, feed.parseTrips},
+		{"stop_times.txt", true, feed.parseStopTimes},
+		{"calendar.txt", false, feed.parseCalendars},
+		{"calendar_dates.txt", false, feed.parseCalendarDates},
+		{"shapes.txt", false, feed.parseShapes},
+		{"feed_info.txt", false, feed.parseFeedInfo},
+	}
+
+	if files["calendar.txt"] == nil && files["calendar_dates.txt"] == nil {
+		return nil, fmt.Errorf("missing required file calendar.txt or calendar_dates.txt")
+	}
+
+	for _, step := range steps {
+		file, ok := files[step.name]
+		if !ok {
+			if step.required {
+				return nil, fmt.Errorf("missing required file %s", step.name)
+			}
+			continue
+		}
+
+		if err := readTable(file, step.parse); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", step.name, err)
+		}
+	}
+
+	return feed, nil
+}
+
+// table iterates CSV records and resolves columns by header name.
+type table struct {
+	reader  *csv.Reader
+	columns map[string]int
+	record  []string
+	line    int
+}
+
+func readTable(file *zip.File, parse func(*table) error) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(rc)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	return parse(&table{reader: reader, columns: columns, line: 1})
+}
+
+func (t *table) next() (bool, error) {
+	record, err := t.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	t.record = record
+	t.line++
+	return true, nil
+}
+
+func (t *table) require(names ...string) error {
+	for _, name := range names {
+		if _, ok := t.columns[name]; !ok {
+			return fmt.Errorf("missing required column %s", name)
+		}
+	}
+	return nil
+}
+
+func (t *table) get(name string) string {
+	i, ok := t.columns[name]
+	if !ok || i >= len(t.record) {
+		return ""
+	}
+	return strings.TrimSpace(t.record[i])
+}
+
+func (t *table) int(name string) (int, error) {
+	value := t.get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid %s %q", t.line, name, value)
+	}
+	return n, nil
+}
+
+func (t *table) float(name string) (float64, error) {
+	value := t.get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid %s %q", t.line, name, value)
+	}
+	return f, nil
+}
+
+// seconds parses a GTFS time (H:MM:SS, may exceed 24:00:00). Empty values
+// are allowed for non-timepoint stops and reported with ok=false.
+func (t *table) seconds(name string) (int, bool, error) {
+	value := t.get(name)
+	if value == "" {
+		return 0, false, nil
+	}
+
+	seconds, err := ParseTime(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("line %d: %w", t.line, err)
+	}
+	return seconds, true, nil
+}
+
+// date parses a GTFS date (YYYYMMDD) and returns it unchanged.
+func (t *table) date(name string) (string, error) {
+	value := t.get(name)
+	if _, err := time.Parse(DateLayout, value); err != nil {
+		return "", fmt.Errorf("line %d: invalid %s %q", t.line, name, value)
+	}
+	return value, nil
+}
+
+// flag parses a 0/1 field.
+func (t *table) flag(name string) (bool, error) {
+	switch value := t.get(name); value {
+	case "0", "":
+		return false, nil
+	case "1":
+		return true, nil
+	default:
+		return false, fmt.Errorf("line %d: invalid %s %q", t.line, name, value)
+	}
+}
+
+// ParseTime converts a GTFS time string to seconds after midnight.
+func ParseTime(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	var total int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		total = total*60 + n
+		if i > 0 && n >= 60 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+	}
+
+	return total, nil
+}
+
+func (f *Feed) parseAgencies(t *table) error {
+	if err := t.require("agency_name"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		f.Agencies = append(f.Agencies, &models.GTFSAgency{
+			AgencyID: t.get("agency_id"),
+			Name:     t.get("agency_name"),
+			URL:      t.get("agency_url"),
+			Timezone: t.get("agency_timezone"),
+		})
+	}
+}
+
+func (f *Feed) parseRoutes(t *table) error {
+	if err := t.require("route_id", "route_type"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		routeType, err := t.int("route_type")
+		if err != nil {
+			return err
+		}
+
+		f.Routes = append(f.Routes, &models.GTFSRoute{
+			RouteID:   t.get("route_id"),
+			AgencyID:  t.get("agency_id"),
+			ShortName: t.get("route_short_name"),
+			LongName:  t.get("route_long_name"),
+			RouteType: routeType,
+			Color:     t.get("route_color"),
+		})
+	}
+}
+
+func (f *Feed) parseStops(t *table) error {
+	if err := t.require("stop_id", "stop_lat", "stop_lon"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		latitude, err := t.float("stop_lat")
+		if err != nil {
+			return err
+		}
+		longitude, err := t.float("stop_lon")
+		if err != nil {
+			return err
+		}
+		locationType, err := t.int("location_type")
+		if err != nil {
+			return err
+		}
+
+		f.Stops = append(f.Stops, &models.GTFSStop{
+			StopID:        t.get("stop_id"),
+			Code:          t.get("stop_code"),
+			Name:          t.get("stop_name"),
+			Latitude:      latitude,
+			Longitude:     longitude,
+			LocationType:  locationType,
+			ParentStation: t.get("parent_station"),
+		})
+	}
+}
+
+func (f *Feed) parseTrips(t *table) error {
+	if err := t.require("route_id", "service_id", "trip_id"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		directionID, err := t.int("direction_id")
+		if err != nil {
+			return err
+		}
+
+		f.Trips = append(f.Trips, &models.GTFSTrip{
+			TripID:      t.get("trip_id"),
+			RouteID:     t.get("route_id"),
+			ServiceID:   t.get("service_id"),
+			Headsign:    t.get("trip_headsign"),
+			DirectionID: directionID,
+			ShapeID:     t.get("shape_id"),
+		})
+	}
+}
+
+func (f *Feed) parseStopTimes(t *table) error {
+	if err := t.require("trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"); err != nil {
+		return err
+	}
+
+	// Non-timepoint stops may omit times; they inherit the previous stop's
+	// departure so every stored row carries a usable schedule.
+	var lastTripID string
+	var lastDeparture int
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		tripID := t.get("trip_id")
+		arrival, hasArrival, err := t.seconds("arrival_time")
+		if err != nil {
+			return err
+		}
+		departure, hasDeparture, err := t.seconds("departure_time")
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case hasArrival && !hasDeparture:
+			departure = arrival
+		case !hasArrival && hasDeparture:
+			arrival = departure
+		case !hasArrival && !hasDeparture:
+			if tripID != lastTripID {
+				return fmt.Errorf("line %d: first stop of trip %s has no times", t.line, tripID)
+			}
+			arrival, departure = lastDeparture, lastDeparture
+		}
+		lastTripID, lastDeparture = tripID, departure
+
+		sequence, err := t.int("stop_sequence")
+		if err != nil {
+			return err
+		}
+		distance, err := t.float("shape_dist_traveled")
+		if err != nil {
+			return err
+		}
+
+		f.StopTimes = append(f.StopTimes, &models.GTFSStopTime{
+			TripID:            tripID,
+			StopSequence:      sequence,
+			StopID:            t.get("stop_id"),
+			ArrivalSeconds:    arrival,
+			DepartureSeconds:  departure,
+			ShapeDistTraveled: distance,
+		})
+	}
+}
+
+func (f *Feed) parseCalendars(t *table) error {
+	if err := t.require("service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		calendar := &models.GTFSCalendar{ServiceID: t.get("service_id")}
+		days := []struct {
+			name string
+			day  *bool
+		}{
+			{"monday", &calendar.Monday},
+			{"tuesday", &calendar.Tuesday},
+			{"wednesday", &calendar.Wednesday},
+			{"thursday", &calendar.Thursday},
+			{"friday", &calendar.Friday},
+			{"saturday", &calendar.Saturday},
+			{"sunday", &calendar.Sunday},
+		}
+		for _, d := range days {
+			if *d.day, err = t.flag(d.name); err != nil {
+				return err
+			}
+		}
+		if calendar.StartDate, err = t.date("start_date"); err != nil {
+			return err
+		}
+		if calendar.EndDate, err = t.date("end_date"); err != nil {
+			return err
+		}
+
+		f.Calendars = append(f.Calendars, calendar)
+	}
+}
+
+func (f *Feed) parseCalendarDates(t *table) error {
+	if err := t.require("service_id", "date", "exception_type"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		date, err := t.date("date")
+		if err != nil {
+			return err
+		}
+		exceptionType, err := t.int("exception_type")
+		if err != nil {
+			return err
+		}
+		if exceptionType != models.GTFSServiceAdded && exceptionType != models.GTFSServiceRemoved {
+			return fmt.Errorf("line %d: invalid exception_type %d", t.line, exceptionType)
+		}
+
+		f.CalendarDates = append(f.CalendarDates, &models.GTFSCalendarDate{
+			ServiceID:     t.get("service_id"),
+			Date:          date,
+			ExceptionType: exceptionType,
+		})
+	}
+}
+
+func (f *Feed) parseShapes(t *table) error {
+	if err := t.require("shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence"); err != nil {
+		return err
+	}
+
+	for {
+		ok, err := t.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		latitude, err := t.float("shape_pt_lat")
+		if err != nil {
+			return err
+		}
+		longitude, err := t.float("shape_pt_lon")
+		if err != nil {
+			return err
+		}
+		sequence, err := t.int("shape_pt_sequence")
+		if err != nil {
+			return err
+		}
+		distance, err := t.float("shape_dist_traveled")
+		if err != nil {
+			return err
+		}
+
+		f.Shapes = append(f.Shapes, &models.GTFSShapePoint{
+			ShapeID:           t.get("shape_id"),
+			Sequence:          sequence,
+			Latitude:          latitude,
+			Longitude:         longitude,
+			ShapeDistTraveled: distance,
+		})
+	}
+}
+
+func (f *Feed) parseFeedInfo(t *table) error {
+	ok, err := t.next()
+	if err != nil || !ok {
+		return err
+	}
+
+	f.Version = t.get("feed_version")
+	return nil
+}
@@ -0,0 +1,229 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "00:00:00", want: 0},
+		{value: "7:05:30", want: 7*3600 + 5*60 + 30},
+		{value: "23:59:59", want: 86399},
+		{value: "24:00:00", want: 86400},
+		{value: "25:10:00", want: 25*3600 + 10*60},
+		{value: "47:59:59", want: 47*3600 + 59*60 + 59},
+		{value: "12:60:00", wantErr: true},
+		{value: "12:00:60", wantErr: true},
+		{value: "12:00", wantErr: true},
+		{value: "-1:00:00", wantErr: true},
+		{value: "ab:cd:ef", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) = %d, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTime(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// minimalFeed holds the files of a valid feed, overridden per test case.
+var minimalFeed = map[string]string{
+	"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+		"TJ,Transjakarta,https://transjakarta.co.id,Asia/Jakarta\n",
+	"routes.txt": "route_id,agency_id,route_short_name,route_long_name,route_type\n" +
+		"1,TJ,1,Blok M - Kota,3\n",
+	"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\n" +
+		"S1,Blok M,-6.2440,106.8000\n" +
+		"S2,Bundaran HI,-6.1950,106.8230\n" +
+		"S3,Kota,-6.1376,106.8134\n",
+	"trips.txt": "route_id,service_id,trip_id,direction_id\n" +
+		"1,WD,T1,0\n",
+	"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"T1,23:50:00,23:51:00,S1,1\n" +
+		"T1,,,S2,2\n" +
+		"T1,24:20:00,,S3,3\n",
+	"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+		"WD,1,1,1,1,1,0,0,20260101,20261231\n",
+}
+
+func buildArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		override map[string]string
+		// Files removed from the minimal feed
+		remove  []string
+		wantErr string
+		check   func(t *testing.T, feed *Feed)
+	}{
+		{
+			name: "minimal feed",
+			check: func(t *testing.T, feed *Feed) {
+				if len(feed.Agencies) != 1 || len(feed.Routes) != 1 || len(feed.Stops) != 3 || len(feed.Trips) != 1 {
+					t.Fatalf("got %d agencies, %d routes, %d stops, %d trips",
+						len(feed.Agencies), len(feed.Routes), len(feed.Stops), len(feed.Trips))
+				}
+				if len(feed.Calendars) != 1 || !feed.Calendars[0].Friday || feed.Calendars[0].Saturday {
+					t.Fatalf("calendars = %+v", feed.Calendars)
+				}
+			},
+		},
+		{
+			name: "times past midnight and untimed stops",
+			check: func(t *testing.T, feed *Feed) {
+				want := [][2]int{
+					{23*3600 + 50*60, 23*3600 + 51*60},
+					// Untimed stop inherits the previous departure
+					{23*3600 + 51*60, 23*3600 + 51*60},
+					// Missing departure takes the arrival, past 24:00
+					{24*3600 + 20*60, 24*3600 + 20*60},
+				}
+				if len(feed.StopTimes) != len(want) {
+					t.Fatalf("got %d stop times, want %d", len(feed.StopTimes), len(want))
+				}
+				for i, st := range feed.StopTimes {
+					if st.ArrivalSeconds != want[i][0] || st.DepartureSeconds != want[i][1] {
+						t.Errorf("stop time %d = %d/%d, want %d/%d",
+							i, st.ArrivalSeconds, st.DepartureSeconds, want[i][0], want[i][1])
+					}
+				}
+			},
+		},
+		{
+			name: "files in an enclosing folder and BOM header",
+			override: map[string]string{
+				"feed/feed_info.txt": "\ufefffeed_publisher_name,feed_version\nTJ,2026.10\n",
+			},
+			check: func(t *testing.T, feed *Feed) {
+				if feed.Version != "2026.10" {
+					t.Errorf("version = %q, want 2026.10", feed.Version)
+				}
+			},
+		},
+		{
+			name:   "calendar_dates alone is enough",
+			remove: []string{"calendar.txt"},
+			override: map[string]string{
+				"calendar_dates.txt": "service_id,date,exception_type\nWD,20261017,1\n",
+			},
+			check: func(t *testing.T, feed *Feed) {
+				if len(feed.CalendarDates) != 1 || feed.CalendarDates[0].Date != "20261017" {
+					t.Fatalf("calendar dates = %+v", feed.CalendarDates)
+				}
+			},
+		},
+		{
+			name:    "missing required file",
+			remove:  []string{"stop_times.txt"},
+			wantErr: "missing required file stop_times.txt",
+		},
+		{
+			name:    "missing calendar",
+			remove:  []string{"calendar.txt"},
+			wantErr: "calendar.txt or calendar_dates.txt",
+		},
+		{
+			name: "missing required column",
+			override: map[string]string{
+				"stops.txt": "stop_id,stop_name,stop_lat\nS1,Blok M,-6.2440\n",
+			},
+			wantErr: "missing required column stop_lon",
+		},
+		{
+			name: "first stop without times",
+			override: map[string]string{
+				"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,,,S1,1\n",
+			},
+			wantErr: "first stop of trip T1 has no times",
+		},
+		{
+			name: "invalid time",
+			override: map[string]string{
+				"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:61:00,08:61:00,S1,1\n",
+			},
+			wantErr: "invalid time",
+		},
+		{
+			name: "invalid calendar date",
+			override: map[string]string{
+				"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+					"WD,1,1,1,1,1,0,0,2026-01-01,20261231\n",
+			},
+			wantErr: "invalid start_date",
+		},
+		{
+			name: "invalid exception type",
+			override: map[string]string{
+				"calendar_dates.txt": "service_id,date,exception_type\nWD,20261017,3\n",
+			},
+			wantErr: "invalid exception_type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]string, len(minimalFeed))
+			for name, content := range minimalFeed {
+				files[name] = content
+			}
+			for _, name := range tt.remove {
+				delete(files, name)
+			}
+			for name, content := range tt.override {
+				files[name] = content
+			}
+
+			archive := buildArchive(t, files)
+			feed, err := Parse(archive, archive.Size())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			tt.check(t, feed)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package idle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+)
+
+// Transition is emitted when a vehicle starts or stops idling.
+type Transition struct {
+	Started   bool
+	Ended     bool
+	VehicleID string
+	StartTime int64
+	EndTime   int64
+	Latitude  float64
+	Longitude float64
+}
+
+// Detector flags vehicles that stay within StationaryRadius meters for
+// longer than Threshold while the engine is (or may be) running.
+type Detector struct {
+	threshold        int64
+	stationaryRadius float64
+	requireIgnition  bool
+	mu               sync.Mutex
+	states           map[string]*vehicleState
+}
+
+type vehicleState struct {
+	anchorLatitude  float64
+	anchorLongitude float64
+	anchorTimestamp int64
+	lastTimestamp   int64
+	idling          bool
+}
+
+func NewDetector(threshold time.Duration, stationaryRadius float64, requireIgnition bool) *Detector {
+	return &Detector{
+		threshold:        int64(threshold.Seconds()),
+		stationaryRadius: stationaryRadius,
+		requireIgnition:  requireIgnition,
+		states:           make(map[string]*vehicleState),
+	}
+}
+
+// Process evaluates a location. inDepot suppresses idle detection, since
+// buses parked at a depot are expected to be stationary.
+func (d *Detector) Process(location *models.VehicleLocation, inDepot bool) *Transition {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.states[location.VehicleID]
+	if !ok {
+		d.states[location.VehicleID] = d.newState(location)
+		return nil
+	}
+
+	if location.Timestamp <= state.lastTimestamp {
+		return nil
+	}
+	state.lastTimestamp = location.Timestamp
+
+	distance := geofence.HaversineDistance(state.anchorLatitude, state.anchorLongitude, location.Latitude, location.Longitude)
+	stationary := distance <= d.stationaryRadius
+	engineOn := d.engineRunning(location)
+
+	if stationary && engineOn && !inDepot {
+		if !state.idling && location.Timestamp-state.anchorTimestamp >= d.threshold {
+			state.idling = true
+			return &Transition{
+				Started:   true,
+				VehicleID: location.VehicleID,
+				StartTime: state.anchorTimestamp,
+				Latitude:  state.anchorLatitude,
+				Longitude: state.anchorLongitude,
+			}
+		}
+		return nil
+	}
+
+	var transition *Transition
+	if state.idling {
+		transition = &Transition{
+			Ended:     true,
+			VehicleID: location.VehicleID,
+			StartTime: state.anchorTimestamp,
+			EndTime:   location.Timestamp,
+			Latitude:  state.anchorLatitude,
+			Longitude: state.anchorLongitude,
+		}
+	}
+
+	// Restart the stationary window from the current point
+	d.states[location.VehicleID] = d.newState(location)
+
+	return transition
+}
+
+func (d *Detector) newState(location *models.VehicleLocation) *vehicleState {
+	return &vehicleState{
+		anchorLatitude:  location.Latitude,
+		anchorLongitude: location.Longitude,
+		anchorTimestamp: location.Timestamp,
+		lastTimestamp:   location.Timestamp,
+	}
+}
+
+func (d *Detector) engineRunning(location *models.VehicleLocation) bool {
+	if location.Ignition == nil {
+		return !d.requireIgnition
+	}
+	return *location.Ignition
+}
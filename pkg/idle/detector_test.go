@@ -0,0 +1,114 @@
+package idle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+type sample struct {
+	metersNorth float64
+	timestamp   int64
+	ignition    *bool
+	inDepot     bool
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestDetectorProcess(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireIgnition bool
+		samples         []sample
+		// Transitions expected per sample: "" for none, "start" or "end"
+		want []string
+		// StartTime of the last transition
+		wantStartTime int64
+	}{
+		{
+			name:          "stationary past threshold starts idling",
+			samples:       []sample{{0, 0, nil, false}, {5, 200, nil, false}, {3, 300, nil, false}, {2, 400, nil, false}},
+			want:          []string{"", "", "start", ""},
+			wantStartTime: 0,
+		},
+		{
+			name:          "moving away ends idling",
+			samples:       []sample{{0, 0, nil, false}, {5, 300, nil, false}, {500, 360, nil, false}},
+			want:          []string{"", "start", "end"},
+			wantStartTime: 0,
+		},
+		{
+			name:    "movement restarts the stationary window",
+			samples: []sample{{0, 0, nil, false}, {200, 200, nil, false}, {205, 400, nil, false}, {200, 500, nil, false}},
+			want:    []string{"", "", "", "start"},
+			// Window restarted at the second sample
+			wantStartTime: 200,
+		},
+		{
+			name:    "depot suppresses idling",
+			samples: []sample{{0, 0, nil, true}, {5, 300, nil, true}, {3, 600, nil, true}},
+			want:    []string{"", "", ""},
+		},
+		{
+			name:            "unknown ignition is not idling when required",
+			requireIgnition: true,
+			samples:         []sample{{0, 0, nil, false}, {5, 300, nil, false}},
+			want:            []string{"", ""},
+		},
+		{
+			name:    "ignition off is not idling",
+			samples: []sample{{0, 0, boolPtr(false), false}, {5, 300, boolPtr(false), false}},
+			want:    []string{"", ""},
+		},
+		{
+			name:            "ignition on with required ignition idles",
+			requireIgnition: true,
+			samples:         []sample{{0, 0, boolPtr(true), false}, {5, 300, boolPtr(true), false}},
+			want:            []string{"", "start"},
+			wantStartTime:   0,
+		},
+		{
+			name:          "out-of-order point does not end idling",
+			samples:       []sample{{0, 0, nil, false}, {5, 300, nil, false}, {900, 200, nil, false}, {4, 360, nil, false}},
+			want:          []string{"", "start", "", ""},
+			wantStartTime: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector(5*time.Minute, 30, tt.requireIgnition)
+
+			var last *Transition
+			for i, s := range tt.samples {
+				transition := detector.Process(&models.VehicleLocation{
+					VehicleID: "B1234XYZ",
+					Latitude:  -6.2 + s.metersNorth/111195,
+					Longitude: 106.8,
+					Timestamp: s.timestamp,
+					Ignition:  s.ignition,
+				}, s.inDepot)
+
+				got := ""
+				switch {
+				case transition == nil:
+				case transition.Started:
+					got = "start"
+				case transition.Ended:
+					got = "end"
+				}
+				if got != tt.want[i] {
+					t.Fatalf("sample %d: transition %q, want %q", i, got, tt.want[i])
+				}
+				if transition != nil {
+					last = transition
+				}
+			}
+
+			if last != nil && last.StartTime != tt.wantStartTime {
+				t.Errorf("start time = %d, want %d", last.StartTime, tt.wantStartTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package mapmatch
+
+import (
+	"math"
+
+	"github.com/ivanadhi/transjakarta-fleet/pkg/roadnet"
+)
+
+const metersPerDegree = 111320.0
+
+// Match is a position snapped to the road network.
+type Match struct {
+	Latitude  float64
+	Longitude float64
+	RoadID    string
+	RoadName  string
+	// Offset is the distance from the raw position to the road, in meters
+	Offset float64
+}
+
+// Matcher snaps GPS positions to roads with a hidden Markov model: road
+// candidates near each position are the states, the distance to the road
+// gives the emission probability and the agreement between network and
+// straight-line distance of consecutive positions the transition
+// probability (Newson & Krumm, 2009).
+type Matcher struct {
+	Graph *roadnet.Graph
+	// SearchRadius is the farthest a road may be from a position, in meters
+	SearchRadius float64
+	// MaxCandidates limits the roads considered per position
+	MaxCandidates int
+	// Sigma is the GPS noise, in meters
+	Sigma float64
+	// Beta scales how much network distance may exceed straight-line
+	// distance, in meters
+	Beta float64
+	// MaxRouteFactor bounds the network distance searched between positions
+	// as a multiple of the straight-line distance
+	MaxRouteFactor float64
+	// Window is the number of positions kept per track
+	Window int
+	// MaxGap, in seconds, between positions restarts a track
+	MaxGap int64
+}
+
+type column struct {
+	lat, lng   float64
+	timestamp  int64
+	candidates []roadnet.Candidate
+	// score is the log probability of the best path ending in each candidate
+	score []float64
+	// back indexes the previous column's candidate on that path
+	back []int
+}
+
+// Track holds a vehicle's recent Viterbi lattice.
+type Track struct {
+	columns []column
+}
+
+// Match adds a position to the track and returns its snapped location, the
+// end of the most likely path through the window. It reports false when no
+// road is within the search radius.
+func (m *Matcher) Match(track *Track, lat, lng float64, timestamp int64) (Match, bool) {
+	candidates := m.Graph.Candidates(lat, lng, m.SearchRadius, m.MaxCandidates)
+	if len(candidates) == 0 {
+		track.columns = nil
+		return Match{}, false
+	}
+
+	col := column{
+		lat:        lat,
+		lng:        lng,
+		timestamp:  timestamp,
+		candidates: candidates,
+		score:      make([]float64, len(candidates)),
+		back:       make([]int, len(candidates)),
+	}
+
+	connected := false
+	if n := len(track.columns); n > 0 && timestamp-track.columns[n-1].timestamp <= m.MaxGap {
+		connected = m.step(&track.columns[n-1], &col)
+	}
+	if !connected {
+		// Start over: the chain is broken by a gap or an unreachable move
+		track.columns = nil
+		for j, c := range candidates {
+			col.score[j] = m.emission(c)
+			col.back[j] = -1
+		}
+	}
+
+	track.columns = append(track.columns, col)
+	if len(track.columns) > m.Window {
+		track.columns = track.columns[len(track.columns)-m.Window:]
+		track.columns[0].back = nil
+	}
+
+	return m.match(col.candidates[bestCandidate(col.score)]), true
+}
+
+// Path returns the snapped location of every position in the window along
+// the most likely path, oldest first. Earlier positions may differ from what
+// Match returned for them once later positions are known.
+func (m *Matcher) Path(track *Track) []Match {
+	n := len(track.columns)
+	if n == 0 {
+		return nil
+	}
+
+	best := bestCandidate(track.columns[n-1].score)
+	path := make([]Match, n)
+	for i := n - 1; i >= 0; i-- {
+		col := track.columns[i]
+		path[i] = m.match(col.candidates[best])
+		if col.back == nil || col.back[best] < 0 {
+			return path[i:]
+		}
+		best = col.back[best]
+	}
+	return path
+}
+
+// Len returns the number of positions in the track's window.
+func (t *Track) Len() int {
+	return len(t.columns)
+}
+
+// step fills the column's scores from the previous one. It reports false when
+// no candidate is reachable.
+func (m *Matcher) step(prev, col *column) bool {
+	straight := distance(prev.lat, prev.lng, col.lat, col.lng)
+	limit := straight*m.MaxRouteFactor + 2*m.SearchRadius
+
+	for j := range col.score {
+		col.score[j] = math.Inf(-1)
+		col.back[j] = -1
+	}
+
+	for i, from := range prev.candidates {
+		if math.IsInf(prev.score[i], -1) {
+			continue
+		}
+		fromEdge := m.Graph.Edge(from.Edge)
+		reachable := m.Graph.Distances(fromEdge.To, limit)
+
+		for j, to := range col.candidates {
+			route, ok := m.route(from, fromEdge, to, reachable)
+			if !ok || route > limit {
+				continue
+			}
+			score := prev.score[i] - math.Abs(route-straight)/m.Beta + m.emission(to)
+			if score > col.score[j] {
+				col.score[j] = score
+				col.back[j] = i
+			}
+		}
+	}
+
+	// Normalise so scores stay bounded on long tracks
+	best := math.Inf(-1)
+	for _, s := range col.score {
+		best = math.Max(best, s)
+	}
+	if math.IsInf(best, -1) {
+		return false
+	}
+	for j := range col.score {
+		col.score[j] -= best
+	}
+	return true
+}
+
+// route returns the network distance between two candidates. Small moves
+// backwards along the same edge are GPS noise and cost their length.
+func (m *Matcher) route(from roadnet.Candidate, fromEdge *roadnet.Edge, to roadnet.Candidate, reachable map[int]float64) (float64, bool) {
+	if from.Edge == to.Edge {
+		return math.Abs(to.Along - from.Along), true
+	}
+	toEdge := m.Graph.Edge(to.Edge)
+	d, ok := reachable[toEdge.From]
+	if !ok {
+		return 0, false
+	}
+	return fromEdge.Length - from.Along + d + to.Along, true
+}
+
+func (m *Matcher) match(c roadnet.Candidate) Match {
+	edge := m.Graph.Edge(c.Edge)
+	return Match{
+		Latitude:  c.Latitude,
+		Longitude: c.Longitude,
+		RoadID:    edge.RoadID,
+		RoadName:  edge.Name,
+		Offset:    c.Offset,
+	}
+}
+
+func bestCandidate(score []float64) int {
+	best := 0
+	for j := range score {
+		if score[j] > score[best] {
+			best = j
+		}
+	}
+	return best
+}
+
+func (m *Matcher) emission(c roadnet.Candidate) float64 {
+	z := c.Offset / m.Sigma
+	return -0.5 * z * z
+}
+
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	scale := math.Cos((lat1 + lat2) / 2 * math.Pi / 180)
+	return math.Hypot((lng2-lng1)*scale*metersPerDegree, (lat2-lat1)*metersPerDegree)
+}
@@ -0,0 +1,151 @@
+package mapmatch
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ivanadhi/transjakarta-fleet/pkg/roadnet"
+)
+
+const (
+	originLat = -6.2
+	originLng = 106.8
+)
+
+// offset returns the coordinate metersNorth and metersEast of the origin.
+func offset(metersNorth, metersEast float64) (float64, float64) {
+	lat := originLat + metersNorth/metersPerDegree
+	lng := originLng + metersEast/(metersPerDegree*math.Cos(originLat*math.Pi/180))
+	return lat, lng
+}
+
+// testGraph has a main road along the origin's latitude and a parallel,
+// unconnected service road 70 meters south of it, both 2 km long.
+func testGraph(t *testing.T) *roadnet.Graph {
+	t.Helper()
+
+	line := func(id string, metersNorth float64) string {
+		lat1, lng1 := offset(metersNorth, 0)
+		lat2, lng2 := offset(metersNorth, 2000)
+		return `{"type":"Feature","id":"` + id + `","properties":{},"geometry":{"type":"LineString","coordinates":[[` +
+			ftoa(lng1) + `,` + ftoa(lat1) + `],[` + ftoa(lng2) + `,` + ftoa(lat2) + `]]}}`
+	}
+
+	graph, err := roadnet.LoadGeoJSON(strings.NewReader(
+		`{"type":"FeatureCollection","features":[` + line("main", 0) + `,` + line("service", -70) + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func TestMatcherMatch(t *testing.T) {
+	type position struct {
+		north, east float64
+		timestamp   int64
+	}
+
+	tests := []struct {
+		name      string
+		positions []position
+		wantOK    bool
+		wantRoad  string
+		// Positions in the track window after the last one
+		wantLen int
+	}{
+		{
+			name:      "snaps to the nearest road",
+			positions: []position{{10, 100, 0}},
+			wantOK:    true,
+			wantRoad:  "main",
+			wantLen:   1,
+		},
+		{
+			name:      "empty candidate set resets the track",
+			positions: []position{{0, 100, 0}, {0, 200, 10}, {1000, 300, 20}},
+			wantOK:    false,
+			wantLen:   0,
+		},
+		{
+			name:      "track restarts after empty candidate set",
+			positions: []position{{0, 100, 0}, {1000, 200, 10}, {0, 300, 20}},
+			wantOK:    true,
+			wantRoad:  "main",
+			wantLen:   1,
+		},
+		{
+			name: "connected road wins over a nearer unreachable one",
+			// The last position is slightly nearer the service road
+			positions: []position{{0, 100, 0}, {0, 200, 10}, {-36, 300, 20}},
+			wantOK:    true,
+			wantRoad:  "main",
+			wantLen:   3,
+		},
+		{
+			name:      "unreachable move restarts the track",
+			positions: []position{{0, 100, 0}, {0, 200, 10}, {-68, 300, 20}},
+			wantOK:    true,
+			wantRoad:  "service",
+			wantLen:   1,
+		},
+		{
+			name:      "reporting gap restarts the track",
+			positions: []position{{0, 100, 0}, {0, 200, 10}, {0, 300, 100}},
+			wantOK:    true,
+			wantRoad:  "main",
+			wantLen:   1,
+		},
+		{
+			name: "window is bounded",
+			positions: []position{
+				{0, 100, 0}, {0, 200, 10}, {0, 300, 20}, {0, 400, 30},
+				{0, 500, 40}, {0, 600, 50}, {0, 700, 60},
+			},
+			wantOK:   true,
+			wantRoad: "main",
+			wantLen:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := &Matcher{
+				Graph:          testGraph(t),
+				SearchRadius:   50,
+				MaxCandidates:  4,
+				Sigma:          10,
+				Beta:           5,
+				MaxRouteFactor: 2,
+				Window:         5,
+				MaxGap:         60,
+			}
+			track := &Track{}
+
+			var match Match
+			var ok bool
+			for _, p := range tt.positions {
+				lat, lng := offset(p.north, p.east)
+				match, ok = matcher.Match(track, lat, lng, p.timestamp)
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && match.RoadID != tt.wantRoad {
+				t.Errorf("road = %q, want %q", match.RoadID, tt.wantRoad)
+			}
+			if track.Len() != tt.wantLen {
+				t.Errorf("track length = %d, want %d", track.Len(), tt.wantLen)
+			}
+			if path := matcher.Path(track); len(path) != tt.wantLen {
+				t.Errorf("path length = %d, want %d", len(path), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package odometer
+
+import (
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+)
+
+// Point is a reference position used to measure travelled distance.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+	Timestamp int64
+}
+
+// Decision tells the caller what to do with a new point.
+type Decision int
+
+const (
+	// Accept counts the distance and moves the reference to the new point
+	Accept Decision = iota
+	// Hold ignores the point (GPS jitter or out-of-order) and keeps the
+	// reference, see Keep
+	Hold
+	// Outlier ignores an implausible jump and keeps the reference
+	Outlier
+	// Gap moves the reference without counting distance (reporting gap)
+	Gap
+)
+
+// Filter decides which consecutive points contribute to the odometer.
+type Filter struct {
+	maxSpeedKmh float64
+	maxGap      int64
+	minDistance float64
+}
+
+func NewFilter(maxSpeedKmh float64, maxGap time.Duration, minDistance float64) *Filter {
+	return &Filter{
+		maxSpeedKmh: maxSpeedKmh,
+		maxGap:      int64(maxGap.Seconds()),
+		minDistance: minDistance,
+	}
+}
+
+// Evaluate compares the new point against the last accepted reference and
+// returns the decision together with the distance in meters to count.
+func (f *Filter) Evaluate(reference, current Point) (Decision, float64) {
+	elapsed := current.Timestamp - reference.Timestamp
+	if elapsed <= 0 {
+		return Hold, 0
+	}
+
+	if f.maxGap > 0 && elapsed > f.maxGap {
+		return Gap, 0
+	}
+
+	distance := geofence.HaversineDistance(reference.Latitude, reference.Longitude, current.Latitude, current.Longitude)
+	if distance < f.minDistance {
+		return Hold, 0
+	}
+
+	speedKmh := distance / float64(elapsed) * 3.6
+	if f.maxSpeedKmh > 0 && speedKmh > f.maxSpeedKmh {
+		return Outlier, 0
+	}
+
+	return Accept, distance
+}
+
+// Keep returns the reference to carry on with after a Hold. The position
+// stays, but a later timestamp moves the reference forward in time, so a bus
+// reporting while it waits longer than the max gap is not taken for one
+// that stopped reporting once it moves again.
+func Keep(reference, current Point) Point {
+	if current.Timestamp > reference.Timestamp {
+		reference.Timestamp = current.Timestamp
+	}
+	return reference
+}
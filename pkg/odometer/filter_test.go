@@ -0,0 +1,117 @@
+package odometer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFilterEvaluate(t *testing.T) {
+	// 0.001 degrees of latitude is about 111 meters
+	reference := Point{Latitude: -6.2, Longitude: 106.8, Timestamp: 1000}
+
+	tests := []struct {
+		name         string
+		filter       *Filter
+		current      Point
+		wantDecision Decision
+		wantMeters   float64
+	}{
+		{
+			name:         "plausible movement is accepted",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.199, Longitude: 106.8, Timestamp: 1030},
+			wantDecision: Accept,
+			wantMeters:   111,
+		},
+		{
+			name:         "jitter below min distance is held",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.19995, Longitude: 106.8, Timestamp: 1030},
+			wantDecision: Hold,
+		},
+		{
+			name:         "out-of-order point is held",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.199, Longitude: 106.8, Timestamp: 990},
+			wantDecision: Hold,
+		},
+		{
+			name:         "same timestamp is held",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.199, Longitude: 106.8, Timestamp: 1000},
+			wantDecision: Hold,
+		},
+		{
+			name:         "implausible jump is an outlier",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.19, Longitude: 106.8, Timestamp: 1010},
+			wantDecision: Outlier,
+		},
+		{
+			name:         "reporting gap moves the reference without distance",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.19, Longitude: 106.8, Timestamp: 1000 + 301},
+			wantDecision: Gap,
+		},
+		{
+			name:         "gap exactly at max gap is still measured",
+			filter:       NewFilter(120, 5*time.Minute, 10),
+			current:      Point{Latitude: -6.199, Longitude: 106.8, Timestamp: 1000 + 300},
+			wantDecision: Accept,
+			wantMeters:   111,
+		},
+		{
+			name:         "zero max gap and max speed disable those checks",
+			filter:       NewFilter(0, 0, 10),
+			current:      Point{Latitude: -6.1, Longitude: 106.8, Timestamp: 1000 + 86400},
+			wantDecision: Accept,
+			wantMeters:   11120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, meters := tt.filter.Evaluate(reference, tt.current)
+			if decision != tt.wantDecision {
+				t.Fatalf("decision = %d, want %d", decision, tt.wantDecision)
+			}
+			if math.Abs(meters-tt.wantMeters) > tt.wantMeters*0.01+0.5 {
+				t.Errorf("meters = %.1f, want about %.1f", meters, tt.wantMeters)
+			}
+		})
+	}
+}
+
+func TestFilterStationaryWait(t *testing.T) {
+	filter := NewFilter(120, 5*time.Minute, 10)
+	reference := Point{Latitude: -6.2, Longitude: 106.8, Timestamp: 1000}
+
+	// Ten minutes at a terminal, reporting every 30 seconds with jitter
+	for elapsed := int64(30); elapsed <= 600; elapsed += 30 {
+		current := Point{Latitude: -6.2 + 0.00002*float64(elapsed%60/30), Longitude: 106.8, Timestamp: 1000 + elapsed}
+		decision, _ := filter.Evaluate(reference, current)
+		if decision != Hold {
+			t.Fatalf("decision after %ds = %d, want Hold", elapsed, decision)
+		}
+		reference = Keep(reference, current)
+	}
+
+	if reference.Latitude != -6.2 || reference.Timestamp != 1600 {
+		t.Fatalf("reference = %+v, want the first position at 1600", reference)
+	}
+
+	// An out-of-order point does not move the reference back
+	if kept := Keep(reference, Point{Latitude: -6.2, Longitude: 106.8, Timestamp: 1500}); kept != reference {
+		t.Fatalf("reference = %+v, want %+v", kept, reference)
+	}
+
+	// Then the bus moves off 100 meters
+	decision, meters := filter.Evaluate(reference, Point{Latitude: -6.2 + 0.0009, Longitude: 106.8, Timestamp: 1630})
+	if decision != Accept {
+		t.Fatalf("decision = %d, want Accept", decision)
+	}
+	if math.Abs(meters-100) > 1 {
+		t.Errorf("meters = %.1f, want about 100", meters)
+	}
+}
@@ -0,0 +1,193 @@
+package roadnet
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+const (
+	metersPerDegree = 111320.0
+	// cellSize is the side of a spatial index cell, in degrees (about 110 m)
+	cellSize = 0.001
+)
+
+// Edge is a directed road segment between two consecutive vertices of a road.
+// Two-way roads have an edge in each direction.
+type Edge struct {
+	From, To int
+	RoadID   string
+	Name     string
+	Length   float64
+}
+
+// Candidate is the projection of a position onto an edge.
+type Candidate struct {
+	Edge int
+	// Along is the distance from the start of the edge, in meters
+	Along float64
+	// Offset is the distance from the position to the edge, in meters
+	Offset    float64
+	Latitude  float64
+	Longitude float64
+}
+
+type cell struct{ x, y int }
+
+// Graph is a road network built from a local extract, indexed for nearest
+// segment lookups and routing.
+type Graph struct {
+	lats, lngs []float64
+	edges      []Edge
+	// out[n] lists the edges leaving node n
+	out  [][]int
+	grid map[cell][]int
+}
+
+// Nodes returns the number of vertices in the graph.
+func (g *Graph) Nodes() int {
+	return len(g.lats)
+}
+
+// Edges returns the number of directed edges in the graph.
+func (g *Graph) Edges() int {
+	return len(g.edges)
+}
+
+// Edge returns the edge with the given index.
+func (g *Graph) Edge(i int) *Edge {
+	return &g.edges[i]
+}
+
+// Candidates returns the projections of (lat, lng) onto the edges within
+// radius meters, nearest first, at most limit of them (all when limit <= 0).
+func (g *Graph) Candidates(lat, lng, radius float64, limit int) []Candidate {
+	scale := math.Cos(lat * math.Pi / 180)
+	dLat := radius / metersPerDegree
+	dLng := radius / (metersPerDegree * math.Max(scale, 0.01))
+	minCell, maxCell := toCell(lat-dLat, lng-dLng), toCell(lat+dLat, lng+dLng)
+
+	seen := make(map[int]bool)
+	var candidates []Candidate
+	for x := minCell.x; x <= maxCell.x; x++ {
+		for y := minCell.y; y <= maxCell.y; y++ {
+			for _, i := range g.grid[cell{x, y}] {
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+
+				e := &g.edges[i]
+				t, offset := projectOnSegment(lat, lng, g.lats[e.From], g.lngs[e.From], g.lats[e.To], g.lngs[e.To])
+				if offset > radius {
+					continue
+				}
+				candidates = append(candidates, Candidate{
+					Edge:      i,
+					Along:     t * e.Length,
+					Offset:    offset,
+					Latitude:  g.lats[e.From] + t*(g.lats[e.To]-g.lats[e.From]),
+					Longitude: g.lngs[e.From] + t*(g.lngs[e.To]-g.lngs[e.From]),
+				})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Offset < candidates[j].Offset
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// Distances returns the network distance from node to every node reachable
+// within limit meters.
+func (g *Graph) Distances(node int, limit float64) map[int]float64 {
+	dist := map[int]float64{node: 0}
+	queue := &nodeQueue{{node: node}}
+
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(queueItem)
+		if current.dist > dist[current.node] {
+			continue // stale entry
+		}
+		for _, i := range g.out[current.node] {
+			e := &g.edges[i]
+			d := current.dist + e.Length
+			if d > limit {
+				continue
+			}
+			if known, ok := dist[e.To]; !ok || d < known {
+				dist[e.To] = d
+				heap.Push(queue, queueItem{node: e.To, dist: d})
+			}
+		}
+	}
+
+	return dist
+}
+
+func (g *Graph) index() {
+	g.out = make([][]int, len(g.lats))
+	g.grid = make(map[cell][]int)
+
+	for i, e := range g.edges {
+		g.out[e.From] = append(g.out[e.From], i)
+
+		a, b := toCell(g.lats[e.From], g.lngs[e.From]), toCell(g.lats[e.To], g.lngs[e.To])
+		for x := min(a.x, b.x); x <= max(a.x, b.x); x++ {
+			for y := min(a.y, b.y); y <= max(a.y, b.y); y++ {
+				g.grid[cell{x, y}] = append(g.grid[cell{x, y}], i)
+			}
+		}
+	}
+}
+
+func toCell(lat, lng float64) cell {
+	return cell{int(math.Floor(lng / cellSize)), int(math.Floor(lat / cellSize))}
+}
+
+// projectOnSegment returns the position of the point's projection along the
+// segment, as a fraction in [0, 1], and the distance to it in meters, using
+// an equirectangular approximation that holds over short segments.
+func projectOnSegment(lat, lng, lat1, lng1, lat2, lng2 float64) (float64, float64) {
+	scale := math.Cos(lat * math.Pi / 180)
+	ax, ay := (lng1-lng)*scale*metersPerDegree, (lat1-lat)*metersPerDegree
+	bx, by := (lng2-lng)*scale*metersPerDegree, (lat2-lat)*metersPerDegree
+
+	dx, dy := bx-ax, by-ay
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return 0, math.Hypot(ax, ay)
+	}
+
+	t := -(ax*dx + ay*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+
+	return t, math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+func segmentLength(lat1, lng1, lat2, lng2 float64) float64 {
+	scale := math.Cos((lat1 + lat2) / 2 * math.Pi / 180)
+	return math.Hypot((lng2-lng1)*scale*metersPerDegree, (lat2-lat1)*metersPerDegree)
+}
+
+type queueItem struct {
+	node int
+	dist float64
+}
+
+type nodeQueue []queueItem
+
+func (q nodeQueue) Len() int           { return len(q) }
+func (q nodeQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x any)        { *q = append(*q, x.(queueItem)) }
+func (q *nodeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
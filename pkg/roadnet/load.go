@@ -0,0 +1,269 @@
+package roadnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// drivable lists the OSM highway values buses may run on.
+var drivable = map[string]bool{
+	"motorway": true, "motorway_link": true,
+	"trunk": true, "trunk_link": true,
+	"primary": true, "primary_link": true,
+	"secondary": true, "secondary_link": true,
+	"tertiary": true, "tertiary_link": true,
+	"unclassified": true, "residential": true, "living_street": true,
+	"service": true, "road": true, "busway": true,
+}
+
+// Load reads a road network from an OSM PBF (.pbf) or GeoJSON (.geojson,
+// .json) extract.
+func Load(path string) (*Graph, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pbf":
+		return LoadOSM(path)
+	case ".geojson", ".json":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return LoadGeoJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported road network format: %s", path)
+	}
+}
+
+// LoadOSM reads the drivable ways of an OSM PBF extract. The file is scanned
+// twice, for ways and then for the nodes they use, to keep memory bounded on
+// large extracts.
+func LoadOSM(path string) (*Graph, error) {
+	type way struct {
+		id     osm.WayID
+		name   string
+		oneway int
+		nodes  []osm.NodeID
+	}
+
+	var ways []way
+	needed := make(map[osm.NodeID]int)
+	err := scanOSM(path, true, func(o osm.Object) {
+		w, ok := o.(*osm.Way)
+		if !ok || !drivable[w.Tags.Find("highway")] || len(w.Nodes) < 2 {
+			return
+		}
+		nodes := make([]osm.NodeID, len(w.Nodes))
+		for i, n := range w.Nodes {
+			nodes[i] = n.ID
+			needed[n.ID] = -1
+		}
+		ways = append(ways, way{id: w.ID, name: w.Tags.Find("name"), oneway: osmOneway(w.Tags), nodes: nodes})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ways: %w", err)
+	}
+
+	b := newBuilder()
+	err = scanOSM(path, false, func(o osm.Object) {
+		n, ok := o.(*osm.Node)
+		if !ok {
+			return
+		}
+		if _, ok := needed[n.ID]; ok {
+			needed[n.ID] = b.addNode(n.Lat, n.Lon)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodes: %w", err)
+	}
+
+	for _, w := range ways {
+		nodes := make([]int, 0, len(w.nodes))
+		for _, id := range w.nodes {
+			if n := needed[id]; n >= 0 {
+				nodes = append(nodes, n) // nodes outside the extract are dropped
+			}
+		}
+		b.addRoad("way/"+strconv.FormatInt(int64(w.id), 10), w.name, w.oneway, nodes)
+	}
+
+	return b.build(), nil
+}
+
+func scanOSM(path string, ways bool, fn func(osm.Object)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(context.Background(), f, 1)
+	defer scanner.Close()
+	scanner.SkipNodes = ways
+	scanner.SkipWays = !ways
+	scanner.SkipRelations = true
+
+	for scanner.Scan() {
+		fn(scanner.Object())
+	}
+	return scanner.Err()
+}
+
+// osmOneway returns 1 for roads open only in the way's direction, -1 for
+// roads open only against it and 0 for two-way roads.
+func osmOneway(tags osm.Tags) int {
+	switch tags.Find("oneway") {
+	case "yes", "true", "1":
+		return 1
+	case "-1", "reverse":
+		return -1
+	case "no", "false", "0":
+		return 0
+	}
+	if tags.Find("highway") == "motorway" || tags.Find("junction") == "roundabout" {
+		return 1
+	}
+	return 0
+}
+
+type geoJSONCollection struct {
+	Features []struct {
+		ID       any            `json:"id"`
+		Geometry geoJSONGeom    `json:"geometry"`
+		Props    map[string]any `json:"properties"`
+	} `json:"features"`
+}
+
+type geoJSONGeom struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LoadGeoJSON reads LineString and MultiLineString features of a GeoJSON
+// FeatureCollection as roads. Lines sharing an end or vertex coordinate are
+// connected. The road id is taken from the feature id or the road_id, osm_id
+// or id property; a oneway property of yes, true, 1 or -1 restricts
+// direction.
+func LoadGeoJSON(r io.Reader) (*Graph, error) {
+	var collection geoJSONCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	b := newBuilder()
+	for i, f := range collection.Features {
+		var lines [][][]float64
+		switch f.Geometry.Type {
+		case "LineString":
+			var line [][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &line); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			lines = append(lines, line)
+		case "MultiLineString":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &lines); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		default:
+			continue
+		}
+
+		roadID := featureID(f.ID, f.Props)
+		if roadID == "" {
+			roadID = "feature/" + strconv.Itoa(i)
+		}
+		name, _ := f.Props["name"].(string)
+		oneway := 0
+		switch v := fmt.Sprint(f.Props["oneway"]); v {
+		case "yes", "true", "1":
+			oneway = 1
+		case "-1":
+			oneway = -1
+		}
+
+		for _, line := range lines {
+			nodes := make([]int, 0, len(line))
+			for _, c := range line {
+				if len(c) < 2 {
+					return nil, fmt.Errorf("feature %d: invalid coordinate", i)
+				}
+				nodes = append(nodes, b.coordNode(c[1], c[0]))
+			}
+			b.addRoad(roadID, name, oneway, nodes)
+		}
+	}
+
+	return b.build(), nil
+}
+
+func featureID(id any, props map[string]any) string {
+	for _, v := range []any{id, props["road_id"], props["osm_id"], props["id"]} {
+		switch v := v.(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+type builder struct {
+	g      *Graph
+	coords map[[2]int64]int
+}
+
+func newBuilder() *builder {
+	return &builder{g: &Graph{}, coords: make(map[[2]int64]int)}
+}
+
+func (b *builder) addNode(lat, lng float64) int {
+	b.g.lats = append(b.g.lats, lat)
+	b.g.lngs = append(b.g.lngs, lng)
+	return len(b.g.lats) - 1
+}
+
+// coordNode returns the node at a coordinate, rounded to about a centimeter,
+// creating it on first use.
+func (b *builder) coordNode(lat, lng float64) int {
+	key := [2]int64{int64(lat * 1e7), int64(lng * 1e7)}
+	if n, ok := b.coords[key]; ok {
+		return n
+	}
+	n := b.addNode(lat, lng)
+	b.coords[key] = n
+	return n
+}
+
+func (b *builder) addRoad(roadID, name string, oneway int, nodes []int) {
+	g := b.g
+	for i := 1; i < len(nodes); i++ {
+		from, to := nodes[i-1], nodes[i]
+		if from == to {
+			continue
+		}
+		length := segmentLength(g.lats[from], g.lngs[from], g.lats[to], g.lngs[to])
+		if oneway >= 0 {
+			g.edges = append(g.edges, Edge{From: from, To: to, RoadID: roadID, Name: name, Length: length})
+		}
+		if oneway <= 0 {
+			g.edges = append(g.edges, Edge{From: to, To: from, RoadID: roadID, Name: name, Length: length})
+		}
+	}
+}
+
+func (b *builder) build() *Graph {
+	b.g.index()
+	return b.g
+}
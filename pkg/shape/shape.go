@@ -0,0 +1,113 @@
+package shape
+
+import (
+	"math"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+const metersPerDegree = 111320.0
+
+// Shape is a route polyline with cumulative distances, for locating vehicles
+// along a route.
+type Shape struct {
+	lats, lngs []float64
+	// cumulative[i] is the distance along the shape to point i, in meters
+	cumulative []float64
+}
+
+// New builds a shape from GTFS shape points ordered by sequence. Distances
+// are computed from the coordinates; shape_dist_traveled is not used since its
+// units vary between feeds.
+func New(points []*models.GTFSShapePoint) *Shape {
+	s := &Shape{
+		lats:       make([]float64, len(points)),
+		lngs:       make([]float64, len(points)),
+		cumulative: make([]float64, len(points)),
+	}
+
+	for i, p := range points {
+		s.lats[i], s.lngs[i] = p.Latitude, p.Longitude
+		if i > 0 {
+			s.cumulative[i] = s.cumulative[i-1] + segmentLength(s.lats[i-1], s.lngs[i-1], p.Latitude, p.Longitude)
+		}
+	}
+
+	return s
+}
+
+// Length returns the length of the shape in meters.
+func (s *Shape) Length() float64 {
+	if len(s.cumulative) == 0 {
+		return 0
+	}
+	return s.cumulative[len(s.cumulative)-1]
+}
+
+// Project returns the distance along the shape of the point closest to
+// (lat, lng), and the distance from the shape to the position. On shapes
+// that pass the same place twice, such as loops, a non-negative hint (the
+// previous distance along) prefers the solution nearest to it.
+func (s *Shape) Project(lat, lng, hint float64) (along, offset float64) {
+	if len(s.lats) == 0 {
+		return 0, math.Inf(1)
+	}
+	if len(s.lats) == 1 {
+		return 0, segmentLength(lat, lng, s.lats[0], s.lngs[0])
+	}
+
+	// Offsets within this slack of the best are considered equally good
+	const slack = 30.0
+
+	type candidate struct{ along, offset float64 }
+	var candidates []candidate
+	best := math.Inf(1)
+	for i := 1; i < len(s.lats); i++ {
+		t, d := projectOnSegment(lat, lng, s.lats[i-1], s.lngs[i-1], s.lats[i], s.lngs[i])
+		along := s.cumulative[i-1] + t*(s.cumulative[i]-s.cumulative[i-1])
+		candidates = append(candidates, candidate{along, d})
+		best = math.Min(best, d)
+	}
+
+	chosen := candidate{offset: math.Inf(1)}
+	for _, c := range candidates {
+		if c.offset > best+slack {
+			continue
+		}
+		switch {
+		case hint < 0:
+			if c.offset < chosen.offset {
+				chosen = c
+			}
+		case math.IsInf(chosen.offset, 1) || math.Abs(c.along-hint) < math.Abs(chosen.along-hint):
+			chosen = c
+		}
+	}
+
+	return chosen.along, chosen.offset
+}
+
+// projectOnSegment returns the position of the point's projection along the
+// segment, as a fraction in [0, 1], and the distance to it in meters, using
+// an equirectangular approximation that holds over short segments.
+func projectOnSegment(lat, lng, lat1, lng1, lat2, lng2 float64) (float64, float64) {
+	scale := math.Cos(lat * math.Pi / 180)
+	ax, ay := (lng1-lng)*scale*metersPerDegree, (lat1-lat)*metersPerDegree
+	bx, by := (lng2-lng)*scale*metersPerDegree, (lat2-lat)*metersPerDegree
+
+	dx, dy := bx-ax, by-ay
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return 0, math.Hypot(ax, ay)
+	}
+
+	t := -(ax*dx + ay*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+
+	return t, math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+func segmentLength(lat1, lng1, lat2, lng2 float64) float64 {
+	scale := math.Cos((lat1 + lat2) / 2 * math.Pi / 180)
+	return math.Hypot((lng2-lng1)*scale*metersPerDegree, (lat2-lat1)*metersPerDegree)
+}
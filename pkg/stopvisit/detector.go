@@ -0,0 +1,85 @@
+package stopvisit
+
+import (
+	"sync"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+)
+
+// Visit is a vehicle's stay inside a stop geofence. LastSeen is the last
+// timestamp observed inside, used as the departure time.
+type Visit struct {
+	VehicleID   string
+	Stop        *models.Geofence
+	ArrivalTime int64
+	LastSeen    int64
+}
+
+// Result reports a departure from the previous stop, an arrival at a new
+// one, or both when a vehicle moves between adjacent stops in one fix.
+type Result struct {
+	Departed *Visit
+	Arrived  *Visit
+}
+
+// Detector turns location streams into stop arrivals and departures. A
+// vehicle enters a stop within its radius but only leaves beyond radius plus
+// exitMargin, so GPS jitter at the boundary does not split a visit.
+type Detector struct {
+	exitMargin float64
+	mu         sync.Mutex
+	visits     map[string]*Visit
+	lastSeen   map[string]int64
+}
+
+func NewDetector(exitMargin float64) *Detector {
+	return &Detector{
+		exitMargin: exitMargin,
+		visits:     make(map[string]*Visit),
+		lastSeen:   make(map[string]int64),
+	}
+}
+
+// Process evaluates a location against the stop index.
+func (d *Detector) Process(location *models.VehicleLocation, stops *geofence.Index) *Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Drop out-of-order fixes
+	if location.Timestamp <= d.lastSeen[location.VehicleID] {
+		return nil
+	}
+	d.lastSeen[location.VehicleID] = location.Timestamp
+
+	current := d.visits[location.VehicleID]
+	if current != nil {
+		distance := geofence.Distance(current.Stop, location.Latitude, location.Longitude)
+		if distance <= float64(current.Stop.Radius)+d.exitMargin {
+			current.LastSeen = location.Timestamp
+			return nil
+		}
+	}
+
+	result := &Result{}
+	if current != nil {
+		result.Departed = current
+		delete(d.visits, location.VehicleID)
+	}
+
+	if stop, _ := stops.Nearest(location.Latitude, location.Longitude); stop != nil {
+		visit := &Visit{
+			VehicleID:   location.VehicleID,
+			Stop:        stop,
+			ArrivalTime: location.Timestamp,
+			LastSeen:    location.Timestamp,
+		}
+		d.visits[location.VehicleID] = visit
+		result.Arrived = visit
+	}
+
+	if result.Departed == nil && result.Arrived == nil {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package trips
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+	"github.com/ivanadhi/transjakarta-fleet/pkg/geofence"
+)
+
+// Segmenter splits a vehicle's location stream into stops and trips.
+// A stop is a stay within StopRadius meters for at least StopMinDuration,
+// a trip is the movement between two stops.
+type Segmenter struct {
+	stopRadius      float64
+	stopMinDuration int64
+	mu              sync.Mutex
+	states          map[string]*vehicleState
+}
+
+// Result holds the stop and trip closed by a single location, if any.
+type Result struct {
+	Stop *models.VehicleStop
+	Trip *models.Trip
+}
+
+type point struct {
+	latitude  float64
+	longitude float64
+	timestamp int64
+}
+
+type stayCandidate struct {
+	anchor       point
+	last         point
+	sumLatitude  float64
+	sumLongitude float64
+	count        int
+	// trip distance accumulated before the stay started
+	distanceBefore float64
+	maxSpeedBefore float64
+}
+
+type openTrip struct {
+	start    point
+	distance float64
+	maxSpeed float64
+}
+
+type vehicleState struct {
+	last *point
+	stay *stayCandidate
+	trip *openTrip
+}
+
+func NewSegmenter(stopRadius float64, stopMinDuration time.Duration) *Segmenter {
+	return &Segmenter{
+		stopRadius:      stopRadius,
+		stopMinDuration: int64(stopMinDuration.Seconds()),
+		states:          make(map[string]*vehicleState),
+	}
+}
+
+// Process feeds one location into the segmenter. Locations older than the
+// last processed one for the same vehicle are ignored.
+func (s *Segmenter) Process(location *models.VehicleLocation) *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[location.VehicleID]
+	if !ok {
+		state = &vehicleState{}
+		s.states[location.VehicleID] = state
+	}
+
+	p := point{
+		latitude:  location.Latitude,
+		longitude: location.Longitude,
+		timestamp: location.Timestamp,
+	}
+
+	if state.last != nil && p.timestamp <= state.last.timestamp {
+		return nil
+	}
+
+	// Accumulate movement since the previous point
+	if state.last != nil && state.trip != nil {
+		distance := geofence.HaversineDistance(state.last.latitude, state.last.longitude, p.latitude, p.longitude)
+		state.trip.distance += distance
+
+		speed := speedKmh(distance, p.timestamp-state.last.timestamp)
+		if speed > state.trip.maxSpeed {
+			state.trip.maxSpeed = speed
+		}
+	}
+	if state.trip == nil {
+		// The stream starts with movement until a first stop is seen
+		state.trip = &openTrip{start: p}
+	}
+	state.last = &p
+
+	// Extend current stay candidate while within radius of its anchor
+	if state.stay != nil {
+		distance := geofence.HaversineDistance(state.stay.anchor.latitude, state.stay.anchor.longitude, p.latitude, p.longitude)
+		if distance <= s.stopRadius {
+			state.stay.last = p
+			state.stay.sumLatitude += p.latitude
+			state.stay.sumLongitude += p.longitude
+			state.stay.count++
+			return nil
+		}
+	}
+
+	var result *Result
+	if state.stay != nil && state.stay.last.timestamp-state.stay.anchor.timestamp >= s.stopMinDuration {
+		result = s.closeStop(location.VehicleID, state, p)
+	}
+
+	// Start a new stay candidate anchored at the current point
+	state.stay = &stayCandidate{
+		anchor:         p,
+		last:           p,
+		sumLatitude:    p.latitude,
+		sumLongitude:   p.longitude,
+		count:          1,
+		distanceBefore: state.trip.distance,
+		maxSpeedBefore: state.trip.maxSpeed,
+	}
+
+	return result
+}
+
+// Reset drops the in-memory state of a vehicle.
+func (s *Segmenter) Reset(vehicleID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, vehicleID)
+}
+
+func (s *Segmenter) closeStop(vehicleID string, state *vehicleState, current point) *Result {
+	stay := state.stay
+	stopLatitude := stay.sumLatitude / float64(stay.count)
+	stopLongitude := stay.sumLongitude / float64(stay.count)
+
+	stop := &models.VehicleStop{
+		VehicleID:       vehicleID,
+		StartTime:       stay.anchor.timestamp,
+		EndTime:         stay.last.timestamp,
+		Latitude:        stopLatitude,
+		Longitude:       stopLongitude,
+		DurationSeconds: stay.last.timestamp - stay.anchor.timestamp,
+	}
+
+	result := &Result{Stop: stop}
+
+	// Close the trip that led into this stop
+	trip := state.trip
+	if stay.anchor.timestamp > trip.start.timestamp {
+		duration := stay.anchor.timestamp - trip.start.timestamp
+		result.Trip = &models.Trip{
+			VehicleID:       vehicleID,
+			StartTime:       trip.start.timestamp,
+			EndTime:         stay.anchor.timestamp,
+			StartLatitude:   trip.start.latitude,
+			StartLongitude:  trip.start.longitude,
+			EndLatitude:     stopLatitude,
+			EndLongitude:    stopLongitude,
+			DistanceMeters:  stay.distanceBefore,
+			DurationSeconds: duration,
+			MaxSpeedKmh:     stay.maxSpeedBefore,
+			AvgSpeedKmh:     speedKmh(stay.distanceBefore, duration),
+		}
+	}
+
+	// Next trip departs from the stop; count the leg to the current point
+	departure := point{latitude: stopLatitude, longitude: stopLongitude, timestamp: stay.last.timestamp}
+	distance := geofence.HaversineDistance(stay.last.latitude, stay.last.longitude, current.latitude, current.longitude)
+	state.trip = &openTrip{
+		start:    departure,
+		distance: distance,
+		maxSpeed: speedKmh(distance, current.timestamp-stay.last.timestamp),
+	}
+
+	return result
+}
+
+func speedKmh(distanceMeters float64, seconds int64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return distanceMeters / float64(seconds) * 3.6
+}
@@ -0,0 +1,127 @@
+package trips
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ivanadhi/transjakarta-fleet/internal/models"
+)
+
+// north returns a location metersNorth of a fixed origin in Jakarta.
+func north(metersNorth float64, timestamp int64) *models.VehicleLocation {
+	return &models.VehicleLocation{
+		VehicleID: "B1234XYZ",
+		Latitude:  -6.2 + metersNorth/111195,
+		Longitude: 106.8,
+		Timestamp: timestamp,
+	}
+}
+
+func TestSegmenterProcess(t *testing.T) {
+	tests := []struct {
+		name      string
+		locations []*models.VehicleLocation
+		// Index of the location closing a stop, or -1 when none does
+		stopAt           int
+		wantStopDuration int64
+		wantTrip         bool
+		wantTripDistance float64
+	}{
+		{
+			name: "stay long enough closes stop and trip",
+			locations: []*models.VehicleLocation{
+				north(0, 0),
+				north(500, 60),
+				north(1000, 120),
+				north(1010, 300),
+				north(1005, 400),
+				north(1500, 460),
+			},
+			stopAt:           5,
+			wantStopDuration: 280,
+			wantTrip:         true,
+			wantTripDistance: 1000,
+		},
+		{
+			name: "short stay is not a stop",
+			locations: []*models.VehicleLocation{
+				north(0, 0),
+				north(500, 60),
+				north(1000, 120),
+				north(1010, 200),
+				north(1005, 250),
+				north(1500, 310),
+			},
+			stopAt: -1,
+		},
+		{
+			name: "stop at stream start has no trip",
+			locations: []*models.VehicleLocation{
+				north(0, 0),
+				north(10, 120),
+				north(5, 240),
+				north(600, 300),
+			},
+			stopAt:           3,
+			wantStopDuration: 240,
+		},
+		{
+			name: "out-of-order point is ignored",
+			locations: []*models.VehicleLocation{
+				north(0, 0),
+				north(500, 60),
+				north(1000, 120),
+				north(1010, 300),
+				north(5000, 200),
+				north(1005, 400),
+				north(1500, 460),
+			},
+			stopAt:           6,
+			wantStopDuration: 280,
+			wantTrip:         true,
+			wantTripDistance: 1000,
+		},
+		{
+			name: "repeated timestamp is ignored",
+			locations: []*models.VehicleLocation{
+				north(0, 0),
+				north(10, 120),
+				north(800, 120),
+				north(5, 240),
+				north(600, 300),
+			},
+			stopAt:           4,
+			wantStopDuration: 240,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segmenter := NewSegmenter(50, 3*time.Minute)
+
+			for i, location := range tt.locations {
+				result := segmenter.Process(location)
+				if i != tt.stopAt {
+					if result != nil {
+						t.Fatalf("location %d: got %+v, want no result", i, result)
+					}
+					continue
+				}
+
+				if result == nil || result.Stop == nil {
+					t.Fatalf("location %d: got no stop", i)
+				}
+				if result.Stop.DurationSeconds != tt.wantStopDuration {
+					t.Errorf("stop duration = %d, want %d", result.Stop.DurationSeconds, tt.wantStopDuration)
+				}
+				if (result.Trip != nil) != tt.wantTrip {
+					t.Fatalf("trip = %+v, want trip %v", result.Trip, tt.wantTrip)
+				}
+				if result.Trip != nil && math.Abs(result.Trip.DistanceMeters-tt.wantTripDistance) > 5 {
+					t.Errorf("trip distance = %.1f, want about %.1f", result.Trip.DistanceMeters, tt.wantTripDistance)
+				}
+			}
+		})
+	}
+}